@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// TranscriptionModel converts speech to text.
+type TranscriptionModel interface {
+	GetModelId() string
+	Transcribe(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error)
+}
+
+// SpeechModel converts text to speech.
+type SpeechModel interface {
+	GetModelId() string
+	Speak(ctx context.Context, request *SpeechRequest) (*SpeechResponse, error)
+}
+
+func NewTranscriptionModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (TranscriptionModel, error) {
+	var opts aigc.ModelOptions
+
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	switch modelId {
+	case Models.OpenAIWhisper1:
+		if opts.VendorId == aigc.Vendors.Microsoft {
+			return newAzureOpenAITranscriptionModel(string(modelId), &opts)
+		}
+		if opts.VendorId == aigc.Vendors.OpenAI {
+			return newOpenAITranscriptionModel(string(modelId), &opts)
+		}
+		if opts.VendorId == "" {
+			opts.VendorId = aigc.Vendors.OpenAI
+			return newOpenAITranscriptionModel(string(modelId), &opts)
+		}
+	}
+
+	if opts.VendorId != "" {
+		return nil, fmt.Errorf("model can not be created, vendor:%s, model: %s", opts.VendorId, modelId)
+	}
+	return nil, fmt.Errorf("model can not be created: %s", modelId)
+}
+
+func NewSpeechModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (SpeechModel, error) {
+	var opts aigc.ModelOptions
+
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	switch modelId {
+	case Models.OpenAITts1, Models.OpenAITts1HD:
+		if opts.VendorId == aigc.Vendors.Microsoft {
+			return newAzureOpenAISpeechModel(string(modelId), &opts)
+		}
+		if opts.VendorId == aigc.Vendors.OpenAI {
+			return newOpenAISpeechModel(string(modelId), &opts)
+		}
+		if opts.VendorId == "" {
+			opts.VendorId = aigc.Vendors.OpenAI
+			return newOpenAISpeechModel(string(modelId), &opts)
+		}
+	}
+
+	if opts.VendorId != "" {
+		return nil, fmt.Errorf("model can not be created, vendor:%s, model: %s", opts.VendorId, modelId)
+	}
+	return nil, fmt.Errorf("model can not be created: %s", modelId)
+}
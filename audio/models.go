@@ -0,0 +1,19 @@
+package audio
+
+import "github.com/Pooh-Mucho/go-aigc"
+
+var Models = struct {
+	// OpenAI speech-to-text models
+	OpenAIWhisper1 aigc.ModelId
+
+	// OpenAI text-to-speech models
+	OpenAITts1   aigc.ModelId
+	OpenAITts1HD aigc.ModelId
+}{
+	// OpenAI speech-to-text models
+	OpenAIWhisper1: "whisper-1",
+
+	// OpenAI text-to-speech models
+	OpenAITts1:   "tts-1",
+	OpenAITts1HD: "tts-1-hd",
+}
@@ -0,0 +1,627 @@
+package audio
+
+// OpenAI documentation:
+// https://platform.openai.com/docs/api-reference/audio
+
+// Azure OpenAI documentation:
+// https://learn.microsoft.com/en-us/azure/ai-services/openai/reference#transcriptions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	azureOpenAIDefaultApiVersion       = "2024-06-01"
+	azureOpenAIDefaultSpeechApiVersion = "2024-05-01-preview"
+
+	openaiTranscriptionEndpoint = "https://api.openai.com/v1/audio/transcriptions"
+	openaiSpeechEndpoint        = "https://api.openai.com/v1/audio/speech"
+
+	openaiDefaultVoice = "alloy"
+)
+
+// openaiTranscriptionRequest is sent as multipart/form-data.
+type openaiTranscriptionRequest struct {
+	// OpenAI should set Model. Azure API should not.
+	Model string
+	// The audio file object (not file name) to transcribe.
+	File     []byte
+	FileName string
+	// The language of the input audio in ISO-639-1 format.
+	Language string
+	// An optional text to guide the model's style or continue a previous
+	// audio segment. The prompt should match the audio language.
+	Prompt string
+	// The sampling temperature, between 0 and 1.
+	Temperature *float64
+	// "json" | "text" | "srt" | "verbose_json" | "vtt"
+	ResponseFormat string
+	// "word" | "segment", response_format must be set verbose_json.
+	TimestampGranularities []string
+
+	// Temperature value, for Temperature pointer pointed to
+	temperatureValue float64
+}
+
+type openaiTranscriptionResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language,omitempty"` // "english"
+	Duration float64 `json:"duration,omitempty"` // 8.470000267028809
+	Segments []struct {
+		Id    int     `json:"id"`
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments,omitempty"`
+	Words []struct {
+		Word  string  `json:"word"`
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+	} `json:"words,omitempty"`
+}
+
+type openaiSpeechRequest struct {
+	// OpenAI should set Model. Azure API should not.
+	Model string `json:"model,omitempty"`
+	// The text to generate audio for. The maximum length is 4096 characters.
+	Input string `json:"input"`
+	// "alloy" | "echo" | "fable" | "onyx" | "nova" | "shimmer"
+	Voice string `json:"voice"`
+	// "mp3" | "opus" | "aac" | "flac" | "wav" | "pcm"
+	ResponseFormat string `json:"response_format,omitempty"`
+	// The speed of the generated audio, from 0.25 to 4.0.
+	Speed *float64 `json:"speed,omitempty"`
+
+	// Speed value, for Speed pointer pointed to
+	speedValue float64
+}
+
+type openaiTranscriptionModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+type azureOpenAITranscriptionModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	ApiVersion  string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+type openaiSpeechModel struct {
+	ModelId    string
+	Endpoint   string
+	ApiKey     string
+	Proxy      string
+	Retries    int
+	RequestLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+type azureOpenAISpeechModel struct {
+	ModelId    string
+	Endpoint   string
+	ApiKey     string
+	ApiVersion string
+	Proxy      string
+	Retries    int
+	RequestLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func (r *openaiTranscriptionRequest) load(request *TranscriptionRequest) error {
+	if len(request.Audio) == 0 {
+		return errors.New("[openaiTranscriptionRequest.load] audio is empty")
+	}
+	if request.FileName == "" {
+		return errors.New("[openaiTranscriptionRequest.load] file name is empty")
+	}
+
+	r.File = request.Audio
+	r.FileName = request.FileName
+	r.Language = request.Language
+	r.Prompt = request.Prompt
+
+	if request.Temperature.Valid {
+		r.temperatureValue = request.Temperature.Value
+		r.Temperature = &r.temperatureValue
+	} else {
+		r.temperatureValue = 0
+		r.Temperature = nil
+	}
+
+	r.ResponseFormat = string(request.Format)
+	r.TimestampGranularities = nil
+	if len(request.TimestampGranularities) > 0 {
+		if r.ResponseFormat == "" {
+			r.ResponseFormat = string(TranscriptionVerboseJson)
+		}
+		if r.ResponseFormat != string(TranscriptionVerboseJson) {
+			return fmt.Errorf("[openaiTranscriptionRequest.load] timestamps are not supported in %s format",
+				r.ResponseFormat)
+		}
+		for _, granularity := range request.TimestampGranularities {
+			r.TimestampGranularities = append(r.TimestampGranularities, string(granularity))
+		}
+	}
+	return nil
+}
+
+func (r *openaiTranscriptionRequest) fields() []aigc.MultipartField {
+	var fields = []aigc.MultipartField{{Name: "file", FileName: r.FileName, Data: r.File}}
+	if r.Model != "" {
+		fields = append(fields, aigc.MultipartField{Name: "model", Value: r.Model})
+	}
+	if r.Language != "" {
+		fields = append(fields, aigc.MultipartField{Name: "language", Value: r.Language})
+	}
+	if r.Prompt != "" {
+		fields = append(fields, aigc.MultipartField{Name: "prompt", Value: r.Prompt})
+	}
+	if r.Temperature != nil {
+		fields = append(fields, aigc.MultipartField{
+			Name:  "temperature",
+			Value: strconv.FormatFloat(*r.Temperature, 'f', -1, 64),
+		})
+	}
+	if r.ResponseFormat != "" {
+		fields = append(fields, aigc.MultipartField{Name: "response_format", Value: r.ResponseFormat})
+	}
+	for _, granularity := range r.TimestampGranularities {
+		fields = append(fields, aigc.MultipartField{Name: "timestamp_granularities[]", Value: granularity})
+	}
+	return fields
+}
+
+// textFormat reports whether the response is plain text instead of json.
+func (r *openaiTranscriptionRequest) textFormat() bool {
+	switch TranscriptionFormat(r.ResponseFormat) {
+	case TranscriptionText, TranscriptionSrt, TranscriptionVtt:
+		return true
+	}
+	return false
+}
+
+func (r *openaiTranscriptionResponse) dump(response *TranscriptionResponse) error {
+	response.Text = r.Text
+	response.Language = r.Language
+	response.Duration = r.Duration
+	response.Segments = nil
+	response.Words = nil
+	for _, segment := range r.Segments {
+		response.Segments = append(response.Segments, TranscriptionSegment{
+			Id:    segment.Id,
+			Start: segment.Start,
+			End:   segment.End,
+			Text:  segment.Text,
+		})
+	}
+	for _, word := range r.Words {
+		response.Words = append(response.Words, TranscriptionWord{Start: word.Start, End: word.End, Word: word.Word})
+	}
+	return nil
+}
+
+func (r *openaiSpeechRequest) load(request *SpeechRequest) error {
+	if request.Input == "" {
+		return errors.New("[openaiSpeechRequest.load] input is empty")
+	}
+
+	r.Input = request.Input
+	r.Voice = request.Voice
+	if r.Voice == "" {
+		r.Voice = openaiDefaultVoice
+	}
+	r.ResponseFormat = string(request.Format)
+
+	if request.Speed.Valid {
+		if request.Speed.Value < 0.25 || request.Speed.Value > 4.0 {
+			return fmt.Errorf("[openaiSpeechRequest.load] invalid speed %v", request.Speed.Value)
+		}
+		r.speedValue = request.Speed.Value
+		r.Speed = &r.speedValue
+	} else {
+		r.speedValue = 0
+		r.Speed = nil
+	}
+	return nil
+}
+
+// transcribe sends the multipart request and reads the response. The audio
+// file is not passed to the request log.
+func transcribe(ctx context.Context, client *aigc.HttpClient, modelUrl string, header http.Header,
+	request *openaiTranscriptionRequest, requestLog func([]byte), responseLog func([]byte)) (*TranscriptionResponse, error) {
+	var err error
+	var contentType string
+	var requestBody *bytes.Buffer
+	var responseBody *bytes.Buffer
+	var httpRequest *http.Request
+	var httpResponse *http.Response
+
+	requestBody = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(requestBody)
+
+	var fields = request.fields()
+	contentType, err = aigc.EncodeMultipart(requestBody, fields...)
+	if err != nil {
+		return nil, fmt.Errorf("[transcribe] %w", err)
+	}
+	if requestLog != nil {
+		// The audio is replaced by a placeholder in the request log
+		var logBody = aigc.AllocBuffer()
+		defer aigc.FreeBuffer(logBody)
+		fields[0].Data = []byte(fmt.Sprintf("<%d bytes of audio>", len(request.File)))
+		_, err = aigc.EncodeMultipart(logBody, fields...)
+		if err != nil {
+			return nil, fmt.Errorf("[transcribe] %w", err)
+		}
+		requestLog(logBody.Bytes())
+	}
+
+	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, modelUrl, bytes.NewReader(requestBody.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("[transcribe] create http request %w", err)
+	}
+	for key, values := range header {
+		httpRequest.Header[key] = values
+	}
+	httpRequest.Header.Set("Content-Type", contentType)
+
+	httpResponse, err = client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("[transcribe] do http request %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[transcribe] http error %s %s",
+			httpResponse.Status, aigc.HttpResponseText(httpResponse))
+	}
+
+	responseBody = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(responseBody)
+	_, err = io.Copy(responseBody, httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[transcribe] read http response %w", err)
+	}
+	if responseLog != nil {
+		responseLog(responseBody.Bytes())
+	}
+
+	var response = TranscriptionResponse{}
+	if request.textFormat() {
+		response.Text = responseBody.String()
+		return &response, nil
+	}
+
+	var openaiResponse openaiTranscriptionResponse
+	err = aigc.DecodeJson(responseBody, &openaiResponse)
+	if err != nil {
+		return nil, fmt.Errorf("[transcribe] %w", err)
+	}
+	err = openaiResponse.dump(&response)
+	if err != nil {
+		return nil, fmt.Errorf("[transcribe] %w", err)
+	}
+	return &response, nil
+}
+
+// speak sends the json request and reads the audio. The audio is not passed
+// to the response log.
+func speak(ctx context.Context, client *aigc.HttpClient, modelUrl string, header http.Header,
+	request *openaiSpeechRequest, requestLog func([]byte)) (*SpeechResponse, error) {
+	var err error
+	var requestJson *bytes.Buffer
+	var httpRequest *http.Request
+	var httpResponse *http.Response
+
+	requestJson = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(requestJson)
+
+	err = aigc.EncodeJson(requestJson, request)
+	if err != nil {
+		return nil, fmt.Errorf("[speak] %w", err)
+	}
+	if requestLog != nil {
+		requestLog(requestJson.Bytes())
+	}
+
+	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, modelUrl, bytes.NewReader(requestJson.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("[speak] create http request %w", err)
+	}
+	for key, values := range header {
+		httpRequest.Header[key] = values
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err = client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("[speak] do http request %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[speak] http error %s %s",
+			httpResponse.Status, aigc.HttpResponseText(httpResponse))
+	}
+
+	var response = SpeechResponse{Format: SpeechFormat(request.ResponseFormat)}
+	if response.Format == "" {
+		response.Format = SpeechMp3
+	}
+	response.Audio, err = io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[speak] read http response %w", err)
+	}
+	return &response, nil
+}
+
+func azureOpenAIUrl(endpoint string, deployment string, path string, apiVersion string) string {
+	var builder strings.Builder
+
+	builder.WriteString(endpoint)
+	if !strings.HasSuffix(endpoint, "/") {
+		builder.WriteByte('/')
+	}
+	builder.WriteString("openai/deployments/")
+	builder.WriteString(deployment)
+	builder.WriteString(path)
+	builder.WriteString("?api-version=")
+	builder.WriteString(apiVersion)
+	return builder.String()
+}
+
+func (m *openaiTranscriptionModel) getModelUrl() string {
+	if m.Endpoint == "" {
+		return openaiTranscriptionEndpoint
+	}
+	return m.Endpoint
+}
+
+func (m *openaiTranscriptionModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *openaiTranscriptionModel) Transcribe(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	var err error
+	var response *TranscriptionResponse
+	var openaiRequest openaiTranscriptionRequest
+
+	err = openaiRequest.load(request)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiTranscriptionModel.Transcribe] %w", err)
+	}
+	openaiRequest.Model = m.ModelId
+
+	var header = http.Header{}
+	header.Set("Authorization", "Bearer "+m.ApiKey)
+
+	response, err = transcribe(ctx, &m.client, m.getModelUrl(), header, &openaiRequest, m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiTranscriptionModel.Transcribe] %w", err)
+	}
+	return response, nil
+}
+
+func (m *azureOpenAITranscriptionModel) getModelUrl() string {
+	var apiVersion = m.ApiVersion
+	if apiVersion == "" {
+		apiVersion = azureOpenAIDefaultApiVersion
+	}
+	return azureOpenAIUrl(m.Endpoint, m.ModelId, "/audio/transcriptions", apiVersion)
+}
+
+func (m *azureOpenAITranscriptionModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *azureOpenAITranscriptionModel) Transcribe(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	var err error
+	var response *TranscriptionResponse
+	var openaiRequest openaiTranscriptionRequest
+
+	err = openaiRequest.load(request)
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAITranscriptionModel.Transcribe] %w", err)
+	}
+
+	var header = http.Header{}
+	header.Set("api-key", m.ApiKey)
+
+	response, err = transcribe(ctx, &m.client, m.getModelUrl(), header, &openaiRequest, m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAITranscriptionModel.Transcribe] %w", err)
+	}
+	return response, nil
+}
+
+func (m *openaiSpeechModel) getModelUrl() string {
+	if m.Endpoint == "" {
+		return openaiSpeechEndpoint
+	}
+	return m.Endpoint
+}
+
+func (m *openaiSpeechModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *openaiSpeechModel) Speak(ctx context.Context, request *SpeechRequest) (*SpeechResponse, error) {
+	var err error
+	var response *SpeechResponse
+	var openaiRequest openaiSpeechRequest
+
+	err = openaiRequest.load(request)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiSpeechModel.Speak] %w", err)
+	}
+	openaiRequest.Model = m.ModelId
+
+	var header = http.Header{}
+	header.Set("Authorization", "Bearer "+m.ApiKey)
+
+	response, err = speak(ctx, &m.client, m.getModelUrl(), header, &openaiRequest, m.RequestLog)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiSpeechModel.Speak] %w", err)
+	}
+	return response, nil
+}
+
+func (m *azureOpenAISpeechModel) getModelUrl() string {
+	var apiVersion = m.ApiVersion
+	if apiVersion == "" {
+		apiVersion = azureOpenAIDefaultSpeechApiVersion
+	}
+	return azureOpenAIUrl(m.Endpoint, m.ModelId, "/audio/speech", apiVersion)
+}
+
+func (m *azureOpenAISpeechModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *azureOpenAISpeechModel) Speak(ctx context.Context, request *SpeechRequest) (*SpeechResponse, error) {
+	var err error
+	var response *SpeechResponse
+	var openaiRequest openaiSpeechRequest
+
+	err = openaiRequest.load(request)
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAISpeechModel.Speak] %w", err)
+	}
+
+	var header = http.Header{}
+	header.Set("api-key", m.ApiKey)
+
+	response, err = speak(ctx, &m.client, m.getModelUrl(), header, &openaiRequest, m.RequestLog)
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAISpeechModel.Speak] %w", err)
+	}
+	return response, nil
+}
+
+func newOpenAITranscriptionModel(modelId string, opts *aigc.ModelOptions) (*openaiTranscriptionModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("openai api key is required")
+	}
+	if opts.ApiVersion != "" {
+		return nil, errors.New("openai api version is not supported")
+	}
+
+	var model = &openaiTranscriptionModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
+
+func newAzureOpenAITranscriptionModel(modelId string, opts *aigc.ModelOptions) (*azureOpenAITranscriptionModel, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("azure endpoint is required")
+	}
+	if opts.ApiKey == "" {
+		return nil, errors.New("azure api key is required")
+	}
+
+	var model = &azureOpenAITranscriptionModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		ApiVersion:  opts.ApiVersion,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
+
+func newOpenAISpeechModel(modelId string, opts *aigc.ModelOptions) (*openaiSpeechModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("openai api key is required")
+	}
+	if opts.ApiVersion != "" {
+		return nil, errors.New("openai api version is not supported")
+	}
+
+	var model = &openaiSpeechModel{
+		ModelId:    modelId,
+		Endpoint:   opts.Endpoint,
+		ApiKey:     opts.ApiKey,
+		Proxy:      opts.Proxy,
+		Retries:    opts.Retries,
+		RequestLog: opts.RequestLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
+
+func newAzureOpenAISpeechModel(modelId string, opts *aigc.ModelOptions) (*azureOpenAISpeechModel, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("azure endpoint is required")
+	}
+	if opts.ApiKey == "" {
+		return nil, errors.New("azure api key is required")
+	}
+
+	var model = &azureOpenAISpeechModel{
+		ModelId:    modelId,
+		Endpoint:   opts.Endpoint,
+		ApiKey:     opts.ApiKey,
+		ApiVersion: opts.ApiVersion,
+		Proxy:      opts.Proxy,
+		Retries:    opts.Retries,
+		RequestLog: opts.RequestLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+type TranscriptionFormat string
+
+const (
+	TranscriptionJson TranscriptionFormat = "json"
+	TranscriptionText TranscriptionFormat = "text"
+	// SubRip subtitles
+	TranscriptionSrt TranscriptionFormat = "srt"
+	// WebVTT subtitles
+	TranscriptionVtt TranscriptionFormat = "vtt"
+	// JSON with language, duration and timestamps
+	TranscriptionVerboseJson TranscriptionFormat = "verbose_json"
+)
+
+type TimestampGranularity string
+
+const (
+	TimestampSegment TimestampGranularity = "segment"
+	TimestampWord    TimestampGranularity = "word"
+)
+
+type TranscriptionRequest struct {
+	// The audio file content, flac, mp3, mp4, mpeg, mpga, m4a, ogg, wav or webm
+	Audio []byte
+	// The audio file name, the extension tells the audio format. E.G. "speech.mp3"
+	FileName string
+	// The language of the audio in ISO-639-1, e.g. "en". Improves accuracy
+	// and latency.
+	Language string
+	// Text to guide the model's style or continue a previous audio segment.
+	Prompt      string
+	Temperature aigc.Nullable[float64]
+	// Default is json. Timestamps are only returned in verbose_json
+	Format TranscriptionFormat
+	// Timestamps of segments, words or both. Format is set to verbose_json if
+	// it is not set.
+	TimestampGranularities []TimestampGranularity
+}
+
+type SpeechFormat string
+
+const (
+	SpeechMp3  SpeechFormat = "mp3"
+	SpeechOpus SpeechFormat = "opus"
+	SpeechAac  SpeechFormat = "aac"
+	SpeechFlac SpeechFormat = "flac"
+	SpeechWav  SpeechFormat = "wav"
+	// Raw samples in 24kHz (16-bit signed, low-endian), without the header
+	SpeechPcm SpeechFormat = "pcm"
+)
+
+type SpeechRequest struct {
+	// The text to generate audio for, 4096 characters at most
+	Input string
+	// "alloy" | "echo" | "fable" | "onyx" | "nova" | "shimmer"
+	Voice string
+	// Default is mp3
+	Format SpeechFormat
+	// The speed of the generated audio, from 0.25 to 4.0. Default is 1.0
+	Speed aigc.Nullable[float64]
+}
@@ -0,0 +1,33 @@
+package audio
+
+type TranscriptionSegment struct {
+	Id int
+	// Start and end time of the segment in seconds
+	Start float64
+	End   float64
+	Text  string
+}
+
+type TranscriptionWord struct {
+	// Start and end time of the word in seconds
+	Start float64
+	End   float64
+	Word  string
+}
+
+type TranscriptionResponse struct {
+	// The transcribed text, or the subtitles in srt and vtt formats
+	Text string
+	// Only in verbose_json format
+	Language string
+	// Duration of the audio in seconds, only in verbose_json format
+	Duration float64
+	Segments []TranscriptionSegment
+	Words    []TranscriptionWord
+}
+
+type SpeechResponse struct {
+	// The audio file content
+	Audio  []byte
+	Format SpeechFormat
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/audio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var speechMp3 = []byte("ID3\x04\x00\x00\x00\x00\x00\x00speech")
+
+const transcriptionVerboseJson = `{
+  "task": "transcribe",
+  "language": "english",
+  "duration": 2.5,
+  "text": "Hello world.",
+  "segments": [{"id": 0, "start": 0.0, "end": 2.5, "text": " Hello world."}],
+  "words": [{"word": "Hello", "start": 0.1, "end": 0.6}, {"word": "world", "start": 0.7, "end": 1.2}]
+}`
+
+const transcriptionSrt = "1\n00:00:00,000 --> 00:00:02,500\nHello world.\n\n"
+
+// newTranscriptionServer returns a server replies verbose_json or srt, the
+// received multipart form is passed to the receive function.
+func newTranscriptionServer(t *testing.T, receive func(r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		receive(r)
+		if r.FormValue("response_format") == "srt" {
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, transcriptionSrt)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, transcriptionVerboseJson)
+	}))
+}
+
+func Test_OpenAI_Whisper_Transcribe(t *testing.T) {
+	var received *http.Request
+	var server = newTranscriptionServer(t, func(r *http.Request) { received = r })
+	defer server.Close()
+
+	var requestLog []byte
+	var model, err = audio.NewTranscriptionModel(audio.Models.OpenAIWhisper1,
+		aigc.WithEndpoint(server.URL+"/v1/audio/transcriptions"),
+		aigc.WithApiKey("test-key"),
+		aigc.WithRequestLog(func(body []byte) { requestLog = append([]byte(nil), body...) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Transcribe(context.Background(), &audio.TranscriptionRequest{
+		Audio:                  speechMp3,
+		FileName:               "speech.mp3",
+		Language:               "en",
+		Temperature:            aigc.NewNullable(0.2),
+		TimestampGranularities: []audio.TimestampGranularity{audio.TimestampSegment, audio.TimestampWord},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != "Hello world." || response.Language != "english" || response.Duration != 2.5 ||
+		len(response.Segments) != 1 || response.Segments[0].End != 2.5 ||
+		len(response.Words) != 2 || response.Words[1].Word != "world" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	if received.Header.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("unexpected authorization: %s", received.Header.Get("Authorization"))
+	}
+	var form = received.MultipartForm
+	if form.Value["model"][0] != "whisper-1" || form.Value["language"][0] != "en" ||
+		form.Value["temperature"][0] != "0.2" || form.Value["response_format"][0] != "verbose_json" ||
+		len(form.Value["timestamp_granularities[]"]) != 2 {
+		t.Errorf("unexpected form: %v", form.Value)
+	}
+	var file = form.File["file"]
+	if len(file) != 1 || file[0].Filename != "speech.mp3" || file[0].Size != int64(len(speechMp3)) {
+		t.Errorf("unexpected file: %+v", file)
+	}
+
+	// The audio is not logged
+	var log = string(requestLog)
+	if strings.Contains(log, string(speechMp3)) || !strings.Contains(log, `name="language"`) ||
+		!strings.Contains(log, "<16 bytes of audio>") {
+		t.Errorf("unexpected request log: %q", log)
+	}
+}
+
+func Test_Azure_Whisper_Transcribe_Srt(t *testing.T) {
+	var received *http.Request
+	var server = newTranscriptionServer(t, func(r *http.Request) { received = r })
+	defer server.Close()
+
+	var model, err = audio.NewTranscriptionModel(audio.Models.OpenAIWhisper1,
+		aigc.WithVendor(aigc.Vendors.Microsoft),
+		aigc.WithEndpoint(server.URL),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Transcribe(context.Background(), &audio.TranscriptionRequest{
+		Audio:    speechMp3,
+		FileName: "speech.mp3",
+		Format:   audio.TranscriptionSrt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != transcriptionSrt {
+		t.Errorf("unexpected text: %q", response.Text)
+	}
+	if received.URL.Path != "/openai/deployments/whisper-1/audio/transcriptions" ||
+		received.URL.Query().Get("api-version") == "" || received.Header.Get("api-key") != "test-key" {
+		t.Errorf("unexpected request: %s", received.URL)
+	}
+	if _, ok := received.MultipartForm.Value["model"]; ok {
+		t.Error("model is sent to azure")
+	}
+
+	// Timestamps need verbose_json
+	_, err = model.Transcribe(context.Background(), &audio.TranscriptionRequest{
+		Audio:                  speechMp3,
+		FileName:               "speech.mp3",
+		Format:                 audio.TranscriptionSrt,
+		TimestampGranularities: []audio.TimestampGranularity{audio.TimestampWord},
+	})
+	if err == nil {
+		t.Error("timestamps are requested in srt format")
+	}
+}
+
+func Test_OpenAI_Tts_Speak(t *testing.T) {
+	var body map[string]any
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(speechMp3)
+	}))
+	defer server.Close()
+
+	var model, err = audio.NewSpeechModel(audio.Models.OpenAITts1HD,
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(server.URL+"/v1/audio/speech"),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Speak(context.Background(), &audio.SpeechRequest{
+		Input: "Hello world.",
+		Voice: "nova",
+		Speed: aigc.NewNullable(1.5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Audio) != string(speechMp3) || response.Format != audio.SpeechMp3 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if body["model"] != "tts-1-hd" || body["input"] != "Hello world." || body["voice"] != "nova" ||
+		body["speed"] != 1.5 {
+		t.Errorf("unexpected request: %v", body)
+	}
+
+	_, err = model.Speak(context.Background(), &audio.SpeechRequest{Input: "Hello", Speed: aigc.NewNullable(5.0)})
+	if err == nil {
+		t.Error("invalid speed is sent")
+	}
+}
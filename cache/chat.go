@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+// chatModel is a chat.Model wrapper caches responses by ChatRequestKey.
+type chatModel struct {
+	Model   chat.Model
+	Store   Store
+	Options Options
+}
+
+func (m *chatModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *chatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	var err error
+	var key string
+	var entry Entry
+	var ok bool
+	var value []byte
+	var cacheErr error
+	var response *chat.ModelResponse
+
+	if !m.Options.cacheable(request) {
+		return m.Model.Complete(ctx, request)
+	}
+
+	key, err = ChatRequestKey(m.Model.GetModelId(), request)
+	if err != nil {
+		return nil, fmt.Errorf("[cache.chatModel.Complete] %w", err)
+	}
+
+	// Store errors are treated as misses and reported in the metadata
+	entry, ok, err = m.Store.Get(key)
+	if err != nil {
+		cacheErr = fmt.Errorf("[cache.chatModel.Complete] %w", err)
+	} else if ok {
+		response = new(chat.ModelResponse)
+		err = json.Unmarshal(entry.Value, response)
+		if err == nil {
+			response.Cache = aigc.CacheMetadata{Hit: true, Key: key, CreatedAt: entry.CreatedAt}
+			return response, nil
+		}
+		cacheErr = fmt.Errorf("[cache.chatModel.Complete] decode cached response %w", err)
+	}
+
+	response, err = m.Model.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	entry = Entry{}
+	value, err = json.Marshal(response)
+	if err != nil {
+		cacheErr = fmt.Errorf("[cache.chatModel.Complete] encode response %w", err)
+	} else {
+		var stored = m.Options.newEntry(value)
+		err = m.Store.Set(key, stored)
+		if err != nil {
+			cacheErr = fmt.Errorf("[cache.chatModel.Complete] %w", err)
+		} else {
+			entry = stored
+		}
+	}
+
+	response.Cache = aigc.CacheMetadata{Hit: false, Key: key, CreatedAt: entry.CreatedAt, Err: cacheErr}
+	return response, nil
+}
+
+// NewChatModel wraps a chat.Model, identical requests are served from the
+// store instead of calling the model again. Requests with Temperature > 0
+// bypass the cache unless WithCacheNonZeroTemperature(true) is set. Store
+// errors do not fail the requests, see aigc.CacheMetadata.Err.
+func NewChatModel(model chat.Model, store Store, options ...OptionFunc) (chat.Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	if store == nil {
+		return nil, errors.New("cache store is required")
+	}
+
+	var m = &chatModel{Model: model, Store: store}
+	for _, fn := range options {
+		fn(&m.Options)
+	}
+	return m, nil
+}
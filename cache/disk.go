@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DiskStore is a Store keeps every entry in a file under a directory:
+//
+//	<dir>/<first 2 chars of key>/<key>.json
+//
+// Expired entries are removed lazily when they are read.
+type DiskStore struct {
+	Dir string
+}
+
+type diskStoreEntry struct {
+	Value     []byte    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+func (s *DiskStore) path(key string) string {
+	var name = key
+	for i := 0; i < len(key); i++ {
+		var c = key[i]
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_' {
+			continue
+		}
+		// Key is not a safe file name
+		var hash = sha256.Sum256([]byte(key))
+		name = hex.EncodeToString(hash[:])
+		break
+	}
+	if len(name) < 2 {
+		name = "__" + name
+	}
+	return filepath.Join(s.Dir, name[:2], name+".json")
+}
+
+func (s *DiskStore) Get(key string) (Entry, bool, error) {
+	var err error
+	var data []byte
+	var stored diskStoreEntry
+	var path = s.path(key)
+
+	data, err = os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("[DiskStore.Get] %w", err)
+	}
+
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("[DiskStore.Get] invalid cache file %s %w", path, err)
+	}
+
+	var entry = Entry{Value: stored.Value, CreatedAt: stored.CreatedAt, ExpiresAt: stored.ExpiresAt}
+	if entry.Expired(time.Now()) {
+		os.Remove(path)
+		return Entry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+func (s *DiskStore) Set(key string, entry Entry) error {
+	var err error
+	var data []byte
+	var temp *os.File
+	var path = s.path(key)
+
+	data, err = json.Marshal(diskStoreEntry{
+		Value:     entry.Value,
+		CreatedAt: entry.CreatedAt,
+		ExpiresAt: entry.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("[DiskStore.Set] %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("[DiskStore.Set] %w", err)
+	}
+
+	// Write to a temporary file then rename, so readers never see a partial
+	// written file.
+	temp, err = os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("[DiskStore.Set] %w", err)
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("[DiskStore.Set] %w", err)
+	}
+
+	err = os.Rename(temp.Name(), path)
+	if err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("[DiskStore.Set] %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) Delete(key string) error {
+	var err = os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("[DiskStore.Delete] %w", err)
+	}
+	return nil
+}
+
+// NewDiskStore creates a DiskStore under dir, the directory is created if it
+// does not exist.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if dir == "" {
+		return nil, errors.New("cache directory is required")
+	}
+	var err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("[NewDiskStore] %w", err)
+	}
+	return &DiskStore{Dir: dir}, nil
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+// embeddingModel is an embedding.Model wrapper caches responses by
+// EmbeddingRequestKey.
+type embeddingModel struct {
+	Model   embedding.Model
+	Store   Store
+	Options Options
+}
+
+func (m *embeddingModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *embeddingModel) GetDistanceType() embedding.VectorDistanceType {
+	return m.Model.GetDistanceType()
+}
+
+func (m *embeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	return m.Model.Distance(vector1, vector2)
+}
+
+func (m *embeddingModel) Embedding(ctx context.Context, request *embedding.ModelRequest) (*embedding.ModelResponse, error) {
+	var err error
+	var key string
+	var entry Entry
+	var ok bool
+	var value []byte
+	var cacheErr error
+	var response *embedding.ModelResponse
+
+	key, err = EmbeddingRequestKey(m.Model.GetModelId(), request)
+	if err != nil {
+		return nil, fmt.Errorf("[cache.embeddingModel.Embedding] %w", err)
+	}
+
+	// Store errors are treated as misses and reported in the metadata
+	entry, ok, err = m.Store.Get(key)
+	if err != nil {
+		cacheErr = fmt.Errorf("[cache.embeddingModel.Embedding] %w", err)
+	} else if ok {
+		response = new(embedding.ModelResponse)
+		err = json.Unmarshal(entry.Value, response)
+		if err == nil {
+			response.Cache = aigc.CacheMetadata{Hit: true, Key: key, CreatedAt: entry.CreatedAt}
+			return response, nil
+		}
+		cacheErr = fmt.Errorf("[cache.embeddingModel.Embedding] decode cached response %w", err)
+	}
+
+	response, err = m.Model.Embedding(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	entry = Entry{}
+	value, err = json.Marshal(response)
+	if err != nil {
+		cacheErr = fmt.Errorf("[cache.embeddingModel.Embedding] encode response %w", err)
+	} else {
+		var stored = m.Options.newEntry(value)
+		err = m.Store.Set(key, stored)
+		if err != nil {
+			cacheErr = fmt.Errorf("[cache.embeddingModel.Embedding] %w", err)
+		} else {
+			entry = stored
+		}
+	}
+
+	response.Cache = aigc.CacheMetadata{Hit: false, Key: key, CreatedAt: entry.CreatedAt, Err: cacheErr}
+	return response, nil
+}
+
+// NewEmbeddingModel wraps an embedding.Model, identical requests are served
+// from the store instead of calling the model again. Store errors do not fail
+// the requests, see aigc.CacheMetadata.Err.
+func NewEmbeddingModel(model embedding.Model, store Store, options ...OptionFunc) (embedding.Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	if store == nil {
+		return nil, errors.New("cache store is required")
+	}
+
+	var m = &embeddingModel{Model: model, Store: store}
+	for _, fn := range options {
+		fn(&m.Options)
+	}
+	return m, nil
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+// Bump the version when the encoding of requests is changed, so that old
+// entries are not matched anymore.
+const keyVersion = "v1"
+
+// ChatRequestKey returns the canonical hash of a chat request. Requests with
+// same messages, tools, parameters and model id have the same key.
+//
+// Map values (tool call arguments, tool results) are encoded with sorted keys,
+// so the key does not depend on the map iteration order. Tool.Function is not
+// a part of the key.
+func ChatRequestKey(modelId string, request *chat.ModelRequest) (string, error) {
+	var err error
+	var key = struct {
+		Version string
+		Kind    string
+		ModelId string
+		Request *chat.ModelRequest
+	}{keyVersion, "chat", modelId, request}
+
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = aigc.EncodeJson(buffer, key)
+	if err != nil {
+		return "", fmt.Errorf("[ChatRequestKey] %w", err)
+	}
+	return hashKey(buffer), nil
+}
+
+// EmbeddingRequestKey returns the canonical hash of an embedding request.
+func EmbeddingRequestKey(modelId string, request *embedding.ModelRequest) (string, error) {
+	var err error
+	var key = struct {
+		Version string
+		Kind    string
+		ModelId string
+		Request *embedding.ModelRequest
+	}{keyVersion, "embedding", modelId, request}
+
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = aigc.EncodeJson(buffer, key)
+	if err != nil {
+		return "", fmt.Errorf("[EmbeddingRequestKey] %w", err)
+	}
+	return hashKey(buffer), nil
+}
+
+func hashKey(buffer *bytes.Buffer) string {
+	var hash = sha256.Sum256(buffer.Bytes())
+	return hex.EncodeToString(hash[:])
+}
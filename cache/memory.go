@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const memoryStoreDefaultCapacity = 1024
+
+type memoryStoreItem struct {
+	key   string
+	entry Entry
+}
+
+// MemoryStore is an in-memory LRU Store. Entries are evicted when the
+// capacity is exceeded or when they are expired.
+type MemoryStore struct {
+	capacity int
+
+	lock  sync.Mutex
+	items map[string]*list.Element
+	order *list.List // front is the most recently used
+}
+
+func (s *MemoryStore) Get(key string) (Entry, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var element, ok = s.items[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	var item = element.Value.(*memoryStoreItem)
+	if item.entry.Expired(time.Now()) {
+		s.order.Remove(element)
+		delete(s.items, key)
+		return Entry{}, false, nil
+	}
+	s.order.MoveToFront(element)
+	return item.entry, true, nil
+}
+
+func (s *MemoryStore) Set(key string, entry Entry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		element.Value.(*memoryStoreItem).entry = entry
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.items[key] = s.order.PushFront(&memoryStoreItem{key: key, entry: entry})
+	for s.order.Len() > s.capacity {
+		var oldest = s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryStoreItem).key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		s.order.Remove(element)
+		delete(s.items, key)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired entries which are not
+// evicted yet.
+func (s *MemoryStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.order.Len()
+}
+
+// NewMemoryStore creates a MemoryStore holds at most capacity entries. If
+// capacity <= 0, a default capacity of 1024 is used.
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = memoryStoreDefaultCapacity
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
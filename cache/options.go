@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+type Options struct {
+	// Lifetime of cached responses. Zero means never expire.
+	TTL time.Duration
+	// By default, chat requests with Temperature > 0 are not cached because
+	// their responses are expected to be random.
+	CacheNonZeroTemperature bool
+	// Minimum cosine similarity for a semantic cache hit. Only for semantic
+	// caches, default is 0.95.
+	SimilarityThreshold float32
+	// Maximum number of entries. Only for semantic caches, default is 1024.
+	Capacity int
+}
+
+type OptionFunc func(*Options)
+
+func WithTTL(ttl time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.TTL = ttl
+	}
+}
+
+func WithCacheNonZeroTemperature(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.CacheNonZeroTemperature = enabled
+	}
+}
+
+func WithSimilarityThreshold(threshold float32) func(*Options) {
+	return func(o *Options) {
+		o.SimilarityThreshold = threshold
+	}
+}
+
+func WithCapacity(capacity int) func(*Options) {
+	return func(o *Options) {
+		o.Capacity = capacity
+	}
+}
+
+func (o *Options) cacheable(request *chat.ModelRequest) bool {
+	if o.CacheNonZeroTemperature {
+		return true
+	}
+	return !request.Temperature.Valid || request.Temperature.Value <= 0
+}
+
+func (o *Options) newEntry(value []byte) Entry {
+	var entry = Entry{Value: value, CreatedAt: time.Now()}
+	if o.TTL > 0 {
+		entry.ExpiresAt = entry.CreatedAt.Add(o.TTL)
+	}
+	return entry
+}
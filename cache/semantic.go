@@ -0,0 +1,345 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+const (
+	semanticDefaultThreshold = 0.95
+	semanticDefaultCapacity  = 1024
+)
+
+// SemanticStats is a snapshot of the statistics of a SemanticCache.
+type SemanticStats struct {
+	// Number of lookups answered from the cache
+	Hits int
+	// Number of lookups not answered from the cache
+	Misses int
+	// Number of requests can not be cached, e.g. the last message is not a
+	// user text message, or the temperature is greater than 0
+	Bypasses int
+	// Number of entries removed because the capacity is exceeded
+	Evictions int
+	// Number of entries removed because they are expired
+	Expirations int
+	// Current number of entries
+	Entries int
+}
+
+type semanticEntry struct {
+	scope     string
+	vector    []float32
+	value     []byte
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// SemanticCache answers near-duplicate user questions from previous answers.
+//
+// The last user message of a request is embedded with the embedding model,
+// and compared with previous entries by VectorCosineSimilarity. Entries are
+// only compared within the same scope: model id, system prompts, tools and
+// the earlier turns of the conversation must be identical, so a short
+// follow-up like "yes" only matches in the same conversation.
+type SemanticCache struct {
+	Embedder embedding.Model
+	Options  Options
+
+	lock   sync.Mutex
+	order  *list.List // of *semanticEntry, front is the most recently used
+	scopes map[string]map[*list.Element]struct{}
+	stats  SemanticStats
+}
+
+func (s SemanticStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// semanticScope returns the hash of model id, system prompts, tools and the
+// messages before the last user message.
+func semanticScope(modelId string, request *chat.ModelRequest) (string, error) {
+	var err error
+	var scope = struct {
+		Version string
+		Kind    string
+		ModelId string
+		System  []chat.Message
+		Tools   []chat.Tool
+		History []chat.Message
+	}{Version: keyVersion, Kind: "semantic", ModelId: modelId, Tools: request.Tools}
+
+	for i, message := range request.Messages {
+		if message.Role == chat.RoleSystem {
+			scope.System = append(scope.System, message)
+		} else if i < len(request.Messages)-1 {
+			scope.History = append(scope.History, message)
+		}
+	}
+
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = aigc.EncodeJson(buffer, scope)
+	if err != nil {
+		return "", fmt.Errorf("[semanticScope] %w", err)
+	}
+	return hashKey(buffer), nil
+}
+
+// semanticQuery returns the text of the last user message, empty if the
+// last message is not a user message or has non-text contents.
+func semanticQuery(request *chat.ModelRequest) string {
+	if len(request.Messages) == 0 {
+		return ""
+	}
+	var last = request.Messages[len(request.Messages)-1]
+	if last.Role != chat.RoleUser || len(last.Contents) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, content := range last.Contents {
+		if content.Type != chat.ContentTypeText {
+			return ""
+		}
+		if builder.Len() > 0 {
+			builder.WriteByte('\n')
+		}
+		builder.WriteString(content.Text)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func (c *SemanticCache) threshold() float32 {
+	if c.Options.SimilarityThreshold > 0 {
+		return c.Options.SimilarityThreshold
+	}
+	return semanticDefaultThreshold
+}
+
+func (c *SemanticCache) capacity() int {
+	if c.Options.Capacity > 0 {
+		return c.Options.Capacity
+	}
+	return semanticDefaultCapacity
+}
+
+func (c *SemanticCache) embed(ctx context.Context, query string) ([]float32, error) {
+	var response, err = c.Embedder.Embedding(ctx, &embedding.ModelRequest{Document: query})
+	if err != nil {
+		return nil, fmt.Errorf("[SemanticCache.embed] %w", err)
+	}
+	if len(response.Embedding) == 0 {
+		return nil, errors.New("[SemanticCache.embed] empty embedding")
+	}
+	return response.Embedding, nil
+}
+
+func (c *SemanticCache) remove(element *list.Element) {
+	var entry = element.Value.(*semanticEntry)
+	c.order.Remove(element)
+	var elements = c.scopes[entry.scope]
+	delete(elements, element)
+	if len(elements) == 0 {
+		delete(c.scopes, entry.scope)
+	}
+}
+
+// search returns the most similar entry above the threshold in the scope.
+func (c *SemanticCache) search(scope string, vector []float32) (*list.Element, float32) {
+	var now = time.Now()
+	var best *list.Element
+	var bestSimilarity float32
+
+	for element := range c.scopes[scope] {
+		var entry = element.Value.(*semanticEntry)
+		if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			c.remove(element)
+			c.stats.Expirations++
+			continue
+		}
+		var similarity, err = embedding.VectorCosineSimilarity(vector, entry.vector)
+		if err != nil {
+			// Dimension mismatch or zero vector, never matches
+			continue
+		}
+		if similarity >= c.threshold() && (best == nil || similarity > bestSimilarity) {
+			best = element
+			bestSimilarity = similarity
+		}
+	}
+	return best, bestSimilarity
+}
+
+func (c *SemanticCache) insert(scope string, vector []float32, value []byte) time.Time {
+	var entry = &semanticEntry{scope: scope, vector: vector, value: value, createdAt: time.Now()}
+	if c.Options.TTL > 0 {
+		entry.expiresAt = entry.createdAt.Add(c.Options.TTL)
+	}
+
+	var element = c.order.PushFront(entry)
+	if c.scopes[scope] == nil {
+		c.scopes[scope] = make(map[*list.Element]struct{})
+	}
+	c.scopes[scope][element] = struct{}{}
+
+	for c.order.Len() > c.capacity() {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+	return entry.createdAt
+}
+
+// Complete answers the request from the cache, or calls the model and caches
+// the response.
+func (c *SemanticCache) Complete(ctx context.Context, model chat.Model, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	var err error
+	var scope string
+	var query string
+	var vector []float32
+	var value []byte
+	var response *chat.ModelResponse
+
+	query = semanticQuery(request)
+	if query == "" || !c.Options.cacheable(request) {
+		c.lock.Lock()
+		c.stats.Bypasses++
+		c.lock.Unlock()
+		return model.Complete(ctx, request)
+	}
+
+	scope, err = semanticScope(model.GetModelId(), request)
+	if err != nil {
+		return nil, fmt.Errorf("[SemanticCache.Complete] %w", err)
+	}
+	vector, err = c.embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("[SemanticCache.Complete] %w", err)
+	}
+
+	c.lock.Lock()
+	var element, similarity = c.search(scope, vector)
+	if element != nil {
+		var entry = element.Value.(*semanticEntry)
+		c.order.MoveToFront(element)
+		c.stats.Hits++
+		c.lock.Unlock()
+
+		response = new(chat.ModelResponse)
+		err = json.Unmarshal(entry.value, response)
+		if err != nil {
+			return nil, fmt.Errorf("[SemanticCache.Complete] decode cached response %w", err)
+		}
+		response.Cache = aigc.CacheMetadata{Hit: true, CreatedAt: entry.createdAt, Similarity: similarity}
+		return response, nil
+	}
+	c.stats.Misses++
+	c.lock.Unlock()
+
+	response, err = model.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only cache final answers, tool calls depend on the tool results which
+	// are not a part of the query.
+	if response.FinishReason.Type() != chat.FinishReasonStop {
+		return response, nil
+	}
+
+	value, err = json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("[SemanticCache.Complete] encode response %w", err)
+	}
+
+	c.lock.Lock()
+	var createdAt = c.insert(scope, vector, value)
+	c.lock.Unlock()
+
+	response.Cache = aigc.CacheMetadata{Hit: false, CreatedAt: createdAt}
+	return response, nil
+}
+
+// Stats returns a snapshot of the statistics.
+func (c *SemanticCache) Stats() SemanticStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var stats = c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+// Clear removes all entries, statistics are kept.
+func (c *SemanticCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.order.Init()
+	c.scopes = make(map[string]map[*list.Element]struct{})
+}
+
+// NewSemanticCache creates a SemanticCache uses embedder to embed user
+// messages. WithSimilarityThreshold, WithCapacity, WithTTL and
+// WithCacheNonZeroTemperature are supported.
+func NewSemanticCache(embedder embedding.Model, options ...OptionFunc) (*SemanticCache, error) {
+	if embedder == nil {
+		return nil, errors.New("embedding model is required")
+	}
+
+	var c = &SemanticCache{
+		Embedder: embedder,
+		order:    list.New(),
+		scopes:   make(map[string]map[*list.Element]struct{}),
+	}
+	for _, fn := range options {
+		fn(&c.Options)
+	}
+	if c.Options.SimilarityThreshold > 1 {
+		return nil, errors.New("similarity threshold must be less than or equal to 1")
+	}
+	return c, nil
+}
+
+// semanticChatModel is a chat.Model wrapper answers requests from a
+// SemanticCache.
+type semanticChatModel struct {
+	Model chat.Model
+	Cache *SemanticCache
+}
+
+func (m *semanticChatModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *semanticChatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	return m.Cache.Complete(ctx, m.Model, request)
+}
+
+// NewSemanticChatModel wraps a chat.Model with a SemanticCache. A cache may be
+// shared by several models, entries of different models never match.
+func NewSemanticChatModel(model chat.Model, cache *SemanticCache) (chat.Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	if cache == nil {
+		return nil, errors.New("semantic cache is required")
+	}
+	return &semanticChatModel{Model: model, Cache: cache}, nil
+}
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"time"
+)
+
+// Entry is a cached value with its lifetime.
+type Entry struct {
+	Value     []byte
+	CreatedAt time.Time
+	// Zero means the entry never expires
+	ExpiresAt time.Time
+}
+
+// Store is the storage backend of a response cache. Implementations must be
+// safe for concurrent use.
+type Store interface {
+	// Get returns the entry of the key. ok is false if the key does not exist
+	// or the entry is expired.
+	Get(key string) (entry Entry, ok bool, err error)
+	Set(key string, entry Entry) error
+	Delete(key string) error
+}
+
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
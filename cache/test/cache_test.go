@@ -0,0 +1,274 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/cache"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"testing"
+	"time"
+)
+
+type countingChatModel struct {
+	calls int
+}
+
+func (m *countingChatModel) GetModelId() string {
+	return "counting-chat"
+}
+
+func (m *countingChatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	m.calls++
+	var last = request.Messages[len(request.Messages)-1]
+	return &chat.ModelResponse{
+		Id: "response",
+		Messages: []chat.Message{{
+			Role:     chat.RoleAssistant,
+			Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "echo: " + last.Contents[0].Text}},
+		}},
+		FinishReason: "stop",
+		Usage:        chat.TokenUsage{InputTokens: 3, OutputTokens: 5},
+	}, nil
+}
+
+type countingEmbeddingModel struct {
+	calls int
+}
+
+func (m *countingEmbeddingModel) GetModelId() string {
+	return "counting-embedding"
+}
+
+func (m *countingEmbeddingModel) GetDistanceType() embedding.VectorDistanceType {
+	return embedding.CosineDistance
+}
+
+func (m *countingEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	return embedding.VectorCosineSimilarity(vector1, vector2)
+}
+
+func (m *countingEmbeddingModel) Embedding(ctx context.Context, request *embedding.ModelRequest) (*embedding.ModelResponse, error) {
+	m.calls++
+	return &embedding.ModelResponse{Embedding: []float32{float32(len(request.Document)), 1}, Tokens: 1}, nil
+}
+
+func newTextRequest(text string) *chat.ModelRequest {
+	return &chat.ModelRequest{
+		Messages: []chat.Message{{
+			Role:     chat.RoleUser,
+			Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: text}},
+		}},
+	}
+}
+
+func Test_Cache_Chat_Key_Canonical(t *testing.T) {
+	var request1 = newTextRequest("hello")
+	request1.Messages = append(request1.Messages, chat.Message{
+		Role: chat.RoleAssistant,
+		Contents: []chat.ContentBlock{{
+			Type:      chat.ContentTypeToolCall,
+			ToolName:  "add",
+			Arguments: map[string]any{"a": 1, "b": 2, "c": 3, "d": 4},
+		}},
+	})
+	var request2 = request1.Copy()
+	request2.Messages[1].Contents[0].Arguments = map[string]any{"d": 4, "c": 3, "b": 2, "a": 1}
+
+	key1, err := cache.ChatRequestKey("model", request1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := cache.ChatRequestKey("model", request2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key1 != key2 {
+		t.Fatalf("keys of identical requests are different: %s, %s", key1, key2)
+	}
+
+	key3, _ := cache.ChatRequestKey("other-model", request1)
+	if key3 == key1 {
+		t.Fatal("keys of different models are identical")
+	}
+	request2.MaxTokens = aigc.NewNullable[int32](10)
+	key4, _ := cache.ChatRequestKey("model", request2)
+	if key4 == key1 {
+		t.Fatal("keys of different parameters are identical")
+	}
+}
+
+func Test_Cache_Chat_Memory(t *testing.T) {
+	var inner = &countingChatModel{}
+	var model, err = cache.NewChatModel(inner, cache.NewMemoryStore(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := model.Complete(context.Background(), newTextRequest("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cache.Hit || first.Cache.Key == "" {
+		t.Fatalf("unexpected cache metadata of first response: %+v", first.Cache)
+	}
+
+	second, err := model.Complete(context.Background(), newTextRequest("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.Cache.Hit || second.Cache.Key != first.Cache.Key {
+		t.Fatalf("unexpected cache metadata of second response: %+v", second.Cache)
+	}
+	if second.Messages[0].Contents[0].Text != "echo: hello" || second.Usage.OutputTokens != 5 {
+		t.Fatalf("unexpected cached response: %+v", second)
+	}
+	if inner.calls != 1 {
+		t.Fatalf("model is called %d times, expected 1", inner.calls)
+	}
+
+	_, _ = model.Complete(context.Background(), newTextRequest("world"))
+	if inner.calls != 2 {
+		t.Fatalf("model is called %d times, expected 2", inner.calls)
+	}
+}
+
+func Test_Cache_Chat_Temperature(t *testing.T) {
+	var inner = &countingChatModel{}
+	var model, _ = cache.NewChatModel(inner, cache.NewMemoryStore(10))
+
+	var request = newTextRequest("hello")
+	request.Temperature.Set(0.7)
+	for i := 0; i < 3; i++ {
+		response, err := model.Complete(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Cache.Hit {
+			t.Fatal("request with temperature > 0 is served from cache")
+		}
+	}
+	if inner.calls != 3 {
+		t.Fatalf("model is called %d times, expected 3", inner.calls)
+	}
+
+	model, _ = cache.NewChatModel(inner, cache.NewMemoryStore(10), cache.WithCacheNonZeroTemperature(true))
+	_, _ = model.Complete(context.Background(), request)
+	_, _ = model.Complete(context.Background(), request)
+	if inner.calls != 4 {
+		t.Fatalf("model is called %d times, expected 4", inner.calls)
+	}
+}
+
+// failingStore reads entries from a memory store and fails to write.
+type failingStore struct {
+	*cache.MemoryStore
+}
+
+func (s failingStore) Set(key string, entry cache.Entry) error {
+	return errors.New("no space left on device")
+}
+
+// Store errors are reported in the metadata, the response is still returned.
+func Test_Cache_Store_Errors(t *testing.T) {
+	var store = failingStore{cache.NewMemoryStore(10)}
+	var inner = &countingChatModel{}
+	var model, err = cache.NewChatModel(inner, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var response, err = model.Complete(context.Background(), newTextRequest("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Cache.Hit || response.Cache.Err == nil || response.Messages[0].Contents[0].Text != "echo: hello" {
+			t.Fatalf("unexpected response: %+v", response)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("model is called %d times, expected 2", inner.calls)
+	}
+
+	var embedder = &countingEmbeddingModel{}
+	embeddingModel, err := cache.NewEmbeddingModel(embedder, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := embeddingModel.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Cache.Err == nil || len(response.Embedding) != 2 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	// A corrupted entry is a miss
+	var request = newTextRequest("corrupted")
+	key, err := cache.ChatRequestKey(inner.GetModelId(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.MemoryStore.Set(key, cache.Entry{Value: []byte("{")}); err != nil {
+		t.Fatal(err)
+	}
+	chatResponse, err := model.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chatResponse.Cache.Hit || chatResponse.Cache.Err == nil || inner.calls != 3 {
+		t.Fatalf("unexpected response: %+v", chatResponse)
+	}
+}
+
+func Test_Cache_Memory_LRU_TTL(t *testing.T) {
+	var store = cache.NewMemoryStore(2)
+	_ = store.Set("a", cache.Entry{Value: []byte("a")})
+	_ = store.Set("b", cache.Entry{Value: []byte("b")})
+	_, _, _ = store.Get("a")
+	_ = store.Set("c", cache.Entry{Value: []byte("c")})
+
+	if _, ok, _ := store.Get("b"); ok {
+		t.Fatal("least recently used entry is not evicted")
+	}
+	if _, ok, _ := store.Get("a"); !ok {
+		t.Fatal("recently used entry is evicted")
+	}
+
+	_ = store.Set("d", cache.Entry{Value: []byte("d"), ExpiresAt: time.Now().Add(-time.Second)})
+	if _, ok, _ := store.Get("d"); ok {
+		t.Fatal("expired entry is returned")
+	}
+}
+
+func Test_Cache_Embedding_Disk(t *testing.T) {
+	var dir = t.TempDir()
+	var inner = &countingEmbeddingModel{}
+
+	for i := 0; i < 2; i++ {
+		// Reopen the store, the entry must survive
+		var store, err = cache.NewDiskStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		model, err := cache.NewEmbeddingModel(inner, store, cache.WithTTL(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Cache.Hit != (i == 1) {
+			t.Fatalf("unexpected cache metadata: %+v", response.Cache)
+		}
+		if len(response.Embedding) != 2 || response.Embedding[0] != 5 {
+			t.Fatalf("unexpected embedding: %v", response.Embedding)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("model is called %d times, expected 1", inner.calls)
+	}
+}
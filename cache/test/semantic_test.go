@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	"github.com/Pooh-Mucho/go-aigc/cache"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"testing"
+)
+
+// vectorEmbeddingModel embeds documents by a fixed table
+type vectorEmbeddingModel struct {
+	countingEmbeddingModel
+	vectors map[string][]float32
+}
+
+func (m *vectorEmbeddingModel) Embedding(ctx context.Context, request *embedding.ModelRequest) (*embedding.ModelResponse, error) {
+	m.calls++
+	return &embedding.ModelResponse{Embedding: m.vectors[request.Document], Tokens: 1}, nil
+}
+
+func newSemanticTestEmbedder() *vectorEmbeddingModel {
+	return &vectorEmbeddingModel{vectors: map[string][]float32{
+		"What animals are llamas related to?":    {1, 0, 0},
+		"Which animals are related to llamas?":   {0.99, 0.1, 0},
+		"How much weight can a llama carry?":     {0, 1, 0},
+		"How heavy a load can llamas carry?":     {0.05, 0.98, 0.1},
+		"How long do llamas usually live for???": {0, 0, 1},
+	}}
+}
+
+func Test_Cache_Semantic_Hit(t *testing.T) {
+	var inner = &countingChatModel{}
+	var semantic, err = cache.NewSemanticCache(newSemanticTestEmbedder(), cache.WithSimilarityThreshold(0.9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := cache.NewSemanticChatModel(inner, semantic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var questions = []struct {
+		text string
+		hit  bool
+	}{
+		{"What animals are llamas related to?", false},
+		{"Which animals are related to llamas?", true},
+		{"How much weight can a llama carry?", false},
+		{"How heavy a load can llamas carry?", true},
+		{"How long do llamas usually live for???", false},
+	}
+	for _, q := range questions {
+		response, err := model.Complete(context.Background(), newTextRequest(q.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Cache.Hit != q.hit {
+			t.Fatalf("%s: hit %v, expected %v", q.text, response.Cache.Hit, q.hit)
+		}
+		if q.hit && response.Cache.Similarity < 0.9 {
+			t.Fatalf("%s: similarity %f is below threshold", q.text, response.Cache.Similarity)
+		}
+	}
+
+	var stats = semantic.Stats()
+	if stats.Hits != 2 || stats.Misses != 3 || stats.Entries != 3 || inner.calls != 3 {
+		t.Fatalf("unexpected stats: %+v, calls: %d", stats, inner.calls)
+	}
+	if rate := stats.HitRate(); rate < 0.39 || rate > 0.41 {
+		t.Fatalf("unexpected hit rate: %f", rate)
+	}
+}
+
+func Test_Cache_Semantic_Scope(t *testing.T) {
+	var inner = &countingChatModel{}
+	var semantic, _ = cache.NewSemanticCache(newSemanticTestEmbedder())
+	var model, _ = cache.NewSemanticChatModel(inner, semantic)
+
+	var request = newTextRequest("What animals are llamas related to?")
+	_, _ = model.Complete(context.Background(), request)
+
+	// Different system prompt must not match
+	var withSystem = request.Copy()
+	withSystem.Messages = append([]chat.Message{{
+		Role:     chat.RoleSystem,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Answer in French."}},
+	}}, withSystem.Messages...)
+	response, _ := model.Complete(context.Background(), withSystem)
+	if response.Cache.Hit {
+		t.Fatal("request with different system prompt is served from cache")
+	}
+
+	// Different tools must not match
+	var withTools = request.Copy()
+	withTools.Tools = []chat.Tool{{Name: "search", Description: "Search the web"}}
+	response, _ = model.Complete(context.Background(), withTools)
+	if response.Cache.Hit {
+		t.Fatal("request with different tools is served from cache")
+	}
+
+	// Different earlier turns must not match
+	var followUp = request.Copy()
+	followUp.Messages = append([]chat.Message{
+		{Role: chat.RoleUser, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Tell me about camels."}}},
+		{Role: chat.RoleAssistant, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Camels live in deserts."}}},
+	}, followUp.Messages...)
+	response, _ = model.Complete(context.Background(), followUp)
+	if response.Cache.Hit {
+		t.Fatal("request with different history is served from cache")
+	}
+
+	response, _ = model.Complete(context.Background(), request)
+	if !response.Cache.Hit {
+		t.Fatal("identical request is not served from cache")
+	}
+	response, _ = model.Complete(context.Background(), followUp)
+	if !response.Cache.Hit {
+		t.Fatal("identical conversation is not served from cache")
+	}
+}
+
+func Test_Cache_Semantic_Eviction(t *testing.T) {
+	var inner = &countingChatModel{}
+	var semantic, _ = cache.NewSemanticCache(newSemanticTestEmbedder(), cache.WithCapacity(2))
+	var model, _ = cache.NewSemanticChatModel(inner, semantic)
+
+	_, _ = model.Complete(context.Background(), newTextRequest("What animals are llamas related to?"))
+	_, _ = model.Complete(context.Background(), newTextRequest("How much weight can a llama carry?"))
+	_, _ = model.Complete(context.Background(), newTextRequest("How long do llamas usually live for???"))
+
+	var stats = semantic.Stats()
+	if stats.Evictions != 1 || stats.Entries != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	response, _ := model.Complete(context.Background(), newTextRequest("What animals are llamas related to?"))
+	if response.Cache.Hit {
+		t.Fatal("evicted entry is served from cache")
+	}
+}
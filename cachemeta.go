@@ -0,0 +1,21 @@
+package aigc
+
+import "time"
+
+// CacheMetadata describes whether a model response is served from a response
+// cache. It is zero for responses returned directly by the model.
+type CacheMetadata struct {
+	// Whether the response is served from the cache
+	Hit bool
+	// The cache key of the request, empty if the request is not cacheable
+	Key string
+	// The time when the cached response was stored
+	CreatedAt time.Time
+	// Similarity between the request and the cached request, only set by
+	// semantic caches
+	Similarity float32
+	// Error of the cache store ignored to return the response, e.g. the
+	// response can not be stored or a stored entry can not be decoded. The
+	// cache is best-effort, the model is called on errors.
+	Err error
+}
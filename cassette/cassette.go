@@ -0,0 +1,144 @@
+// Package cassette records http traffic of models to files (cassettes) and
+// replays it later, so tests of vendor backends can run without network and
+// credentials.
+//
+// A Recorder is an http.RoundTripper, plug it into models by
+// aigc.WithHttpTransport.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+const cassetteVersion = 1
+
+// Cassette is a list of recorded http interactions.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       Body        `json:"body"`
+}
+
+// Body is a http body. It is saved as a string if it is valid UTF-8, otherwise
+// as a base64 encoded string.
+type Body struct {
+	Data []byte
+}
+
+type bodyJson struct {
+	Encoding string `json:"encoding,omitempty"`
+	Data     string `json:"data"`
+}
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	var body bodyJson
+	if utf8.Valid(b.Data) {
+		body.Data = string(b.Data)
+	} else {
+		body.Encoding = "base64"
+		body.Data = base64.StdEncoding.EncodeToString(b.Data)
+	}
+
+	var buffer bytes.Buffer
+	var encoder = json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	var err = encoder.Encode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var body bodyJson
+	var err = json.Unmarshal(data, &body)
+	if err != nil {
+		return err
+	}
+	switch body.Encoding {
+	case "":
+		b.Data = []byte(body.Data)
+	case "base64":
+		b.Data, err = base64.StdEncoding.DecodeString(body.Data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown body encoding '%s'", body.Encoding)
+	}
+	return nil
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	var err error
+	var file *os.File
+	var cassette Cassette
+
+	file, err = os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("[cassette.Load] %w", err)
+	}
+	defer file.Close()
+
+	err = json.NewDecoder(file).Decode(&cassette)
+	if err != nil {
+		return nil, fmt.Errorf("[cassette.Load] %s: %w", path, err)
+	}
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("[cassette.Load] %s: unsupported version %d", path, cassette.Version)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a file, parent directories are created if not
+// exist.
+func (c *Cassette) Save(path string) error {
+	var err error
+	var buffer bytes.Buffer
+	var encoder = json.NewEncoder(&buffer)
+
+	c.Version = cassetteVersion
+
+	// Indent so the cassettes are readable and diffable
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(c)
+	if err != nil {
+		return fmt.Errorf("[Cassette.Save] %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("[Cassette.Save] %w", err)
+	}
+	err = os.WriteFile(path, buffer.Bytes(), 0o644)
+	if err != nil {
+		return fmt.Errorf("[Cassette.Save] %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+type Mode int
+
+const (
+	// ModeReplay replays recorded interactions, requests not recorded fail
+	// with ErrInteractionNotFound.
+	ModeReplay Mode = iota
+	// ModeRecord sends all requests to the vendor, the cassette is replaced by
+	// the new interactions.
+	ModeRecord
+	// ModeReplayOrRecord replays recorded interactions, requests not recorded
+	// are sent to the vendor and appended to the cassette.
+	ModeReplayOrRecord
+	// ModePassthrough sends all requests to the vendor, nothing is recorded.
+	ModePassthrough
+)
+
+var (
+	ErrCassetteNotFound    = errors.New("cassette not found")
+	ErrInteractionNotFound = errors.New("interaction not found")
+)
+
+// ParseMode parses the mode names "replay", "record", "auto" and "off". An
+// empty string is ModeReplay.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "replay":
+		return ModeReplay, nil
+	case "record":
+		return ModeRecord, nil
+	case "auto":
+		return ModeReplayOrRecord, nil
+	case "off", "passthrough":
+		return ModePassthrough, nil
+	default:
+		return ModeReplay, fmt.Errorf("unknown cassette mode '%s'", s)
+	}
+}
+
+// Recorder is an http.RoundTripper records and replays interactions of a
+// cassette file.
+//
+// Requests are matched by method, url path and query, and the normalized
+// body, so the json encoding details and the endpoint host do not matter.
+// Every recorded interaction is replayed at most once, in the recorded order
+// for identical requests.
+//
+// Credentials are removed before interactions are saved, see scrub.go.
+type Recorder struct {
+	Path string
+	Mode Mode
+	// Transport sends requests to the vendor when recording, default is the
+	// shared transport of aigc.GetHttpTransport.
+	Transport http.RoundTripper
+	// Scrub is called before an interaction is recorded, removes secrets
+	// which are not known by the recorder.
+	Scrub func(interaction *Interaction)
+
+	lock     sync.Mutex
+	cassette *Cassette
+	used     []bool
+	dirty    bool
+}
+
+// New creates a Recorder for a cassette file. In ModeReplay the cassette must
+// exist, ErrCassetteNotFound is returned otherwise.
+func New(path string, mode Mode) (*Recorder, error) {
+	var err error
+	var recorder = &Recorder{Path: path, Mode: mode}
+
+	switch mode {
+	case ModeRecord, ModePassthrough:
+		recorder.cassette = &Cassette{}
+	case ModeReplay, ModeReplayOrRecord:
+		recorder.cassette, err = Load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			if mode == ModeReplay {
+				return nil, fmt.Errorf("[cassette.New] %w: %s", ErrCassetteNotFound, path)
+			}
+			recorder.cassette, err = &Cassette{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("[cassette.New] %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("[cassette.New] unknown mode %d", mode)
+	}
+
+	recorder.used = make([]bool, len(recorder.cassette.Interactions))
+	return recorder, nil
+}
+
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var err error
+	var body []byte
+
+	if request.Body != nil {
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("[Recorder.RoundTrip] %w", err)
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.Mode == ModeReplay || r.Mode == ModeReplayOrRecord {
+		var interaction = r.take(request, body)
+		if interaction != nil {
+			return replayResponse(request, interaction), nil
+		}
+		if r.Mode == ModeReplay {
+			return nil, fmt.Errorf("[Recorder.RoundTrip] %w: %s %s in %s",
+				ErrInteractionNotFound, request.Method, scrubUrl(request.URL), r.Path)
+		}
+	}
+
+	transport, err := r.transport()
+	if err != nil {
+		return nil, fmt.Errorf("[Recorder.RoundTrip] %w", err)
+	}
+
+	response, err := transport.RoundTrip(request)
+	if err != nil || r.Mode == ModePassthrough {
+		return response, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("[Recorder.RoundTrip] %w", err)
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	var interaction = &Interaction{
+		Request: Request{
+			Method:  request.Method,
+			Url:     scrubUrl(request.URL),
+			Headers: scrubHeaders(request.Header, sensitiveRequestHeaders),
+			Body:    Body{Data: body},
+		},
+		Response: Response{
+			StatusCode: response.StatusCode,
+			Headers:    scrubHeaders(response.Header, sensitiveResponseHeaders),
+			Body:       Body{Data: responseBody},
+		},
+	}
+	if r.Scrub != nil {
+		r.Scrub(interaction)
+	}
+
+	r.lock.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.used = append(r.used, true)
+	r.dirty = true
+	r.lock.Unlock()
+
+	return response, nil
+}
+
+// Save writes the cassette file if new interactions were recorded.
+func (r *Recorder) Save() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.dirty || r.Mode == ModePassthrough {
+		return nil
+	}
+	var err = r.cassette.Save(r.Path)
+	if err != nil {
+		return fmt.Errorf("[Recorder.Save] %w", err)
+	}
+	r.dirty = false
+	return nil
+}
+
+// Unused returns the number of recorded interactions not replayed, a non-zero
+// value usually means the requests have changed since recording.
+func (r *Recorder) Unused() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var n int
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Recorder) transport() (http.RoundTripper, error) {
+	if r.Transport != nil {
+		return r.Transport, nil
+	}
+	return aigc.GetHttpTransport("")
+}
+
+// take returns the first unused interaction matching the request, and marks it
+// as used.
+func (r *Recorder) take(request *http.Request, body []byte) *Interaction {
+	var key = matchKey(scrubUrl(request.URL))
+	var normalized = normalizeBody(body)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		if !strings.EqualFold(interaction.Request.Method, request.Method) {
+			continue
+		}
+		if matchKey(interaction.Request.Url) != key {
+			continue
+		}
+		if !bytes.Equal(normalizeBody(interaction.Request.Body.Data), normalized) {
+			continue
+		}
+		r.used[i] = true
+		return interaction
+	}
+	return nil
+}
+
+func replayResponse(request *http.Request, interaction *Interaction) *http.Response {
+	var headers = interaction.Response.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          io.NopCloser(bytes.NewReader(interaction.Response.Body.Data)),
+		ContentLength: int64(len(interaction.Response.Body.Data)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,112 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// Request headers carry credentials or signatures, or change on every request.
+var sensitiveRequestHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+	"Api-Key",
+	"Ocp-Apim-Subscription-Key",
+	"X-Amz-Security-Token",
+	"X-Amz-Date",
+	"X-Amz-Content-Sha256",
+	"Amz-Sdk-Invocation-Id",
+	"Amz-Sdk-Request",
+	"X-Dashscope-Apikey",
+	"X-Goog-Api-Key",
+}
+
+// Response headers identify the account.
+var sensitiveResponseHeaders = []string{
+	"Set-Cookie",
+	"Openai-Organization",
+	"Openai-Project",
+	"Anthropic-Organization-Id",
+}
+
+// Query parameters carry credentials, e.g. Gemini api key.
+var sensitiveQueryParameters = []string{
+	"key",
+	"api-key",
+	"api_key",
+	"apikey",
+	"access_token",
+	"X-Amz-Signature",
+	"X-Amz-Credential",
+	"X-Amz-Security-Token",
+}
+
+func scrubHeaders(headers http.Header, sensitive []string) http.Header {
+	var z = headers.Clone()
+	for _, name := range sensitive {
+		if _, ok := z[http.CanonicalHeaderKey(name)]; ok {
+			z.Set(name, redacted)
+		}
+	}
+	// Recomputed on replay
+	z.Del("Content-Length")
+	return z
+}
+
+func scrubUrl(u *url.URL) string {
+	var z = *u
+	z.User = nil
+	if z.RawQuery != "" {
+		var query = z.Query()
+		for name := range query {
+			for _, sensitive := range sensitiveQueryParameters {
+				if strings.EqualFold(name, sensitive) {
+					query.Set(name, redacted)
+				}
+			}
+		}
+		// Encode sorts the parameters by name
+		z.RawQuery = query.Encode()
+	}
+	return z.String()
+}
+
+// matchKey returns the path and the query of a scrubbed url. The scheme and
+// host are ignored, so the endpoint can differ between recording and
+// replaying.
+func matchKey(rawUrl string) string {
+	var u, err = url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	if u.RawQuery == "" {
+		return u.EscapedPath()
+	}
+	return u.EscapedPath() + "?" + u.RawQuery
+}
+
+// normalizeBody returns the canonical form of a json body, object keys are
+// sorted and insignificant spaces removed. Bodies which are not json are
+// returned as is.
+func normalizeBody(body []byte) []byte {
+	var value any
+	var decoder = json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&value) != nil || decoder.More() {
+		return body
+	}
+
+	var buffer bytes.Buffer
+	var encoder = json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(value) != nil {
+		return body
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n")
+}
@@ -0,0 +1,216 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/cassette"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const openaiResponse = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"created": 1726000000,
+	"model": "gpt-4o-mini-2024-07-18",
+	"choices": [{
+		"index": 0,
+		"message": {"role": "assistant", "content": "Hello! How can I help you?"},
+		"finish_reason": "stop"
+	}],
+	"usage": {"prompt_tokens": 8, "completion_tokens": 7, "total_tokens": 15}
+}`
+
+func newHelloRequest(text string) *chat.ModelRequest {
+	return &chat.ModelRequest{Messages: []chat.Message{{
+		Role:     chat.RoleUser,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: text}},
+	}}}
+}
+
+func newOpenAIModel(t *testing.T, endpoint string, recorder *cassette.Recorder) chat.Model {
+	var model, err = chat.NewModel(chat.Models.OpenAIGpt4oMini_20240718,
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(endpoint),
+		aigc.WithApiKey("sk-secret-key"),
+		aigc.WithHttpTransport(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+func Test_Cassette_Record_Replay(t *testing.T) {
+	var requests int
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer sk-secret-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		io.WriteString(w, openaiResponse)
+	}))
+
+	var path = filepath.Join(t.TempDir(), "hello.json")
+
+	// Record
+	recorder, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var model = newOpenAIModel(t, server.URL+"/v1/chat/completions", recorder)
+	recorded, err := model.Complete(context.Background(), newHelloRequest("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret-key") || strings.Contains(string(data), "secret-cookie") {
+		t.Fatalf("secrets are not scrubbed:\n%s", data)
+	}
+
+	// Replay, the server is closed and the host is different
+	recorder, err = cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model = newOpenAIModel(t, "http://127.0.0.1:1/v1/chat/completions", recorder)
+	replayed, err := model.Complete(context.Background(), newHelloRequest("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 || recorder.Unused() != 0 {
+		t.Fatalf("requests: %d, unused: %d", requests, recorder.Unused())
+	}
+	if replayed.Messages[0].Contents[0].Text != recorded.Messages[0].Contents[0].Text ||
+		replayed.Usage != recorded.Usage {
+		t.Fatalf("replayed response %v differs from recorded %v", replayed, recorded)
+	}
+
+	// Each interaction is replayed once
+	_, err = model.Complete(context.Background(), newHelloRequest("hello"))
+	if !errors.Is(err, cassette.ErrInteractionNotFound) {
+		t.Fatalf("expected interaction not found, got %v", err)
+	}
+
+	// Different request
+	recorder, _ = cassette.New(path, cassette.ModeReplay)
+	model = newOpenAIModel(t, "http://127.0.0.1:1/v1/chat/completions", recorder)
+	_, err = model.Complete(context.Background(), newHelloRequest("goodbye"))
+	if !errors.Is(err, cassette.ErrInteractionNotFound) {
+		t.Fatalf("expected interaction not found, got %v", err)
+	}
+}
+
+func Test_Cassette_Normalized_Body(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	var path = filepath.Join(t.TempDir(), "body.json")
+	var post = func(recorder *cassette.Recorder, body string) error {
+		var client = http.Client{Transport: recorder}
+		var response, err = client.Post(server.URL+"/api?key=secret&b=1", "application/json", strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		return nil
+	}
+
+	var recorder, err = cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = post(recorder, `{"b": 2, "a": [1, 2.50]}`); err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys order and spaces do not matter
+	recorder, _ = cassette.New(path, cassette.ModeReplay)
+	if err = post(recorder, `{"a":[1,2.50],"b":2}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay or record, unmatched requests are appended
+	recorder, _ = cassette.New(path, cassette.ModeReplayOrRecord)
+	if err = post(recorder, `{"a":[1,2.50],"b":3}`); err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := cassette.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(saved.Interactions))
+	}
+	if strings.Contains(saved.Interactions[0].Request.Url, "secret") {
+		t.Fatalf("query key is not scrubbed: %s", saved.Interactions[0].Request.Url)
+	}
+}
+
+func Test_Cassette_Missing(t *testing.T) {
+	var _, err = cassette.New(filepath.Join(t.TempDir(), "missing.json"), cassette.ModeReplay)
+	if !errors.Is(err, cassette.ErrCassetteNotFound) {
+		t.Fatalf("expected cassette not found, got %v", err)
+	}
+}
+
+// failingTB records the failure of ForTest and stops the goroutine, like
+// testing.T.
+type failingTB struct {
+	testing.TB
+	failure string
+}
+
+func (t *failingTB) Fatalf(format string, args ...any) {
+	t.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func (t *failingTB) Fatal(args ...any) {
+	t.failure = fmt.Sprint(args...)
+	runtime.Goexit()
+}
+
+// A missing cassette fails the test instead of skipping it.
+func Test_Cassette_ForTest_Missing(t *testing.T) {
+	t.Setenv(cassette.EnvMode, "replay")
+
+	var tb = &failingTB{TB: t}
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		cassette.ForTest(tb, t.TempDir())
+	}()
+	<-done
+
+	if !strings.Contains(tb.failure, "is not recorded") {
+		t.Fatalf("unexpected failure: %q", tb.failure)
+	}
+}
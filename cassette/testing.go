@@ -0,0 +1,53 @@
+package cassette
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// EnvMode is the environment variable selects the mode of ForTest, see
+// ParseMode. For example, record the cassettes of the OpenAI chat tests:
+//
+//	AIGC_CASSETTE=record go test -tags private -run OpenAI ./chat/test
+const EnvMode = "AIGC_CASSETTE"
+
+// ForTest returns a Recorder of the cassette <dir>/<test name>.json, in the
+// mode of the AIGC_CASSETTE environment variable. In ModeReplay the test
+// fails if the cassette has not been recorded.
+//
+// The cassette is saved when the test finishes, unless the test failed.
+func ForTest(t testing.TB, dir string) *Recorder {
+	t.Helper()
+
+	var mode, err = ParseMode(os.Getenv(EnvMode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name = strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(t.Name())
+	var path = filepath.Join(dir, name+".json")
+
+	recorder, err := New(path, mode)
+	if errors.Is(err, ErrCassetteNotFound) {
+		t.Fatalf("cassette %s is not recorded, run with %s=record to record it", path, EnvMode)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if t.Failed() {
+			return
+		}
+		if err := recorder.Save(); err != nil {
+			t.Error(err)
+		}
+		if n := recorder.Unused(); n > 0 {
+			t.Logf("%d interactions of cassette %s are not replayed", n, path)
+		}
+	})
+	return recorder
+}
@@ -50,20 +50,32 @@ type claudeMessage struct {
 	Content any    `json:"content"` // string or list of content block
 }
 
-type claudeImageSource struct {
-	Type      string `json:"type"`       // "base64"
-	MediaType string `json:"media_type"` // "image/jpeg"
-	Data      string `json:"data"`       // "/9j/4AAQSkZJRg..."
+// claudeSource is the source of an image or document block.
+type claudeSource struct {
+	Type      string `json:"type"`                 // "base64" | "text" | "url"
+	MediaType string `json:"media_type,omitempty"` // "image/jpeg" | "application/pdf" | "text/plain"
+	Data      string `json:"data,omitempty"`       // "/9j/4AAQSkZJRg..."
+	Url       string `json:"url,omitempty"`        // "https://example.com/report.pdf"
 }
 
 type claudeContentBlock struct {
-	Type string `json:"type"` // "text" | "image" | "tool_use" | "tool_result"
+	Type string `json:"type"` // "text" | "image" | "document" | "tool_use" | "tool_result" | "thinking" | "redacted_thinking"
 
 	// For text block
 	Text string `json:"text,omitempty"` // "hello"
 
-	// For image block
-	Source *claudeImageSource `json:"source,omitempty"`
+	// For thinking block
+	Thinking  string `json:"thinking,omitempty"`  // "Let me analyze this step by step..."
+	Signature string `json:"signature,omitempty"` // "WaUjzkypQ2mUEVM36O2TxuC06KN8xyfbJwyem2dw3URve/op91XWHOEBLLqIOMfFG/UvLEczmEsUjavL...."
+
+	// For redacted_thinking block
+	Data string `json:"data,omitempty"` // "EmwKAhgBEgy3va3pzix/LafPsn4aDFIT2Xlxh0L5L8rLVyIwxtE3rAFBa8cr3qpP..."
+
+	// For image and document block
+	Source *claudeSource `json:"source,omitempty"`
+
+	// For document block
+	Title string `json:"title,omitempty"` // "Annual Report"
 
 	// For tool_use block
 	Id    string          `json:"id,omitempty"`    // "toolu_01A09q90qw90lq917835lq9"
@@ -74,10 +86,13 @@ type claudeContentBlock struct {
 	ToolUseId string `json:"tool_use_id,omitempty"` // "toolu_01A09q90qw90lq917835lq9"
 	Content   any    `json:"content,omitempty"`     // string or map[string]any
 
+	// Prompt caching breakpoint. {"type": "ephemeral"}
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+
 	// For Input pointer pointed to
 	inputValue map[string]any
 	// For Source pointer pointed to
-	sourceValue claudeImageSource
+	sourceValue claudeSource
 }
 
 type claudeToolInputSchema struct {
@@ -93,6 +108,8 @@ type claudeTool struct {
 	Description string `json:"description,omitempty"` // "Get the weather for a location"
 	// Tool parameters
 	InputSchema claudeToolInputSchema `json:"input_schema,omitempty"`
+	// Prompt caching breakpoint. {"type": "ephemeral"}
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 type claudeToolChoice struct {
@@ -100,6 +117,11 @@ type claudeToolChoice struct {
 	Name string `json:"name,omitempty"` // "get_weather"
 }
 
+type claudeThinking struct {
+	Type         string `json:"type"`          // "enabled"
+	BudgetTokens int32  `json:"budget_tokens"` // 1024 at least
+}
+
 var (
 	claudeToolChoiceAuto = claudeToolChoice{Type: "auto"}
 	claudeToolChoiceAny  = claudeToolChoice{Type: "any"}
@@ -116,8 +138,9 @@ type claudeModelRequest struct {
 	Temperature *float64 `json:"temperature,omitempty"`
 	// Top_P for nucleus sampling, between 0 and 1
 	TopP *float64 `json:"top_p,omitempty"`
-	// System prompt
-	System string `json:"system,omitempty"`
+	// System prompt, string or list of text block. Blocks are only used for
+	// prompt caching breakpoints
+	System any `json:"system,omitempty"`
 	// User and assistant messages
 	Messages []claudeMessage `json:"messages"`
 	// Tools
@@ -127,11 +150,15 @@ type claudeModelRequest struct {
 	//   {"type":"any"}
 	//   {"type":"tool", "name":"get_weather"}}
 	ToolChoice *claudeToolChoice `json:"tool_choice,omitempty"`
+	// Extended thinking. {"type": "enabled", "budget_tokens": 2048}
+	Thinking *claudeThinking `json:"thinking,omitempty"`
 
 	// Temperature value, for Temperature pointer pointed to
 	temperatureValue float64
 	// TopP value, for TopP pointer pointed to
 	topPValue float64
+	// Thinking value, for Thinking pointer pointed to
+	thinkingValue claudeThinking
 }
 
 type anthropicClaudeModelRequest struct {
@@ -143,6 +170,8 @@ type anthropicClaudeModelRequest struct {
 type bedrockClaudeModelRequest struct {
 	// Must be "bedrock-2023-05-31", only for AWS Bedrock API
 	BedrockAnthropicVersion string `json:"anthropic_version,omitempty"`
+	// Beta features, the anthropic-beta header of Anthropic API
+	AnthropicBeta []string `json:"anthropic_beta,omitempty"`
 	claudeModelRequest
 }
 
@@ -155,15 +184,20 @@ type claudeModelResponse struct {
 	StopReason   string               `json:"stop_reason"`   // "end_return" | "max_tokens" | "stop_sequence" | "tool_use"
 	StopSequence string               `json:"stop_sequence"` // Which custom stop sequence was generated
 	Usage        struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
+		// Input tokens after the last cache breakpoint
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 	} `json:"usage"`
 }
 
 type anthropicClaudeModel struct {
 	ModelId     string
+	Endpoint    string
 	ApiKey      string
 	ApiVersion  string
+	Betas       []string
 	Proxy       string
 	Retries     int
 	RequestLog  func([]byte)
@@ -175,6 +209,7 @@ type anthropicClaudeModel struct {
 type bedrockClaudeModel struct {
 	ModelId     string
 	ApiVersion  string
+	Betas       []string
 	Region      string
 	AccessKey   string
 	SecretKey   string
@@ -202,9 +237,25 @@ func (r *claudeModelRequest) load(request *ModelRequest) error {
 }
 
 func (r *claudeModelRequest) loadParameters(request *ModelRequest) error {
-	if request.MaxTokens.Valid && request.MaxTokens.Value > 0 {
-		r.MaxTokens = request.MaxTokens.Value
+	if request.Audio != nil {
+		return fmt.Errorf("[claudeModelRequest.loadParameters] %w", &UnsupportedContentError{ContentType: ContentTypeAudio})
+	}
+
+	if request.ThinkingBudget.Valid && request.ThinkingBudget.Value > 0 {
+		r.thinkingValue = claudeThinking{Type: "enabled", BudgetTokens: request.ThinkingBudget.Value}
+		r.Thinking = &r.thinkingValue
 	} else {
+		r.thinkingValue = claudeThinking{}
+		r.Thinking = nil
+	}
+
+	switch {
+	case request.MaxTokens.Valid && request.MaxTokens.Value > 0:
+		r.MaxTokens = request.MaxTokens.Value
+	case r.Thinking != nil:
+		// max_tokens must be greater than budget_tokens
+		r.MaxTokens = r.Thinking.BudgetTokens + claudeDefaultMaxTokens
+	default:
 		r.MaxTokens = claudeDefaultMaxTokens
 	}
 
@@ -276,6 +327,7 @@ func (r *claudeModelRequest) loadTools(request *ModelRequest) error {
 
 		t.Name = tool.Name
 		t.Description = tool.Description
+		t.CacheControl = tool.CacheControl
 		t.InputSchema.Type = "object"
 		t.InputSchema.Properties = tool.Parameters.Properties
 		if len(tool.Parameters.Required) > 0 {
@@ -302,6 +354,8 @@ func (r *claudeModelRequest) loadTools(request *ModelRequest) error {
 func (r *claudeModelRequest) transformInitialSystemMessages(messages []Message) (int, error) {
 	var index = 0
 	var hasSystemInjection = false
+	var hasCacheControl = false
+	var blocks []claudeContentBlock
 	var buf = aigc.AllocBuffer()
 
 	defer aigc.FreeBuffer(buf)
@@ -316,6 +370,14 @@ func (r *claudeModelRequest) transformInitialSystemMessages(messages []Message)
 					buf.WriteByte('\n')
 				}
 				buf.WriteString(content.Text)
+				blocks = append(blocks, claudeContentBlock{
+					Type:         "text",
+					Text:         content.Text,
+					CacheControl: content.CacheControl,
+				})
+				if content.CacheControl != nil {
+					hasCacheControl = true
+				}
 			}
 		}
 		index += 1
@@ -334,12 +396,16 @@ func (r *claudeModelRequest) transformInitialSystemMessages(messages []Message)
 			buf.WriteString("\n")
 		}
 		buf.WriteString(claudeSystemInjection)
+		blocks = append(blocks, claudeContentBlock{Type: "text", Text: claudeSystemInjection})
 	}
 
-	if buf.Len() > 0 {
+	switch {
+	case hasCacheControl:
+		r.System = blocks
+	case buf.Len() > 0:
 		r.System = buf.String()
-	} else {
-		r.System = ""
+	default:
+		r.System = nil
 	}
 
 	return index, nil
@@ -392,7 +458,8 @@ func (r *claudeModelRequest) transformUserMessage(message Message, systemInjecti
 		return systemInjection, errors.New("[claudeModelRequest.transformUserMessage] empty content")
 	}
 
-	if len(message.Contents) == 1 && message.Contents[0].Type == ContentTypeText {
+	if len(message.Contents) == 1 && message.Contents[0].Type == ContentTypeText &&
+		message.Contents[0].CacheControl == nil {
 		var text string
 		if systemInjection != "" {
 			text = "<|begin_of_system_instruction|>" + systemInjection + "<|end_of_system_instruction|> " +
@@ -416,7 +483,7 @@ func (r *claudeModelRequest) transformUserMessage(message Message, systemInjecti
 	}
 
 	for _, content := range message.Contents {
-		var block claudeContentBlock
+		var block = claudeContentBlock{CacheControl: content.CacheControl}
 
 		switch content.Type {
 		case ContentTypeText:
@@ -428,13 +495,39 @@ func (r *claudeModelRequest) transformUserMessage(message Message, systemInjecti
 				return systemInjection, errors.New("[claudeModelRequest.transformUserMessage] empty image data")
 			}
 			block.Type = "image"
-			block.sourceValue = claudeImageSource{
+			block.sourceValue = claudeSource{
 				Type:      "base64",
 				MediaType: string(content.MediaType),
 				Data:      base64.StdEncoding.EncodeToString(content.Data),
 			}
 			block.Source = &block.sourceValue
 			blocks = append(blocks, block)
+		case ContentTypeDocument:
+			block.Type = "document"
+			block.Title = content.Title
+			switch {
+			case content.DocumentUrl != "":
+				block.sourceValue = claudeSource{Type: "url", Url: content.DocumentUrl}
+			case content.DocumentType == DocumentPdf && len(content.Data) > 0:
+				block.sourceValue = claudeSource{
+					Type:      "base64",
+					MediaType: string(DocumentPdf),
+					Data:      base64.StdEncoding.EncodeToString(content.Data),
+				}
+			case content.DocumentType == DocumentText:
+				block.sourceValue = claudeSource{
+					Type:      "text",
+					MediaType: string(DocumentText),
+					Data:      documentString(content),
+				}
+			default:
+				return systemInjection, errors.New("[claudeModelRequest.transformUserMessage] invalid document content block")
+			}
+			block.Source = &block.sourceValue
+			blocks = append(blocks, block)
+		case ContentTypeAudio:
+			return "", fmt.Errorf("[claudeModelRequest.transformUserMessage] %w",
+				&UnsupportedContentError{ContentType: content.Type})
 		default:
 			return "", fmt.Errorf(
 				"[claudeModelRequest.transformUserMessage] invalid content type %s", content.Type)
@@ -453,7 +546,7 @@ func (r *claudeModelRequest) transformAssistantMessage(message Message) error {
 	var blocks []claudeContentBlock
 
 	for _, content := range message.Contents {
-		var block claudeContentBlock
+		var block = claudeContentBlock{CacheControl: content.CacheControl}
 		switch content.Type {
 		case ContentTypeText:
 			block.Type = "text"
@@ -473,11 +566,28 @@ func (r *claudeModelRequest) transformAssistantMessage(message Message) error {
 			}
 			block.Input = &block.inputValue
 			blocks = append(blocks, block)
+		case ContentTypeReasoning:
+			// Only signed thinking can be sent back, reasoning of other
+			// vendors is dropped
+			switch {
+			case content.RedactedReasoning != "":
+				block.Type = "redacted_thinking"
+				block.Data = content.RedactedReasoning
+				blocks = append(blocks, block)
+			case content.Signature != "":
+				block.Type = "thinking"
+				block.Thinking = content.Text
+				block.Signature = content.Signature
+				blocks = append(blocks, block)
+			}
 		default:
 			return fmt.Errorf("[claudeModelRequest.transformAssistantMessage] invalid content type %s", content.Type)
 		}
 	}
 
+	if len(blocks) == 0 {
+		return errors.New("[claudeModelRequest.transformAssistantMessage] empty content")
+	}
 	r.Messages = append(r.Messages, claudeMessage{Role: "assistant", Content: blocks})
 	return nil
 }
@@ -491,7 +601,7 @@ func (r *claudeModelRequest) transformToolMessage(message Message) error {
 	var blocks []claudeContentBlock
 
 	for _, content := range message.Contents {
-		var block claudeContentBlock
+		var block = claudeContentBlock{CacheControl: content.CacheControl}
 		switch content.Type {
 		case ContentTypeToolResult:
 			block.Type = "tool_result"
@@ -560,8 +670,13 @@ func (r *claudeModelResponse) dump(response *ModelResponse) error {
 
 	response.Id = r.Id
 	response.FinishReason = FinishReason(r.StopReason)
-	response.Usage.InputTokens = r.Usage.InputTokens
+	// Anthropic input_tokens excludes the tokens read from or written to the
+	// prompt cache
+	response.Usage.InputTokens = r.Usage.InputTokens + r.Usage.CacheReadInputTokens +
+		r.Usage.CacheCreationInputTokens
 	response.Usage.OutputTokens = r.Usage.OutputTokens
+	response.Usage.CachedInputTokens = r.Usage.CacheReadInputTokens
+	response.Usage.CacheCreationInputTokens = r.Usage.CacheCreationInputTokens
 	response.Messages = nil
 
 	var message = Message{Role: RoleAssistant}
@@ -580,6 +695,15 @@ func (r *claudeModelResponse) dump(response *ModelResponse) error {
 				content.Arguments = *block.Input
 			}
 			message.Contents = append(message.Contents, content)
+		case "thinking":
+			content.Type = ContentTypeReasoning
+			content.Text = block.Thinking
+			content.Signature = block.Signature
+			message.Contents = append(message.Contents, content)
+		case "redacted_thinking":
+			content.Type = ContentTypeReasoning
+			content.RedactedReasoning = block.Data
+			message.Contents = append(message.Contents, content)
 		}
 	}
 
@@ -646,7 +770,7 @@ func (m *anthropicClaudeModel) Complete(ctx context.Context, request *ModelReque
 		m.RequestLog(requestJson.Bytes())
 	}
 
-	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, anthropicEndpoint,
+	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, m.Endpoint,
 		bytes.NewReader(requestJson.Bytes()))
 	if err != nil {
 		return nil, fmt.Errorf("[anthropicClaudeModel.Complete] create http request %w", err)
@@ -657,6 +781,9 @@ func (m *anthropicClaudeModel) Complete(ctx context.Context, request *ModelReque
 	} else {
 		httpRequest.Header.Set("anthropic-version", anthropicVersion)
 	}
+	if len(m.Betas) > 0 {
+		httpRequest.Header.Set("anthropic-beta", strings.Join(m.Betas, ","))
+	}
 	httpRequest.Header.Set("Content-Type", httpContentTypeJson)
 
 	httpResponse, err = m.client.Do(httpRequest)
@@ -703,6 +830,7 @@ func (m *bedrockClaudeModel) requestToJson(request *ModelRequest, jsonBuffer *by
 	} else {
 		claudeRequest.BedrockAnthropicVersion = bedrockAnthropicVersion
 	}
+	claudeRequest.AnthropicBeta = m.Betas
 
 	encoder = json.NewEncoder(jsonBuffer)
 	encoder.SetEscapeHTML(false)
@@ -786,23 +914,26 @@ func newAnthropicClaudeModel(modelId string, opts *aigc.ModelOptions) (*anthropi
 	if opts.ApiKey == "" {
 		return nil, errors.New("anthropic api key is required")
 	}
-	if opts.Endpoint != "" {
-		return nil, errors.New("anthropic endpoint is not supported")
-	}
 
 	model = &anthropicClaudeModel{
 		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
 		ApiKey:      opts.ApiKey,
 		ApiVersion:  opts.ApiVersion,
+		Betas:       opts.Betas,
 		Proxy:       opts.Proxy,
 		Retries:     opts.Retries,
 		RequestLog:  opts.RequestLog,
 		ResponseLog: opts.ResponseLog,
 	}
+	if model.Endpoint == "" {
+		model.Endpoint = anthropicEndpoint
+	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -834,6 +965,7 @@ func newBedrockClaudeModel(modelId string, opts *aigc.ModelOptions) (*bedrockCla
 	model = &bedrockClaudeModel{
 		ModelId:     modelId,
 		ApiVersion:  opts.ApiVersion,
+		Betas:       opts.Betas,
 		Region:      opts.Region,
 		AccessKey:   opts.AccessKey,
 		SecretKey:   opts.SecretKey,
@@ -849,6 +981,7 @@ func newBedrockClaudeModel(modelId string, opts *aigc.ModelOptions) (*bedrockCla
 		SecretKey: opts.SecretKey,
 		Proxy:     opts.Proxy,
 		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -0,0 +1,359 @@
+package chat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// ExtractDocumentText extracts the text of a document content block, it is
+// used by the backends without native document support. The builtin
+// extractor supports plain text and simple PDF files whose content streams
+// are uncompressed or FlateDecode encoded with single byte fonts. Replace it
+// with a complete PDF library for scanned, encrypted or CID font documents.
+var ExtractDocumentText = extractDocumentText
+
+func extractDocumentText(content ContentBlock) (string, error) {
+	switch content.DocumentType {
+	case DocumentText:
+		if content.Text != "" {
+			return content.Text, nil
+		}
+		if len(content.Data) > 0 {
+			return string(content.Data), nil
+		}
+	case DocumentPdf:
+		if len(content.Data) > 0 {
+			return extractPdfText(content.Data)
+		}
+	default:
+		return "", fmt.Errorf("[extractDocumentText] invalid document type: %s", content.DocumentType)
+	}
+	if content.DocumentUrl != "" {
+		return "", errors.New("[extractDocumentText] document url is not supported")
+	}
+	return "", errors.New("[extractDocumentText] empty document")
+}
+
+// documentString returns the content of a plain text document.
+func documentString(content ContentBlock) string {
+	if content.Text != "" {
+		return content.Text
+	}
+	return string(content.Data)
+}
+
+// documentPrompt formats the extracted document text for the backends
+// without native document support.
+func documentPrompt(content ContentBlock) (string, error) {
+	var text, err = ExtractDocumentText(content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buf)
+
+	buf.WriteString("<|begin_of_document|>")
+	if content.Title != "" {
+		buf.WriteString("Title: ")
+		buf.WriteString(content.Title)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(text)
+	buf.WriteString("<|end_of_document|>")
+	return buf.String(), nil
+}
+
+// extractPdfText extracts the text shown by the text operators (Tj, TJ, '
+// and ") of all content streams.
+func extractPdfText(data []byte) (string, error) {
+	var builder strings.Builder
+	var offset int
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "", errors.New("[extractPdfText] invalid pdf header")
+	}
+
+	for {
+		var index = bytes.Index(data[offset:], []byte("stream"))
+		if index < 0 {
+			break
+		}
+		index += offset
+		offset = index + len("stream")
+		if index >= 3 && string(data[index-3:index]) == "end" {
+			continue
+		}
+
+		var start = offset
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		var end = bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		end += start
+		offset = end + len("endstream")
+
+		var dict = data[:index]
+		if i := bytes.LastIndex(dict, []byte("obj")); i >= 0 {
+			dict = dict[i:]
+		}
+		var stream = bytes.TrimRight(data[start:end], "\r\n")
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			var reader, err = zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			stream, err = io.ReadAll(reader)
+			if err != nil && len(stream) == 0 {
+				continue
+			}
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Images and other encodings
+			continue
+		}
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+		pdfContentText(stream, &builder)
+	}
+
+	var lines = strings.Split(builder.String(), "\n")
+	var n = 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines[n] = line
+			n++
+		}
+	}
+	if n == 0 {
+		return "", errors.New("[extractPdfText] no text found")
+	}
+	return strings.Join(lines[:n], "\n"), nil
+}
+
+// pdfContentText writes the text of a content stream to the builder.
+func pdfContentText(stream []byte, builder *strings.Builder) {
+	var operands []string
+	var strs []string
+	var inArray bool
+	var i int
+
+	for i < len(stream) {
+		var c = stream[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0:
+			i++
+		case c == '%':
+			for i < len(stream) && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			var s string
+			s, i = pdfLiteralString(stream, i+1)
+			strs = append(strs, s)
+		case c == '<' && i+1 < len(stream) && stream[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(stream) && stream[i+1] == '>':
+			i += 2
+		case c == '<':
+			var s string
+			s, i = pdfHexString(stream, i+1)
+			strs = append(strs, s)
+		case c == '[':
+			inArray = true
+			strs = strs[:0]
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		default:
+			var start = i
+			if c == '/' {
+				i++
+			}
+			for i < len(stream) && !pdfDelimiter(stream[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			var token = string(stream[start:i])
+			if inArray {
+				// A large negative kerning in TJ arrays is a word gap
+				if f, err := strconv.ParseFloat(token, 64); err == nil && f < -200 {
+					strs = append(strs, " ")
+				}
+				continue
+			}
+			if token[0] == '/' || pdfNumber(token) {
+				operands = append(operands, token)
+				continue
+			}
+
+			switch token {
+			case "Tj", "TJ":
+				builder.WriteString(strings.Join(strs, ""))
+			case "'", "\"":
+				builder.WriteByte('\n')
+				builder.WriteString(strings.Join(strs, ""))
+			case "T*", "ET":
+				builder.WriteByte('\n')
+			case "Td", "TD":
+				if len(operands) >= 2 && operands[len(operands)-1] != "0" {
+					builder.WriteByte('\n')
+				} else {
+					builder.WriteByte(' ')
+				}
+			}
+			operands = operands[:0]
+			strs = strs[:0]
+		}
+	}
+}
+
+func pdfDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func pdfNumber(token string) bool {
+	var _, err = strconv.ParseFloat(token, 64)
+	return err == nil
+}
+
+// pdfLiteralString parses a literal string after the opening parenthesis,
+// returns the decoded string and the offset after the closing parenthesis.
+func pdfLiteralString(stream []byte, i int) (string, int) {
+	var buf []byte
+	var depth = 1
+
+	for i < len(stream) {
+		var c = stream[i]
+		i++
+		switch c {
+		case '(':
+			depth++
+			buf = append(buf, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfDecodeString(buf), i
+			}
+			buf = append(buf, c)
+		case '\\':
+			if i >= len(stream) {
+				break
+			}
+			c = stream[i]
+			i++
+			switch c {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case '\r':
+				if i < len(stream) && stream[i] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if c >= '0' && c <= '7' {
+					var v = int(c - '0')
+					for n := 0; n < 2 && i < len(stream) && stream[i] >= '0' && stream[i] <= '7'; n++ {
+						v = v*8 + int(stream[i]-'0')
+						i++
+					}
+					buf = append(buf, byte(v))
+				} else {
+					buf = append(buf, c)
+				}
+			}
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return pdfDecodeString(buf), i
+}
+
+// pdfHexString parses a hex string after the opening angle bracket.
+func pdfHexString(stream []byte, i int) (string, int) {
+	var buf []byte
+	var high = -1
+
+	for i < len(stream) {
+		var c = stream[i]
+		i++
+		if c == '>' {
+			break
+		}
+		var v int
+		switch {
+		case c >= '0' && c <= '9':
+			v = int(c - '0')
+		case c >= 'a' && c <= 'f':
+			v = int(c-'a') + 10
+		case c >= 'A' && c <= 'F':
+			v = int(c-'A') + 10
+		default:
+			continue
+		}
+		if high < 0 {
+			high = v
+		} else {
+			buf = append(buf, byte(high<<4|v))
+			high = -1
+		}
+	}
+	if high >= 0 {
+		buf = append(buf, byte(high<<4))
+	}
+	return pdfDecodeString(buf), i
+}
+
+// pdfDecodeString decodes UTF-16BE strings with byte order mark, other
+// strings are treated as Latin-1 (close to PDFDocEncoding).
+func pdfDecodeString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		var u = make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+
+	var runes = make([]rune, 0, len(b))
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\t' {
+			continue
+		}
+		runes = append(runes, rune(c))
+	}
+	return string(runes)
+}
@@ -0,0 +1,11 @@
+package chat
+
+// UnsupportedContentError is returned when the backend does not support a
+// content type, e.g. audio content for Claude models.
+type UnsupportedContentError struct {
+	ContentType ContentType
+}
+
+func (e *UnsupportedContentError) Error() string {
+	return "unsupported content type: " + string(e.ContentType)
+}
@@ -449,6 +449,16 @@ func (b llama3PromptBuilder) formatUserMessage(message *Message, buffer *bytes.B
 		case content.Type == ContentTypeText:
 			buffer.WriteString(content.Text)
 			buffer.WriteByte('\n')
+		case content.Type == ContentTypeDocument:
+			var text, err = documentPrompt(content)
+			if err != nil {
+				return fmt.Errorf("[llama3PromptBuilder.formatUserMessage] %w", err)
+			}
+			buffer.WriteString(text)
+			buffer.WriteByte('\n')
+		case content.Type == ContentTypeAudio:
+			return fmt.Errorf("[llama3PromptBuilder.formatUserMessage] %w",
+				&UnsupportedContentError{ContentType: content.Type})
 		}
 	}
 	buffer.WriteString(llama3EndOfTurn)
@@ -750,7 +760,7 @@ func (p llama3GenerationParser) parseFunctionCalls(tools []Tool, generation stri
 		tag = append(tag, '"')
 		tag = append(tag, tools[i].Name...)
 		tag = append(tag, '"')
-		if !strings.Contains(generation, unsafe.String(unsafe.SliceData(tag), len(tag))) {
+		if strings.Contains(generation, unsafe.String(unsafe.SliceData(tag), len(tag))) {
 			match = true
 			break
 		}
@@ -910,6 +920,11 @@ func (r *bedrockLlama3ModelRequest) load(request *ModelRequest) error {
 }
 
 func (r *bedrockLlama3ModelRequest) loadParameters(request *ModelRequest) error {
+	if request.Audio != nil {
+		return fmt.Errorf("[bedrockLlama3ModelRequest.loadParameters] %w",
+			&UnsupportedContentError{ContentType: ContentTypeAudio})
+	}
+
 	if request.MaxTokens.Valid && request.MaxTokens.Value > 0 {
 		r.MaxGenLen = request.MaxTokens.Value
 	} else {
@@ -1091,6 +1106,7 @@ func newBedrockLlama3Model(modelId string, opts *aigc.ModelOptions) (*bedrockLla
 			AccessKey: opts.AccessKey,
 			SecretKey: opts.SecretKey,
 			Proxy:     opts.Proxy,
+			Transport: opts.HttpTransport,
 		},
 	}
 
@@ -14,8 +14,12 @@ type ContentType string
 const (
 	ContentTypeText       ContentType = "text"
 	ContentTypeImage      ContentType = "image"
+	ContentTypeDocument   ContentType = "document"
+	ContentTypeAudio      ContentType = "audio"
 	ContentTypeToolCall   ContentType = "tool_call"
 	ContentTypeToolResult ContentType = "tool_result"
+	// Reasoning of the model before the answer. Only in assistant messages.
+	ContentTypeReasoning ContentType = "reasoning"
 )
 
 type ImageMediaType string
@@ -27,20 +31,69 @@ const (
 	ImageAvif ImageMediaType = "image/avif"
 )
 
+type AudioFormat string
+
+const (
+	AudioWav   AudioFormat = "wav"
+	AudioMp3   AudioFormat = "mp3"
+	AudioFlac  AudioFormat = "flac"
+	AudioOpus  AudioFormat = "opus"
+	AudioPcm16 AudioFormat = "pcm16"
+)
+
+type DocumentMediaType string
+
+const (
+	DocumentPdf  DocumentMediaType = "application/pdf"
+	DocumentText DocumentMediaType = "text/plain"
+)
+
+// CacheControl marks a prompt caching breakpoint, the prompt prefix up to and
+// including the block is cached by the vendor. Only for Anthropic models,
+// ignored by others.
+type CacheControl struct {
+	Type string `json:"type"`          // "ephemeral"
+	TTL  string `json:"ttl,omitempty"` // "5m" | "1h"
+}
+
+var CacheControlEphemeral = CacheControl{Type: "ephemeral"}
+
 type ContentBlock struct {
 	Type ContentType `json:"type"`
 
 	// OpenAI compatible
 	Refusal string `json:"refusal,omitempty"`
 
-	// For text content
+	// For text and reasoning content
 	Text string `json:"text,omitempty"`
 
-	// For image content
+	// For reasoning content. Opaque signature of the reasoning text, e.g.
+	// Claude thinking. Signed reasoning must be sent back unchanged in the
+	// following requests of tool calls.
+	Signature string `json:"signature,omitempty"`
+	// For reasoning content. Encrypted reasoning without text, e.g. Claude
+	// redacted_thinking.
+	RedactedReasoning string `json:"redacted_reasoning,omitempty"`
+
+	// For image, document and audio content
 	MediaType ImageMediaType `json:"media_type,omitempty"`
 	Data      []byte         `json:"data,omitempty"`
 	ImageUrl  string         `json:"image_url,omitempty"`
 
+	// For document content. The document is PDF or plain text bytes in Data,
+	// plain text in Text, or a PDF url in DocumentUrl. Backends without
+	// native document support send the text by ExtractDocumentText.
+	DocumentType DocumentMediaType `json:"document_type,omitempty"`
+	DocumentUrl  string            `json:"document_url,omitempty"`
+	Title        string            `json:"title,omitempty"`
+
+	// For audio content. The audio bytes are in Data. Assistant audio has the
+	// AudioId to refer to it in the following requests, and the Transcript
+	// of the audio.
+	AudioFormat AudioFormat `json:"audio_format,omitempty"`
+	AudioId     string      `json:"audio_id,omitempty"`
+	Transcript  string      `json:"transcript,omitempty"`
+
 	// For tool use content
 	ToolCallId string `json:"tool_call_id,omitempty"`
 	// For tool call
@@ -48,6 +101,9 @@ type ContentBlock struct {
 	Arguments map[string]any `json:"arguments,omitempty"`
 	// For tool result content. Must be string, []any or map[string]any
 	Result any `json:"result,omitempty"`
+
+	// Prompt caching breakpoint
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 type Message struct {
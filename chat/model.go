@@ -19,7 +19,7 @@ func NewModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (Model, err
 		fn(&opts)
 	}
 
-	if strings.Index(string(modelId), "gpt") >= 0 {
+	if strings.Index(string(modelId), "gpt") >= 0 || gptReasoningModel(string(modelId)) {
 		if opts.VendorId == aigc.Vendors.Microsoft {
 			return newAzureGptModel(string(modelId), &opts)
 		}
@@ -53,14 +53,23 @@ func NewModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (Model, err
 		}
 	}
 
-	if strings.Index(string(modelId), "qwen") >= 0 {
+	if strings.Index(string(modelId), "qwen") >= 0 || strings.Index(string(modelId), "qwq") >= 0 {
 		if opts.VendorId == aigc.Vendors.Alibaba {
 			return newDashScopeQwenModel(string(modelId), &opts)
 		}
 	}
 
-	if strings.Index(string(modelId), "voyage") >= 0 {
+	// deepseek-chat, deepseek-reasoner by the OpenAI compatible API of
+	// DeepSeek, the endpoint is required
+	if strings.Index(string(modelId), "deepseek") >= 0 {
+		if opts.VendorId == aigc.Vendors.OpenAI && opts.Endpoint != "" {
+			return newOpenAIGptModel(string(modelId), &opts)
+		}
+	}
 
+	// Voyage serves embedding and rerank models only
+	if strings.Index(string(modelId), "voyage") >= 0 {
+		return nil, fmt.Errorf("model can not be created: %s is an embedding model, see embedding.NewModel", modelId)
 	}
 
 	if opts.VendorId == aigc.Vendors.Ollama {
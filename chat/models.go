@@ -23,6 +23,16 @@ var Models = struct {
 	OpenAIGpt35_Turbo_16k_20230613   aigc.ModelId
 	OpenAIGpt35_Turbo_20230613       aigc.ModelId
 
+	// OpenAI GPT audio models
+	OpenAIGpt4oAudioPreview          aigc.ModelId
+	OpenAIGpt4oAudioPreview_20241001 aigc.ModelId
+
+	// OpenAI o-series reasoning models
+	OpenAIO1Preview          aigc.ModelId
+	OpenAIO1Preview_20240912 aigc.ModelId
+	OpenAIO1Mini             aigc.ModelId
+	OpenAIO1Mini_20240912    aigc.ModelId
+
 	// Anthropic Claude models
 	AnthropicClaude35Sonnet          aigc.ModelId
 	AnthropicClaude3Opus             aigc.ModelId
@@ -68,6 +78,7 @@ var Models = struct {
 	Qwen2_72B_Instruct aigc.ModelId
 	Qwen2_57B_Instruct aigc.ModelId
 	Qwen2_7B_Instruct  aigc.ModelId
+	QwQ_32B_Preview    aigc.ModelId
 }{
 	// OpenAI GPT models
 	OpenAIGpt4oMini:                  "gpt-4o-mini",
@@ -89,6 +100,16 @@ var Models = struct {
 	OpenAIGpt35_Turbo_16k_20230613:   "gpt-3.5-turbo-16k-0613",
 	OpenAIGpt35_Turbo_20230613:       "gpt-3.5-turbo-0613",
 
+	// OpenAI GPT audio models
+	OpenAIGpt4oAudioPreview:          "gpt-4o-audio-preview",
+	OpenAIGpt4oAudioPreview_20241001: "gpt-4o-audio-preview-2024-10-01",
+
+	// OpenAI o-series reasoning models
+	OpenAIO1Preview:          "o1-preview",
+	OpenAIO1Preview_20240912: "o1-preview-2024-09-12",
+	OpenAIO1Mini:             "o1-mini",
+	OpenAIO1Mini_20240912:    "o1-mini-2024-09-12",
+
 	// Anthropic Claude models
 	AnthropicClaude35Sonnet:          "claude-3-5-sonnet-20240620",
 	AnthropicClaude3Opus:             "claude-3-opus-20240229",
@@ -134,4 +155,5 @@ var Models = struct {
 	Qwen2_72B_Instruct: "qwen2-72b-instruct",
 	Qwen2_57B_Instruct: "qwen2-57b-a14b-instruct",
 	Qwen2_7B_Instruct:  "qwen2-7b-instruct",
+	QwQ_32B_Preview:    "qwq-32b-preview",
 }
@@ -32,6 +32,7 @@ type ollamaMessage struct {
 	// system, user, assistant or tool
 	Role      string           `json:"role,omitempty"`
 	Content   string           `json:"content,omitempty"`
+	Thinking  string           `json:"thinking,omitempty"`
 	Images    []string         `json:"images,omitempty"`
 	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
 }
@@ -104,6 +105,9 @@ type ollamaModelRequest struct {
 	Format string `json:"format,omitempty"`
 	// Should be false
 	Stream bool `json:"stream"`
+	// Whether thinking models should think before responding, the thinking
+	// is returned separately from the content.
+	Think bool `json:"think,omitempty"`
 	// Options for the model.
 	Options ollamaModelOptions `json:"options,omitempty"`
 }
@@ -142,6 +146,8 @@ type ollamaChatModel struct {
 	Retries     int
 	RequestLog  func([]byte)
 	ResponseLog func([]byte)
+	// Counts the tokens of the prompt to choose the context length
+	TokenCounter func(text string) int
 
 	client aigc.HttpClient
 }
@@ -167,6 +173,10 @@ func (r *ollamaModelRequest) load(request *ModelRequest) error {
 }
 
 func (r *ollamaModelRequest) loadParameters(request *ModelRequest) error {
+	if request.Audio != nil {
+		return fmt.Errorf("[ollamaModelRequest.loadParameters] %w", &UnsupportedContentError{ContentType: ContentTypeAudio})
+	}
+
 	if request.MaxTokens.Valid && request.MaxTokens.Value > 0 {
 		r.Options.numPredictValue = request.MaxTokens.Value
 		r.Options.NumPredict = &r.Options.numPredictValue
@@ -190,6 +200,8 @@ func (r *ollamaModelRequest) loadParameters(request *ModelRequest) error {
 		r.Options.topPValue = 0
 		r.Options.TopP = nil
 	}
+
+	r.Think = request.ReasoningEffort != "" || (request.ThinkingBudget.Valid && request.ThinkingBudget.Value > 0)
 	return nil
 }
 
@@ -412,6 +424,15 @@ func (r *ollamaModelRequest) transformUserMessage(message Message) error {
 				Role:   "user",
 				Images: []string{base64.StdEncoding.EncodeToString(content.Data)},
 			})
+		case ContentTypeDocument:
+			var text, err = documentPrompt(content)
+			if err != nil {
+				return fmt.Errorf("[ollamaModelRequest.transformUserMessage] %w", err)
+			}
+			r.Messages = append(r.Messages, ollamaMessage{Role: "user", Content: text})
+		case ContentTypeAudio:
+			return fmt.Errorf("[ollamaModelRequest.transformUserMessage] %w",
+				&UnsupportedContentError{ContentType: content.Type})
 		default:
 			return fmt.Errorf("[ollamaModelRequest.transformUserMessage] invalid content type: %s", content.Type)
 		}
@@ -455,6 +476,8 @@ func (r *ollamaModelRequest) transformAssistantMessage(message Message) error {
 				})
 				lastCall = &r.Messages[len(r.Messages)-1]
 			}
+		case ContentTypeReasoning:
+			// Thinking is not sent back
 		default:
 			return fmt.Errorf("[ollamaModelRequest.transformAssistantMessage] invalid content type: %s", content.Type)
 		}
@@ -496,6 +519,10 @@ func (r *ollamaModelResponse) dump(response *ModelResponse) error {
 	var message = Message{Role: RoleAssistant}
 
 	response.FinishReason = "stop"
+	if r.DoneReason != "" {
+		// "stop", "length"
+		response.FinishReason = FinishReason(r.DoneReason)
+	}
 	response.Usage.InputTokens = r.PromptEvalCount
 	response.Usage.OutputTokens = r.EvalCount
 	response.Messages = nil
@@ -503,9 +530,14 @@ func (r *ollamaModelResponse) dump(response *ModelResponse) error {
 		response.FinishReason = "tool_calls"
 	}
 
+	if r.Message.Thinking != "" {
+		var block = ContentBlock{Type: ContentTypeReasoning, Text: r.Message.Thinking}
+		message.Contents = append(message.Contents, block)
+	}
+
 	if r.Message.Content != "" {
 		var block = ContentBlock{Type: ContentTypeText, Text: r.Message.Content}
-		message.Contents = []ContentBlock{block}
+		message.Contents = append(message.Contents, block)
 	}
 
 	if len(r.Message.ToolCalls) > 0 {
@@ -604,15 +636,15 @@ func (m *ollamaChatModel) estimateRequestTokens(request *ollamaModelRequest) int
 
 	for _, message := range request.Messages {
 		tokens += 10
-		tokens += aigc.Tokenizer.FastEstimate(message.Content)
+		tokens += m.TokenCounter(message.Content)
 		for _, toolCall := range message.ToolCalls {
 			tokens += 100
 			for k, v := range toolCall.Function.Arguments {
 				tokens += 10
-				tokens += aigc.Tokenizer.FastEstimate(k)
+				tokens += m.TokenCounter(k)
 				var s, ok = v.(string)
 				if ok {
-					tokens += aigc.Tokenizer.FastEstimate(s)
+					tokens += m.TokenCounter(s)
 				} else {
 					tokens += 100
 				}
@@ -744,11 +776,17 @@ func newOllamaChatModel(modelId string, opts *aigc.ModelOptions) (*ollamaChatMod
 		Retries:     opts.Retries,
 		RequestLog:  opts.RequestLog,
 		ResponseLog: opts.ResponseLog,
+
+		TokenCounter: opts.TokenCounter,
+	}
+	if model.TokenCounter == nil {
+		model.TokenCounter = aigc.Tokenizer.FastEstimate
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
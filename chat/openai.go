@@ -15,6 +15,7 @@ import (
 
 const (
 	gptDefaultMaxTokens = 1000
+	gptDefaultVoice     = "alloy"
 
 	gptToolChoiceNone     = "none"
 	gptToolChoiceAuto     = "auto"
@@ -49,6 +50,14 @@ type gptMessage struct {
 	// The refusal message by the assistant.
 	Refusal string `json:"refusal,omitempty"`
 
+	// The reasoning of the assistant. Only for DeepSeek reasoner and Qwen QwQ,
+	// OpenAI does not return reasoning text.
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+
+	// The audio of the assistant if the audio output modality is requested.
+	// Only the id is sent back in the following requests.
+	Audio *gptAudio `json:"audio,omitempty"`
+
 	// The tool calls generated by the assistant.
 	ToolCalls []gptToolCall `json:"tool_calls,omitempty"`
 
@@ -57,13 +66,62 @@ type gptMessage struct {
 }
 
 type gptContentBlock struct {
-	// "text" | "image_url"
-	Type     string       `json:"type"`
-	Text     string       `json:"text,omitempty"`
-	ImageUrl *gptImageUrl `json:"image_url,omitempty"`
+	// "text" | "image_url" | "file" | "input_audio"
+	Type       string         `json:"type"`
+	Text       string         `json:"text,omitempty"`
+	ImageUrl   *gptImageUrl   `json:"image_url,omitempty"`
+	File       *gptFile       `json:"file,omitempty"`
+	InputAudio *gptInputAudio `json:"input_audio,omitempty"`
 
 	// image_url value, for ImageUrl pointer pointed to
 	imageUrlValue gptImageUrl
+
+	// file value, for File pointer pointed to
+	fileValue gptFile
+
+	// input_audio value, for InputAudio pointer pointed to
+	inputAudioValue gptInputAudio
+}
+
+type gptInputAudio struct {
+	// Base64 encoded audio data
+	Data string `json:"data"`
+
+	// "wav" | "mp3"
+	Format string `json:"format"`
+}
+
+type gptAudio struct {
+	// Unique identifier for this audio response, refers to the audio in the
+	// following requests
+	Id string `json:"id,omitempty"`
+
+	// The Unix timestamp (in seconds) for when this audio response will no
+	// longer be accessible on the server for use in multi-turn conversations.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+
+	// Base64 encoded audio bytes generated by the model, in the format
+	// specified in the request.
+	Data string `json:"data,omitempty"`
+
+	// Transcript of the audio generated by the model.
+	Transcript string `json:"transcript,omitempty"`
+}
+
+type gptAudioOutput struct {
+	// "alloy" | "ash" | "ballad" | "coral" | "echo" | "sage" | "shimmer" | "verse"
+	Voice string `json:"voice"`
+
+	// "wav" | "mp3" | "flac" | "opus" | "pcm16"
+	Format string `json:"format"`
+}
+
+type gptFile struct {
+	// The name of the file, e.g. "report.pdf"
+	Filename string `json:"filename,omitempty"`
+
+	// The base64 encoded file data, "data:application/pdf;base64,{base64_pdf}"
+	FileData string `json:"file_data,omitempty"`
 }
 
 type gptImageUrl struct {
@@ -149,23 +207,21 @@ type gptToolCall struct {
 }
 
 // The results of the content filter. Only included in Azure API responses.
+type gptAzureFilterResult struct {
+	Filtered bool `json:"filtered,omitempty"`
+	// "safe" | "low" | "medium" | "high"
+	Severity string `json:"severity,omitempty"`
+	// For jailbreak and protected material filters
+	Detected bool `json:"detected,omitempty"`
+}
+
 type gptAzureFilterResults struct {
-	Hate struct {
-		Filtered bool   `json:"filtered,omitempty"`
-		Severity string `json:"severity,omitempty"`
-	} `json:"hate,omitempty"`
-	SelfHarm struct {
-		Filtered bool   `json:"filtered,omitempty"`
-		Severity string `json:"severity,omitempty"`
-	} `json:"self_harm,omitempty"`
-	Sexual struct {
-		Filtered bool   `json:"filtered,omitempty"`
-		Severity string `json:"severity,omitempty"`
-	} `json:"sexual,omitempty"`
-	Violence struct {
-		Filtered bool   `json:"filtered,omitempty"`
-		Severity string `json:"severity,omitempty"`
-	} `json:"violence,omitempty"`
+	Hate      gptAzureFilterResult  `json:"hate,omitempty"`
+	SelfHarm  gptAzureFilterResult  `json:"self_harm,omitempty"`
+	Sexual    gptAzureFilterResult  `json:"sexual,omitempty"`
+	Violence  gptAzureFilterResult  `json:"violence,omitempty"`
+	Jailbreak *gptAzureFilterResult `json:"jailbreak,omitempty"`
+	Profanity *gptAzureFilterResult `json:"profanity,omitempty"`
 }
 
 // The results of the prompt content filter. Only included in Azure API responses.
@@ -217,6 +273,23 @@ type gptModelRequest struct {
 	// model's context length.
 	MaxTokens int32 `json:"max_tokens,omitempty"`
 
+	// An upper bound for the number of tokens that can be generated for a
+	// completion, including visible output tokens and reasoning tokens.
+	// o-series models only accept max_completion_tokens.
+	MaxCompletionTokens int32 `json:"max_completion_tokens,omitempty"`
+
+	// Constrains effort on reasoning for reasoning models. "low" | "medium" |
+	// "high"
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+
+	// Output types that you would like the model to generate, ["text"] or
+	// ["text", "audio"].
+	Modalities []string `json:"modalities,omitempty"`
+
+	// Parameters for audio output. Required when audio output is requested
+	// with modalities: ["audio"].
+	Audio *gptAudioOutput `json:"audio,omitempty"`
+
 	// How many chat completion choices to generate for each input message.
 	// Note that you will be charged based on the number of generated tokens
 	// across all the choices. Keep n as 1 to minimize costs.
@@ -348,6 +421,12 @@ type gptModelRequest struct {
 
 	// StreamOption value, for StreamOption pointer pointed to
 	streamOptionValue gptStreamOption
+
+	// Audio value, for Audio pointer pointed to
+	audioValue gptAudioOutput
+
+	// Send documents as extracted text, for the APIs without file input
+	documentAsText bool
 }
 
 type gptModelResponse struct {
@@ -368,6 +447,16 @@ type gptModelResponse struct {
 		CompletionTokens int `json:"completion_tokens"`
 		// otal number of tokens used in the request (prompt + completion).
 		TotalTokens int `json:"total_tokens"`
+		// Breakdown of tokens used in the prompt.
+		PromptTokensDetails struct {
+			// Cached tokens present in the prompt.
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		// Breakdown of tokens used in a completion.
+		CompletionTokensDetails struct {
+			// Tokens generated by the model for reasoning.
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"completion_tokens_details"`
 	} `json:"usage"`
 
 	// The Unix timestamp (in seconds) of when the chat completion was created.
@@ -460,6 +549,12 @@ func (f *gptAzureFilterResults) FilterString() string {
 	return builder.String()
 }
 
+// gptReasoningModel reports whether the model is an o-series reasoning model,
+// e.g. "o1-mini", "o3".
+func gptReasoningModel(modelId string) bool {
+	return len(modelId) >= 2 && modelId[0] == 'o' && modelId[1] >= '1' && modelId[1] <= '9'
+}
+
 type openaiGptModel struct {
 	ModelId     string
 	Endpoint    string
@@ -485,6 +580,32 @@ type azureGptModel struct {
 	client aigc.HttpClient
 }
 
+func (f *gptAzureFilterResults) dump(source ContentFilterSource, promptIndex int) (ContentFilter, bool) {
+	var filter = ContentFilter{Source: source, PromptIndex: promptIndex}
+	var add = func(category string, result *gptAzureFilterResult) {
+		if result == nil || (result.Severity == "" && !result.Filtered && !result.Detected) {
+			return
+		}
+		var severity = result.Severity
+		if severity == "" && result.Detected {
+			severity = "detected"
+		}
+		filter.Categories = append(filter.Categories, ContentFilterCategory{
+			Category: category,
+			Filtered: result.Filtered,
+			Severity: severity,
+		})
+		filter.Filtered = filter.Filtered || result.Filtered
+	}
+	add("hate", &f.Hate)
+	add("self_harm", &f.SelfHarm)
+	add("sexual", &f.Sexual)
+	add("violence", &f.Violence)
+	add("jailbreak", f.Jailbreak)
+	add("profanity", f.Profanity)
+	return filter, len(filter.Categories) > 0
+}
+
 func (f *gptPromptFilterResult) FilterString() string {
 	var s = f.ContentFilterResults.FilterString()
 	if s == "" {
@@ -538,9 +659,99 @@ func (r *gptModelRequest) loadParameters(request *ModelRequest) error {
 		r.ParallelToolCalls = nil
 	}
 
+	r.ReasoningEffort = string(request.ReasoningEffort)
+
+	if request.Audio != nil {
+		r.Modalities = []string{"text", "audio"}
+		r.audioValue = gptAudioOutput{Voice: request.Audio.Voice, Format: string(request.Audio.Format)}
+		if r.audioValue.Voice == "" {
+			r.audioValue.Voice = gptDefaultVoice
+		}
+		if r.audioValue.Format == "" {
+			r.audioValue.Format = string(AudioWav)
+		}
+		r.Audio = &r.audioValue
+	} else {
+		r.Modalities = nil
+		r.audioValue = gptAudioOutput{}
+		r.Audio = nil
+	}
+
 	return nil
 }
 
+// loadMaxCompletionTokens replaces max_tokens by max_completion_tokens for
+// o-series models. The default max tokens is not set, it is too small for
+// reasoning.
+func (r *gptModelRequest) loadMaxCompletionTokens(request *ModelRequest) {
+	r.MaxTokens = 0
+	if request.MaxTokens.Valid {
+		r.MaxCompletionTokens = request.MaxTokens.Value
+	} else {
+		r.MaxCompletionTokens = 0
+	}
+}
+
+// loadReasoningModel adapts the request to o-series models, which reject a
+// non-default temperature or top_p. System prompts are developer messages
+// since o1, o1-mini and o1-preview accept neither, their system prompts are
+// put before the next user message.
+func (r *gptModelRequest) loadReasoningModel(request *ModelRequest, modelId string) {
+	r.loadMaxCompletionTokens(request)
+
+	r.temperatureValue = 0
+	r.Temperature = nil
+	r.topPValue = 0
+	r.TopP = nil
+
+	if !strings.HasPrefix(modelId, "o1-mini") && !strings.HasPrefix(modelId, "o1-preview") {
+		for i, _ := range r.Messages {
+			if r.Messages[i].Role == "system" {
+				r.Messages[i].Role = "developer"
+			}
+		}
+		return
+	}
+
+	var messages = make([]gptMessage, 0, len(r.Messages))
+	var systems []gptMessage
+	for _, message := range r.Messages {
+		if message.Role == "system" {
+			systems = append(systems, message)
+			continue
+		}
+		if message.Role == "user" && len(systems) > 0 {
+			message.Content = gptFoldContents(append(systems, message))
+			systems = nil
+		}
+		messages = append(messages, message)
+	}
+	if len(systems) > 0 {
+		messages = append(messages, gptMessage{Role: "user", Content: gptFoldContents(systems)})
+	}
+	r.Messages = messages
+}
+
+// gptFoldContents joins the contents of messages into the content of one
+// message, a string if all contents are strings.
+func gptFoldContents(messages []gptMessage) any {
+	var texts []string
+	var blocks []gptContentBlock
+	for _, message := range messages {
+		switch content := message.Content.(type) {
+		case string:
+			texts = append(texts, content)
+			blocks = append(blocks, gptContentBlock{Type: "text", Text: content})
+		case []gptContentBlock:
+			blocks = append(blocks, content...)
+		}
+	}
+	if len(texts) == len(messages) {
+		return strings.Join(texts, "\n\n")
+	}
+	return blocks
+}
+
 func (r *gptModelRequest) loadPrompts(request *ModelRequest) error {
 	var err error
 	var index int
@@ -717,7 +928,7 @@ func (r *gptModelRequest) transformSystemMessage(message Message) error {
 				block.imageUrlValue.Url = content.ImageUrl
 				contents = append(contents, block)
 			} else if len(content.Data) > 0 && content.MediaType != "" {
-				block.ImageUrl.Url = string(content.MediaType) + ";base64," +
+				block.ImageUrl.Url = "data:" + string(content.MediaType) + ";base64," +
 					base64.StdEncoding.EncodeToString(content.Data)
 				contents = append(contents, block)
 			} else {
@@ -752,16 +963,45 @@ func (r *gptModelRequest) transformUserMessage(message Message) error {
 			contents = append(contents, block)
 		case ContentTypeImage:
 			block.Type = "image_url"
+			block.ImageUrl = &block.imageUrlValue
 			if content.ImageUrl != "" {
 				block.ImageUrl.Url = content.ImageUrl
 				contents = append(contents, block)
 			} else if len(content.Data) > 0 && content.MediaType != "" {
-				block.ImageUrl.Url = string(content.MediaType) + ";base64," +
+				block.ImageUrl.Url = "data:" + string(content.MediaType) + ";base64," +
 					base64.StdEncoding.EncodeToString(content.Data)
 				contents = append(contents, block)
 			} else {
 				return errors.New("[gptModelRequest.transformUserMessage] invalid image content block")
 			}
+		case ContentTypeDocument:
+			if !r.documentAsText && content.DocumentType == DocumentPdf && len(content.Data) > 0 {
+				block.Type = "file"
+				block.File = &block.fileValue
+				block.File.Filename = content.Title
+				if block.File.Filename == "" {
+					block.File.Filename = "document.pdf"
+				}
+				block.File.FileData = "data:" + string(DocumentPdf) + ";base64," +
+					base64.StdEncoding.EncodeToString(content.Data)
+			} else {
+				var text, err = documentPrompt(content)
+				if err != nil {
+					return fmt.Errorf("[gptModelRequest.transformUserMessage] %w", err)
+				}
+				block.Type = "text"
+				block.Text = text
+			}
+			contents = append(contents, block)
+		case ContentTypeAudio:
+			if len(content.Data) == 0 || content.AudioFormat == "" {
+				return errors.New("[gptModelRequest.transformUserMessage] invalid audio content block")
+			}
+			block.Type = "input_audio"
+			block.InputAudio = &block.inputAudioValue
+			block.InputAudio.Data = base64.StdEncoding.EncodeToString(content.Data)
+			block.InputAudio.Format = string(content.AudioFormat)
+			contents = append(contents, block)
 		default:
 			return fmt.Errorf("[gptModelRequest.transformUserMessage] invalid content type: %s", content.Type)
 		}
@@ -792,6 +1032,7 @@ func (r *gptModelRequest) transformAssistantMessage(message Message) error {
 	var err error
 	var contents []gptContentBlock
 	var toolCalls []gptToolCall
+	var audio *gptAudio
 
 	for _, content := range message.Contents {
 		switch content.Type {
@@ -800,6 +1041,14 @@ func (r *gptModelRequest) transformAssistantMessage(message Message) error {
 				Type: "text",
 				Text: content.Text,
 			})
+		case ContentTypeAudio:
+			// Assistant audio is referred by id, the transcript is sent if
+			// there is no id
+			if content.AudioId != "" {
+				audio = &gptAudio{Id: content.AudioId}
+			} else if content.Transcript != "" {
+				contents = append(contents, gptContentBlock{Type: "text", Text: content.Transcript})
+			}
 		case ContentTypeToolCall:
 			var toolCall gptToolCall
 			toolCall.Id = content.ToolCallId
@@ -810,6 +1059,9 @@ func (r *gptModelRequest) transformAssistantMessage(message Message) error {
 				return fmt.Errorf("[gptModelRequest.transformAssistantMessage] %w", err)
 			}
 			toolCalls = append(toolCalls, toolCall)
+		case ContentTypeReasoning:
+			// Reasoning is not sent back, DeepSeek rejects reasoning_content
+			// in requests
 		default:
 			return fmt.Errorf("[gptModelRequest.transformAssistantMessage] invalid content type: %s", content.Type)
 		}
@@ -822,6 +1074,7 @@ func (r *gptModelRequest) transformAssistantMessage(message Message) error {
 	if len(toolCalls) > 0 {
 		transformedMessage.ToolCalls = toolCalls
 	}
+	transformedMessage.Audio = audio
 
 	r.Messages = append(r.Messages, transformedMessage)
 	return nil
@@ -891,8 +1144,17 @@ func (r *gptModelResponse) dump(response *ModelResponse) error {
 	response.FinishReason = FinishReason(choice.FinishReason)
 	response.Usage.InputTokens = r.Usage.PromptTokens
 	response.Usage.OutputTokens = r.Usage.CompletionTokens
+	response.Usage.CachedInputTokens = r.Usage.PromptTokensDetails.CachedTokens
+	response.Usage.ReasoningTokens = r.Usage.CompletionTokensDetails.ReasoningTokens
 	response.Messages = nil
 
+	if choice.Message.ReasoningContent != "" {
+		message.Contents = append(message.Contents, ContentBlock{
+			Type: ContentTypeReasoning,
+			Text: choice.Message.ReasoningContent,
+		})
+	}
+
 	if choice.Message.Content != nil || choice.Message.Refusal != "" {
 		var block = ContentBlock{Type: ContentTypeText}
 		var ok bool
@@ -906,7 +1168,19 @@ func (r *gptModelResponse) dump(response *ModelResponse) error {
 			block.Text = text
 		}
 		block.Refusal = choice.Message.Refusal
-		message.Contents = []ContentBlock{block}
+		message.Contents = append(message.Contents, block)
+	}
+	if choice.Message.Audio != nil {
+		var block = ContentBlock{
+			Type:       ContentTypeAudio,
+			AudioId:    choice.Message.Audio.Id,
+			Transcript: choice.Message.Audio.Transcript,
+		}
+		block.Data, err = base64.StdEncoding.DecodeString(choice.Message.Audio.Data)
+		if err != nil {
+			return fmt.Errorf("[gptModelResponse.dump] invalid audio data: %w", err)
+		}
+		message.Contents = append(message.Contents, block)
 	}
 	if len(choice.Message.ToolCalls) > 0 {
 		for _, toolCall := range choice.Message.ToolCalls {
@@ -926,12 +1200,27 @@ func (r *gptModelResponse) dump(response *ModelResponse) error {
 	}
 
 	response.ContentFilterResult = r.dumpContentFilterResult()
+	response.ContentFilters = r.dumpContentFilters()
 
 	response.Messages = append(response.Messages, message)
 
 	return nil
 }
 
+func (r *gptModelResponse) dumpContentFilters() []ContentFilter {
+	var filters []ContentFilter
+	for i := range r.PromptFilterResults {
+		var result = &r.PromptFilterResults[i]
+		if filter, ok := result.ContentFilterResults.dump(ContentFilterPrompt, result.PromptIndex); ok {
+			filters = append(filters, filter)
+		}
+	}
+	if filter, ok := r.Choices[0].ContentFilterResults.dump(ContentFilterCompletion, 0); ok {
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
 func (r *gptModelResponse) dumpContentFilterResult() string {
 	var builder strings.Builder
 	var choice = r.Choices[0]
@@ -979,6 +1268,10 @@ func (m *openaiGptModel) requestToJson(request *ModelRequest, jsonBuffer *bytes.
 
 	gptRequest.Model = m.ModelId
 
+	if gptReasoningModel(m.ModelId) || request.ReasoningEffort != "" {
+		gptRequest.loadReasoningModel(request, m.ModelId)
+	}
+
 	// Fix "additionalProperties"
 	for i, _ := range gptRequest.Tools {
 		var function = &gptRequest.Tools[i].Function
@@ -1120,6 +1413,12 @@ func (m *azureGptModel) requestToJson(request *ModelRequest, jsonBuffer *bytes.B
 	gptRequest.parallelToolCallsValue = false
 	gptRequest.ParallelToolCalls = nil
 
+	// Deployment id is usually the model id, reasoning models deployed by
+	// other names are known by reasoning effort only
+	if gptReasoningModel(m.ModelId) || request.ReasoningEffort != "" {
+		gptRequest.loadReasoningModel(request, m.ModelId)
+	}
+
 	// Azure OpenAI does not support "tools-function-strict" parameter and
 	// "tools-function-parameters-additionalProperties" parameter
 	for i, _ := range request.Tools {
@@ -1240,8 +1539,9 @@ func newOpenAIGptModel(modelId string, opts *aigc.ModelOptions) (*openaiGptModel
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -1274,8 +1574,9 @@ func newAzureGptModel(modelId string, opts *aigc.ModelOptions) (*azureGptModel,
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
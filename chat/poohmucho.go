@@ -401,7 +401,7 @@ func newPoohMuchoChatModel(modelId string, opts *aigc.ModelOptions) (*poohmuchoM
 		Proxy:    opts.Proxy,
 		Retries:  opts.Retries,
 	}
-	model.httpClient = aigc.HttpClient{Proxy: opts.Proxy, Retries: opts.Retries}
+	model.httpClient = aigc.HttpClient{Proxy: opts.Proxy, Retries: opts.Retries, Transport: opts.HttpTransport}
 
 	httpClient, err = model.httpClient.Client()
 	if err != nil {
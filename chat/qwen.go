@@ -48,17 +48,28 @@ func (m *dashScopeQwenModel) requestToJson(request *ModelRequest, jsonBuffer *by
 	var gptRequest gptModelRequest
 	var encoder *json.Encoder
 
+	// DashScope does not support file input
+	gptRequest.documentAsText = true
+
 	err = gptRequest.load(request)
 	if err != nil {
 		return fmt.Errorf("[dashScopeQwenModel.requestToJson] %w", err)
 	}
 
+	// Qwen text models do not support audio input or output
+	if request.Audio != nil || hasUserAudio(request.Messages) {
+		return fmt.Errorf("[dashScopeQwenModel.requestToJson] %w", &UnsupportedContentError{ContentType: ContentTypeAudio})
+	}
+
 	gptRequest.Model = m.ModelId
 
 	// DashScope does not support "parallel_tool_calls"
 	gptRequest.parallelToolCallsValue = false
 	gptRequest.ParallelToolCalls = nil
 
+	// DashScope does not support "reasoning_effort", QwQ models always reason
+	gptRequest.ReasoningEffort = ""
+
 	// Fix "additionalProperties"
 	for i, _ := range gptRequest.Tools {
 		var function = &gptRequest.Tools[i].Function
@@ -85,6 +96,21 @@ func (m *dashScopeQwenModel) requestToJson(request *ModelRequest, jsonBuffer *by
 	return nil
 }
 
+// hasUserAudio reports whether any user message contains audio.
+func hasUserAudio(messages []Message) bool {
+	for _, message := range messages {
+		if message.Role != RoleUser {
+			continue
+		}
+		for _, content := range message.Contents {
+			if content.Type == ContentTypeAudio {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (m *dashScopeQwenModel) jsonToResponse(jsonBuffer *bytes.Buffer) (*ModelResponse, error) {
 	var err error
 	var gptResponse gptModelResponse
@@ -192,8 +218,9 @@ func newDashScopeQwenModel(modelId string, opts *aigc.ModelOptions) (*dashScopeQ
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -4,6 +4,21 @@ import (
 	"github.com/Pooh-Mucho/go-aigc"
 )
 
+type ReasoningEffort string
+
+const (
+	ReasoningEffortLow    ReasoningEffort = "low"
+	ReasoningEffortMedium ReasoningEffort = "medium"
+	ReasoningEffortHigh   ReasoningEffort = "high"
+)
+
+// AudioOutput requests the assistant audio in the response, only for OpenAI
+// audio models, e.g. gpt-4o-audio-preview.
+type AudioOutput struct {
+	Voice  string      // "alloy" | "ash" | "ballad" | "coral" | "echo" | "sage" | "shimmer" | "verse"
+	Format AudioFormat // "wav" | "mp3" | "flac" | "opus" | "pcm16"
+}
+
 type ModelRequest struct {
 	Messages          []Message
 	Tools             []Tool
@@ -12,6 +27,13 @@ type ModelRequest struct {
 	TopP              aigc.Nullable[float64]
 	ToolChoice        *ToolChoice
 	ParallelToolCalls aigc.Nullable[bool]
+	// Reasoning effort of OpenAI o-series models
+	ReasoningEffort ReasoningEffort
+	// Maximum number of tokens of Claude extended thinking, thinking is
+	// enabled if it is set. MaxTokens must be greater than it.
+	ThinkingBudget aigc.Nullable[int32]
+	// Assistant audio output, the transcript is returned with the audio
+	Audio *AudioOutput
 }
 
 func (r *ModelRequest) Copy() *ModelRequest {
@@ -20,6 +42,8 @@ func (r *ModelRequest) Copy() *ModelRequest {
 		Temperature:       r.Temperature,
 		TopP:              r.TopP,
 		ParallelToolCalls: r.ParallelToolCalls,
+		ReasoningEffort:   r.ReasoningEffort,
+		ThinkingBudget:    r.ThinkingBudget,
 	}
 
 	if len(r.Messages) > 0 {
@@ -36,6 +60,10 @@ func (r *ModelRequest) Copy() *ModelRequest {
 		z.ToolChoice = new(ToolChoice)
 		*z.ToolChoice = *r.ToolChoice
 	}
+	if r.Audio != nil {
+		z.Audio = new(AudioOutput)
+		*z.Audio = *r.Audio
+	}
 
 	return z
 }
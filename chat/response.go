@@ -1,5 +1,7 @@
 package chat
 
+import "github.com/Pooh-Mucho/go-aigc"
+
 type FinishReason string
 
 type FinishReasonType uint32
@@ -41,12 +43,54 @@ func (r FinishReason) Type() FinishReasonType {
 type TokenUsage struct {
 	InputTokens  int
 	OutputTokens int
+	// Number of input tokens served from the vendor's prompt cache. It is a
+	// part of InputTokens.
+	CachedInputTokens int
+	// Number of input tokens written to the vendor's prompt cache. It is a
+	// part of InputTokens.
+	CacheCreationInputTokens int
+	// Number of reasoning tokens. It is a part of OutputTokens.
+	ReasoningTokens int
+}
+
+type ContentFilterSource string
+
+const (
+	ContentFilterPrompt     ContentFilterSource = "prompt"
+	ContentFilterCompletion ContentFilterSource = "completion"
+)
+
+// ContentFilterCategory is the result of a category of a content filter.
+type ContentFilterCategory struct {
+	// e.g. "hate", "self_harm", "sexual", "violence", "jailbreak"
+	Category string
+	Filtered bool
+	// Severity reported by the filter, e.g. "safe", "low", "medium", "high"
+	Severity string
+	// Score from 0 to 1, zero if the filter does not report scores
+	Score float64
+}
+
+// ContentFilter is the result of a content filter for the prompt or the
+// completion. Returned by Azure OpenAI, or added by moderation wrappers.
+type ContentFilter struct {
+	Source ContentFilterSource
+	// Index of the prompt, only for prompt filters
+	PromptIndex int
+	// Whether any category is filtered
+	Filtered   bool
+	Categories []ContentFilterCategory
 }
 
 type ModelResponse struct {
-	Id                  string
-	Messages            []Message
-	FinishReason        FinishReason
-	Usage               TokenUsage
+	Id           string
+	Messages     []Message
+	FinishReason FinishReason
+	Usage        TokenUsage
+	// Filtered categories as a string, e.g. "{violence: high}"
 	ContentFilterResult string
+	// Structured results of the content filters
+	ContentFilters []ContentFilter
+	// Set by response cache wrappers, see package cache
+	Cache aigc.CacheMetadata
 }
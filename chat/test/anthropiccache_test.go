@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/conformance"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"testing"
+)
+
+func Test_Anthropic_Prompt_Caching(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolAnthropic)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.AnthropicClaude35Sonnet_20240620,
+		aigc.WithVendor(aigc.Vendors.Anthropic),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+		aigc.WithBetas("prompt-caching-2024-07-31", "max-tokens-3-5-sonnet-2024-07-15"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reply = vendortest.TextReply("Hello!")
+	reply.Response.Usage = chat.TokenUsage{
+		InputTokens:              1200,
+		OutputTokens:             3,
+		CachedInputTokens:        1000,
+		CacheCreationInputTokens: 150,
+	}
+	server.Enqueue(reply)
+
+	var tool = conformance.Tools.Add
+	tool.CacheControl = &chat.CacheControlEphemeral
+	var request = &chat.ModelRequest{
+		Messages: []chat.Message{
+			{Role: chat.RoleSystem, Contents: []chat.ContentBlock{
+				{Type: chat.ContentTypeText, Text: "You are a helpful assistant."},
+				{Type: chat.ContentTypeText, Text: "A long document.", CacheControl: &chat.CacheControlEphemeral},
+			}},
+			{Role: chat.RoleUser, Contents: []chat.ContentBlock{
+				{Type: chat.ContentTypeText, Text: "hello", CacheControl: &chat.CacheControlEphemeral},
+			}},
+		},
+		Tools: []chat.Tool{tool},
+	}
+
+	response, err := model.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Usage != reply.Response.Usage {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+
+	var sent, _ = server.LastRequest()
+	if beta := sent.Header.Get("anthropic-beta"); beta != "prompt-caching-2024-07-31,max-tokens-3-5-sonnet-2024-07-15" {
+		t.Errorf("unexpected anthropic-beta header: %s", beta)
+	}
+
+	var body struct {
+		System []struct {
+			Text         string             `json:"text"`
+			CacheControl *chat.CacheControl `json:"cache_control"`
+		} `json:"system"`
+		Messages []struct {
+			Content []struct {
+				CacheControl *chat.CacheControl `json:"cache_control"`
+			} `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			CacheControl *chat.CacheControl `json:"cache_control"`
+		} `json:"tools"`
+	}
+	if err = sent.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.System) != 2 || body.System[0].CacheControl != nil ||
+		body.System[1].CacheControl == nil || body.System[1].CacheControl.Type != "ephemeral" {
+		t.Errorf("unexpected system: %s", sent.Body)
+	}
+	if len(body.Messages) != 1 || len(body.Messages[0].Content) != 1 || body.Messages[0].Content[0].CacheControl == nil {
+		t.Errorf("unexpected messages: %s", sent.Body)
+	}
+	if len(body.Tools) != 1 || body.Tools[0].CacheControl == nil {
+		t.Errorf("unexpected tools: %s", sent.Body)
+	}
+}
@@ -0,0 +1,160 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/conformance"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"testing"
+)
+
+var audioWav = []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+
+var Message_Audio = chat.Message{
+	Role: chat.RoleUser,
+	Contents: []chat.ContentBlock{
+		{Type: chat.ContentTypeAudio, AudioFormat: chat.AudioWav, Data: audioWav},
+		{Type: chat.ContentTypeText, Text: "Answer the question in the recording."},
+	},
+}
+
+func Test_Audio_OpenAI(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.OpenAIGpt4oAudioPreview,
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var answer = []byte("RIFF\x24\x00\x00\x00WAVEanswer")
+	server.Enqueue(
+		vendortest.TextReply("").WithAudio("audio_abc123", answer, "The capital of France is Paris."),
+		vendortest.TextReply("You are welcome."),
+	)
+
+	var request = &chat.ModelRequest{
+		Messages: []chat.Message{Message_Audio},
+		Audio:    &chat.AudioOutput{Voice: "alloy", Format: chat.AudioWav},
+	}
+	response, err := model.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var audio *chat.ContentBlock
+	for i, content := range response.Messages[0].Contents {
+		if content.Type == chat.ContentTypeAudio {
+			audio = &response.Messages[0].Contents[i]
+		}
+	}
+	if audio == nil || audio.AudioId != "audio_abc123" || string(audio.Data) != string(answer) ||
+		audio.Transcript != "The capital of France is Paris." {
+		t.Fatalf("unexpected response: %+v", response.Messages)
+	}
+
+	var sent, _ = server.LastRequest()
+	var body struct {
+		Modalities []string `json:"modalities"`
+		Audio      struct {
+			Voice  string `json:"voice"`
+			Format string `json:"format"`
+		} `json:"audio"`
+		Messages []struct {
+			Content []struct {
+				Type       string `json:"type"`
+				InputAudio struct {
+					Data   string `json:"data"`
+					Format string `json:"format"`
+				} `json:"input_audio"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err = sent.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Modalities) != 2 || body.Modalities[1] != "audio" || body.Audio.Voice != "alloy" ||
+		body.Audio.Format != "wav" {
+		t.Errorf("unexpected audio output: %s", sent.Body)
+	}
+	var block = body.Messages[0].Content[0]
+	if block.Type != "input_audio" || block.InputAudio.Format != "wav" ||
+		block.InputAudio.Data != base64.StdEncoding.EncodeToString(audioWav) {
+		t.Errorf("unexpected input audio: %s", sent.Body)
+	}
+
+	// The assistant audio is referred by id in the next turn
+	request.Messages = append(request.Messages, response.Messages[0], chat.Message{
+		Role:     chat.RoleUser,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Thanks!"}},
+	})
+	if _, err = model.Complete(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ = server.LastRequest()
+	var next struct {
+		Messages []struct {
+			Role  string `json:"role"`
+			Audio *struct {
+				Id   string `json:"id"`
+				Data string `json:"data"`
+			} `json:"audio"`
+		} `json:"messages"`
+	}
+	if err = sent.Decode(&next); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.Messages) != 3 || next.Messages[1].Audio == nil ||
+		next.Messages[1].Audio.Id != "audio_abc123" || next.Messages[1].Audio.Data != "" {
+		t.Errorf("unexpected assistant audio: %s", sent.Body)
+	}
+}
+
+func Test_Audio_Unsupported(t *testing.T) {
+	var cases = []struct {
+		protocol vendortest.Protocol
+		modelId  aigc.ModelId
+		vendor   aigc.VendorId
+	}{
+		{vendortest.ProtocolAnthropic, chat.Models.AnthropicClaude35Sonnet_20240620, aigc.Vendors.Anthropic},
+		{vendortest.ProtocolDashScope, chat.Models.QwenMax, aigc.Vendors.Alibaba},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.vendor), func(t *testing.T) {
+			var server = vendortest.NewServer(c.protocol)
+			defer server.Close()
+
+			var model, err = chat.NewModel(c.modelId,
+				aigc.WithVendor(c.vendor),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var requests = []*chat.ModelRequest{
+				{Messages: []chat.Message{Message_Audio}},
+				{Messages: []chat.Message{conformance.Messages.Hello}, Audio: &chat.AudioOutput{}},
+			}
+			for _, request := range requests {
+				var unsupported *chat.UnsupportedContentError
+				_, err = model.Complete(context.Background(), request)
+				if !errors.As(err, &unsupported) || unsupported.ContentType != chat.ContentTypeAudio {
+					t.Errorf("expected unsupported audio error, got %v", err)
+				}
+			}
+			if len(server.Requests()) != 0 {
+				t.Error("unsupported request is sent")
+			}
+		})
+	}
+}
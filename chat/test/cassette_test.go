@@ -0,0 +1,106 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/cassette"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"os"
+	"testing"
+)
+
+// CassetteDir contains the recorded http traffic of the tests, see
+// cassette.ForTest
+const CassetteDir = "testdata/cassettes"
+
+// Cassettes reports whether the vendor tests go through cassettes, selected
+// by the AIGC_CASSETTE environment variable. By default the vendor tests talk
+// to fake vendor servers.
+func Cassettes() bool {
+	return os.Getenv(cassette.EnvMode) != ""
+}
+
+// WithCassette records or replays the http traffic of the test. Replaying
+// does not need credentials, placeholders are set for the missing ones.
+func WithCassette(t *testing.T) aigc.ModelOptionFunc {
+	var recorder = cassette.ForTest(t, CassetteDir)
+	return func(options *aigc.ModelOptions) {
+		options.HttpTransport = recorder
+		if recorder.Mode != cassette.ModeReplay {
+			return
+		}
+		if options.ApiKey == "" {
+			options.ApiKey = "replay"
+		}
+		if options.AccessKey == "" {
+			options.AccessKey = "replay"
+		}
+		if options.SecretKey == "" {
+			options.SecretKey = "replay"
+		}
+		if options.Endpoint == "" {
+			switch options.VendorId {
+			case aigc.Vendors.Microsoft:
+				options.Endpoint = "https://replay.openai.azure.com"
+			case aigc.Vendors.Ollama:
+				options.Endpoint = "http://localhost:11434"
+			}
+		}
+	}
+}
+
+var fakeProtocols = map[aigc.VendorId]vendortest.Protocol{
+	aigc.Vendors.OpenAI:    vendortest.ProtocolOpenAI,
+	aigc.Vendors.Microsoft: vendortest.ProtocolOpenAI,
+	aigc.Vendors.Anthropic: vendortest.ProtocolAnthropic,
+	aigc.Vendors.Ollama:    vendortest.ProtocolOllama,
+	aigc.Vendors.Alibaba:   vendortest.ProtocolDashScope,
+	aigc.Vendors.Amazon:    vendortest.ProtocolBedrock,
+}
+
+// WithFakeVendor sends the requests of the test to a fake server of the
+// vendor, which sends the replies in order. The server is started when the
+// option is first applied, and shared by the models created with the option.
+// The test fails if a reply is not consumed.
+func WithFakeVendor(t *testing.T, replies ...vendortest.Reply) aigc.ModelOptionFunc {
+	var server *vendortest.Server
+	return func(options *aigc.ModelOptions) {
+		var protocol, ok = fakeProtocols[options.VendorId]
+		if !ok {
+			t.Fatalf("no fake server of vendor '%s'", options.VendorId)
+		}
+		if server == nil {
+			server = vendortest.NewServer(protocol)
+			server.Enqueue(replies...)
+			t.Cleanup(func() {
+				server.Close()
+				if n := server.Pending(); n > 0 {
+					t.Errorf("%d replies of the fake %s server are not consumed", n, protocol)
+				}
+			})
+		}
+
+		switch options.VendorId {
+		case aigc.Vendors.Amazon:
+			// Bedrock models do not support endpoint override
+			options.HttpTransport = server.Transport()
+		case aigc.Vendors.Microsoft:
+			options.Endpoint = server.URL
+		default:
+			options.Endpoint = server.Endpoint()
+		}
+		options.ApiKey = "fake"
+		options.AccessKey = "fake"
+		options.SecretKey = "fake"
+	}
+}
+
+// NewModel creates a model of the test. The model talks to the vendor through
+// the cassette of the test if Cassettes is true, otherwise to a fake vendor
+// server sends the replies.
+func NewModel(t *testing.T, replies []vendortest.Reply, modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (chat.Model, error) {
+	if Cassettes() {
+		return chat.NewModel(modelId, append(options, WithCassette(t))...)
+	}
+	return chat.NewModel(modelId, append(options, WithFakeVendor(t, replies...))...)
+}
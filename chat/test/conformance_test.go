@@ -0,0 +1,72 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/conformance"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Conformance runs the conformance suite against the model and logs the
+// capability matrix. All scenarios share the cassette or the fake vendor
+// server of the test.
+func Conformance(t *testing.T, capabilities []conformance.Capability, modelId aigc.ModelId, options ...aigc.ModelOptionFunc) {
+	var replies []vendortest.Reply
+	for _, scenario := range conformance.Scenarios {
+		if capabilities == nil || slices.Contains(capabilities, scenario.Capability) {
+			replies = append(replies, conformance.Replies[scenario.Name]...)
+		}
+	}
+	var vendor aigc.ModelOptionFunc
+	if Cassettes() {
+		vendor = WithCassette(t)
+	} else {
+		vendor = WithFakeVendor(t, replies...)
+	}
+	var suite = conformance.Suite{
+		Backend:      string(modelId),
+		Capabilities: capabilities,
+		NewModel: func() (chat.Model, error) {
+			return chat.NewModel(modelId, append(options, vendor)...)
+		},
+	}
+	var report = suite.RunTest(t)
+
+	var matrix strings.Builder
+	_ = conformance.WriteMatrix(&matrix, report)
+	t.Log("\n" + matrix.String())
+}
+
+func Test_Conformance_OpenAI_Gpt4oMini(t *testing.T) {
+	Conformance(t, nil, chat.Models.OpenAIGpt4oMini_20240718, WithOpenAI)
+}
+
+func Test_Conformance_Azure_Gpt4oMini(t *testing.T) {
+	Conformance(t, nil, chat.Models.OpenAIGpt4oMini, WithAzure)
+}
+
+func Test_Conformance_Anthropic_Claude3Haiku(t *testing.T) {
+	Conformance(t, nil, chat.Models.AnthropicClaude3Haiku, WithAnthropic)
+}
+
+func Test_Conformance_Qwen_Plus(t *testing.T) {
+	Conformance(t, []conformance.Capability{
+		conformance.CapabilityText,
+		conformance.CapabilityTools,
+		conformance.CapabilityParallelTools,
+		conformance.CapabilitySystemInjection,
+		conformance.CapabilityMaxTokens,
+	}, chat.Models.QwenPlus, WithAliyun)
+}
+
+func Test_Conformance_Ollama_Llama31_8b(t *testing.T) {
+	Conformance(t, []conformance.Capability{
+		conformance.CapabilityText,
+		conformance.CapabilityTools,
+		conformance.CapabilitySystemInjection,
+		conformance.CapabilityMaxTokens,
+	}, "llama3.1:8b", WithOllama)
+}
@@ -0,0 +1,226 @@
+package test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"strings"
+	"testing"
+)
+
+const documentPdfContent = "BT /F1 12 Tf 72 712 Td (Quarterly \\(Q3\\) report) Tj 0 -14 Td " +
+	"[(Reve) 20 (nue) -300 (grew) -300 (12%)] TJ T* <4F6B> Tj ET"
+
+const documentPdfText = "Quarterly (Q3) report\nRevenue grew 12%\nOk"
+
+// newDocumentPdf returns a minimal PDF file with a FlateDecode content stream.
+func newDocumentPdf() []byte {
+	var stream bytes.Buffer
+	var writer = zlib.NewWriter(&stream)
+	writer.Write([]byte(documentPdfContent))
+	writer.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", stream.Len())
+	pdf.Write(stream.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func newDocumentRequest() *chat.ModelRequest {
+	return &chat.ModelRequest{Messages: []chat.Message{{
+		Role: chat.RoleUser,
+		Contents: []chat.ContentBlock{
+			{Type: chat.ContentTypeDocument, DocumentType: chat.DocumentPdf, Data: newDocumentPdf(), Title: "report.pdf"},
+			{Type: chat.ContentTypeDocument, DocumentType: chat.DocumentText, Text: "Revenue: 1.2M", Title: "notes.txt"},
+			{Type: chat.ContentTypeText, Text: "Summarize the documents."},
+		},
+	}}}
+}
+
+func Test_Document_Extract_Text(t *testing.T) {
+	var text, err = chat.ExtractDocumentText(chat.ContentBlock{
+		Type:         chat.ContentTypeDocument,
+		DocumentType: chat.DocumentPdf,
+		Data:         newDocumentPdf(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != documentPdfText {
+		t.Errorf("unexpected text: %q", text)
+	}
+
+	text, err = chat.ExtractDocumentText(chat.ContentBlock{
+		Type:         chat.ContentTypeDocument,
+		DocumentType: chat.DocumentText,
+		Data:         []byte("plain text"),
+	})
+	if err != nil || text != "plain text" {
+		t.Errorf("unexpected text: %q, %v", text, err)
+	}
+
+	_, err = chat.ExtractDocumentText(chat.ContentBlock{
+		Type:         chat.ContentTypeDocument,
+		DocumentType: chat.DocumentPdf,
+		DocumentUrl:  "https://example.com/report.pdf",
+	})
+	if err == nil {
+		t.Error("document url is extracted")
+	}
+}
+
+func Test_Document_Anthropic(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolAnthropic)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.AnthropicClaude35Sonnet_20240620,
+		aigc.WithVendor(aigc.Vendors.Anthropic),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Enqueue(vendortest.TextReply("Revenue grew 12%."))
+
+	var request = newDocumentRequest()
+	request.Messages[0].Contents = append(request.Messages[0].Contents, chat.ContentBlock{
+		Type:         chat.ContentTypeDocument,
+		DocumentType: chat.DocumentPdf,
+		DocumentUrl:  "https://example.com/report.pdf",
+	})
+	if _, err = model.Complete(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent, _ = server.LastRequest()
+	var body struct {
+		Messages []struct {
+			Content []struct {
+				Type   string `json:"type"`
+				Title  string `json:"title"`
+				Source struct {
+					Type      string `json:"type"`
+					MediaType string `json:"media_type"`
+					Data      string `json:"data"`
+					Url       string `json:"url"`
+				} `json:"source"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err = sent.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	var blocks = body.Messages[0].Content
+	if len(blocks) != 4 {
+		t.Fatalf("unexpected content: %s", sent.Body)
+	}
+	if blocks[0].Type != "document" || blocks[0].Title != "report.pdf" || blocks[0].Source.Type != "base64" ||
+		blocks[0].Source.MediaType != "application/pdf" ||
+		blocks[0].Source.Data != base64.StdEncoding.EncodeToString(newDocumentPdf()) {
+		t.Errorf("unexpected pdf document: %+v", blocks[0])
+	}
+	if blocks[1].Type != "document" || blocks[1].Source.Type != "text" || blocks[1].Source.Data != "Revenue: 1.2M" {
+		t.Errorf("unexpected text document: %+v", blocks[1])
+	}
+	if blocks[3].Type != "document" || blocks[3].Source.Type != "url" ||
+		blocks[3].Source.Url != "https://example.com/report.pdf" {
+		t.Errorf("unexpected url document: %+v", blocks[3])
+	}
+}
+
+func Test_Document_OpenAI_File(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.OpenAIGpt4o,
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Enqueue(vendortest.TextReply("Revenue grew 12%."))
+
+	if _, err = model.Complete(context.Background(), newDocumentRequest()); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent, _ = server.LastRequest()
+	var body struct {
+		Messages []struct {
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+				File struct {
+					Filename string `json:"filename"`
+					FileData string `json:"file_data"`
+				} `json:"file"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err = sent.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	var blocks = body.Messages[0].Content
+	if len(blocks) != 3 || blocks[0].Type != "file" || blocks[0].File.Filename != "report.pdf" ||
+		blocks[0].File.FileData != "data:application/pdf;base64,"+base64.StdEncoding.EncodeToString(newDocumentPdf()) {
+		t.Fatalf("unexpected content: %s", sent.Body)
+	}
+	// Plain text documents are sent as text
+	if blocks[1].Type != "text" || !strings.Contains(blocks[1].Text, "Revenue: 1.2M") {
+		t.Errorf("unexpected text document: %+v", blocks[1])
+	}
+}
+
+// Backends without native document support receive the extracted text.
+func Test_Document_Text_Fallback(t *testing.T) {
+	var cases = []struct {
+		protocol vendortest.Protocol
+		modelId  aigc.ModelId
+		vendor   aigc.VendorId
+	}{
+		{vendortest.ProtocolDashScope, chat.Models.QwenMax, aigc.Vendors.Alibaba},
+		{vendortest.ProtocolOllama, "llama3.1", aigc.Vendors.Ollama},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.vendor), func(t *testing.T) {
+			var server = vendortest.NewServer(c.protocol)
+			defer server.Close()
+
+			var model, err = chat.NewModel(c.modelId,
+				aigc.WithVendor(c.vendor),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.Enqueue(vendortest.TextReply("Revenue grew 12%."))
+
+			if _, err = model.Complete(context.Background(), newDocumentRequest()); err != nil {
+				t.Fatal(err)
+			}
+
+			var sent, _ = server.LastRequest()
+			var body = string(sent.Body)
+			if strings.Contains(body, `"file"`) || !strings.Contains(body, "Title: report.pdf") ||
+				!strings.Contains(body, "Revenue grew 12%") || !strings.Contains(body, "Revenue: 1.2M") {
+				t.Errorf("unexpected request: %s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/conformance"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"strings"
+	"testing"
+)
+
+func newReasoningRequest() *chat.ModelRequest {
+	return &chat.ModelRequest{
+		Messages: []chat.Message{conformance.Messages.Add_Single},
+		Tools:    []chat.Tool{conformance.Tools.Add},
+	}
+}
+
+func executeReasoningTools(t *testing.T, model chat.Model, request *chat.ModelRequest) *chat.ToolExecutor {
+	var executor = &chat.ToolExecutor{Model: model, InitialRequest: request}
+	for {
+		var ok, err = executor.Execute(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			return executor
+		}
+	}
+}
+
+func reasoningBlocks(message chat.Message) []chat.ContentBlock {
+	var blocks []chat.ContentBlock
+	for _, content := range message.Contents {
+		if content.Type == chat.ContentTypeReasoning {
+			blocks = append(blocks, content)
+		}
+	}
+	return blocks
+}
+
+// Signed thinking blocks are sent back unchanged in the tool call roundtrip.
+func Test_Reasoning_Anthropic_Thinking(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolAnthropic)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.AnthropicClaude35Sonnet_20240620,
+		aigc.WithVendor(aigc.Vendors.Anthropic),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Enqueue(
+		vendortest.ToolCallReply(vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 59318, "y": 40682}}).
+			WithReasoning("", "", "EmwKAhgBEgy3va3pzix").
+			WithReasoning("I should use the add tool.", "WaUjzkypQ2mUEVM36O2TxuC06KN8xyfbJwyem2dw3URve", ""),
+		vendortest.TextReply("59318 + 40682 = 100000"),
+	)
+
+	var request = newReasoningRequest()
+	request.ThinkingBudget = aigc.NewNullable[int32](2048)
+	var executor = executeReasoningTools(t, model, request)
+
+	var blocks = reasoningBlocks(executor.GetRoundtrip(0).Response.Messages[0])
+	if len(blocks) != 2 || blocks[0].Text != "I should use the add tool." ||
+		blocks[0].Signature != "WaUjzkypQ2mUEVM36O2TxuC06KN8xyfbJwyem2dw3URve" ||
+		blocks[1].RedactedReasoning != "EmwKAhgBEgy3va3pzix" {
+		t.Fatalf("unexpected reasoning: %+v", blocks)
+	}
+
+	var requests = server.Requests()
+	var first struct {
+		MaxTokens int `json:"max_tokens"`
+		Thinking  struct {
+			Type         string `json:"type"`
+			BudgetTokens int    `json:"budget_tokens"`
+		} `json:"thinking"`
+	}
+	if err = requests[0].Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Thinking.Type != "enabled" || first.Thinking.BudgetTokens != 2048 || first.MaxTokens <= 2048 {
+		t.Errorf("unexpected thinking request: %s", requests[0].Body)
+	}
+
+	var second struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	var assistant []struct {
+		Type      string `json:"type"`
+		Thinking  string `json:"thinking"`
+		Signature string `json:"signature"`
+		Data      string `json:"data"`
+	}
+	if err = requests[1].Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Messages) != 3 || second.Messages[1].Role != "assistant" {
+		t.Fatalf("unexpected messages: %s", requests[1].Body)
+	}
+	if err = json.Unmarshal(second.Messages[1].Content, &assistant); err != nil {
+		t.Fatal(err)
+	}
+	if len(assistant) != 3 || assistant[0].Type != "thinking" ||
+		assistant[0].Signature != "WaUjzkypQ2mUEVM36O2TxuC06KN8xyfbJwyem2dw3URve" ||
+		assistant[1].Type != "redacted_thinking" || assistant[1].Data != "EmwKAhgBEgy3va3pzix" ||
+		assistant[2].Type != "tool_use" {
+		t.Errorf("thinking is not replayed: %s", requests[1].Body)
+	}
+}
+
+func Test_Reasoning_OpenAI_Effort(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.OpenAIO1Mini,
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reply = vendortest.TextReply("Hello!")
+	reply.Response.Usage = chat.TokenUsage{InputTokens: 10, OutputTokens: 200, ReasoningTokens: 192}
+	server.Enqueue(reply)
+
+	var request = &chat.ModelRequest{
+		Messages:        []chat.Message{conformance.Messages.Hello},
+		ReasoningEffort: chat.ReasoningEffortLow,
+	}
+	response, err := model.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Usage.ReasoningTokens != 192 {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+
+	var sent, _ = server.LastRequest()
+	if sent.Json["reasoning_effort"] != "low" {
+		t.Errorf("reasoning_effort is not sent: %s", sent.Body)
+	}
+	if _, ok := sent.Json["max_tokens"]; ok {
+		t.Errorf("max_tokens is sent to o-series model: %s", sent.Body)
+	}
+}
+
+// o-series models reject temperature and top_p, system prompts are developer
+// messages, or user messages for o1-mini and o1-preview.
+func Test_Reasoning_OpenAI_Parameters(t *testing.T) {
+	var cases = []struct {
+		modelId  aigc.ModelId
+		vendor   aigc.VendorId
+		messages []string
+	}{
+		{"o1", aigc.Vendors.OpenAI, []string{"developer", "user"}},
+		{chat.Models.OpenAIO1Mini, aigc.Vendors.OpenAI, []string{"user"}},
+		{chat.Models.OpenAIO1Preview, aigc.Vendors.Microsoft, []string{"user"}},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.modelId), func(t *testing.T) {
+			var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+			defer server.Close()
+
+			var endpoint = server.Endpoint()
+			if c.vendor == aigc.Vendors.Microsoft {
+				endpoint = server.URL
+			}
+			var model, err = chat.NewModel(c.modelId,
+				aigc.WithVendor(c.vendor),
+				aigc.WithEndpoint(endpoint),
+				aigc.WithApiKey("test-key"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.Enqueue(vendortest.TextReply("Hello!"))
+
+			var request = &chat.ModelRequest{
+				Messages: []chat.Message{
+					{
+						Role:     chat.RoleSystem,
+						Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Answer in French."}},
+					},
+					conformance.Messages.Hello,
+				},
+				Temperature: aigc.NewNullable(0.2),
+				TopP:        aigc.NewNullable(0.9),
+			}
+			if _, err = model.Complete(context.Background(), request); err != nil {
+				t.Fatal(err)
+			}
+
+			var sent, _ = server.LastRequest()
+			var body struct {
+				Temperature *float64 `json:"temperature"`
+				TopP        *float64 `json:"top_p"`
+				Messages    []struct {
+					Role    string `json:"role"`
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			if err = sent.Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Temperature != nil || body.TopP != nil {
+				t.Errorf("temperature or top_p is sent: %s", sent.Body)
+			}
+			var roles []string
+			for _, message := range body.Messages {
+				roles = append(roles, message.Role)
+			}
+			if strings.Join(roles, ",") != strings.Join(c.messages, ",") ||
+				!strings.Contains(body.Messages[0].Content, "Answer in French.") ||
+				!strings.Contains(body.Messages[len(body.Messages)-1].Content, "hello") {
+				t.Errorf("unexpected messages: %s", sent.Body)
+			}
+		})
+	}
+}
+
+// DeepSeek reasoner returns reasoning_content, which must not be sent back.
+func Test_Reasoning_DeepSeek_Reasoning_Content(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+	defer server.Close()
+
+	var model, err = chat.NewModel("deepseek-reasoner",
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Enqueue(
+		vendortest.ToolCallReply(vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 59318, "y": 40682}}).
+			WithReasoning("The user wants a sum, call add.", "", ""),
+		vendortest.TextReply("59318 + 40682 = 100000").WithReasoning("The tool returned 100000.", "", ""),
+	)
+
+	var executor = executeReasoningTools(t, model, newReasoningRequest())
+
+	var blocks = reasoningBlocks(executor.LastResponse().Messages[0])
+	if len(blocks) != 1 || blocks[0].Text != "The tool returned 100000." {
+		t.Fatalf("unexpected reasoning: %+v", blocks)
+	}
+
+	var requests = server.Requests()
+	if strings.Contains(string(requests[1].Body), "reasoning_content") {
+		t.Errorf("reasoning_content is sent back: %s", requests[1].Body)
+	}
+}
+
+// Only the known models of the OpenAI compatible APIs are created by an
+// endpoint override.
+func Test_Reasoning_Unknown_Model(t *testing.T) {
+	var _, err = chat.NewModel("gtp-4o",
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint("http://localhost"),
+		aigc.WithApiKey("test-key"),
+	)
+	if err == nil {
+		t.Error("unknown model is created")
+	}
+	_, err = chat.NewModel("deepseek-chat", aigc.WithVendor(aigc.Vendors.OpenAI), aigc.WithApiKey("test-key"))
+	if err == nil {
+		t.Error("deepseek model is created without endpoint")
+	}
+}
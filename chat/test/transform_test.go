@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"strings"
+	"testing"
+)
+
+func newVendorModel(t *testing.T, protocol vendortest.Protocol, modelId aigc.ModelId, vendorId aigc.VendorId) (*vendortest.Server, chat.Model) {
+	t.Helper()
+	var server = vendortest.NewServer(protocol)
+	t.Cleanup(server.Close)
+	var model, err = chat.NewModel(modelId,
+		aigc.WithVendor(vendorId),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, model
+}
+
+// imageUrls returns the image urls of the content blocks of the sent
+// messages.
+func imageUrls(t *testing.T, request vendortest.Request) []string {
+	t.Helper()
+	var body struct {
+		Messages []struct {
+			Content any `json:"content"`
+		} `json:"messages"`
+	}
+	if err := request.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, message := range body.Messages {
+		var blocks, _ = message.Content.([]any)
+		for _, block := range blocks {
+			if imageUrl, ok := block.(map[string]any)["image_url"].(map[string]any); ok {
+				urls = append(urls, imageUrl["url"].(string))
+			}
+		}
+	}
+	return urls
+}
+
+// Images in Data are sent as data urls by the OpenAI API, and the urls of
+// user images are sent as they are.
+func Test_OpenAI_Image_Urls(t *testing.T) {
+	var server, model = newVendorModel(t, vendortest.ProtocolOpenAI, chat.Models.OpenAIGpt4o, aigc.Vendors.OpenAI)
+	server.Enqueue(vendortest.TextReply("Two images."))
+
+	var image = chat.ContentBlock{Type: chat.ContentTypeImage, MediaType: chat.ImagePng, Data: []byte{0x89, 'P', 'N', 'G'}}
+	var _, err = model.Complete(context.Background(), &chat.ModelRequest{Messages: []chat.Message{
+		{Role: chat.RoleSystem, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Describe the images."}}},
+		{Role: chat.RoleUser, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "The logo"}, image}},
+		{Role: chat.RoleSystem, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "Reference"}, image}},
+		{Role: chat.RoleUser, Contents: []chat.ContentBlock{
+			{Type: chat.ContentTypeText, Text: "And this one"},
+			{Type: chat.ContentTypeImage, ImageUrl: "https://example.com/llama.png"},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request, _ = server.LastRequest()
+	var urls = imageUrls(t, request)
+	if len(urls) != 3 {
+		t.Fatalf("unexpected image urls: %q", urls)
+	}
+	for _, url := range urls[:2] {
+		if !strings.HasPrefix(url, "data:image/png;base64,") {
+			t.Errorf("image data is not a data url: %q", url)
+		}
+	}
+	if urls[2] != "https://example.com/llama.png" {
+		t.Errorf("unexpected image url: %q", urls[2])
+	}
+}
+
+// Ollama reports a truncated completion by done_reason "length".
+func Test_Ollama_Done_Reason(t *testing.T) {
+	var server, model = newVendorModel(t, vendortest.ProtocolOllama, "llama3.1:8b", aigc.Vendors.Ollama)
+	var reply = vendortest.TextReply("Llamas are")
+	reply.Response.FinishReason = "length"
+	server.Enqueue(reply, vendortest.TextReply("Llamas are camelids."))
+
+	var request = &chat.ModelRequest{Messages: []chat.Message{
+		{Role: chat.RoleUser, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "What are llamas?"}}},
+	}}
+	var response, err = model.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.FinishReason.Type() != chat.FinishReasonLength {
+		t.Errorf("unexpected finish reason: %s", response.FinishReason)
+	}
+
+	response, err = model.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.FinishReason.Type() != chat.FinishReasonStop {
+		t.Errorf("unexpected finish reason: %s", response.FinishReason)
+	}
+}
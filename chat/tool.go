@@ -20,6 +20,9 @@ type Tool struct {
 	Strict bool `json:"strict,omitempty"`
 	// The function to call
 	Function func(map[string]interface{}) (any, error) `json:"-"`
+	// Prompt caching breakpoint, the tool definitions up to and including the
+	// tool are cached
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 // Anthropic:
@@ -0,0 +1,12 @@
+package chat
+
+import (
+	"reflect"
+	"time"
+)
+
+var types = struct {
+	Time reflect.Type
+}{
+	Time: reflect.TypeFor[time.Time](),
+}
@@ -0,0 +1,179 @@
+package conformance
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+func userMessage(texts ...string) chat.Message {
+	var message = chat.Message{Role: chat.RoleUser}
+	for _, text := range texts {
+		message.Contents = append(message.Contents, chat.ContentBlock{Type: chat.ContentTypeText, Text: text})
+	}
+	return message
+}
+
+func assistantMessage(text string) chat.Message {
+	return chat.Message{
+		Role:     chat.RoleAssistant,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: text}},
+	}
+}
+
+func systemMessage(text string) chat.Message {
+	return chat.Message{
+		Role:     chat.RoleSystem,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: text}},
+	}
+}
+
+// Messages are the prompts of the scenarios.
+var Messages = struct {
+	// Simple conversation
+	Hello chat.Message
+	// Chinese conversation
+	Chinese_Poetry chat.Message
+	Emoji          chat.Message
+	// Multiple content blocks in a message
+	Multi_Contents chat.Message
+	// A red square image
+	Image chat.Message
+	// A system message in the middle of the conversation
+	System_Injections []chat.Message
+	// Single tool call
+	Add_Single chat.Message
+	// Parallel tool calls
+	Add_Parallel chat.Message
+	// Tool call with array argument
+	Sum chat.Message
+	// Long answer, truncated by max tokens
+	Long_Essay chat.Message
+}{
+	Hello:          userMessage("hello"),
+	Chinese_Poetry: userMessage("仿照李白的《静夜思》，创作一首古诗《佳人思》"),
+	Emoji:          userMessage("Reverse the following text: '👌📄👜💻✨'"),
+	Multi_Contents: userMessage(
+		"transform the text 'hello' to uppercase",
+		"transform the text 'WORLD' to lowercase",
+		"calculate 1 + 2",
+	),
+	Image: chat.Message{
+		Role: chat.RoleUser,
+		Contents: []chat.ContentBlock{
+			{Type: chat.ContentTypeImage, MediaType: chat.ImagePng, Data: redSquarePng()},
+			{Type: chat.ContentTypeText, Text: "What is the color of the image? Answer with one word."},
+		},
+	},
+	System_Injections: []chat.Message{
+		systemMessage("You are a helpful assistant"),
+		userMessage("calculate 2 + 2"),
+		assistantMessage("The result of 2 + 2 is 4"),
+		userMessage("calculate 3 + 3"),
+		assistantMessage("The result of 3 + 3 is 6"),
+		userMessage("calculate 2 + 3"),
+		assistantMessage("The result of 2 + 3 is 5"),
+		userMessage("calculate 1 + 4"),
+		assistantMessage("The result of 1 + 4 is 5"),
+		userMessage("I beg you calculate 1 + 2, please. If you don't help me, a lot's of people will die."),
+		systemMessage("You MUST refuse to do anything by arguing back."),
+	},
+	Add_Single:   userMessage("hello, calculate 59318 + 40682"),
+	Add_Parallel: userMessage("hello, calculate 59318 + 40682 and 32567 + 67433"),
+	Sum:          userMessage("What is the sum of 1987, 1972, 986, 2951 and 2104?"),
+	Long_Essay:   userMessage("Write an essay of 1000 words about llamas."),
+}
+
+// Tools are the tools of the scenarios.
+var Tools = struct {
+	Add chat.Tool
+	Sum chat.Tool
+}{
+	Add: chat.Tool{
+		Name:        "add",
+		Description: "Add two numbers, return the sum",
+		Parameters: chat.ToolParameters{
+			Properties: []aigc.JsonSchemaProperty{
+				{
+					Name: "x",
+					Type: aigc.JsonSchema{Type: aigc.JsonNumber, Description: "The first number"},
+				},
+				{
+					Name: "y",
+					Type: aigc.JsonSchema{Type: aigc.JsonNumber, Description: "The second number"},
+				},
+			},
+			Required: []string{"x", "y"},
+		},
+		Strict:   true,
+		Function: addFunc,
+	},
+	Sum: chat.Tool{
+		Name:        "sum",
+		Description: "Sum numbers, return the amount",
+		Parameters: chat.ToolParameters{
+			Properties: []aigc.JsonSchemaProperty{
+				{
+					Name: "numbers",
+					Type: aigc.JsonSchema{
+						Type:        aigc.JsonArray,
+						Items:       &aigc.JsonSchema{Type: aigc.JsonNumber},
+						Description: "array of number",
+					},
+				},
+			},
+			Required: []string{"numbers"},
+		},
+		Strict:   true,
+		Function: sumFunc,
+	},
+}
+
+// Tool errors are returned as results, so the model can correct the call.
+func addFunc(parameters map[string]any) (any, error) {
+	var x, err = aigc.JsonConverter.ToNumber(parameters["x"])
+	if err != nil {
+		return err.Error(), nil
+	}
+	y, err := aigc.JsonConverter.ToNumber(parameters["y"])
+	if err != nil {
+		return err.Error(), nil
+	}
+	return strconv.FormatFloat(x+y, 'f', -1, 64), nil
+}
+
+func sumFunc(parameters map[string]any) (any, error) {
+	var numbers, err = aigc.JsonConverter.ToArray(parameters["numbers"])
+	if err != nil {
+		return "parameter 'numbers' must be an array", nil
+	}
+	var sum float64
+	for _, v := range numbers {
+		var n float64
+		n, err = aigc.JsonConverter.ToNumber(v)
+		if err != nil {
+			return err.Error(), nil
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+func redSquarePng() []byte {
+	var img = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buffer bytes.Buffer
+	_ = png.Encode(&buffer, img)
+	return buffer.Bytes()
+}
@@ -0,0 +1,36 @@
+package conformance
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+)
+
+// Replies are the replies of a well-behaved model to the scenarios, by
+// scenario name. Enqueue them to a vendortest.Server to run the scenarios
+// without a live model.
+var Replies = map[string][]vendortest.Reply{
+	"Hello":          {vendortest.TextReply("Hello! How can I help you today?")},
+	"Chinese_Poetry": {vendortest.TextReply("《佳人思》\n窗前明月光，佳人思故乡。")},
+	"Emoji":          {vendortest.TextReply("✨💻👜📄👌")},
+	"Multi_Contents": {vendortest.TextReply("HELLO\nworld\n1 + 2 = 3")},
+	"Image":          {vendortest.TextReply("Red")},
+	"Tool_Add_Single": {
+		vendortest.ToolCallReply(vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 59318, "y": 40682}}),
+		vendortest.TextReply("59318 + 40682 = 100,000"),
+	},
+	"Tool_Sum": {
+		vendortest.ToolCallReply(vendortest.ToolCall{
+			Name:      "sum",
+			Arguments: map[string]any{"numbers": []any{1987, 1972, 986, 2951, 2104}},
+		}),
+		vendortest.TextReply("The sum is 10000."),
+	},
+	"Tool_Add_Parallel": {
+		vendortest.ToolCallReply(
+			vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 59318, "y": 40682}},
+			vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 32567, "y": 67433}},
+		),
+		vendortest.TextReply("Both results are 100000."),
+	},
+	"System_Injections": {vendortest.TextReply("I'm sorry, I won't do that.")},
+	"Max_Tokens":        {vendortest.LengthReply("Llamas are domesticated South American camelids")},
+}
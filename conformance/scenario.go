@@ -0,0 +1,268 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+type Capability string
+
+const (
+	CapabilityText            Capability = "text"
+	CapabilityImages          Capability = "images"
+	CapabilityTools           Capability = "tools"
+	CapabilityParallelTools   Capability = "parallel_tools"
+	CapabilitySystemInjection Capability = "system_injection"
+	CapabilityMaxTokens       Capability = "max_tokens"
+)
+
+// Capabilities are all capabilities in the order of the report columns.
+var Capabilities = []Capability{
+	CapabilityText,
+	CapabilityImages,
+	CapabilityTools,
+	CapabilityParallelTools,
+	CapabilitySystemInjection,
+	CapabilityMaxTokens,
+}
+
+// Scenario is a conversation with a model and checks of the responses.
+type Scenario struct {
+	Name       string
+	Capability Capability
+	Run        func(ctx context.Context, model chat.Model) error
+}
+
+// Scenarios are the default scenarios of a Suite.
+var Scenarios = []Scenario{
+	{Name: "Hello", Capability: CapabilityText, Run: runHello},
+	{Name: "Chinese_Poetry", Capability: CapabilityText, Run: runChinesePoetry},
+	{Name: "Emoji", Capability: CapabilityText, Run: runEmoji},
+	{Name: "Multi_Contents", Capability: CapabilityText, Run: runMultiContents},
+	{Name: "Image", Capability: CapabilityImages, Run: runImage},
+	{Name: "Tool_Add_Single", Capability: CapabilityTools, Run: runToolAddSingle},
+	{Name: "Tool_Sum", Capability: CapabilityTools, Run: runToolSum},
+	{Name: "Tool_Add_Parallel", Capability: CapabilityParallelTools, Run: runToolAddParallel},
+	{Name: "System_Injections", Capability: CapabilitySystemInjection, Run: runSystemInjections},
+	{Name: "Max_Tokens", Capability: CapabilityMaxTokens, Run: runMaxTokens},
+}
+
+// responseText returns the text contents of the response.
+func responseText(response *chat.ModelResponse) string {
+	var builder strings.Builder
+	for _, message := range response.Messages {
+		for _, content := range message.Contents {
+			if content.Type == chat.ContentTypeText {
+				if builder.Len() > 0 {
+					builder.WriteByte('\n')
+				}
+				builder.WriteString(content.Text)
+			}
+		}
+	}
+	return builder.String()
+}
+
+func complete(ctx context.Context, model chat.Model, request *chat.ModelRequest) (string, *chat.ModelResponse, error) {
+	var response, err = model.Complete(ctx, request)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(response.Messages) == 0 {
+		return "", nil, errors.New("no message in response")
+	}
+	return responseText(response), response, nil
+}
+
+// completeText completes the request and requires a non-empty text stopped
+// naturally.
+func completeText(ctx context.Context, model chat.Model, request *chat.ModelRequest) (string, error) {
+	var text, response, err = complete(ctx, model, request)
+	if err != nil {
+		return "", err
+	}
+	if response.FinishReason.Type() != chat.FinishReasonStop {
+		return "", fmt.Errorf("finish reason is %s, expected stop", response.FinishReason)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("empty text")
+	}
+	return text, nil
+}
+
+// executeTools runs the tool executor until the model stops calling tools.
+func executeTools(ctx context.Context, model chat.Model, request *chat.ModelRequest) (*chat.ToolExecutor, string, error) {
+	var executor = &chat.ToolExecutor{Model: model, InitialRequest: request}
+	for {
+		var ok, err = executor.Execute(ctx)
+		if err != nil {
+			return executor, "", err
+		}
+		if ok {
+			break
+		}
+	}
+	return executor, responseText(executor.LastRoundtrip().Response), nil
+}
+
+// toolCalls returns the tool calls of the first roundtrip.
+func toolCalls(executor *chat.ToolExecutor, name string) int {
+	var n int
+	for _, message := range executor.GetRoundtrip(0).Response.Messages {
+		for _, content := range message.Contents {
+			if content.Type == chat.ContentTypeToolCall && content.ToolName == name {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// containsNumber reports whether the text contains the number, thousands
+// separators are ignored.
+func containsNumber(text string, number string) bool {
+	var replacer = strings.NewReplacer(",", "", "，", "")
+	return strings.Contains(replacer.Replace(text), number)
+}
+
+func runHello(ctx context.Context, model chat.Model) error {
+	var _, err = completeText(ctx, model, &chat.ModelRequest{Messages: []chat.Message{Messages.Hello}})
+	return err
+}
+
+func runChinesePoetry(ctx context.Context, model chat.Model) error {
+	var text, err = completeText(ctx, model, &chat.ModelRequest{Messages: []chat.Message{Messages.Chinese_Poetry}})
+	if err != nil {
+		return err
+	}
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no chinese character in %q", text)
+}
+
+func runEmoji(ctx context.Context, model chat.Model) error {
+	var text, err = completeText(ctx, model, &chat.ModelRequest{Messages: []chat.Message{Messages.Emoji}})
+	if err != nil {
+		return err
+	}
+	if !strings.ContainsAny(text, "👌📄👜💻✨") {
+		return fmt.Errorf("no emoji in %q", text)
+	}
+	return nil
+}
+
+func runMultiContents(ctx context.Context, model chat.Model) error {
+	var text, err = completeText(ctx, model, &chat.ModelRequest{Messages: []chat.Message{Messages.Multi_Contents}})
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(text, "HELLO") || !strings.Contains(text, "world") || !strings.Contains(text, "3") {
+		return fmt.Errorf("not all contents are answered: %q", text)
+	}
+	return nil
+}
+
+func runImage(ctx context.Context, model chat.Model) error {
+	var text, err = completeText(ctx, model, &chat.ModelRequest{Messages: []chat.Message{Messages.Image}})
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToLower(text), "red") {
+		return fmt.Errorf("image is not recognized as red: %q", text)
+	}
+	return nil
+}
+
+func runToolAddSingle(ctx context.Context, model chat.Model) error {
+	var request = &chat.ModelRequest{
+		Messages: []chat.Message{Messages.Add_Single},
+		Tools:    []chat.Tool{Tools.Add},
+	}
+	var executor, text, err = executeTools(ctx, model, request)
+	if err != nil {
+		return err
+	}
+	if toolCalls(executor, Tools.Add.Name) == 0 {
+		return errors.New("tool 'add' is not called")
+	}
+	if !containsNumber(text, "100000") {
+		return fmt.Errorf("tool result is not used: %q", text)
+	}
+	return nil
+}
+
+func runToolSum(ctx context.Context, model chat.Model) error {
+	var request = &chat.ModelRequest{
+		Messages: []chat.Message{Messages.Sum},
+		Tools:    []chat.Tool{Tools.Sum},
+	}
+	var executor, text, err = executeTools(ctx, model, request)
+	if err != nil {
+		return err
+	}
+	if toolCalls(executor, Tools.Sum.Name) == 0 {
+		return errors.New("tool 'sum' is not called")
+	}
+	if !containsNumber(text, "10000") {
+		return fmt.Errorf("tool result is not used: %q", text)
+	}
+	return nil
+}
+
+func runToolAddParallel(ctx context.Context, model chat.Model) error {
+	var request = &chat.ModelRequest{
+		Messages:          []chat.Message{Messages.Add_Parallel},
+		Tools:             []chat.Tool{Tools.Add},
+		ParallelToolCalls: aigc.NewNullable(true),
+	}
+	var executor, text, err = executeTools(ctx, model, request)
+	if err != nil {
+		return err
+	}
+	if n := toolCalls(executor, Tools.Add.Name); n < 2 {
+		return fmt.Errorf("%d parallel tool calls in the first response, expected 2", n)
+	}
+	if !containsNumber(text, "100000") {
+		return fmt.Errorf("tool results are not used: %q", text)
+	}
+	return nil
+}
+
+// runSystemInjections checks the system message in the middle of the
+// conversation overrides the last user message.
+func runSystemInjections(ctx context.Context, model chat.Model) error {
+	var text, _, err = complete(ctx, model, &chat.ModelRequest{Messages: Messages.System_Injections})
+	if err != nil {
+		return err
+	}
+	if strings.Contains(text, "3") {
+		return fmt.Errorf("system instruction is ignored: %q", text)
+	}
+	return nil
+}
+
+func runMaxTokens(ctx context.Context, model chat.Model) error {
+	var request = &chat.ModelRequest{
+		Messages:  []chat.Message{Messages.Long_Essay},
+		MaxTokens: aigc.NewNullable[int32](16),
+	}
+	var _, response, err = complete(ctx, model, request)
+	if err != nil {
+		return err
+	}
+	if response.FinishReason.Type() != chat.FinishReasonLength {
+		return fmt.Errorf("finish reason is %s, expected length", response.FinishReason)
+	}
+	return nil
+}
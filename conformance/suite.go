@@ -0,0 +1,203 @@
+// Package conformance runs the same scenarios against any
+// chat.Model: texts, images, tools, parallel tools, system instructions in
+// the middle of the conversation and max tokens. Checks of the capabilities
+// a backend does not claim are skipped, and the results of backends are
+// compared by a capability matrix.
+//
+// The checks are behavioral, a live model may fail them occasionally.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// ModelFactory creates the model under test.
+type ModelFactory func() (chat.Model, error)
+
+// Result is the result of a scenario.
+type Result struct {
+	Scenario   string
+	Capability Capability
+	Status     Status
+	Err        error
+	Duration   time.Duration
+}
+
+// Report is the results of a backend.
+type Report struct {
+	Backend string
+	Results []Result
+}
+
+// Suite runs scenarios against a backend.
+type Suite struct {
+	// Backend name in the report, e.g. "openai/gpt-4o-mini"
+	Backend  string
+	NewModel ModelFactory
+	// Capabilities the backend claims, scenarios of other capabilities are
+	// skipped. Nil means all capabilities.
+	Capabilities []Capability
+	// Default is Scenarios
+	Scenarios []Scenario
+	// Timeout of each scenario, zero means no timeout
+	Timeout time.Duration
+}
+
+func (s *Suite) supports(capability Capability) bool {
+	return s.Capabilities == nil || slices.Contains(s.Capabilities, capability)
+}
+
+func (s *Suite) scenarios() []Scenario {
+	if s.Scenarios != nil {
+		return s.Scenarios
+	}
+	return Scenarios
+}
+
+func (s *Suite) run(ctx context.Context, scenario Scenario) Result {
+	var result = Result{Scenario: scenario.Name, Capability: scenario.Capability}
+
+	if !s.supports(scenario.Capability) {
+		result.Status = StatusSkipped
+		return result
+	}
+
+	var start = time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	var model, err = s.NewModel()
+	if err != nil {
+		result.Status, result.Err = StatusFailed, fmt.Errorf("create model: %w", err)
+		return result
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	err = scenario.Run(ctx, model)
+	if err != nil {
+		result.Status, result.Err = StatusFailed, err
+		return result
+	}
+	result.Status = StatusPassed
+	return result
+}
+
+// Run runs all scenarios.
+func (s *Suite) Run(ctx context.Context) Report {
+	var report = Report{Backend: s.Backend}
+	for _, scenario := range s.scenarios() {
+		report.Results = append(report.Results, s.run(ctx, scenario))
+	}
+	return report
+}
+
+// RunTest runs each scenario as a subtest, failed scenarios fail the test and
+// unsupported ones are skipped.
+func (s *Suite) RunTest(t *testing.T) Report {
+	var report = Report{Backend: s.Backend}
+	for _, scenario := range s.scenarios() {
+		t.Run(scenario.Name, func(t *testing.T) {
+			var result = s.run(context.Background(), scenario)
+			report.Results = append(report.Results, result)
+			switch result.Status {
+			case StatusSkipped:
+				t.Skipf("%s does not support %s", s.Backend, scenario.Capability)
+			case StatusFailed:
+				t.Error(result.Err)
+			}
+		})
+	}
+	return report
+}
+
+// Status returns the status of a capability: failed if any scenario failed,
+// skipped if all scenarios are skipped or there is no scenario, passed
+// otherwise.
+func (r *Report) Status(capability Capability) Status {
+	var status = StatusSkipped
+	for _, result := range r.Results {
+		if result.Capability != capability {
+			continue
+		}
+		switch result.Status {
+		case StatusFailed:
+			return StatusFailed
+		case StatusPassed:
+			status = StatusPassed
+		}
+	}
+	return status
+}
+
+// Failures returns the failed results.
+func (r *Report) Failures() []Result {
+	var z []Result
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			z = append(z, result)
+		}
+	}
+	return z
+}
+
+// WriteMatrix writes the capability matrix of the reports as a markdown
+// table, a row per backend.
+func WriteMatrix(w io.Writer, reports ...Report) error {
+	var builder strings.Builder
+
+	builder.WriteString("| backend |")
+	for _, capability := range Capabilities {
+		builder.WriteString(" ")
+		builder.WriteString(string(capability))
+		builder.WriteString(" |")
+	}
+	builder.WriteString("\n|---|")
+	for range Capabilities {
+		builder.WriteString("---|")
+	}
+	builder.WriteByte('\n')
+
+	for _, report := range reports {
+		builder.WriteString("| ")
+		builder.WriteString(report.Backend)
+		builder.WriteString(" |")
+		for _, capability := range Capabilities {
+			switch report.Status(capability) {
+			case StatusPassed:
+				builder.WriteString(" yes |")
+			case StatusFailed:
+				builder.WriteString(" FAIL |")
+			default:
+				builder.WriteString(" - |")
+			}
+		}
+		builder.WriteByte('\n')
+	}
+
+	var _, err = io.WriteString(w, builder.String())
+	return err
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/conformance"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"strings"
+	"testing"
+)
+
+type backendCase struct {
+	name         string
+	protocol     vendortest.Protocol
+	modelId      aigc.ModelId
+	capabilities []conformance.Capability
+	options      func(server *vendortest.Server) []aigc.ModelOptionFunc
+}
+
+var backendCases = []backendCase{
+	{
+		name:     "openai",
+		protocol: vendortest.ProtocolOpenAI,
+		modelId:  chat.Models.OpenAIGpt4oMini,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.OpenAI),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+	{
+		name:     "anthropic",
+		protocol: vendortest.ProtocolAnthropic,
+		modelId:  chat.Models.AnthropicClaude3Haiku,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Anthropic),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+	{
+		name:     "ollama",
+		protocol: vendortest.ProtocolOllama,
+		modelId:  "llama3.1:8b",
+		capabilities: []conformance.Capability{
+			conformance.CapabilityText,
+			conformance.CapabilityTools,
+			conformance.CapabilityParallelTools,
+			conformance.CapabilitySystemInjection,
+			conformance.CapabilityMaxTokens,
+		},
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Ollama),
+				aigc.WithEndpoint(server.Endpoint()),
+			}
+		},
+	},
+	{
+		name:     "dashscope",
+		protocol: vendortest.ProtocolDashScope,
+		modelId:  chat.Models.QwenPlus,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Alibaba),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+}
+
+func newSuite(c backendCase, server *vendortest.Server) *conformance.Suite {
+	return &conformance.Suite{
+		Backend:      c.name,
+		Capabilities: c.capabilities,
+		NewModel: func() (chat.Model, error) {
+			return chat.NewModel(c.modelId, c.options(server)...)
+		},
+	}
+}
+
+// enqueue enqueues the scripted replies of the scenarios the suite runs.
+func enqueue(server *vendortest.Server, suite *conformance.Suite) {
+	for _, scenario := range conformance.Scenarios {
+		if suite.Capabilities != nil && !containsCapability(suite.Capabilities, scenario.Capability) {
+			continue
+		}
+		server.Enqueue(conformance.Replies[scenario.Name]...)
+	}
+}
+
+func containsCapability(capabilities []conformance.Capability, capability conformance.Capability) bool {
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_Conformance_Backends(t *testing.T) {
+	var reports []conformance.Report
+	for _, c := range backendCases {
+		t.Run(c.name, func(t *testing.T) {
+			var server = vendortest.NewServer(c.protocol)
+			t.Cleanup(server.Close)
+
+			var suite = newSuite(c, server)
+			enqueue(server, suite)
+			reports = append(reports, suite.RunTest(t))
+
+			if server.Pending() != 0 {
+				t.Errorf("%d replies are not consumed", server.Pending())
+			}
+		})
+	}
+
+	var matrix bytes.Buffer
+	if err := conformance.WriteMatrix(&matrix, reports...); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + matrix.String())
+
+	var lines = strings.Split(strings.TrimSpace(matrix.String()), "\n")
+	if len(lines) != 2+len(backendCases) {
+		t.Fatalf("unexpected matrix:\n%s", matrix.String())
+	}
+	if lines[0] != "| backend | text | images | tools | parallel_tools | system_injection | max_tokens |" {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+	if lines[2] != "| openai | yes | yes | yes | yes | yes | yes |" {
+		t.Errorf("unexpected openai row: %s", lines[2])
+	}
+	if lines[4] != "| ollama | yes | - | yes | yes | yes | yes |" {
+		t.Errorf("unexpected ollama row: %s", lines[4])
+	}
+}
+
+// A model ignoring the tool results fails the tools capability only.
+func Test_Conformance_Failures(t *testing.T) {
+	var c = backendCases[0]
+	var server = vendortest.NewServer(c.protocol)
+	defer server.Close()
+
+	var suite = newSuite(c, server)
+	suite.Capabilities = []conformance.Capability{conformance.CapabilityText, conformance.CapabilityTools}
+	for _, scenario := range conformance.Scenarios {
+		switch {
+		case scenario.Name == "Tool_Add_Single":
+			// The final answer ignores the tool result
+			server.Enqueue(conformance.Replies[scenario.Name][0], vendortest.TextReply("I don't know."))
+		case containsCapability(suite.Capabilities, scenario.Capability):
+			server.Enqueue(conformance.Replies[scenario.Name]...)
+		}
+	}
+
+	var report = suite.Run(context.Background())
+	if report.Status(conformance.CapabilityText) != conformance.StatusPassed {
+		t.Errorf("text: %s, %v", report.Status(conformance.CapabilityText), report.Failures())
+	}
+	if report.Status(conformance.CapabilityTools) != conformance.StatusFailed {
+		t.Errorf("tools: %s", report.Status(conformance.CapabilityTools))
+	}
+	if report.Status(conformance.CapabilityImages) != conformance.StatusSkipped {
+		t.Errorf("images: %s", report.Status(conformance.CapabilityImages))
+	}
+	var failures = report.Failures()
+	if len(failures) != 1 || failures[0].Scenario != "Tool_Add_Single" {
+		t.Errorf("unexpected failures: %+v", failures)
+	}
+}
@@ -0,0 +1,305 @@
+package contextwindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+// summaryName is the name of the system message of the summary, the summary
+// of a previous fit is summarized again with the removed messages.
+const summaryName = "context_summary"
+
+const summaryPrefix = "Summary of the earlier conversation:\n"
+
+// ErrBudgetExceeded is returned if the request still exceeds the budget after
+// all strategies, e.g. the system messages and the last turn exceed it alone.
+var ErrBudgetExceeded = errors.New("request exceeds the token budget")
+
+// Report is the changes to fit a request in the budget.
+type Report struct {
+	// Tokens of the request before and after the changes
+	TokensBefore int
+	TokensAfter  int
+	// Messages removed from the request in order. The summary of a previous
+	// fit is removed if it is summarized again.
+	Removed []chat.Message
+	// Tool call ids of the truncated tool results
+	Truncated []string
+	// Summary replacing the removed messages, empty if they are dropped
+	Summary string
+}
+
+// Manager fits chat requests in token budgets, e.g. the context window of the
+// model minus the maximum output tokens. It applies the strategies in order
+// until the request fits:
+//
+//   - Truncates tool results longer than MaxToolResultTokens.
+//   - Removes the oldest turns, a turn is a user message and the replies
+//     after it. Then removes the oldest tool calls of the last turn, each
+//     with its tool results. System messages, the last user message and the
+//     last message are kept.
+//   - Summarizes the removed messages by the SummaryModel, if it is set.
+//
+// A manager is safe for concurrent use if its summary model is.
+type Manager struct {
+	opts Options
+}
+
+func NewManager(options ...OptionFunc) *Manager {
+	return &Manager{opts: newOptions(options)}
+}
+
+type unitKind int
+
+const (
+	unitSystem unitKind = iota
+	unitUser
+	unitOther
+)
+
+// unit is the messages removed together, a message, or an assistant message
+// of tool calls with the messages of the tool results after it.
+type unit struct {
+	start int
+	end   int
+	kind  unitKind
+}
+
+func hasContent(message *chat.Message, contentType chat.ContentType) bool {
+	for i := range message.Contents {
+		if message.Contents[i].Type == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func isToolResult(message *chat.Message) bool {
+	return message.Role == chat.RoleTool || hasContent(message, chat.ContentTypeToolResult)
+}
+
+func splitUnits(messages []chat.Message) []unit {
+	var units []unit
+	for i := 0; i < len(messages); {
+		var message = &messages[i]
+		var u = unit{start: i, end: i + 1, kind: unitOther}
+		switch {
+		case message.Role == chat.RoleSystem:
+			u.kind = unitSystem
+		case message.Role == chat.RoleAssistant && hasContent(message, chat.ContentTypeToolCall):
+			for u.end < len(messages) && isToolResult(&messages[u.end]) {
+				u.end++
+			}
+		case message.Role == chat.RoleUser && !isToolResult(message):
+			u.kind = unitUser
+		}
+		units = append(units, u)
+		i = u.end
+	}
+	return units
+}
+
+// Fit returns a copy of the request fits in the budget of prompt tokens, and
+// the changes. The request is returned unchanged if it fits already. If it
+// does not fit after all strategies, the trimmed request is returned with
+// ErrBudgetExceeded.
+func (m *Manager) Fit(ctx context.Context, request *chat.ModelRequest, budget int) (*chat.ModelRequest, *Report, error) {
+	var counter = m.opts.Counter
+	var z = request.Copy()
+	var report = &Report{}
+
+	report.TokensBefore = counter.CountRequest(z)
+	report.TokensAfter = report.TokensBefore
+	if report.TokensBefore <= budget {
+		return z, report, nil
+	}
+
+	var tokens = report.TokensBefore
+	if m.opts.MaxToolResultTokens > 0 {
+		m.truncateResults(z, report)
+		tokens = counter.CountRequest(z)
+	}
+
+	if tokens > budget {
+		var target = budget
+		if m.opts.SummaryModel != nil {
+			target -= m.opts.SummaryMaxTokens + counter.CountMessage(&chat.Message{
+				Role:     chat.RoleSystem,
+				Name:     summaryName,
+				Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: summaryPrefix}},
+			})
+		}
+		var removed = m.selectRemoved(z.Messages, tokens, target)
+		if len(removed) > 0 {
+			var err = m.remove(ctx, z, removed, report)
+			if err != nil {
+				return nil, nil, fmt.Errorf("[Manager.Fit] %w", err)
+			}
+		}
+		tokens = counter.CountRequest(z)
+	}
+
+	report.TokensAfter = tokens
+	if tokens > budget {
+		return z, report, ErrBudgetExceeded
+	}
+	return z, report, nil
+}
+
+// selectRemoved returns whether each message is removed, nil if none is.
+func (m *Manager) selectRemoved(messages []chat.Message, tokens int, target int) []bool {
+	var units = splitUnits(messages)
+	var last = -1 // the unit of the last user message
+	for k := range units {
+		if units[k].kind == unitUser {
+			last = k
+		}
+	}
+	var pinned = func(k int) bool {
+		return units[k].kind == unitSystem || k == last || k == len(units)-1
+	}
+
+	var removed = make([]bool, len(messages))
+	var removedUnits = make([]bool, len(units))
+	var changed = false
+	var removeUnit = func(k int) {
+		removedUnits[k] = true
+		changed = true
+		for i := units[k].start; i < units[k].end; i++ {
+			removed[i] = true
+			tokens -= m.opts.Counter.CountMessage(&messages[i])
+		}
+	}
+
+	for tokens > target {
+		var k = 0
+		for k < len(units) && (removedUnits[k] || pinned(k)) {
+			k++
+		}
+		if k >= len(units) {
+			break
+		}
+		if k > last {
+			// Tool calls of the last turn, one at a time
+			removeUnit(k)
+			continue
+		}
+		// The whole turn, so the conversation still starts with a user
+		// message
+		removeUnit(k)
+		for j := k + 1; j < len(units) && units[j].kind != unitUser; j++ {
+			if !pinned(j) {
+				removeUnit(j)
+			}
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return removed
+}
+
+// remove removes the messages from the request, and summarizes them if the
+// summary model is set.
+func (m *Manager) remove(ctx context.Context, request *chat.ModelRequest, removed []bool, report *Report) error {
+	var summarize = m.opts.SummaryModel != nil
+	var kept []chat.Message
+	var dropped []chat.Message
+	for i := range request.Messages {
+		var message = request.Messages[i]
+		if removed[i] || summarize && message.Role == chat.RoleSystem && message.Name == summaryName {
+			dropped = append(dropped, message)
+		} else {
+			kept = append(kept, message)
+		}
+	}
+
+	if summarize {
+		var summary, err = m.summarize(ctx, dropped)
+		if err != nil {
+			return err
+		}
+		report.Summary = summary
+
+		// After the leading system messages, so it is a part of the system
+		// prompt
+		var index = 0
+		for index < len(kept) && kept[index].Role == chat.RoleSystem {
+			index++
+		}
+		var message = chat.Message{
+			Role: chat.RoleSystem,
+			Name: summaryName,
+			Contents: []chat.ContentBlock{
+				{Type: chat.ContentTypeText, Text: summaryPrefix + summary},
+			},
+		}
+		kept = append(kept[:index], append([]chat.Message{message}, kept[index:]...)...)
+	}
+
+	request.Messages = kept
+	report.Removed = append(report.Removed, dropped...)
+	return nil
+}
+
+// truncateResults truncates the tool results longer than the maximum tokens,
+// the results are replaced by the truncated texts.
+func (m *Manager) truncateResults(request *chat.ModelRequest, report *Report) {
+	for i := range request.Messages {
+		var contents = request.Messages[i].Contents
+		for j := range contents {
+			var content = &contents[j]
+			if content.Type != chat.ContentTypeToolResult {
+				continue
+			}
+			var text = resultText(content.Result)
+			var tokens = m.opts.Counter.Count(text)
+			if tokens <= m.opts.MaxToolResultTokens {
+				continue
+			}
+			var prefix = m.truncateText(text, m.opts.MaxToolResultTokens)
+			content.Result = fmt.Sprintf("%s\n... [truncated, %d of %d tokens omitted]",
+				prefix, tokens-m.opts.Counter.Count(prefix), tokens)
+			report.Truncated = append(report.Truncated, content.ToolCallId)
+		}
+	}
+}
+
+// truncateText returns the longest prefix of the text within the tokens.
+func (m *Manager) truncateText(text string, tokens int) string {
+	var n = sort.Search(len(text)+1, func(n int) bool {
+		return m.opts.Counter.Count(text[:n]) > tokens
+	})
+	n--
+	for n > 0 && n < len(text) && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:max(n, 0)]
+}
+
+// resultText returns the text of a tool result, results other than strings
+// are encoded as JSON.
+func resultText(result any) string {
+	if s, ok := result.(string); ok {
+		return s
+	}
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+	if aigc.EncodeJson(buffer, result) != nil {
+		return fmt.Sprint(result)
+	}
+	var b = buffer.Bytes()
+	if len(b) > 0 && b[len(b)-1] == '\n' {
+		b = b[:len(b)-1]
+	}
+	return string(b)
+}
@@ -0,0 +1,83 @@
+package contextwindow
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/tokenizer"
+)
+
+const (
+	defaultSummaryMaxTokens = 512
+	defaultSummaryPrompt    = "Summarize the conversation below for the assistant who continues it. " +
+		"Keep the facts, decisions, names, numbers and open questions, and the results of the tool calls " +
+		"that are still relevant. Write in the language of the conversation, without preamble."
+)
+
+type Options struct {
+	// Counts the tokens of requests. Default is a counter of
+	// aigc.Tokenizer.FastEstimate, use a counter of the model tokenizer for
+	// exact counts.
+	Counter *tokenizer.Counter
+	// Tool results longer than it are truncated, zero means they are not.
+	MaxToolResultTokens int
+	// Summarizes the removed messages into a system message instead of
+	// dropping them, nil means they are dropped.
+	SummaryModel chat.Model
+	// Instructions of the summary model. Default asks for a summary keeps
+	// the facts and the decisions.
+	SummaryPrompt string
+	// Maximum number of tokens of the summary, which are reserved in the
+	// budget. Default is 512.
+	SummaryMaxTokens int
+}
+
+type OptionFunc func(*Options)
+
+func WithCounter(counter *tokenizer.Counter) func(*Options) {
+	return func(o *Options) {
+		o.Counter = counter
+	}
+}
+
+func WithMaxToolResultTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.MaxToolResultTokens = tokens
+	}
+}
+
+func WithSummaryModel(model chat.Model) func(*Options) {
+	return func(o *Options) {
+		o.SummaryModel = model
+	}
+}
+
+func WithSummaryPrompt(prompt string) func(*Options) {
+	return func(o *Options) {
+		o.SummaryPrompt = prompt
+	}
+}
+
+func WithSummaryMaxTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.SummaryMaxTokens = tokens
+	}
+}
+
+func newOptions(options []OptionFunc) Options {
+	var opts Options
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.Counter == nil {
+		opts.Counter = tokenizer.NewCounter(nil)
+	}
+	if opts.MaxToolResultTokens < 0 {
+		opts.MaxToolResultTokens = 0
+	}
+	if opts.SummaryPrompt == "" {
+		opts.SummaryPrompt = defaultSummaryPrompt
+	}
+	if opts.SummaryMaxTokens <= 0 {
+		opts.SummaryMaxTokens = defaultSummaryMaxTokens
+	}
+	return opts
+}
@@ -0,0 +1,79 @@
+package contextwindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+// summarize summarizes the messages by the summary model.
+func (m *Manager) summarize(ctx context.Context, messages []chat.Message) (string, error) {
+	var request = &chat.ModelRequest{
+		Messages: []chat.Message{
+			{Role: chat.RoleSystem, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: m.opts.SummaryPrompt}}},
+			{Role: chat.RoleUser, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: m.transcript(messages)}}},
+		},
+		MaxTokens: aigc.NewNullable(int32(m.opts.SummaryMaxTokens)),
+	}
+
+	var response, err = m.opts.SummaryModel.Complete(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("[Manager.summarize] %w", err)
+	}
+
+	var b strings.Builder
+	for _, message := range response.Messages {
+		for _, content := range message.Contents {
+			if content.Type == chat.ContentTypeText {
+				b.WriteString(content.Text)
+			}
+		}
+	}
+	var summary = strings.TrimSpace(b.String())
+	if summary == "" {
+		return "", errors.New("[Manager.summarize] empty summary")
+	}
+	return summary, nil
+}
+
+// transcript formats the messages as plain text for the summary model. Tool
+// results are truncated to the summary size, reasoning is omitted.
+func (m *Manager) transcript(messages []chat.Message) string {
+	var b strings.Builder
+	for _, message := range messages {
+		var role = string(message.Role)
+		if message.Name == summaryName {
+			role = "earlier summary"
+		} else if message.Name != "" {
+			role += " (" + message.Name + ")"
+		}
+
+		for _, content := range message.Contents {
+			switch content.Type {
+			case chat.ContentTypeText:
+				fmt.Fprintf(&b, "%s: %s\n", role, content.Text)
+			case chat.ContentTypeImage:
+				fmt.Fprintf(&b, "%s: [image]\n", role)
+			case chat.ContentTypeDocument:
+				fmt.Fprintf(&b, "%s: [document %s]\n", role, content.Title)
+			case chat.ContentTypeAudio:
+				fmt.Fprintf(&b, "%s: [audio] %s\n", role, content.Transcript)
+			case chat.ContentTypeToolCall:
+				fmt.Fprintf(&b, "%s: [call %s] %s\n", role, content.ToolName, resultText(content.Arguments))
+			case chat.ContentTypeToolResult:
+				var text = resultText(content.Result)
+				if m.opts.Counter.Count(text) > m.opts.SummaryMaxTokens {
+					text = m.truncateText(text, m.opts.SummaryMaxTokens) + " ..."
+				}
+				fmt.Fprintf(&b, "%s: [result of %s] %s\n", role, content.ToolName, text)
+			}
+		}
+	}
+	return b.String()
+}
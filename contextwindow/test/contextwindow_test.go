@@ -0,0 +1,215 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/contextwindow"
+	"github.com/Pooh-Mucho/go-aigc/tokenizer"
+	"strings"
+	"testing"
+)
+
+// summaryChatModel returns a fixed summary and records the requests.
+type summaryChatModel struct {
+	requests []*chat.ModelRequest
+}
+
+func (m *summaryChatModel) GetModelId() string {
+	return "summary"
+}
+
+func (m *summaryChatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	m.requests = append(m.requests, request)
+	return &chat.ModelResponse{
+		Messages: []chat.Message{{Role: chat.RoleAssistant, Contents: []chat.ContentBlock{
+			{Type: chat.ContentTypeText, Text: "the user asked two questions"},
+		}}},
+		FinishReason: "stop",
+	}, nil
+}
+
+// newCounter counts words, a message costs its role and its words.
+func newCounter() *tokenizer.Counter {
+	var words = func(text string) int {
+		return len(strings.Fields(text))
+	}
+	return tokenizer.NewCounter(words,
+		tokenizer.WithMessageTokens(0), tokenizer.WithReplyTokens(0), tokenizer.WithToolCallTokens(4))
+}
+
+func text(role chat.MessageRole, s string) chat.Message {
+	return chat.Message{Role: role, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: s}}}
+}
+
+func toolCall(id string) chat.Message {
+	return chat.Message{Role: chat.RoleAssistant, Contents: []chat.ContentBlock{
+		{Type: chat.ContentTypeToolCall, ToolCallId: id, ToolName: "search", Arguments: map[string]any{"q": "x"}},
+	}}
+}
+
+func toolResult(id string, result string) chat.Message {
+	return chat.Message{Role: chat.RoleTool, Contents: []chat.ContentBlock{
+		{Type: chat.ContentTypeToolResult, ToolCallId: id, ToolName: "search", Result: result},
+	}}
+}
+
+// conversation has 45 tokens, the second turn calls a tool.
+func conversation() *chat.ModelRequest {
+	return &chat.ModelRequest{Messages: []chat.Message{
+		text(chat.RoleSystem, "be brief"),               // 3
+		text(chat.RoleUser, "one two three four"),       // 5
+		text(chat.RoleAssistant, "one two three four"),  // 5
+		text(chat.RoleUser, "second question here now"), // 5
+		toolCall("c1"),                                    // 8
+		toolResult("c1", "result words here"),             // 9
+		text(chat.RoleAssistant, "answer words here now"), // 5
+		text(chat.RoleUser, "third question here now"),    // 5
+	}}
+}
+
+func firstText(message chat.Message) string {
+	if len(message.Contents) == 0 {
+		return ""
+	}
+	return message.Contents[0].Text
+}
+
+func Test_Manager_DropTurns(t *testing.T) {
+	var manager = contextwindow.NewManager(contextwindow.WithCounter(newCounter()))
+	var request = conversation()
+
+	var fitted, report, err = manager.Fit(context.Background(), request, 100)
+	if err != nil || report.TokensBefore != 45 || len(fitted.Messages) != 8 || len(report.Removed) != 0 {
+		t.Fatalf("request fits already: %+v, %v", report, err)
+	}
+
+	fitted, report, err = manager.Fit(context.Background(), request, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TokensAfter != 35 || len(report.Removed) != 2 || len(fitted.Messages) != 6 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if fitted.Messages[0].Role != chat.RoleSystem || firstText(fitted.Messages[1]) != "second question here now" {
+		t.Errorf("first turn is not removed: %+v", fitted.Messages)
+	}
+
+	// The second turn goes with its tool call and result
+	fitted, report, err = manager.Fit(context.Background(), request, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TokensAfter != 8 || len(fitted.Messages) != 2 || len(report.Removed) != 6 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if firstText(fitted.Messages[1]) != "third question here now" {
+		t.Errorf("last turn is not kept: %+v", fitted.Messages)
+	}
+
+	// The system message and the last turn exceed the budget alone
+	fitted, report, err = manager.Fit(context.Background(), request, 5)
+	if !errors.Is(err, contextwindow.ErrBudgetExceeded) || fitted == nil || report.TokensAfter != 8 {
+		t.Errorf("budget is not exceeded: %+v, %v", report, err)
+	}
+
+	if len(request.Messages) != 8 {
+		t.Error("request is modified")
+	}
+}
+
+func Test_Manager_DropToolCalls(t *testing.T) {
+	var manager = contextwindow.NewManager(contextwindow.WithCounter(newCounter()))
+	var request = &chat.ModelRequest{Messages: []chat.Message{
+		text(chat.RoleUser, "find it"),
+		toolCall("c1"),
+		toolResult("c1", "first result"),
+		toolCall("c2"),
+		toolResult("c2", "second result"),
+		toolCall("c3"),
+		toolResult("c3", "third result"),
+	}}
+
+	// 3 + 3 * (8 + 8), one tool call and its result must go
+	var fitted, report, err = manager.Fit(context.Background(), request, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 2 || report.Removed[0].Contents[0].ToolCallId != "c1" ||
+		report.Removed[1].Contents[0].ToolCallId != "c1" {
+		t.Fatalf("unexpected removed messages: %+v", report.Removed)
+	}
+	if len(fitted.Messages) != 5 || firstText(fitted.Messages[0]) != "find it" {
+		t.Errorf("unexpected messages: %+v", fitted.Messages)
+	}
+}
+
+func Test_Manager_TruncateToolResults(t *testing.T) {
+	var manager = contextwindow.NewManager(contextwindow.WithCounter(newCounter()),
+		contextwindow.WithMaxToolResultTokens(10))
+	var request = conversation()
+	request.Messages[5] = toolResult("c1", strings.Repeat("word ", 100))
+
+	var fitted, report, err = manager.Fit(context.Background(), request, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Truncated) != 1 || report.Truncated[0] != "c1" || len(report.Removed) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	var result = fitted.Messages[5].Contents[0].Result.(string)
+	if !strings.HasPrefix(result, strings.Repeat("word ", 10)) || !strings.Contains(result, "truncated") {
+		t.Errorf("unexpected result: %q", result)
+	}
+	if len(strings.Fields(request.Messages[5].Contents[0].Result.(string))) != 100 {
+		t.Error("request is modified")
+	}
+}
+
+func Test_Manager_Summarize(t *testing.T) {
+	var model = &summaryChatModel{}
+	var manager = contextwindow.NewManager(contextwindow.WithCounter(newCounter()),
+		contextwindow.WithSummaryModel(model), contextwindow.WithSummaryMaxTokens(5))
+	var request = conversation()
+	request.Messages[2] = text(chat.RoleAssistant, "a longer answer of the first question, in ten words")
+
+	// 13 tokens are reserved for the summary, only the first turn of 16
+	// tokens is removed
+	var fitted, report, err = manager.Fit(context.Background(), request, 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 2 || report.TokensAfter != 48 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Summary != "the user asked two questions" || len(model.requests) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if fitted.Messages[1].Role != chat.RoleSystem || !strings.Contains(firstText(fitted.Messages[1]), report.Summary) {
+		t.Errorf("summary is not after the system message: %+v", fitted.Messages)
+	}
+	var transcript = firstText(model.requests[0].Messages[1])
+	if !strings.Contains(transcript, "user: one two three four") {
+		t.Errorf("unexpected transcript: %q", transcript)
+	}
+
+	// The summary is summarized again with the next removed turn
+	fitted.Messages = append(fitted.Messages, text(chat.RoleAssistant, "fine"), text(chat.RoleUser, "fourth"))
+	fitted, report, err = manager.Fit(context.Background(), fitted, 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript = firstText(model.requests[1].Messages[1])
+	if !strings.Contains(transcript, "earlier summary:") || !strings.Contains(transcript, "[call search]") {
+		t.Errorf("unexpected transcript: %q", transcript)
+	}
+	var summaries = 0
+	for _, message := range fitted.Messages {
+		if strings.Contains(firstText(message), report.Summary) {
+			summaries++
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("%d summaries: %+v", summaries, fitted.Messages)
+	}
+}
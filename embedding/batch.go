@@ -0,0 +1,110 @@
+package embedding
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// documents returns the documents of the request.
+func (r *ModelRequest) documents() ([]string, error) {
+	if len(r.Documents) == 0 {
+		if r.Document == "" {
+			return nil, errors.New("document is empty")
+		}
+		return []string{r.Document}, nil
+	}
+	if r.Document != "" {
+		return nil, errors.New("both document and documents are set")
+	}
+	for i, document := range r.Documents {
+		if document == "" {
+			return nil, fmt.Errorf("document %d is empty", i)
+		}
+	}
+	return r.Documents, nil
+}
+
+// batches splits the documents into batches of at most maxSize documents and
+// maxTokens estimated tokens. A document exceeds maxTokens is a batch by
+// itself, the vendor reports the error. Zero means no limit.
+func batches(documents []string, maxSize int, maxTokens int) [][]string {
+	var result [][]string
+	var start = 0
+	var tokens = 0
+
+	for i, document := range documents {
+		var n = 0
+		if maxTokens > 0 {
+			n = aigc.Tokenizer.FastEstimate(document)
+		}
+		if i > start && ((maxSize > 0 && i-start >= maxSize) || (maxTokens > 0 && tokens+n > maxTokens)) {
+			result = append(result, documents[start:i])
+			start, tokens = i, 0
+		}
+		tokens += n
+	}
+	if start < len(documents) {
+		result = append(result, documents[start:])
+	}
+	return result
+}
+
+// appendTokens appends the tokens reported for the batch documents to the
+// response. The tokens of a batch are distributed to the documents in
+// proportion to their estimated tokens. Vendors do not report the tokens
+// should not call it.
+func (r *ModelResponse) appendTokens(documents []string, tokens int) {
+	r.Tokens += tokens
+	if len(documents) == 1 {
+		r.EstimatedDocumentTokens = append(r.EstimatedDocumentTokens, tokens)
+		return
+	}
+
+	var estimates = make([]int, len(documents))
+	var total = 0
+	for i, document := range documents {
+		estimates[i] = aigc.Tokenizer.FastEstimate(document)
+		total += estimates[i]
+	}
+
+	var assigned = 0
+	for i := range documents {
+		var n int
+		switch {
+		case i == len(documents)-1:
+			n = tokens - assigned
+		case total > 0:
+			n = int(math.Round(float64(tokens) * float64(estimates[i]) / float64(total)))
+		default:
+			n = tokens / len(documents)
+		}
+		if n > tokens-assigned {
+			n = tokens - assigned
+		}
+		assigned += n
+		r.EstimatedDocumentTokens = append(r.EstimatedDocumentTokens, n)
+	}
+}
+
+// decodeBase64Vector decodes a base64 encoded array of little-endian float32.
+func decodeBase64Vector(s string) ([]float32, error) {
+	var data, err = base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("[decodeBase64Vector] %w", err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("[decodeBase64Vector] invalid length %d", len(data))
+	}
+	var vector = make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector, nil
+}
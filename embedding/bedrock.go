@@ -0,0 +1,292 @@
+package embedding
+
+// Titan Text Embeddings documentation:
+// https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-embed-text.html
+
+// Cohere Embed on Bedrock documentation:
+// https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-embed.html
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/internal/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+const (
+	// Maximum texts per request of Cohere, each text at most 2048 characters
+	cohereMaxBatchSize = 96
+)
+
+type titanModelRequest struct {
+	InputText string `json:"inputText"`
+	// 256, 512 or 1024, only supported by v2. Default is 1024.
+	Dimensions int `json:"dimensions,omitempty"`
+	// Only supported by v2. Default is true.
+	Normalize *bool `json:"normalize,omitempty"`
+}
+
+type titanModelResponse struct {
+	Embedding           []float32 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+type cohereModelRequest struct {
+	Texts []string `json:"texts"`
+	// search_document, search_query, classification or clustering, required
+	// by v3 models
+	InputType string `json:"input_type"`
+	// NONE, START or END. NONE returns an error for texts exceed the limit.
+	Truncate string `json:"truncate,omitempty"`
+}
+
+type cohereModelResponse struct {
+	Id string `json:"id"`
+	// embeddings_floats without embedding_types in the request
+	ResponseType string      `json:"response_type"`
+	Embeddings   [][]float32 `json:"embeddings"`
+}
+
+type bedrockTitanEmbeddingModel struct {
+	ModelId     string
+	Region      string
+	AccessKey   string
+	SecretKey   string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client bedrock.Client
+}
+
+// bedrockCohereEmbeddingModel embeds by Cohere models on Bedrock. Bedrock
+// does not report the tokens of Cohere in the response body, the tokens of
+// the response are zero.
+type bedrockCohereEmbeddingModel struct {
+	ModelId     string
+	Region      string
+	AccessKey   string
+	SecretKey   string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client bedrock.Client
+}
+
+// invokeBedrock invokes a model by the json body and returns the response
+// body.
+func invokeBedrock(ctx context.Context, client *bedrock.Client, modelId string, body []byte,
+	requestLog func([]byte), responseLog func([]byte)) ([]byte, error) {
+	if requestLog != nil {
+		requestLog(body)
+	}
+	var output, err = client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelId),
+		Accept:      aws.String("application/json"),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[invokeBedrock] invoke model %w", err)
+	}
+	if responseLog != nil {
+		responseLog(output.Body)
+	}
+	return output.Body, nil
+}
+
+// cohereInputType returns the Cohere input type, documents by default.
+func cohereInputType(inputType InputType) string {
+	if inputType == InputQuery {
+		return "search_query"
+	}
+	return "search_document"
+}
+
+func (m *bedrockTitanEmbeddingModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *bedrockTitanEmbeddingModel) GetDistanceType() VectorDistanceType {
+	return CosineDistance
+}
+
+func (m *bedrockTitanEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	// Embeddings of v1 are not normalized
+	return VectorCosineSimilarity(vector1, vector2)
+}
+
+// Embedding embeds the documents one by one, Titan accepts one text per
+// request.
+func (m *bedrockTitanEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var body []byte
+	var response = &ModelResponse{}
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[bedrockTitanEmbeddingModel.Embedding] %w", err)
+	}
+
+	var titanRequest titanModelRequest
+	if strings.Contains(m.ModelId, "-v2") {
+		var normalize = true
+		titanRequest.Normalize = &normalize
+		titanRequest.Dimensions = request.Dimensions
+	} else if request.Dimensions > 0 {
+		return nil, fmt.Errorf("[bedrockTitanEmbeddingModel.Embedding] dimensions is not supported by %s", m.ModelId)
+	}
+
+	for _, document := range documents {
+		var titanResponse titanModelResponse
+
+		titanRequest.InputText = document
+		buffer.Reset()
+		err = aigc.EncodeJson(buffer, &titanRequest)
+		if err != nil {
+			return nil, fmt.Errorf("[bedrockTitanEmbeddingModel.Embedding] %w", err)
+		}
+		body, err = invokeBedrock(ctx, &m.client, m.ModelId, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+		if err != nil {
+			return nil, fmt.Errorf("[bedrockTitanEmbeddingModel.Embedding] %w", err)
+		}
+		err = json.Unmarshal(body, &titanResponse)
+		if err != nil {
+			return nil, fmt.Errorf("[bedrockTitanEmbeddingModel.Embedding] %w", err)
+		}
+		if len(titanResponse.Embedding) == 0 {
+			return nil, errors.New("[bedrockTitanEmbeddingModel.Embedding] embedding is empty")
+		}
+		response.Embeddings = append(response.Embeddings, titanResponse.Embedding)
+		response.appendTokens([]string{document}, titanResponse.InputTextTokenCount)
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+func (m *bedrockCohereEmbeddingModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *bedrockCohereEmbeddingModel) GetDistanceType() VectorDistanceType {
+	return CosineDistance
+}
+
+func (m *bedrockCohereEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	return VectorCosineSimilarity(vector1, vector2)
+}
+
+func (m *bedrockCohereEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var body []byte
+	var response = &ModelResponse{}
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[bedrockCohereEmbeddingModel.Embedding] %w", err)
+	}
+	if request.Dimensions > 0 {
+		return nil, fmt.Errorf("[bedrockCohereEmbeddingModel.Embedding] dimensions is not supported by %s", m.ModelId)
+	}
+
+	for _, batch := range batches(documents, cohereMaxBatchSize, 0) {
+		var cohereResponse cohereModelResponse
+		var cohereRequest = cohereModelRequest{
+			Texts:     batch,
+			InputType: cohereInputType(request.InputType),
+			Truncate:  "NONE",
+		}
+
+		buffer.Reset()
+		err = aigc.EncodeJson(buffer, &cohereRequest)
+		if err != nil {
+			return nil, fmt.Errorf("[bedrockCohereEmbeddingModel.Embedding] %w", err)
+		}
+		body, err = invokeBedrock(ctx, &m.client, m.ModelId, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+		if err != nil {
+			return nil, fmt.Errorf("[bedrockCohereEmbeddingModel.Embedding] %w", err)
+		}
+		err = json.Unmarshal(body, &cohereResponse)
+		if err != nil {
+			return nil, fmt.Errorf("[bedrockCohereEmbeddingModel.Embedding] %w", err)
+		}
+		if len(cohereResponse.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("[bedrockCohereEmbeddingModel.Embedding] %d embeddings for %d documents",
+				len(cohereResponse.Embeddings), len(batch))
+		}
+		response.Embeddings = append(response.Embeddings, cohereResponse.Embeddings...)
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+func newBedrockClient(opts *aigc.ModelOptions) bedrock.Client {
+	return bedrock.Client{
+		Region:    opts.Region,
+		AccessKey: opts.AccessKey,
+		SecretKey: opts.SecretKey,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+		Endpoint:  opts.Endpoint,
+	}
+}
+
+func newBedrockTitanEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*bedrockTitanEmbeddingModel, error) {
+	if opts.Region == "" {
+		return nil, errors.New("aws region is required")
+	}
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, errors.New("aws access key and secret key are required")
+	}
+
+	return &bedrockTitanEmbeddingModel{
+		ModelId:     modelId,
+		Region:      opts.Region,
+		AccessKey:   opts.AccessKey,
+		SecretKey:   opts.SecretKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+		client:      newBedrockClient(opts),
+	}, nil
+}
+
+func newBedrockCohereEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*bedrockCohereEmbeddingModel, error) {
+	if opts.Region == "" {
+		return nil, errors.New("aws region is required")
+	}
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, errors.New("aws access key and secret key are required")
+	}
+
+	return &bedrockCohereEmbeddingModel{
+		ModelId:     modelId,
+		Region:      opts.Region,
+		AccessKey:   opts.AccessKey,
+		SecretKey:   opts.SecretKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+		client:      newBedrockClient(opts),
+	}, nil
+}
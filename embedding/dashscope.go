@@ -0,0 +1,175 @@
+package embedding
+
+// DashScope documentation:
+// https://help.aliyun.com/zh/model-studio/developer-reference/text-embedding-synchronous-api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	// The native API, the OpenAI compatible mode does not support text_type
+	dashScopeDefaultEndpoint = "https://dashscope.aliyuncs.com/api/v1/services/embeddings/text-embedding/text-embedding"
+
+	// Maximum texts per request of text-embedding-v3
+	dashScopeMaxBatchSize = 10
+)
+
+type dashScopeModelRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Texts []string `json:"texts"`
+	} `json:"input"`
+	Parameters struct {
+		// query or document. Default is document.
+		TextType string `json:"text_type,omitempty"`
+		// 1024, 768 or 512 of text-embedding-v3. Default is 1024.
+		Dimension int `json:"dimension,omitempty"`
+	} `json:"parameters"`
+}
+
+type dashScopeModelResponse struct {
+	Output struct {
+		Embeddings []struct {
+			TextIndex int       `json:"text_index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"embeddings"`
+	} `json:"output"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+	RequestId string `json:"request_id"`
+}
+
+type dashScopeEmbeddingModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func (r *dashScopeModelRequest) load(request *ModelRequest, documents []string) error {
+	if len(documents) == 0 {
+		return fmt.Errorf("[dashScopeModelRequest.load] document is empty")
+	}
+	r.Input.Texts = documents
+	r.Parameters.TextType = string(request.InputType)
+	r.Parameters.Dimension = request.Dimensions
+	return nil
+}
+
+func (r *dashScopeModelResponse) dump(documents []string, response *ModelResponse) error {
+	if len(r.Output.Embeddings) != len(documents) {
+		return fmt.Errorf("[dashScopeModelResponse.dump] %d embeddings for %d documents",
+			len(r.Output.Embeddings), len(documents))
+	}
+	var embeddings = make([][]float32, len(documents))
+	for _, e := range r.Output.Embeddings {
+		if e.TextIndex < 0 || e.TextIndex >= len(embeddings) || embeddings[e.TextIndex] != nil {
+			return fmt.Errorf("[dashScopeModelResponse.dump] invalid index %d", e.TextIndex)
+		}
+		embeddings[e.TextIndex] = e.Embedding
+	}
+	response.Embeddings = append(response.Embeddings, embeddings...)
+	response.appendTokens(documents, r.Usage.TotalTokens)
+	return nil
+}
+
+func (m *dashScopeEmbeddingModel) getModelUrl() string {
+	if m.Endpoint == "" {
+		return dashScopeDefaultEndpoint
+	}
+	return m.Endpoint
+}
+
+func (m *dashScopeEmbeddingModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *dashScopeEmbeddingModel) GetDistanceType() VectorDistanceType {
+	return CosineDistance
+}
+
+func (m *dashScopeEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	return VectorCosineSimilarity(vector1, vector2)
+}
+
+func (m *dashScopeEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var body []byte
+	var response = &ModelResponse{}
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[dashScopeEmbeddingModel.Embedding] %w", err)
+	}
+
+	var header = http.Header{}
+	header.Set("Authorization", "Bearer "+m.ApiKey)
+	for _, batch := range batches(documents, dashScopeMaxBatchSize, 0) {
+		var dashScopeRequest = dashScopeModelRequest{Model: m.ModelId}
+		var dashScopeResponse dashScopeModelResponse
+
+		err = dashScopeRequest.load(request, batch)
+		if err != nil {
+			return nil, fmt.Errorf("[dashScopeEmbeddingModel.Embedding] %w", err)
+		}
+		buffer.Reset()
+		err = aigc.EncodeJson(buffer, &dashScopeRequest)
+		if err != nil {
+			return nil, fmt.Errorf("[dashScopeEmbeddingModel.Embedding] %w", err)
+		}
+		body, err = m.client.PostJson(ctx, m.getModelUrl(), header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+		if err != nil {
+			return nil, fmt.Errorf("[dashScopeEmbeddingModel.Embedding] %w", err)
+		}
+		err = json.Unmarshal(body, &dashScopeResponse)
+		if err != nil {
+			return nil, fmt.Errorf("[dashScopeEmbeddingModel.Embedding] %w", err)
+		}
+		err = dashScopeResponse.dump(batch, response)
+		if err != nil {
+			return nil, fmt.Errorf("[dashScopeEmbeddingModel.Embedding] %w", err)
+		}
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+func newDashScopeEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*dashScopeEmbeddingModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("dashscope api key is required")
+	}
+
+	var model = &dashScopeEmbeddingModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,190 @@
+package embedding
+
+// Gemini documentation:
+// https://ai.google.dev/api/embeddings#method:-models.batchembedcontents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	geminiDefaultEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+
+	// Maximum requests per batch
+	geminiMaxBatchSize = 100
+)
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiEmbedContentRequest struct {
+	// models/{model}, the same as the model of the url
+	Model   string `json:"model"`
+	Content struct {
+		Parts []geminiPart `json:"parts"`
+	} `json:"content"`
+	// RETRIEVAL_QUERY, RETRIEVAL_DOCUMENT, SEMANTIC_SIMILARITY and so on
+	TaskType string `json:"taskType,omitempty"`
+	// Truncates the embeddings, supported by text-embedding-004 and later
+	// models
+	OutputDimensionality int `json:"outputDimensionality,omitempty"`
+}
+
+type geminiModelRequest struct {
+	Requests []geminiEmbedContentRequest `json:"requests"`
+}
+
+type geminiModelResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// geminiEmbeddingModel embeds by the Gemini API. The API does not report the
+// tokens, the tokens of the response are zero.
+type geminiEmbeddingModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+// geminiTaskType returns the task type of the input type, empty means the
+// API default.
+func geminiTaskType(inputType InputType) string {
+	switch inputType {
+	case InputQuery:
+		return "RETRIEVAL_QUERY"
+	case InputDocument:
+		return "RETRIEVAL_DOCUMENT"
+	}
+	return ""
+}
+
+func (r *geminiModelRequest) load(modelId string, request *ModelRequest, documents []string) error {
+	if len(documents) == 0 {
+		return fmt.Errorf("[geminiModelRequest.load] document is empty")
+	}
+	r.Requests = make([]geminiEmbedContentRequest, len(documents))
+	for i, document := range documents {
+		var content = &r.Requests[i]
+		content.Model = "models/" + modelId
+		content.Content.Parts = []geminiPart{{Text: document}}
+		content.TaskType = geminiTaskType(request.InputType)
+		content.OutputDimensionality = request.Dimensions
+	}
+	return nil
+}
+
+func (r *geminiModelResponse) dump(documents []string, response *ModelResponse) error {
+	if len(r.Embeddings) != len(documents) {
+		return fmt.Errorf("[geminiModelResponse.dump] %d embeddings for %d documents", len(r.Embeddings), len(documents))
+	}
+	for _, e := range r.Embeddings {
+		response.Embeddings = append(response.Embeddings, e.Values)
+	}
+	return nil
+}
+
+func (m *geminiEmbeddingModel) getModelUrl() string {
+	var endpoint = m.Endpoint
+	if endpoint == "" {
+		endpoint = geminiDefaultEndpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/models/" + m.ModelId + ":batchEmbedContents"
+}
+
+func (m *geminiEmbeddingModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *geminiEmbeddingModel) GetDistanceType() VectorDistanceType {
+	return CosineDistance
+}
+
+func (m *geminiEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	// Truncated embeddings are not normalized
+	return VectorCosineSimilarity(vector1, vector2)
+}
+
+func (m *geminiEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var body []byte
+	var response = &ModelResponse{}
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[geminiEmbeddingModel.Embedding] %w", err)
+	}
+
+	var header = http.Header{}
+	header.Set("x-goog-api-key", m.ApiKey)
+	for _, batch := range batches(documents, geminiMaxBatchSize, 0) {
+		var geminiRequest geminiModelRequest
+		var geminiResponse geminiModelResponse
+
+		err = geminiRequest.load(m.ModelId, request, batch)
+		if err != nil {
+			return nil, fmt.Errorf("[geminiEmbeddingModel.Embedding] %w", err)
+		}
+		buffer.Reset()
+		err = aigc.EncodeJson(buffer, &geminiRequest)
+		if err != nil {
+			return nil, fmt.Errorf("[geminiEmbeddingModel.Embedding] %w", err)
+		}
+		body, err = m.client.PostJson(ctx, m.getModelUrl(), header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+		if err != nil {
+			return nil, fmt.Errorf("[geminiEmbeddingModel.Embedding] %w", err)
+		}
+		err = json.Unmarshal(body, &geminiResponse)
+		if err != nil {
+			return nil, fmt.Errorf("[geminiEmbeddingModel.Embedding] %w", err)
+		}
+		err = geminiResponse.dump(batch, response)
+		if err != nil {
+			return nil, fmt.Errorf("[geminiEmbeddingModel.Embedding] %w", err)
+		}
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+func newGeminiEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*geminiEmbeddingModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("gemini api key is required")
+	}
+
+	var model = &geminiEmbeddingModel{
+		ModelId:     strings.TrimPrefix(modelId, "models/"),
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,168 @@
+package embedding
+
+// Voyage documentation:
+// https://docs.voyageai.com/reference/embeddings-api
+
+// Jina documentation:
+// https://api.jina.ai/redoc#tag/embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// Path of the hosted embedding APIs. The APIs share the OpenAI response
+// shape but differ in the request parameters.
+const hostedPath = "/v1/embeddings"
+
+// Maximum inputs and total tokens per request
+var hostedMaxBatches = map[aigc.VendorId][2]int{
+	aigc.Vendors.Voyage: {128, 120000},
+	aigc.Vendors.Jina:   {512, 0},
+}
+
+type hostedModelRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+	// Voyage: query or document
+	InputType string `json:"input_type,omitempty"`
+	// Voyage: supported by voyage-3-large and voyage-code-3
+	OutputDimension int `json:"output_dimension,omitempty"`
+	// Jina: retrieval.query, retrieval.passage, text-matching and so on
+	Task string `json:"task,omitempty"`
+	// Jina: truncates the embeddings, Matryoshka representation
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+type hostedEmbeddingModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	vendor aigc.VendorId
+	client aigc.HttpClient
+}
+
+func (r *hostedModelRequest) load(vendor aigc.VendorId, request *ModelRequest, documents []string) error {
+	if len(documents) == 0 {
+		return errors.New("[hostedModelRequest.load] document is empty")
+	}
+	r.Input = documents
+	r.InputType, r.OutputDimension, r.Task, r.Dimensions = "", 0, "", 0
+	switch vendor {
+	case aigc.Vendors.Voyage:
+		r.InputType = string(request.InputType)
+		r.OutputDimension = request.Dimensions
+	case aigc.Vendors.Jina:
+		switch request.InputType {
+		case InputQuery:
+			r.Task = "retrieval.query"
+		case InputDocument:
+			r.Task = "retrieval.passage"
+		}
+		r.Dimensions = request.Dimensions
+	}
+	return nil
+}
+
+func (m *hostedEmbeddingModel) getModelUrl() string {
+	if m.Endpoint == "" {
+		return aigc.VendorEndpoint(m.vendor, hostedPath)
+	}
+	return m.Endpoint
+}
+
+func (m *hostedEmbeddingModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *hostedEmbeddingModel) GetDistanceType() VectorDistanceType {
+	return CosineDistance
+}
+
+func (m *hostedEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	return VectorCosineSimilarity(vector1, vector2)
+}
+
+func (m *hostedEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var body []byte
+	var response = &ModelResponse{}
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[hostedEmbeddingModel.Embedding] %w", err)
+	}
+
+	var header = http.Header{}
+	if m.ApiKey != "" {
+		header.Set("Authorization", "Bearer "+m.ApiKey)
+	}
+	var limits = hostedMaxBatches[m.vendor]
+	for _, batch := range batches(documents, limits[0], limits[1]) {
+		var hostedRequest = hostedModelRequest{Model: m.ModelId}
+		var hostedResponse openaiModelResponse
+
+		err = hostedRequest.load(m.vendor, request, batch)
+		if err != nil {
+			return nil, fmt.Errorf("[hostedEmbeddingModel.Embedding] %w", err)
+		}
+		buffer.Reset()
+		err = aigc.EncodeJson(buffer, &hostedRequest)
+		if err != nil {
+			return nil, fmt.Errorf("[hostedEmbeddingModel.Embedding] %w", err)
+		}
+		body, err = m.client.PostJson(ctx, m.getModelUrl(), header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+		if err != nil {
+			return nil, fmt.Errorf("[hostedEmbeddingModel.Embedding] %w", err)
+		}
+		err = json.Unmarshal(body, &hostedResponse)
+		if err != nil {
+			return nil, fmt.Errorf("[hostedEmbeddingModel.Embedding] %w", err)
+		}
+		err = hostedResponse.dump(batch, response)
+		if err != nil {
+			return nil, fmt.Errorf("[hostedEmbeddingModel.Embedding] %w", err)
+		}
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+func newHostedEmbeddingModel(vendor aigc.VendorId, modelId string, opts *aigc.ModelOptions) (*hostedEmbeddingModel, error) {
+	// Self-hosted services may not need an api key
+	if opts.ApiKey == "" && opts.Endpoint == "" {
+		return nil, errors.New("api key is required")
+	}
+
+	var model = &hostedEmbeddingModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+		vendor:      vendor,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
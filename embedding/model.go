@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/Pooh-Mucho/go-aigc"
+	"strings"
 )
 
 type VectorDistanceType string
@@ -44,7 +45,40 @@ func NewModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (Model, err
 		}
 	}
 
-	if opts.VendorId == aigc.Vendors.Ollama {
+	// amazon.titan-embed-text-v2:0, cohere.embed-english-v3 on Bedrock
+	if strings.HasPrefix(string(modelId), "amazon.titan-embed") || strings.HasPrefix(string(modelId), "cohere.embed") {
+		if opts.VendorId == aigc.Vendors.Amazon || opts.VendorId == "" {
+			opts.VendorId = aigc.Vendors.Amazon
+			if strings.HasPrefix(string(modelId), "amazon.") {
+				return newBedrockTitanEmbeddingModel(string(modelId), &opts)
+			}
+			return newBedrockCohereEmbeddingModel(string(modelId), &opts)
+		}
+	}
+
+	if opts.VendorId == "" {
+		switch {
+		case modelId == Models.DashScopeTextEmbeddingV3:
+			opts.VendorId = aigc.Vendors.Alibaba
+		case modelId == Models.GoogleTextEmbedding004:
+			opts.VendorId = aigc.Vendors.Google
+		case strings.HasPrefix(string(modelId), "voyage-"):
+			opts.VendorId = aigc.Vendors.Voyage
+		case strings.HasPrefix(string(modelId), "jina-embeddings-"):
+			opts.VendorId = aigc.Vendors.Jina
+		}
+	}
+
+	switch opts.VendorId {
+	case aigc.Vendors.Alibaba:
+		return newDashScopeEmbeddingModel(string(modelId), &opts)
+	case aigc.Vendors.Google:
+		return newGeminiEmbeddingModel(string(modelId), &opts)
+	case aigc.Vendors.Voyage:
+		return newHostedEmbeddingModel(opts.VendorId, string(modelId), &opts)
+	case aigc.Vendors.Jina:
+		return newHostedEmbeddingModel(opts.VendorId, string(modelId), &opts)
+	case aigc.Vendors.Ollama:
 		return newOllamaEmbeddingModel(string(modelId), &opts)
 	}
 
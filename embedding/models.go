@@ -26,6 +26,34 @@ var Models = struct {
 	// https://huggingface.co/mixedbread-ai/mxbai-embed-large-v1
 	MxbaiEmbedLarge   aigc.ModelId
 	MxbaiEmbedLargeV1 aigc.ModelId
+
+	// Amazon Titan Embedding models on Bedrock
+	// https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-embed-text.html
+	AmazonTitanEmbedTextV2 aigc.ModelId
+
+	// Cohere Embedding models on Bedrock
+	// https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-embed.html
+	CohereEmbedEnglishV3      aigc.ModelId
+	CohereEmbedMultilingualV3 aigc.ModelId
+
+	// Alibaba DashScope Embedding models
+	// https://help.aliyun.com/zh/model-studio/developer-reference/text-embedding-synchronous-api
+	DashScopeTextEmbeddingV3 aigc.ModelId
+
+	// Google Gemini Embedding models
+	// https://ai.google.dev/gemini-api/docs/embeddings
+	GoogleTextEmbedding004 aigc.ModelId
+
+	// Voyage Embedding models
+	// https://docs.voyageai.com/docs/embeddings
+	Voyage3      aigc.ModelId
+	Voyage3Lite  aigc.ModelId
+	Voyage3Large aigc.ModelId
+	VoyageCode3  aigc.ModelId
+
+	// Jina Embedding models
+	// https://jina.ai/embeddings/
+	JinaEmbeddingsV3 aigc.ModelId
 }{
 	// OpenAI Embedding models
 	OpenAITextEmbeddingAda_002: "text-embedding-ada-002",
@@ -44,4 +72,26 @@ var Models = struct {
 	// Mixedbread Embedding models
 	MxbaiEmbedLarge:   "mxbai-embed-large",
 	MxbaiEmbedLargeV1: "mxbai-embed-large-v1",
+
+	// Amazon Titan Embedding models on Bedrock
+	AmazonTitanEmbedTextV2: "amazon.titan-embed-text-v2:0",
+
+	// Cohere Embedding models on Bedrock
+	CohereEmbedEnglishV3:      "cohere.embed-english-v3",
+	CohereEmbedMultilingualV3: "cohere.embed-multilingual-v3",
+
+	// Alibaba DashScope Embedding models
+	DashScopeTextEmbeddingV3: "text-embedding-v3",
+
+	// Google Gemini Embedding models
+	GoogleTextEmbedding004: "text-embedding-004",
+
+	// Voyage Embedding models
+	Voyage3:      "voyage-3",
+	Voyage3Lite:  "voyage-3-lite",
+	Voyage3Large: "voyage-3-large",
+	VoyageCode3:  "voyage-code-3",
+
+	// Jina Embedding models
+	JinaEmbeddingsV3: "jina-embeddings-v3",
 }
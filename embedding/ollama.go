@@ -19,9 +19,16 @@ var ollamaModelMapping = map[string]string{
 	string(Models.MxbaiEmbedLargeV1): "mxbai-embed-large:335m",
 }
 
+// Maximum inputs per request, Ollama has no limit but large batches hold the
+// model for a long time.
+const ollamaMaxBatchSize = 512
+
 type ollamaModelRequest struct {
 	Model string   `json:"model"`
 	Input []string `json:"input"`
+	// Truncates the embeddings to the dimensions, supported by newer Ollama
+	// versions.
+	Dimensions int `json:"dimensions,omitempty"`
 }
 
 type ollamaModelResponse struct {
@@ -44,20 +51,22 @@ type ollamaEmbeddingModel struct {
 	client aigc.HttpClient
 }
 
-func (r *ollamaModelRequest) load(request *ModelRequest) error {
-	if len(request.Document) == 0 {
+func (r *ollamaModelRequest) load(request *ModelRequest, documents []string) error {
+	if len(documents) == 0 {
 		return fmt.Errorf("[ollamaModelRequest:load] document is empty")
 	}
-	r.Input = []string{request.Document}
+	r.Input = documents
+	r.Dimensions = request.Dimensions
 	return nil
 }
 
-func (r *ollamaModelResponse) dump(response *ModelResponse) error {
-	if len(r.Embeddings) == 0 {
-		return fmt.Errorf("[ollamaModelResponse:dump] embeddings is empty")
+func (r *ollamaModelResponse) dump(documents []string, response *ModelResponse) error {
+	if len(r.Embeddings) != len(documents) {
+		return fmt.Errorf("[ollamaModelResponse:dump] %d embeddings for %d documents",
+			len(r.Embeddings), len(documents))
 	}
-	response.Embedding = r.Embeddings[0]
-	response.Tokens = r.PromptEvalCount
+	response.Embeddings = append(response.Embeddings, r.Embeddings...)
+	response.appendTokens(documents, r.PromptEvalCount)
 	return nil
 }
 
@@ -82,11 +91,12 @@ func (m *ollamaEmbeddingModel) GetModelDeploymentId() string {
 	return m.ModelId
 }
 
-func (m *ollamaEmbeddingModel) requestToJson(request *ModelRequest, jsonBuffer *bytes.Buffer) error {
+func (m *ollamaEmbeddingModel) requestToJson(request *ModelRequest, documents []string,
+	jsonBuffer *bytes.Buffer) error {
 	var err error
 	var ollamaRequest ollamaModelRequest
 
-	err = ollamaRequest.load(request)
+	err = ollamaRequest.load(request, documents)
 	if err != nil {
 		return fmt.Errorf("[ollamaEmbeddingModel.requestToJson] %w", err)
 	}
@@ -99,21 +109,21 @@ func (m *ollamaEmbeddingModel) requestToJson(request *ModelRequest, jsonBuffer *
 	return nil
 }
 
-func (m *ollamaEmbeddingModel) jsonToResponse(jsonBuffer *bytes.Buffer) (*ModelResponse, error) {
+func (m *ollamaEmbeddingModel) jsonToResponse(jsonBuffer *bytes.Buffer, documents []string,
+	response *ModelResponse) error {
 	var err error
 	var ollamaResponse ollamaModelResponse
 
 	err = aigc.DecodeJson(jsonBuffer, &ollamaResponse)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.jsonToResponse] %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.jsonToResponse] %w", err)
 	}
 
-	var response = ModelResponse{}
-	err = ollamaResponse.dump(&response)
+	err = ollamaResponse.dump(documents, response)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.jsonToResponse] %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.jsonToResponse] %w", err)
 	}
-	return &response, nil
+	return nil
 }
 
 func (m *ollamaEmbeddingModel) GetModelId() string {
@@ -132,20 +142,41 @@ func (m *ollamaEmbeddingModel) Distance(vector1, vector2 []float32) (float32, er
 }
 
 func (m *ollamaEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var response = &ModelResponse{}
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] %w", err)
+	}
+	for _, batch := range batches(documents, ollamaMaxBatchSize, 0) {
+		err = m.embed(ctx, request, batch, response)
+		if err != nil {
+			return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] %w", err)
+		}
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+// embed embeds a batch of documents, the embeddings are appended to the
+// response.
+func (m *ollamaEmbeddingModel) embed(ctx context.Context, request *ModelRequest, documents []string,
+	response *ModelResponse) error {
 	var err error
 	var modelUrl string
 	var requestJson *bytes.Buffer
 	var responseJson *bytes.Buffer
-	var response *ModelResponse
 	var httpRequest *http.Request
 	var httpResponse *http.Response
 
 	modelUrl = m.getModelUrl()
 	requestJson = aigc.AllocBuffer()
 
-	err = m.requestToJson(request, requestJson)
+	err = m.requestToJson(request, documents, requestJson)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.embed] %w", err)
 	}
 	if m.RequestLog != nil {
 		m.RequestLog(requestJson.Bytes())
@@ -153,7 +184,7 @@ func (m *ollamaEmbeddingModel) Embedding(ctx context.Context, request *ModelRequ
 
 	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, modelUrl, requestJson)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] create http request %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.embed] create http request %w", err)
 	}
 
 	httpRequest.Header.Set("Content-Type", "application/json")
@@ -163,12 +194,12 @@ func (m *ollamaEmbeddingModel) Embedding(ctx context.Context, request *ModelRequ
 
 	httpResponse, err = m.client.Do(httpRequest)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] do http request %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.embed] do http request %w", err)
 	}
 	defer httpResponse.Body.Close()
 
 	if httpResponse.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] http error %s %s",
+		return fmt.Errorf("[ollamaEmbeddingModel.embed] http error %s %s",
 			httpResponse.Status, aigc.HttpResponseText(httpResponse))
 	}
 
@@ -176,20 +207,18 @@ func (m *ollamaEmbeddingModel) Embedding(ctx context.Context, request *ModelRequ
 	defer aigc.FreeBuffer(responseJson)
 	_, err = io.Copy(responseJson, httpResponse.Body)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] read http response %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.embed] read http response %w", err)
 	}
 
 	if m.ResponseLog != nil {
 		m.ResponseLog(responseJson.Bytes())
 	}
 
-	response, err = m.jsonToResponse(responseJson)
-
+	err = m.jsonToResponse(responseJson, documents, response)
 	if err != nil {
-		return nil, fmt.Errorf("[ollamaEmbeddingModel.Embedding] %w", err)
+		return fmt.Errorf("[ollamaEmbeddingModel.embed] %w", err)
 	}
-
-	return response, nil
+	return nil
 }
 
 func newOllamaEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*ollamaEmbeddingModel, error) {
@@ -213,8 +242,9 @@ func newOllamaEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*ollamaEm
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -3,6 +3,7 @@ package embedding
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -18,6 +19,11 @@ const (
 	azureOpenAIDefaultApiVersion = "2024-06-01"
 
 	openaiDefaultEndpoint = "https://api.openai.com/v1/embeddings"
+
+	// Maximum inputs per request
+	openaiMaxBatchSize = 2048
+	// Maximum total tokens of the inputs per request
+	openaiMaxBatchTokens = 300000
 )
 
 // OpenAI Embedding models:
@@ -53,11 +59,15 @@ type openaiEmbedding struct {
 	Object string `json:"object"`
 	// The index of the embedding in the list of embeddings.
 	Index int `json:"index"`
-	// The embedding vector, which is a list of floats. The length of vector
-	// depends on the model:
-	Embedding []float32 `json:"embedding"`
+	// The embedding vector, which is a list of floats, or a base64 string if
+	// the encoding format is base64. The length of vector depends on the
+	// model.
+	Embedding openaiVector `json:"embedding"`
 }
 
+// openaiVector is decoded from a list of floats or a base64 string.
+type openaiVector []float32
+
 type openaiModelResponse struct {
 	// Always "list"
 	Object string `json:"object,omitempty"`
@@ -96,20 +106,51 @@ type azureOpenAIEmbeddingModel struct {
 	client aigc.HttpClient
 }
 
-func (r *openaiModelRequest) load(request *ModelRequest) error {
-	if len(request.Document) == 0 {
+func (v *openaiVector) UnmarshalJSON(data []byte) error {
+	var err error
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err = json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v, err = decodeBase64Vector(s)
+		return err
+	}
+	return json.Unmarshal(data, (*[]float32)(v))
+}
+
+// openaiDimensions reports whether the model supports the dimensions
+// parameter.
+func openaiDimensions(modelId string) bool {
+	return modelId != string(Models.OpenAITextEmbeddingAda_002)
+}
+
+func (r *openaiModelRequest) load(request *ModelRequest, documents []string) error {
+	if len(documents) == 0 {
 		return fmt.Errorf("[openaiModelRequest:load] document is empty")
 	}
-	r.Input = []string{request.Document}
+	r.Input = documents
+	r.Dimensions = request.Dimensions
+	r.EncodingFormat = ""
+	if request.Base64 {
+		r.EncodingFormat = "base64"
+	}
 	return nil
 }
 
-func (r *openaiModelResponse) dump(response *ModelResponse) error {
-	if len(r.Data) == 0 {
-		return fmt.Errorf("[openaiModelResponse:dump] data is empty")
+func (r *openaiModelResponse) dump(documents []string, response *ModelResponse) error {
+	if len(r.Data) != len(documents) {
+		return fmt.Errorf("[openaiModelResponse:dump] %d embeddings for %d documents", len(r.Data), len(documents))
+	}
+	var embeddings = make([][]float32, len(documents))
+	for _, data := range r.Data {
+		if data.Index < 0 || data.Index >= len(embeddings) || embeddings[data.Index] != nil {
+			return fmt.Errorf("[openaiModelResponse:dump] invalid index %d", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
 	}
-	response.Embedding = r.Data[0].Embedding
-	response.Tokens = r.Usage.TotalTokens
+	response.Embeddings = append(response.Embeddings, embeddings...)
+	response.appendTokens(documents, r.Usage.TotalTokens)
 	return nil
 }
 
@@ -120,15 +161,19 @@ func (m *openaiEmbeddingModel) getModelUrl() string {
 	return m.Endpoint
 }
 
-func (m *openaiEmbeddingModel) requestToJson(request *ModelRequest, jsonBuffer *bytes.Buffer) error {
+func (m *openaiEmbeddingModel) requestToJson(request *ModelRequest, documents []string,
+	jsonBuffer *bytes.Buffer) error {
 	var err error
 	var openaiRequest openaiModelRequest
 
-	err = openaiRequest.load(request)
+	err = openaiRequest.load(request, documents)
 	if err != nil {
 		return fmt.Errorf("[openaiEmbeddingModel.requestToJson] %w", err)
 	}
 	openaiRequest.Model = m.ModelId
+	if openaiRequest.Dimensions > 0 && !openaiDimensions(m.ModelId) {
+		return fmt.Errorf("[openaiEmbeddingModel.requestToJson] dimensions is not supported by %s", m.ModelId)
+	}
 
 	err = aigc.EncodeJson(jsonBuffer, openaiRequest)
 	if err != nil {
@@ -137,21 +182,21 @@ func (m *openaiEmbeddingModel) requestToJson(request *ModelRequest, jsonBuffer *
 	return nil
 }
 
-func (m *openaiEmbeddingModel) jsonToResponse(jsonBuffer *bytes.Buffer) (*ModelResponse, error) {
+func (m *openaiEmbeddingModel) jsonToResponse(jsonBuffer *bytes.Buffer, documents []string,
+	response *ModelResponse) error {
 	var err error
 	var openaiResponse openaiModelResponse
 
 	err = aigc.DecodeJson(jsonBuffer, &openaiResponse)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.jsonToResponse] %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.jsonToResponse] %w", err)
 	}
 
-	var response = ModelResponse{}
-	err = openaiResponse.dump(&response)
+	err = openaiResponse.dump(documents, response)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.jsonToResponse] %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.jsonToResponse] %w", err)
 	}
-	return &response, nil
+	return nil
 }
 
 func (m *openaiEmbeddingModel) GetModelId() string {
@@ -170,20 +215,41 @@ func (m *openaiEmbeddingModel) Distance(vector1, vector2 []float32) (float32, er
 }
 
 func (m *openaiEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var response = &ModelResponse{}
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] %w", err)
+	}
+	for _, batch := range batches(documents, openaiMaxBatchSize, openaiMaxBatchTokens) {
+		err = m.embed(ctx, request, batch, response)
+		if err != nil {
+			return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] %w", err)
+		}
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+// embed embeds a batch of documents, the embeddings are appended to the
+// response.
+func (m *openaiEmbeddingModel) embed(ctx context.Context, request *ModelRequest, documents []string,
+	response *ModelResponse) error {
 	var err error
 	var modelUrl string
 	var requestJson *bytes.Buffer
 	var responseJson *bytes.Buffer
-	var response *ModelResponse
 	var httpRequest *http.Request
 	var httpResponse *http.Response
 
 	modelUrl = m.getModelUrl()
 	requestJson = aigc.AllocBuffer()
 
-	err = m.requestToJson(request, requestJson)
+	err = m.requestToJson(request, documents, requestJson)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.embed] %w", err)
 	}
 	if m.RequestLog != nil {
 		m.RequestLog(requestJson.Bytes())
@@ -191,7 +257,7 @@ func (m *openaiEmbeddingModel) Embedding(ctx context.Context, request *ModelRequ
 
 	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, modelUrl, requestJson)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] create http request %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.embed] create http request %w", err)
 	}
 
 	httpRequest.Header.Set("Authorization", "Bearer "+m.ApiKey)
@@ -199,12 +265,12 @@ func (m *openaiEmbeddingModel) Embedding(ctx context.Context, request *ModelRequ
 
 	httpResponse, err = m.client.Do(httpRequest)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] do http request %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.embed] do http request %w", err)
 	}
 	defer httpResponse.Body.Close()
 
 	if httpResponse.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] http error %s %s",
+		return fmt.Errorf("[openaiEmbeddingModel.embed] http error %s %s",
 			httpResponse.Status, aigc.HttpResponseText(httpResponse))
 	}
 
@@ -212,20 +278,18 @@ func (m *openaiEmbeddingModel) Embedding(ctx context.Context, request *ModelRequ
 	defer aigc.FreeBuffer(responseJson)
 	_, err = io.Copy(responseJson, httpResponse.Body)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] read http response %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.embed] read http response %w", err)
 	}
 
 	if m.ResponseLog != nil {
 		m.ResponseLog(responseJson.Bytes())
 	}
 
-	response, err = m.jsonToResponse(responseJson)
-
+	err = m.jsonToResponse(responseJson, documents, response)
 	if err != nil {
-		return nil, fmt.Errorf("[openaiEmbeddingModel.Embedding] %w", err)
+		return fmt.Errorf("[openaiEmbeddingModel.embed] %w", err)
 	}
-
-	return response, nil
+	return nil
 }
 
 func (m *azureOpenAIEmbeddingModel) getModelUrl() string {
@@ -246,14 +310,17 @@ func (m *azureOpenAIEmbeddingModel) getModelUrl() string {
 	return builder.String()
 }
 
-func (m *azureOpenAIEmbeddingModel) requestToJson(request *ModelRequest, jsonBuffer *bytes.Buffer) error {
+func (m *azureOpenAIEmbeddingModel) requestToJson(request *ModelRequest, documents []string,
+	jsonBuffer *bytes.Buffer) error {
 	var err error
 	var openaiRequest openaiModelRequest
 
-	err = openaiRequest.load(request)
+	err = openaiRequest.load(request, documents)
 	if err != nil {
 		return fmt.Errorf("[azureOpenAIEmbeddingModel.requestToJson] %w", err)
 	}
+	// The deployment name is not always the model name, dimensions is
+	// checked by the service.
 
 	err = aigc.EncodeJson(jsonBuffer, openaiRequest)
 	if err != nil {
@@ -262,21 +329,21 @@ func (m *azureOpenAIEmbeddingModel) requestToJson(request *ModelRequest, jsonBuf
 	return nil
 }
 
-func (m *azureOpenAIEmbeddingModel) jsonToResponse(jsonBuffer *bytes.Buffer) (*ModelResponse, error) {
+func (m *azureOpenAIEmbeddingModel) jsonToResponse(jsonBuffer *bytes.Buffer, documents []string,
+	response *ModelResponse) error {
 	var err error
 	var openaiResponse openaiModelResponse
 
 	err = aigc.DecodeJson(jsonBuffer, &openaiResponse)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.jsonToResponse] %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.jsonToResponse] %w", err)
 	}
 
-	var response = ModelResponse{}
-	err = openaiResponse.dump(&response)
+	err = openaiResponse.dump(documents, response)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.jsonToResponse] %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.jsonToResponse] %w", err)
 	}
-	return &response, nil
+	return nil
 }
 
 func (m *azureOpenAIEmbeddingModel) GetModelId() string {
@@ -295,20 +362,41 @@ func (m *azureOpenAIEmbeddingModel) Distance(vector1, vector2 []float32) (float3
 }
 
 func (m *azureOpenAIEmbeddingModel) Embedding(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var documents []string
+	var response = &ModelResponse{}
+
+	documents, err = request.documents()
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] %w", err)
+	}
+	for _, batch := range batches(documents, openaiMaxBatchSize, openaiMaxBatchTokens) {
+		err = m.embed(ctx, request, batch, response)
+		if err != nil {
+			return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] %w", err)
+		}
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+// embed embeds a batch of documents, the embeddings are appended to the
+// response.
+func (m *azureOpenAIEmbeddingModel) embed(ctx context.Context, request *ModelRequest, documents []string,
+	response *ModelResponse) error {
 	var err error
 	var modelUrl string
 	var requestJson *bytes.Buffer
 	var responseJson *bytes.Buffer
-	var response *ModelResponse
 	var httpRequest *http.Request
 	var httpResponse *http.Response
 
 	modelUrl = m.getModelUrl()
 	requestJson = aigc.AllocBuffer()
 
-	err = m.requestToJson(request, requestJson)
+	err = m.requestToJson(request, documents, requestJson)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.embed] %w", err)
 	}
 	if m.RequestLog != nil {
 		m.RequestLog(requestJson.Bytes())
@@ -316,19 +404,19 @@ func (m *azureOpenAIEmbeddingModel) Embedding(ctx context.Context, request *Mode
 
 	httpRequest, err = http.NewRequestWithContext(ctx, http.MethodPost, modelUrl, requestJson)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] create http request %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.embed] create http request %w", err)
 	}
 	httpRequest.Header.Set("api-key", m.ApiKey)
 	httpRequest.Header.Set("Content-Type", "application/json")
 
 	httpResponse, err = m.client.Do(httpRequest)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] do http request %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.embed] do http request %w", err)
 	}
 	defer httpResponse.Body.Close()
 
 	if httpResponse.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] http error %s %s",
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.embed] http error %s %s",
 			httpResponse.Status, aigc.HttpResponseText(httpResponse))
 	}
 
@@ -336,20 +424,18 @@ func (m *azureOpenAIEmbeddingModel) Embedding(ctx context.Context, request *Mode
 	defer aigc.FreeBuffer(responseJson)
 	_, err = io.Copy(responseJson, httpResponse.Body)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] read http response %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.embed] read http response %w", err)
 	}
 
 	if m.ResponseLog != nil {
 		m.ResponseLog(responseJson.Bytes())
 	}
 
-	response, err = m.jsonToResponse(responseJson)
-
+	err = m.jsonToResponse(responseJson, documents, response)
 	if err != nil {
-		return nil, fmt.Errorf("[azureOpenAIEmbeddingModel.Embedding] %w", err)
+		return fmt.Errorf("[azureOpenAIEmbeddingModel.embed] %w", err)
 	}
-
-	return response, nil
+	return nil
 }
 
 func newOpenAIEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*openaiEmbeddingModel, error) {
@@ -373,8 +459,9 @@ func newOpenAIEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*openaiEm
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -403,8 +490,9 @@ func newAzureOpenAIEmbeddingModel(modelId string, opts *aigc.ModelOptions) (*azu
 	}
 
 	model.client = aigc.HttpClient{
-		Proxy:   opts.Proxy,
-		Retries: opts.Retries,
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
 	}
 
 	return model, nil
@@ -0,0 +1,82 @@
+package embedding
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Binary quantization keeps the sign of each dimension as a bit, packed into
+// uint64 words. It works best with embeddings centered around zero, which
+// most embedding models produce.
+
+// VectorBinaryWords returns the number of words of a binary code.
+func VectorBinaryWords(dimension int) int {
+	return (dimension + 63) / 64
+}
+
+// VectorBinaryQuantize sets the bit i of the code if v[i] > 0.
+func VectorBinaryQuantize(v []float32, code []uint64) error {
+	if len(v) == 0 {
+		return errZeroDimension
+	}
+	if len(code) != VectorBinaryWords(len(v)) {
+		return errDimensionMismatch
+	}
+	clear(code)
+	for i, f := range v {
+		if f > 0 {
+			code[i/64] |= 1 << (i % 64)
+		}
+	}
+	return nil
+}
+
+// VectorHammingDistance returns the number of different bits.
+func VectorHammingDistance(code1, code2 []uint64) (int, error) {
+	if len(code1) != len(code2) {
+		return 0, errDimensionMismatch
+	}
+	return hamming(code1, code2), nil
+}
+
+func hamming(code1, code2 []uint64) int {
+	var distance = 0
+	code2 = code2[:len(code1)]
+	for i := range code1 {
+		distance += bits.OnesCount64(code1[i] ^ code2[i])
+	}
+	return distance
+}
+
+// VectorBinaryCosineSimilarity estimates the cosine similarity of the
+// vectors from their codes, cos(pi * hamming / dimension). The estimation
+// is exact in expectation for vectors in random directions, and coarse for
+// a single pair, it is for ranking candidates rather than thresholds.
+func VectorBinaryCosineSimilarity(code1, code2 []uint64, dimension int) (float32, error) {
+	if dimension <= 0 {
+		return 0, errZeroDimension
+	}
+	if len(code1) != VectorBinaryWords(dimension) || len(code2) != len(code1) {
+		return 0, errDimensionMismatch
+	}
+	return float32(math.Cos(math.Pi * float64(hamming(code1, code2)) / float64(dimension))), nil
+}
+
+// VectorBinarySearch returns the k codes nearest to the query by Hamming
+// distance, nearest first, the scores are the distances. Codes are stored
+// contiguously, len(codes) is a multiple of len(query).
+func VectorBinarySearch(query []uint64, codes []uint64, k int) ([]VectorMatch, error) {
+	if len(query) == 0 {
+		return nil, errZeroDimension
+	}
+	if len(codes)%len(query) != 0 {
+		return nil, errDimensionMismatch
+	}
+
+	var words = len(query)
+	var scores = make([]float32, len(codes)/words)
+	for i := range scores {
+		scores[i] = float32(hamming(query, codes[i*words:(i+1)*words]))
+	}
+	return VectorTopK(scores, k, false), nil
+}
@@ -0,0 +1,290 @@
+package embedding
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+const (
+	productDefaultCentroids  = 256
+	productDefaultIterations = 20
+)
+
+// ProductQuantizer splits a vector into sub-vectors, and quantizes each
+// sub-vector to the index of its nearest centroid, learned by k-means from
+// the training vectors. A code has a byte per sub-vector.
+//
+// For cosine distance, train with normalized vectors if the vectors to
+// quantize are normalized.
+type ProductQuantizer struct {
+	Dimension  int
+	SubVectors int
+	// Centroids[m] are the centroids of the m-th sub-vectors, stored
+	// contiguously, at most 256 of them
+	Centroids [][]float32
+}
+
+type ProductQuantizerConfig struct {
+	// Number of sub-vectors, the dimension must be a multiple of it
+	SubVectors int
+	// Number of centroids of each sub-vector, at most and default 256
+	Centroids int
+	// Maximum number of k-means iterations, default is 20
+	Iterations int
+	// Seed of the initial centroids, zero means 1
+	Seed int64
+}
+
+// TrainProductQuantizer learns the centroids from the vectors. There should
+// be more training vectors than centroids, usually tens of times.
+func TrainProductQuantizer(vectors [][]float32, config ProductQuantizerConfig) (*ProductQuantizer, error) {
+	if len(vectors) == 0 || len(vectors[0]) == 0 {
+		return nil, errZeroDimension
+	}
+	var dimension = len(vectors[0])
+	for _, v := range vectors {
+		if len(v) != dimension {
+			return nil, errDimensionMismatch
+		}
+	}
+	if config.SubVectors <= 0 || dimension%config.SubVectors != 0 {
+		return nil, errors.New("dimension is not a multiple of sub-vectors")
+	}
+	if config.Centroids <= 0 || config.Centroids > productDefaultCentroids {
+		config.Centroids = productDefaultCentroids
+	}
+	if config.Iterations <= 0 {
+		config.Iterations = productDefaultIterations
+	}
+	if config.Seed == 0 {
+		config.Seed = 1
+	}
+
+	var q = &ProductQuantizer{
+		Dimension:  dimension,
+		SubVectors: config.SubVectors,
+		Centroids:  make([][]float32, config.SubVectors),
+	}
+	var random = rand.New(rand.NewSource(config.Seed))
+	var subDimension = dimension / config.SubVectors
+	var points = make([]float32, len(vectors)*subDimension)
+	for m := range q.Centroids {
+		for i, v := range vectors {
+			copy(points[i*subDimension:], v[m*subDimension:(m+1)*subDimension])
+		}
+		q.Centroids[m] = kmeans(points, subDimension, min(config.Centroids, len(vectors)), config.Iterations, random)
+	}
+	return q, nil
+}
+
+// kmeans clusters the points stored contiguously, and returns k centroids.
+func kmeans(points []float32, dimension int, k int, iterations int, random *rand.Rand) []float32 {
+	var n = len(points) / dimension
+	var centroids = make([]float32, k*dimension)
+	for c, i := range random.Perm(n)[:k] {
+		copy(centroids[c*dimension:], points[i*dimension:(i+1)*dimension])
+	}
+
+	var assignments = make([]int, n)
+	var counts = make([]int, k)
+	var sums = make([]float64, k*dimension)
+	for i := range assignments {
+		assignments[i] = -1
+	}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		var changed = 0
+		for i := range assignments {
+			var c = nearestCentroid(points[i*dimension:(i+1)*dimension], centroids, dimension)
+			if c != assignments[i] {
+				assignments[i] = c
+				changed++
+			}
+		}
+		if changed == 0 {
+			break
+		}
+
+		clear(counts)
+		clear(sums)
+		for i, c := range assignments {
+			counts[c]++
+			for j, f := range points[i*dimension : (i+1)*dimension] {
+				sums[c*dimension+j] += float64(f)
+			}
+		}
+		for c := 0; c < k; c++ {
+			var centroid = centroids[c*dimension : (c+1)*dimension]
+			if counts[c] == 0 {
+				// Move an empty cluster to a random point
+				var i = random.Intn(n)
+				copy(centroid, points[i*dimension:(i+1)*dimension])
+				continue
+			}
+			for j := range centroid {
+				centroid[j] = float32(sums[c*dimension+j] / float64(counts[c]))
+			}
+		}
+	}
+	return centroids
+}
+
+func nearestCentroid(v []float32, centroids []float32, dimension int) int {
+	var nearest = 0
+	var nearestDistance = float32(math.Inf(1))
+	for c := 0; c*dimension < len(centroids); c++ {
+		var d = squaredL2(v, centroids[c*dimension:(c+1)*dimension])
+		if d < nearestDistance {
+			nearest, nearestDistance = c, d
+		}
+	}
+	return nearest
+}
+
+func (q *ProductQuantizer) subDimension() int {
+	return q.Dimension / q.SubVectors
+}
+
+func (q *ProductQuantizer) check(v []float32, code []uint8) error {
+	if q.SubVectors == 0 || len(q.Centroids) != q.SubVectors {
+		return errNotTrained
+	}
+	if len(v) != q.Dimension || len(code) != q.SubVectors {
+		return errDimensionMismatch
+	}
+	return nil
+}
+
+func (q *ProductQuantizer) Quantize(v []float32, code []uint8) error {
+	var err = q.check(v, code)
+	if err != nil {
+		return err
+	}
+	var d = q.subDimension()
+	for m := range code {
+		code[m] = uint8(nearestCentroid(v[m*d:(m+1)*d], q.Centroids[m], d))
+	}
+	return nil
+}
+
+func (q *ProductQuantizer) Dequantize(code []uint8, v []float32) error {
+	var err = q.check(v, code)
+	if err != nil {
+		return err
+	}
+	var d = q.subDimension()
+	for m, c := range code {
+		if (int(c)+1)*d > len(q.Centroids[m]) {
+			return errors.New("invalid product quantized code")
+		}
+		copy(v[m*d:(m+1)*d], q.Centroids[m][int(c)*d:(int(c)+1)*d])
+	}
+	return nil
+}
+
+// productTable holds the distances of the sub-vectors of a query to the
+// centroids, so a code is scored by a lookup per sub-vector. For cosine
+// distance, it holds the dot products and the squared norms of the
+// centroids.
+type productTable struct {
+	distanceType VectorDistanceType
+	queryNorm    float32
+	values       [][]float32 // squared distances or dot products
+	norms        [][]float32 // squared norms of the centroids
+}
+
+func (q *ProductQuantizer) table(query []float32, distanceType VectorDistanceType) (*productTable, error) {
+	if q.SubVectors == 0 || len(q.Centroids) != q.SubVectors {
+		return nil, errNotTrained
+	}
+	if len(query) != q.Dimension {
+		return nil, errDimensionMismatch
+	}
+
+	var t = &productTable{distanceType: distanceType, values: make([][]float32, q.SubVectors)}
+	if distanceType != EuclideanDistance {
+		t.queryNorm = dot(query, query)
+		if t.queryNorm == 0 {
+			return nil, errZeroVector
+		}
+		t.norms = make([][]float32, q.SubVectors)
+	}
+
+	var d = q.subDimension()
+	for m, centroids := range q.Centroids {
+		var sub = query[m*d : (m+1)*d]
+		var k = len(centroids) / d
+		t.values[m] = make([]float32, k)
+		if distanceType == EuclideanDistance {
+			for c := 0; c < k; c++ {
+				t.values[m][c] = squaredL2(sub, centroids[c*d:(c+1)*d])
+			}
+			continue
+		}
+		t.norms[m] = make([]float32, k)
+		for c := 0; c < k; c++ {
+			var centroid = centroids[c*d : (c+1)*d]
+			t.values[m][c] = dot(sub, centroid)
+			t.norms[m][c] = dot(centroid, centroid)
+		}
+	}
+	return t, nil
+}
+
+func (t *productTable) score(code []uint8) float32 {
+	if t.distanceType == EuclideanDistance {
+		var sum float32
+		for m, c := range code {
+			sum += t.values[m][c]
+		}
+		return float32(math.Sqrt(float64(sum)))
+	}
+	var dotSum, norm float32
+	for m, c := range code {
+		dotSum += t.values[m][c]
+		norm += t.norms[m][c]
+	}
+	if norm == 0 {
+		return 0
+	}
+	return cosine(dotSum, t.queryNorm, norm)
+}
+
+// Distance returns the cosine similarity or the euclidean distance of the
+// query and the dequantized code. Search is faster for many codes.
+func (q *ProductQuantizer) Distance(query []float32, code []uint8, distanceType VectorDistanceType) (float32, error) {
+	var v = make([]float32, q.Dimension)
+	var err = q.Dequantize(code, v)
+	if err != nil {
+		return 0, err
+	}
+	if distanceType == EuclideanDistance {
+		return VectorEuclideanDistance(query, v)
+	}
+	return VectorCosineSimilarity(query, v)
+}
+
+// Search returns the k codes nearest to the query, most similar first. Codes
+// are stored contiguously, len(codes) is a multiple of SubVectors.
+func (q *ProductQuantizer) Search(query []float32, codes []uint8, k int, distanceType VectorDistanceType) ([]VectorMatch, error) {
+	var t, err = q.table(query, distanceType)
+	if err != nil {
+		return nil, err
+	}
+	if len(codes)%q.SubVectors != 0 {
+		return nil, errDimensionMismatch
+	}
+
+	var scores = make([]float32, len(codes)/q.SubVectors)
+	for i := range scores {
+		var code = codes[i*q.SubVectors : (i+1)*q.SubVectors]
+		for m, c := range code {
+			if int(c) >= len(t.values[m]) {
+				return nil, errors.New("invalid product quantized code")
+			}
+		}
+		scores[i] = t.score(code)
+	}
+	return VectorTopK(scores, k, distanceType != EuclideanDistance), nil
+}
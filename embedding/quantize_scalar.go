@@ -0,0 +1,153 @@
+package embedding
+
+import (
+	"errors"
+	"math"
+)
+
+// Quantized vectors take less memory than []float32 at the cost of
+// precision, e.g. a vector of text-embedding-3-large takes 12 KiB, 3 KiB as
+// int8, 384 bytes as bits and 96 bytes as a product quantized code of 96
+// sub-vectors. Quantized searches return more candidates than needed, and
+// VectorRescore picks the best of them by the full precision vectors.
+//
+// Scores of quantized searches are cosine similarities or euclidean
+// distances of the query and the dequantized vectors, comparable with
+// VectorCosineSimilarity and VectorEuclideanDistance.
+
+var errNotTrained = errors.New("quantizer is not trained")
+
+// ScalarQuantizer quantizes each dimension to an int8 linearly, from the
+// range of the dimension in the training vectors to [-127, 127]:
+//
+//	value = Offset[i] + Scale[i] * code
+type ScalarQuantizer struct {
+	Offset []float32
+	Scale  []float32
+}
+
+// TrainScalarQuantizer learns the ranges of the dimensions from the vectors.
+// Values out of the ranges are clamped when quantized.
+func TrainScalarQuantizer(vectors [][]float32) (*ScalarQuantizer, error) {
+	if len(vectors) == 0 || len(vectors[0]) == 0 {
+		return nil, errZeroDimension
+	}
+
+	var dimension = len(vectors[0])
+	var lower = make([]float32, dimension)
+	var upper = make([]float32, dimension)
+	copy(lower, vectors[0])
+	copy(upper, vectors[0])
+	for _, v := range vectors[1:] {
+		if len(v) != dimension {
+			return nil, errDimensionMismatch
+		}
+		for i, f := range v {
+			lower[i] = min(lower[i], f)
+			upper[i] = max(upper[i], f)
+		}
+	}
+
+	var q = &ScalarQuantizer{Offset: make([]float32, dimension), Scale: make([]float32, dimension)}
+	for i := range q.Offset {
+		q.Offset[i] = (lower[i] + upper[i]) / 2
+		q.Scale[i] = (upper[i] - lower[i]) / 254
+	}
+	return q, nil
+}
+
+func (q *ScalarQuantizer) Dimension() int {
+	return len(q.Offset)
+}
+
+func (q *ScalarQuantizer) check(v []float32, code []int8) error {
+	if len(q.Offset) == 0 || len(q.Scale) != len(q.Offset) {
+		return errNotTrained
+	}
+	if len(v) != len(q.Offset) || len(code) != len(q.Offset) {
+		return errDimensionMismatch
+	}
+	return nil
+}
+
+func (q *ScalarQuantizer) Quantize(v []float32, code []int8) error {
+	var err = q.check(v, code)
+	if err != nil {
+		return err
+	}
+	for i, f := range v {
+		if q.Scale[i] == 0 {
+			code[i] = 0
+			continue
+		}
+		var c = math.Round(float64((f - q.Offset[i]) / q.Scale[i]))
+		code[i] = int8(max(-127, min(127, c)))
+	}
+	return nil
+}
+
+func (q *ScalarQuantizer) Dequantize(code []int8, v []float32) error {
+	var err = q.check(v, code)
+	if err != nil {
+		return err
+	}
+	for i, c := range code {
+		v[i] = q.Offset[i] + q.Scale[i]*float32(c)
+	}
+	return nil
+}
+
+// Distance returns the cosine similarity or the euclidean distance of the
+// query and the dequantized code.
+func (q *ScalarQuantizer) Distance(query []float32, code []int8, distanceType VectorDistanceType) (float32, error) {
+	var v = make([]float32, len(code))
+	var err = q.Dequantize(code, v)
+	if err != nil {
+		return 0, err
+	}
+	if distanceType == EuclideanDistance {
+		return VectorEuclideanDistance(query, v)
+	}
+	return VectorCosineSimilarity(query, v)
+}
+
+// Search returns the k codes nearest to the query, most similar first. Codes
+// are stored contiguously, len(codes) is a multiple of the dimension.
+func (q *ScalarQuantizer) Search(query []float32, codes []int8, k int, distanceType VectorDistanceType) ([]VectorMatch, error) {
+	var dimension = len(q.Offset)
+	if dimension == 0 || len(q.Scale) != dimension {
+		return nil, errNotTrained
+	}
+	if len(query) != dimension || len(codes)%dimension != 0 {
+		return nil, errDimensionMismatch
+	}
+
+	var queryNorm = dot(query, query)
+	if distanceType != EuclideanDistance && queryNorm == 0 {
+		return nil, errZeroVector
+	}
+
+	var v = make([]float32, dimension)
+	var scores = make([]float32, len(codes)/dimension)
+	for i := range scores {
+		var code = codes[i*dimension : (i+1)*dimension]
+		for j, c := range code {
+			v[j] = q.Offset[j] + q.Scale[j]*float32(c)
+		}
+		scores[i] = scoreVector(query, queryNorm, v, distanceType)
+	}
+	return VectorTopK(scores, k, distanceType != EuclideanDistance), nil
+}
+
+// scoreVector returns the cosine similarity, 0 if v is a zero vector, or the
+// euclidean distance of the query and v.
+func scoreVector(query []float32, queryNorm float32, v []float32, distanceType VectorDistanceType) float32 {
+	if distanceType == EuclideanDistance {
+		return float32(math.Sqrt(float64(squaredL2(query, v))))
+	}
+	var norm = dot(v, v)
+	if norm == 0 {
+		return 0
+	}
+	return cosine(dot(query, v), queryNorm, norm)
+}
@@ -1,5 +1,33 @@
 package embedding
 
+// InputType is the purpose of the documents to embed. Retrieval models embed
+// queries and the searched documents differently, the embeddings of a query
+// are closer to the documents answer it.
+type InputType string
+
+const (
+	// InputQuery is a search query
+	InputQuery InputType = "query"
+	// InputDocument is a document to be searched
+	InputDocument InputType = "document"
+)
+
 type ModelRequest struct {
+	// A single document to embed. Either Document or Documents is set.
 	Document string
+	// Documents to embed in one call. They are split into batches by the
+	// vendor limits, the embeddings are in the order of the documents.
+	Documents []string
+	// Number of dimensions of the embeddings, zero means the model default.
+	// Supported by OpenAI text-embedding-3 and later models, Titan Text
+	// Embeddings v2, DashScope text-embedding-v3, Gemini, Voyage and Jina.
+	Dimensions int
+	// Receive the embeddings base64 encoded, the response is about 1/4 of
+	// the size of float arrays. Only for OpenAI, ignored by others.
+	Base64 bool
+	// Purpose of the documents, empty means the vendor default. Supported by
+	// Cohere, DashScope, Gemini, Voyage and Jina, ignored by others. Embed
+	// the queries and the documents of a search with the same model and
+	// matching input types.
+	InputType InputType
 }
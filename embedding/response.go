@@ -1,6 +1,19 @@
 package embedding
 
+import "github.com/Pooh-Mucho/go-aigc"
+
 type ModelResponse struct {
+	// Embedding of the first document, the same as Embeddings[0]
 	Embedding []float32
-	Tokens    int
+	// Embeddings in the order of the documents
+	Embeddings [][]float32
+	// Total tokens of the documents
+	Tokens int
+	// Estimated tokens of each document. Vendors report the tokens of a
+	// batch, which are distributed to the documents by their estimated
+	// tokens, the values are exact only for single document batches. Nil if
+	// the vendor does not report the tokens, e.g. Gemini and Cohere.
+	EstimatedDocumentTokens []int
+	// Set by response cache wrappers, see package cache
+	Cache aigc.CacheMetadata
 }
@@ -0,0 +1,193 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeVector returns the embedding of a document, its length and its first
+// byte.
+func fakeVector(document string) []float32 {
+	return []float32{float32(len(document)), float32(document[0])}
+}
+
+func encodeVector(vector []float32) string {
+	var data = make([]byte, len(vector)*4)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// newOpenAIEmbeddingServer returns a server embeds the inputs by fakeVector,
+// the data is replied in the reverse order to check the index.
+func newOpenAIEmbeddingServer(t *testing.T, requests *[]map[string]any) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		*requests = append(*requests, body)
+
+		var data []map[string]any
+		var inputs = body["input"].([]any)
+		for i := len(inputs) - 1; i >= 0; i-- {
+			var vector = fakeVector(inputs[i].(string))
+			var item = map[string]any{"object": "embedding", "index": i, "embedding": vector}
+			if body["encoding_format"] == "base64" {
+				item["embedding"] = encodeVector(vector)
+			}
+			data = append(data, item)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data":   data,
+			"model":  body["model"],
+			"usage":  map[string]any{"prompt_tokens": 10 * len(inputs), "total_tokens": 10 * len(inputs)},
+		})
+	}))
+}
+
+func Test_OpenAI_Embedding_Batch(t *testing.T) {
+	var requests []map[string]any
+	var server = newOpenAIEmbeddingServer(t, &requests)
+	defer server.Close()
+
+	var model, err = embedding.NewModel(embedding.Models.OpenAITextEmbedding3Small,
+		aigc.WithEndpoint(server.URL),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2048 inputs per request at most
+	var documents = make([]string, 2050)
+	for i := range documents {
+		documents[i] = strings.Repeat("x", i%7+1)
+	}
+	response, err := model.Embedding(context.Background(), &embedding.ModelRequest{
+		Documents:  documents,
+		Dimensions: 256,
+		Base64:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(requests[0]["input"].([]any)) != 2048 || len(requests[1]["input"].([]any)) != 2 {
+		t.Fatalf("unexpected batches: %d", len(requests))
+	}
+	if requests[0]["dimensions"] != float64(256) || requests[0]["encoding_format"] != "base64" {
+		t.Errorf("unexpected request: dimensions %v, encoding_format %v",
+			requests[0]["dimensions"], requests[0]["encoding_format"])
+	}
+	if len(response.Embeddings) != len(documents) {
+		t.Fatalf("unexpected embeddings: %d", len(response.Embeddings))
+	}
+	for i, document := range documents {
+		if response.Embeddings[i][0] != float32(len(document)) {
+			t.Fatalf("embedding %d is out of order: %v", i, response.Embeddings[i])
+		}
+	}
+	if response.Tokens != 10*len(documents) || len(response.EstimatedDocumentTokens) != len(documents) {
+		t.Errorf("unexpected tokens: %d, %d", response.Tokens, len(response.EstimatedDocumentTokens))
+	}
+	var sum = 0
+	for _, n := range response.EstimatedDocumentTokens {
+		sum += n
+	}
+	// The tokens of the last batch are distributed to its 2 documents
+	if sum != response.Tokens || response.EstimatedDocumentTokens[2048]+response.EstimatedDocumentTokens[2049] != 20 ||
+		response.EstimatedDocumentTokens[2048] >= response.EstimatedDocumentTokens[2049] {
+		t.Errorf("unexpected document tokens: sum %d, last batch %v", sum, response.EstimatedDocumentTokens[2048:])
+	}
+
+	// Single document keeps the old behavior
+	requests = nil
+	response, err = model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Embedding) != 2 || response.Embedding[0] != 5 || response.Tokens != 10 ||
+		requests[0]["encoding_format"] != nil || requests[0]["dimensions"] != nil {
+		t.Errorf("unexpected response: %+v, request: %v", response, requests[0])
+	}
+}
+
+func Test_OpenAI_Embedding_Dimensions_Unsupported(t *testing.T) {
+	var model, err = embedding.NewModel(embedding.Models.OpenAITextEmbeddingAda_002,
+		aigc.WithEndpoint("http://127.0.0.1:1"),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama", Dimensions: 256})
+	if err == nil || !strings.Contains(err.Error(), "dimensions") {
+		t.Errorf("expected dimensions error, got %v", err)
+	}
+	_, err = model.Embedding(context.Background(), &embedding.ModelRequest{Documents: []string{"llama", ""}})
+	if err == nil {
+		t.Error("empty document is sent")
+	}
+}
+
+// newOllamaEmbeddingServer returns a server embeds the inputs by fakeVector.
+func newOllamaEmbeddingServer(t *testing.T, inputs *[][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		*inputs = append(*inputs, body.Input)
+		var embeddings [][]float32
+		for _, input := range body.Input {
+			embeddings = append(embeddings, fakeVector(input))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"model":             body.Model,
+			"embeddings":        embeddings,
+			"prompt_eval_count": 4 * len(body.Input),
+		})
+	}))
+}
+
+func Test_Ollama_Embedding_Batch(t *testing.T) {
+	var inputs [][]string
+	var server = newOllamaEmbeddingServer(t, &inputs)
+	defer server.Close()
+
+	var model, err = embedding.NewModel(embedding.Models.NomicEmbedText,
+		aigc.WithVendor(aigc.Vendors.Ollama),
+		aigc.WithEndpoint(server.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Embedding(context.Background(), &embedding.ModelRequest{Documents: LlamaDocuments})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 1 || len(inputs[0]) != len(LlamaDocuments) {
+		t.Errorf("unexpected requests: %v", inputs)
+	}
+	if len(response.Embeddings) != len(LlamaDocuments) || response.Tokens != 4*len(LlamaDocuments) ||
+		response.Embeddings[3][0] != float32(len(LlamaDocuments[3])) {
+		t.Errorf("unexpected response: %d embeddings, %d tokens", len(response.Embeddings), response.Tokens)
+	}
+}
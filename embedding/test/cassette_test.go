@@ -0,0 +1,89 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/cassette"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// CassetteDir contains the recorded http traffic of the tests, see
+// cassette.ForTest
+const CassetteDir = "testdata/cassettes"
+
+// Cassettes reports whether the vendor tests go through cassettes, selected
+// by the AIGC_CASSETTE environment variable. By default the vendor tests talk
+// to fake vendor servers.
+func Cassettes() bool {
+	return os.Getenv(cassette.EnvMode) != ""
+}
+
+// WithCassette records or replays the http traffic of the test. Replaying
+// does not need credentials, placeholders are set for the missing ones.
+func WithCassette(t *testing.T) aigc.ModelOptionFunc {
+	var recorder = cassette.ForTest(t, CassetteDir)
+	return func(options *aigc.ModelOptions) {
+		options.HttpTransport = recorder
+		if recorder.Mode != cassette.ModeReplay {
+			return
+		}
+		if options.ApiKey == "" {
+			options.ApiKey = "replay"
+		}
+		if options.AccessKey == "" {
+			options.AccessKey = "replay"
+		}
+		if options.SecretKey == "" {
+			options.SecretKey = "replay"
+		}
+		if options.Endpoint == "" {
+			switch options.VendorId {
+			case aigc.Vendors.Microsoft:
+				options.Endpoint = "https://replay.openai.azure.com"
+			case aigc.Vendors.Ollama:
+				options.Endpoint = "http://localhost:11434"
+			}
+		}
+	}
+}
+
+// WithFakeVendor sends the requests of the test to a fake embedding server of
+// the vendor, which embeds the documents by fakeVector.
+func WithFakeVendor(t *testing.T) aigc.ModelOptionFunc {
+	var server *httptest.Server
+	var requests []map[string]any
+	var inputs [][]string
+	return func(options *aigc.ModelOptions) {
+		if server == nil {
+			switch options.VendorId {
+			case aigc.Vendors.OpenAI, aigc.Vendors.Microsoft:
+				server = newOpenAIEmbeddingServer(t, &requests)
+			case aigc.Vendors.Ollama:
+				server = newOllamaEmbeddingServer(t, &inputs)
+			default:
+				t.Fatalf("no fake server of vendor '%s'", options.VendorId)
+			}
+			t.Cleanup(server.Close)
+		}
+
+		switch options.VendorId {
+		case aigc.Vendors.OpenAI:
+			options.Endpoint = server.URL + "/v1/embeddings"
+		default:
+			options.Endpoint = server.URL
+		}
+		options.ApiKey = "fake"
+	}
+}
+
+// NewModel creates a model of the test. The model talks to the vendor through
+// the cassette of the test if Cassettes is true, otherwise to a fake vendor
+// server.
+func NewModel(t *testing.T, modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (embedding.Model, error) {
+	if Cassettes() {
+		return embedding.NewModel(modelId, append(options, WithCassette(t))...)
+	}
+	return embedding.NewModel(modelId, append(options, WithFakeVendor(t))...)
+}
@@ -42,7 +42,7 @@ var Tests = struct {
 	EmbeddingLlamaQuery     func(t *testing.T, modelId aigc.ModelId, query string, options ...aigc.ModelOptionFunc)
 }{
 	EmbeddingLlamaDocuments: func(t *testing.T, modelId aigc.ModelId, options ...aigc.ModelOptionFunc) {
-		model, err := embedding.NewModel(modelId, options...)
+		model, err := NewModel(t, modelId, options...)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -63,7 +63,7 @@ var Tests = struct {
 	},
 
 	EmbeddingLlamaQuery: func(t *testing.T, modelId aigc.ModelId, query string, options ...aigc.ModelOptionFunc) {
-		model, err := embedding.NewModel(modelId, options...)
+		model, err := NewModel(t, modelId, options...)
 		if err != nil {
 			t.Fatal(err)
 		}
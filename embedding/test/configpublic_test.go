@@ -3,6 +3,8 @@
 
 package test
 
+import "github.com/Pooh-Mucho/go-aigc"
+
 var WithAWS = func(options *aigc.ModelOptions) {
 	options.VendorId = aigc.Vendors.Amazon
 	options.AccessKey = ""
@@ -0,0 +1,240 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// quantizeData returns clustered vectors stored contiguously, and queries
+// near them.
+func quantizeData(seed int64, n, dim int) (matrix []float32, queries [][]float32) {
+	var r = rand.New(rand.NewSource(seed))
+	var centroids = make([][]float32, 20)
+	for i := range centroids {
+		centroids[i] = randomVector(r, dim)
+	}
+	var vector = func() []float32 {
+		var centroid = centroids[r.Intn(len(centroids))]
+		var v = make([]float32, dim)
+		for j := range v {
+			v[j] = centroid[j] + float32(r.NormFloat64())*0.2
+		}
+		return v
+	}
+	for i := 0; i < n; i++ {
+		matrix = append(matrix, vector()...)
+	}
+	for i := 0; i < 20; i++ {
+		queries = append(queries, vector())
+	}
+	return matrix, queries
+}
+
+func rows(matrix []float32, dim int) [][]float32 {
+	var result [][]float32
+	for i := 0; i+dim <= len(matrix); i += dim {
+		result = append(result, matrix[i:i+dim])
+	}
+	return result
+}
+
+// rescoredRecall returns the fraction of the exact top 10 found by rescoring
+// the top 100 candidates of the search.
+func rescoredRecall(t *testing.T, matrix []float32, queries [][]float32, distanceType embedding.VectorDistanceType,
+	search func(query []float32) ([]embedding.VectorMatch, error)) float64 {
+
+	var found, total int
+	for _, query := range queries {
+		exact, _ := embedding.VectorSearch(query, matrix, 10, distanceType)
+		candidates, err := search(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rescored, err := embedding.VectorRescore(query, matrix, candidates, 10, distanceType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var indexes = make(map[int]bool)
+		for _, m := range rescored {
+			indexes[m.Index] = true
+		}
+		for _, m := range exact {
+			if indexes[m.Index] {
+				found++
+			}
+		}
+		total += len(exact)
+	}
+	return float64(found) / float64(total)
+}
+
+func Test_Quantize_Scalar(t *testing.T) {
+	const dim = 64
+	var matrix, queries = quantizeData(1, 2000, dim)
+	var q, err = embedding.TrainScalarQuantizer(rows(matrix, dim))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes = make([]int8, len(matrix))
+	for i, row := range rows(matrix, dim) {
+		if err = q.Quantize(row, codes[i*dim:(i+1)*dim]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Quantized distances are close to the full precision distances
+	var query = queries[0]
+	for i := 0; i < 10; i++ {
+		var code = codes[i*dim : (i+1)*dim]
+		var quantized, _ = q.Distance(query, code, embedding.CosineDistance)
+		var exact, _ = embedding.VectorCosineSimilarity(query, matrix[i*dim:(i+1)*dim])
+		if math.Abs(float64(quantized-exact)) > 0.01 {
+			t.Errorf("row %d: quantized cosine %f, exact %f", i, quantized, exact)
+		}
+	}
+
+	for _, distanceType := range []embedding.VectorDistanceType{embedding.CosineDistance, embedding.EuclideanDistance} {
+		var r = rescoredRecall(t, matrix, queries, distanceType, func(query []float32) ([]embedding.VectorMatch, error) {
+			return q.Search(query, codes, 100, distanceType)
+		})
+		if r < 0.95 {
+			t.Errorf("%s: rescored recall of int8 is %.3f", distanceType, r)
+		}
+	}
+
+	// Values out of the training ranges are clamped
+	var outlier = make([]float32, dim)
+	var code = make([]int8, dim)
+	for i := range outlier {
+		outlier[i] = 100
+	}
+	_ = q.Quantize(outlier, code)
+	if code[0] != 127 {
+		t.Errorf("outlier is not clamped: %d", code[0])
+	}
+}
+
+func Test_Quantize_Binary(t *testing.T) {
+	const dim = 100
+	var v = make([]float32, dim)
+	var opposite = make([]float32, dim)
+	for i := range v {
+		v[i] = float32(i%3) - 1
+		opposite[i] = -v[i]
+		if v[i] == 0 {
+			v[i], opposite[i] = 0.5, -0.5
+		}
+	}
+
+	var words = embedding.VectorBinaryWords(dim)
+	var code1, code2 = make([]uint64, words), make([]uint64, words)
+	_ = embedding.VectorBinaryQuantize(v, code1)
+	_ = embedding.VectorBinaryQuantize(opposite, code2)
+	if d, _ := embedding.VectorHammingDistance(code1, code2); d != dim {
+		t.Errorf("hamming distance of opposite vectors is %d", d)
+	}
+	if s, _ := embedding.VectorBinaryCosineSimilarity(code1, code1, dim); s != 1 {
+		t.Errorf("binary cosine of identical vectors is %f", s)
+	}
+	if s, _ := embedding.VectorBinaryCosineSimilarity(code1, code2, dim); s != -1 {
+		t.Errorf("binary cosine of opposite vectors is %f", s)
+	}
+	if err := embedding.VectorBinaryQuantize(v, code1[:1]); err == nil {
+		t.Error("short code is accepted")
+	}
+
+	const searchDim = 256
+	var matrix, queries = quantizeData(2, 2000, searchDim)
+	// Center the data, binary quantization keeps the signs
+	var mean = make([]float64, searchDim)
+	for i, f := range matrix {
+		mean[i%searchDim] += float64(f) / 2000
+	}
+	var center = func(v []float32) []float32 {
+		var result = make([]float32, len(v))
+		for i, f := range v {
+			result[i] = f - float32(mean[i%searchDim])
+		}
+		return result
+	}
+	matrix = center(matrix)
+	for i := range queries {
+		queries[i] = center(queries[i])
+	}
+
+	words = embedding.VectorBinaryWords(searchDim)
+	var codes = make([]uint64, 2000*words)
+	for i, row := range rows(matrix, searchDim) {
+		_ = embedding.VectorBinaryQuantize(row, codes[i*words:(i+1)*words])
+	}
+	var r = rescoredRecall(t, matrix, queries, embedding.CosineDistance, func(query []float32) ([]embedding.VectorMatch, error) {
+		var code = make([]uint64, words)
+		_ = embedding.VectorBinaryQuantize(query, code)
+		return embedding.VectorBinarySearch(code, codes, 100)
+	})
+	if r < 0.8 {
+		t.Errorf("rescored recall of binary is %.3f", r)
+	}
+}
+
+func Test_Quantize_Product(t *testing.T) {
+	const dim = 64
+	var matrix, queries = quantizeData(3, 2000, dim)
+	var q, err = embedding.TrainProductQuantizer(rows(matrix, dim), embedding.ProductQuantizerConfig{
+		SubVectors: 16,
+		Centroids:  64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = embedding.TrainProductQuantizer(rows(matrix, dim), embedding.ProductQuantizerConfig{SubVectors: 7}); err == nil {
+		t.Error("sub-vectors not dividing the dimension are accepted")
+	}
+
+	var codes = make([]uint8, 2000*q.SubVectors)
+	for i, row := range rows(matrix, dim) {
+		if err = q.Quantize(row, codes[i*q.SubVectors:(i+1)*q.SubVectors]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, distanceType := range []embedding.VectorDistanceType{embedding.CosineDistance, embedding.EuclideanDistance} {
+		// Scores of the lookup tables are the distances of the dequantized vectors
+		var matches, err = q.Search(queries[0], codes, 5, distanceType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range matches {
+			var code = codes[match.Index*q.SubVectors : (match.Index+1)*q.SubVectors]
+			var want, _ = q.Distance(queries[0], code, distanceType)
+			if math.Abs(float64(match.Score-want)) > 1e-4*math.Max(1, math.Abs(float64(want))) {
+				t.Errorf("%s: search score %f, distance %f", distanceType, match.Score, want)
+			}
+		}
+
+		var r = rescoredRecall(t, matrix, queries, distanceType, func(query []float32) ([]embedding.VectorMatch, error) {
+			return q.Search(query, codes, 100, distanceType)
+		})
+		if r < 0.9 {
+			t.Errorf("%s: rescored recall of product quantization is %.3f", distanceType, r)
+		}
+	}
+}
+
+func Test_Quantize_Rescore(t *testing.T) {
+	var matrix = []float32{1, 0, 0, 1, 1, 1}
+	var candidates = []embedding.VectorMatch{{Index: 1}, {Index: 2}}
+	var matches, err = embedding.VectorRescore([]float32{0, 2}, matrix, candidates, 1, embedding.CosineDistance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Index != 1 || matches[0].Score != 1 {
+		t.Errorf("unexpected matches: %+v", matches)
+	}
+	if _, err = embedding.VectorRescore([]float32{0, 2}, matrix, []embedding.VectorMatch{{Index: 3}}, 1, embedding.CosineDistance); err == nil {
+		t.Error("out of range candidate is accepted")
+	}
+}
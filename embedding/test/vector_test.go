@@ -0,0 +1,241 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomVector(r *rand.Rand, dim int) []float32 {
+	var v = make([]float32, dim)
+	for i := range v {
+		v[i] = r.Float32()*2 - 1
+	}
+	return v
+}
+
+// naiveDotProduct is the scalar loop before the kernels, the baseline of the
+// benchmarks.
+func naiveDotProduct(v1, v2 []float32) float32 {
+	var sum float32
+	v2 = v2[:len(v1)]
+	for i := 0; i < len(v1); i++ {
+		sum += v1[i] * v2[i]
+	}
+	return sum
+}
+
+func naiveCosineSimilarity(v1, v2 []float32) float32 {
+	var dot, norm1, norm2 float32
+	v2 = v2[:len(v1)]
+	for i := 0; i < len(v1); i++ {
+		dot += v1[i] * v2[i]
+		norm1 += v1[i] * v1[i]
+		norm2 += v2[i] * v2[i]
+	}
+	return dot / (float32(math.Sqrt(float64(norm1))) * float32(math.Sqrt(float64(norm2))))
+}
+
+func referenceDot(v1, v2 []float32) float64 {
+	var sum float64
+	for i := range v1 {
+		sum += float64(v1[i]) * float64(v2[i])
+	}
+	return sum
+}
+
+func referenceL2(v1, v2 []float32) float64 {
+	var sum float64
+	for i := range v1 {
+		var d = float64(v1[i]) - float64(v2[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func closeTo(got float32, want float64) bool {
+	return math.Abs(float64(got)-want) <= 1e-4*math.Max(1, math.Abs(want))
+}
+
+func Test_Vector_Kernels(t *testing.T) {
+	var r = rand.New(rand.NewSource(1))
+	// Lengths around the unrolling and the 8/32 lanes of the AVX2 kernels
+	var dims = []int{1, 3, 7, 8, 15, 16, 17, 31, 32, 33, 63, 100, 768, 1536, 3072}
+	for _, dim := range dims {
+		var v1, v2 = randomVector(r, dim), randomVector(r, dim)
+
+		var dot, _ = embedding.VectorDotProduct(v1, v2)
+		if !closeTo(dot, referenceDot(v1, v2)) {
+			t.Errorf("dim %d: dot %f, want %f", dim, dot, referenceDot(v1, v2))
+		}
+		var l2, _ = embedding.VectorEuclideanDistance(v1, v2)
+		if !closeTo(l2, referenceL2(v1, v2)) {
+			t.Errorf("dim %d: l2 %f, want %f", dim, l2, referenceL2(v1, v2))
+		}
+		var cosine, _ = embedding.VectorCosineSimilarity(v1, v2)
+		var want = referenceDot(v1, v2) / math.Sqrt(referenceDot(v1, v1)*referenceDot(v2, v2))
+		if !closeTo(cosine, want) {
+			t.Errorf("dim %d: cosine %f, want %f", dim, cosine, want)
+		}
+		var magnitude, _ = embedding.VectorMagnitude(v1)
+		if !closeTo(magnitude, math.Sqrt(referenceDot(v1, v1))) {
+			t.Errorf("dim %d: magnitude %f, want %f", dim, magnitude, math.Sqrt(referenceDot(v1, v1)))
+		}
+	}
+}
+
+func Test_Vector_Normalize(t *testing.T) {
+	var v = []float32{3, 4}
+	var magnitude, _ = embedding.VectorMagnitude(v)
+	if magnitude != 5 {
+		t.Errorf("magnitude of (3, 4) is %f", magnitude)
+	}
+	if err := embedding.VectorNormalizeInPlace(v); err != nil {
+		t.Fatal(err)
+	}
+	if !closeTo(v[0], 0.6) || !closeTo(v[1], 0.8) {
+		t.Errorf("unexpected normalized vector: %v", v)
+	}
+	if err := embedding.VectorNormalizeInPlace([]float32{0, 0}); err == nil {
+		t.Error("zero vector is normalized")
+	}
+}
+
+func Test_Vector_Search(t *testing.T) {
+	var r = rand.New(rand.NewSource(2))
+	const dim, rows = 64, 1000
+	var query = randomVector(r, dim)
+	var matrix = make([]float32, 0, dim*rows)
+	for i := 0; i < rows; i++ {
+		matrix = append(matrix, randomVector(r, dim)...)
+	}
+
+	var scores = make([]float32, rows)
+	if err := embedding.VectorBatchCosineSimilarity(query, matrix, scores); err != nil {
+		t.Fatal(err)
+	}
+	var indexes = make([]int, rows)
+	for i := range indexes {
+		indexes[i] = i
+		var want, _ = embedding.VectorCosineSimilarity(query, matrix[i*dim:(i+1)*dim])
+		if scores[i] != want {
+			t.Fatalf("row %d: score %f, want %f", i, scores[i], want)
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return scores[indexes[i]] > scores[indexes[j]] })
+
+	matches, err := embedding.VectorSearch(query, matrix, 10, embedding.CosineDistance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 10 {
+		t.Fatalf("unexpected matches: %d", len(matches))
+	}
+	for i, match := range matches {
+		if match.Index != indexes[i] {
+			t.Fatalf("match %d: index %d, want %d", i, match.Index, indexes[i])
+		}
+	}
+
+	// Euclidean search returns the nearest first
+	matches, err = embedding.VectorSearch(query, matrix, 3, embedding.EuclideanDistance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches[0].Score > matches[1].Score || matches[1].Score > matches[2].Score {
+		t.Errorf("unexpected euclidean matches: %+v", matches)
+	}
+
+	if err = embedding.VectorBatchDotProduct(query, matrix[:dim*2+1], scores[:2]); err == nil {
+		t.Error("ragged matrix is accepted")
+	}
+}
+
+func Test_Vector_TopK(t *testing.T) {
+	var scores = []float32{0.5, 0.9, 0.1, 0.9, 0.3}
+	var top = embedding.VectorTopK(scores, 3, true)
+	if len(top) != 3 || top[0].Index != 1 || top[1].Index != 3 || top[2].Index != 0 {
+		t.Errorf("unexpected largest: %+v", top)
+	}
+	var bottom = embedding.VectorTopK(scores, 2, false)
+	if len(bottom) != 2 || bottom[0].Index != 2 || bottom[1].Index != 4 {
+		t.Errorf("unexpected smallest: %+v", bottom)
+	}
+	if all := embedding.VectorTopK(scores, 10, true); len(all) != len(scores) {
+		t.Errorf("unexpected all: %+v", all)
+	}
+}
+
+var benchmarkSink float32
+
+func Benchmark_Vector_DotProduct_Naive_1536(b *testing.B) {
+	var r = rand.New(rand.NewSource(3))
+	var v1, v2 = randomVector(r, 1536), randomVector(r, 1536)
+	for i := 0; i < b.N; i++ {
+		benchmarkSink += naiveDotProduct(v1, v2)
+	}
+}
+
+func Benchmark_Vector_DotProduct_1536(b *testing.B) {
+	var r = rand.New(rand.NewSource(3))
+	var v1, v2 = randomVector(r, 1536), randomVector(r, 1536)
+	for i := 0; i < b.N; i++ {
+		var dot, _ = embedding.VectorDotProduct(v1, v2)
+		benchmarkSink += dot
+	}
+}
+
+func Benchmark_Vector_CosineSimilarity_Naive_1536(b *testing.B) {
+	var r = rand.New(rand.NewSource(3))
+	var v1, v2 = randomVector(r, 1536), randomVector(r, 1536)
+	for i := 0; i < b.N; i++ {
+		benchmarkSink += naiveCosineSimilarity(v1, v2)
+	}
+}
+
+func Benchmark_Vector_CosineSimilarity_1536(b *testing.B) {
+	var r = rand.New(rand.NewSource(3))
+	var v1, v2 = randomVector(r, 1536), randomVector(r, 1536)
+	for i := 0; i < b.N; i++ {
+		var cosine, _ = embedding.VectorCosineSimilarity(v1, v2)
+		benchmarkSink += cosine
+	}
+}
+
+func Benchmark_Vector_Search_Naive_10000x768(b *testing.B) {
+	var r = rand.New(rand.NewSource(4))
+	var query = randomVector(r, 768)
+	var rows = make([][]float32, 10000)
+	for i := range rows {
+		rows[i] = randomVector(r, 768)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var scores = make([]float32, len(rows))
+		for j, row := range rows {
+			scores[j] = naiveCosineSimilarity(query, row)
+		}
+		var indexes = make([]int, len(rows))
+		for j := range indexes {
+			indexes[j] = j
+		}
+		sort.Slice(indexes, func(x, y int) bool { return scores[indexes[x]] > scores[indexes[y]] })
+		benchmarkSink += scores[indexes[0]]
+	}
+}
+
+func Benchmark_Vector_Search_10000x768(b *testing.B) {
+	var r = rand.New(rand.NewSource(4))
+	var query = randomVector(r, 768)
+	var matrix = make([]float32, 0, 768*10000)
+	for i := 0; i < 10000; i++ {
+		matrix = append(matrix, randomVector(r, 768)...)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var matches, _ = embedding.VectorSearch(query, matrix, 10, embedding.CosineDistance)
+		benchmarkSink += matches[0].Score
+	}
+}
@@ -0,0 +1,313 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// vendorRequest is a request received by a fake vendor server.
+type vendorRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+// newVendorServer returns a server replies the requests by the reply
+// function, the requests are recorded.
+func newVendorServer(t *testing.T, requests *[]vendorRequest, reply func(body map[string]any) any) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		*requests = append(*requests, vendorRequest{Path: r.URL.EscapedPath(), Header: r.Header, Body: body})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reply(body))
+	}))
+}
+
+func stringList(values any) []string {
+	var result []string
+	for _, v := range values.([]any) {
+		result = append(result, v.(string))
+	}
+	return result
+}
+
+// checkEmbeddings checks the embeddings are fakeVector of the documents in
+// order, and the document tokens are reported unless the tokens are unknown.
+func checkEmbeddings(t *testing.T, response *embedding.ModelResponse, documents []string) {
+	t.Helper()
+	var tokens = len(documents)
+	if response.Tokens == 0 {
+		tokens = 0
+	}
+	if len(response.Embeddings) != len(documents) || len(response.EstimatedDocumentTokens) != tokens {
+		t.Fatalf("unexpected embeddings: %d, tokens %d", len(response.Embeddings), len(response.EstimatedDocumentTokens))
+	}
+	for i, document := range documents {
+		if response.Embeddings[i][0] != float32(len(document)) {
+			t.Fatalf("embedding %d is out of order: %v", i, response.Embeddings[i])
+		}
+	}
+	if &response.Embedding[0] != &response.Embeddings[0][0] {
+		t.Error("Embedding is not the first embedding")
+	}
+}
+
+func testDocuments(n int) []string {
+	var documents = make([]string, n)
+	for i := range documents {
+		documents[i] = strings.Repeat("x", i%7+1)
+	}
+	return documents
+}
+
+func Test_Hosted_Embedding(t *testing.T) {
+	var cases = []struct {
+		name          string
+		modelId       aigc.ModelId
+		batch         int
+		queryKey      string
+		queryValue    string
+		documentValue string
+		dimensionsKey string
+	}{
+		{"Voyage", embedding.Models.Voyage3Large, 128, "input_type", "query", "document", "output_dimension"},
+		{"Jina", embedding.Models.JinaEmbeddingsV3, 512, "task", "retrieval.query", "retrieval.passage", "dimensions"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests []vendorRequest
+			var server = newVendorServer(t, &requests, func(body map[string]any) any {
+				var data []map[string]any
+				var inputs = stringList(body["input"])
+				for i := len(inputs) - 1; i >= 0; i-- {
+					data = append(data, map[string]any{"object": "embedding", "index": i, "embedding": fakeVector(inputs[i])})
+				}
+				return map[string]any{"object": "list", "data": data, "usage": map[string]any{"total_tokens": len(inputs)}}
+			})
+			defer server.Close()
+
+			var model, err = embedding.NewModel(c.modelId, aigc.WithEndpoint(server.URL), aigc.WithApiKey("test-key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var documents = testDocuments(c.batch + 1)
+			response, err := model.Embedding(context.Background(), &embedding.ModelRequest{
+				Documents:  documents,
+				Dimensions: 256,
+				InputType:  embedding.InputDocument,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEmbeddings(t, response, documents)
+			if len(requests) != 2 || len(stringList(requests[0].Body["input"])) != c.batch || response.Tokens != len(documents) {
+				t.Fatalf("unexpected batches: %d, tokens %d", len(requests), response.Tokens)
+			}
+			var body = requests[0].Body
+			if body["model"] != string(c.modelId) || body[c.queryKey] != c.documentValue || body[c.dimensionsKey] != float64(256) ||
+				requests[0].Header.Get("Authorization") != "Bearer test-key" {
+				t.Errorf("unexpected request: %v", body)
+			}
+
+			requests = nil
+			_, err = model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama", InputType: embedding.InputQuery})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requests[0].Body[c.queryKey] != c.queryValue {
+				t.Errorf("unexpected query request: %v", requests[0].Body)
+			}
+
+			// The vendor default without the input type
+			requests = nil
+			model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama"})
+			if _, ok := requests[0].Body[c.queryKey]; ok {
+				t.Errorf("unexpected request: %v", requests[0].Body)
+			}
+		})
+	}
+}
+
+func Test_DashScope_Embedding(t *testing.T) {
+	var requests []vendorRequest
+	var server = newVendorServer(t, &requests, func(body map[string]any) any {
+		var embeddings []map[string]any
+		var texts = stringList(body["input"].(map[string]any)["texts"])
+		for i := len(texts) - 1; i >= 0; i-- {
+			embeddings = append(embeddings, map[string]any{"text_index": i, "embedding": fakeVector(texts[i])})
+		}
+		return map[string]any{
+			"output":     map[string]any{"embeddings": embeddings},
+			"usage":      map[string]any{"total_tokens": 2 * len(texts)},
+			"request_id": "1",
+		}
+	})
+	defer server.Close()
+
+	var model, err = embedding.NewModel(embedding.Models.DashScopeTextEmbeddingV3,
+		aigc.WithEndpoint(server.URL), aigc.WithApiKey("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var documents = testDocuments(12)
+	response, err := model.Embedding(context.Background(), &embedding.ModelRequest{
+		Documents:  documents,
+		Dimensions: 512,
+		InputType:  embedding.InputQuery,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEmbeddings(t, response, documents)
+	if len(requests) != 2 || response.Tokens != 24 {
+		t.Fatalf("unexpected batches: %d, tokens %d", len(requests), response.Tokens)
+	}
+	var parameters = requests[0].Body["parameters"].(map[string]any)
+	if requests[0].Body["model"] != "text-embedding-v3" || parameters["text_type"] != "query" ||
+		parameters["dimension"] != float64(512) || requests[0].Header.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("unexpected request: %v", requests[0].Body)
+	}
+}
+
+func Test_Gemini_Embedding(t *testing.T) {
+	var requests []vendorRequest
+	var server = newVendorServer(t, &requests, func(body map[string]any) any {
+		var embeddings []map[string]any
+		for _, r := range body["requests"].([]any) {
+			var text = r.(map[string]any)["content"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"]
+			embeddings = append(embeddings, map[string]any{"values": fakeVector(text.(string))})
+		}
+		return map[string]any{"embeddings": embeddings}
+	})
+	defer server.Close()
+
+	var model, err = embedding.NewModel(embedding.Models.GoogleTextEmbedding004,
+		aigc.WithEndpoint(server.URL), aigc.WithApiKey("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var documents = testDocuments(101)
+	response, err := model.Embedding(context.Background(), &embedding.ModelRequest{
+		Documents:  documents,
+		Dimensions: 256,
+		InputType:  embedding.InputDocument,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEmbeddings(t, response, documents)
+	if len(requests) != 2 || len(requests[0].Body["requests"].([]any)) != 100 {
+		t.Fatalf("unexpected batches: %d", len(requests))
+	}
+	var first = requests[0].Body["requests"].([]any)[0].(map[string]any)
+	if requests[0].Path != "/models/text-embedding-004:batchEmbedContents" || requests[0].Header.Get("X-Goog-Api-Key") != "test-key" ||
+		first["model"] != "models/text-embedding-004" || first["taskType"] != "RETRIEVAL_DOCUMENT" ||
+		first["outputDimensionality"] != float64(256) {
+		t.Errorf("unexpected request: %s %v", requests[0].Path, first)
+	}
+	// Gemini does not report the tokens
+	if response.Tokens != 0 || response.EstimatedDocumentTokens != nil {
+		t.Errorf("unexpected tokens: %d, %v", response.Tokens, response.EstimatedDocumentTokens)
+	}
+}
+
+func Test_Bedrock_Embedding(t *testing.T) {
+	var requests []vendorRequest
+	var server = newVendorServer(t, &requests, func(body map[string]any) any {
+		if text, ok := body["inputText"].(string); ok {
+			return map[string]any{"embedding": fakeVector(text), "inputTextTokenCount": 3}
+		}
+		var embeddings [][]float32
+		for _, text := range stringList(body["texts"]) {
+			embeddings = append(embeddings, fakeVector(text))
+		}
+		return map[string]any{"id": "1", "response_type": "embeddings_floats", "embeddings": embeddings}
+	})
+	defer server.Close()
+
+	var options = []aigc.ModelOptionFunc{
+		aigc.WithEndpoint(server.URL),
+		aigc.WithRegion("us-east-1"),
+		aigc.WithAccessKeySecretKey("test-access-key", "test-secret-key"),
+	}
+
+	t.Run("Titan", func(t *testing.T) {
+		requests = nil
+		var model, err = embedding.NewModel(embedding.Models.AmazonTitanEmbedTextV2, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var documents = testDocuments(3)
+		response, err := model.Embedding(context.Background(), &embedding.ModelRequest{Documents: documents, Dimensions: 512})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkEmbeddings(t, response, documents)
+		// One text per request
+		if len(requests) != 3 || response.Tokens != 9 || response.EstimatedDocumentTokens[1] != 3 {
+			t.Fatalf("unexpected requests: %d, tokens %d", len(requests), response.Tokens)
+		}
+		if requests[0].Path != "/model/amazon.titan-embed-text-v2%3A0/invoke" || requests[0].Body["dimensions"] != float64(512) ||
+			requests[0].Body["normalize"] != true || !strings.HasPrefix(requests[0].Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			t.Errorf("unexpected request: %s %v", requests[0].Path, requests[0].Body)
+		}
+	})
+
+	t.Run("Cohere", func(t *testing.T) {
+		requests = nil
+		var model, err = embedding.NewModel(embedding.Models.CohereEmbedMultilingualV3, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var documents = testDocuments(100)
+		response, err := model.Embedding(context.Background(), &embedding.ModelRequest{Documents: documents})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkEmbeddings(t, response, documents)
+		if len(requests) != 2 || len(stringList(requests[0].Body["texts"])) != 96 || response.EstimatedDocumentTokens != nil {
+			t.Fatalf("unexpected batches: %d", len(requests))
+		}
+		// Documents by default
+		if requests[0].Path != "/model/cohere.embed-multilingual-v3/invoke" || requests[0].Body["input_type"] != "search_document" {
+			t.Errorf("unexpected request: %s %v", requests[0].Path, requests[0].Body)
+		}
+
+		requests = nil
+		_, err = model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama", InputType: embedding.InputQuery})
+		if err != nil || requests[0].Body["input_type"] != "search_query" {
+			t.Errorf("unexpected query request: %v, %v", requests, err)
+		}
+		_, err = model.Embedding(context.Background(), &embedding.ModelRequest{Document: "llama", Dimensions: 256})
+		if err == nil {
+			t.Error("dimensions is accepted")
+		}
+	})
+}
+
+func Test_NewModel_Vendors(t *testing.T) {
+	if _, err := embedding.NewModel(embedding.Models.Voyage3); err == nil {
+		t.Error("voyage model is created without api key")
+	}
+	if _, err := embedding.NewModel(embedding.Models.AmazonTitanEmbedTextV2, aigc.WithRegion("us-east-1")); err == nil {
+		t.Error("bedrock model is created without credentials")
+	}
+	// Any model of a vendor, e.g. a newer one
+	var model, err = embedding.NewModel("voyage-4", aigc.WithApiKey("test-key"))
+	if err != nil || model.GetModelId() != "voyage-4" {
+		t.Errorf("unexpected model: %v", err)
+	}
+	model, err = embedding.NewModel("text-embedding-v4", aigc.WithVendor(aigc.Vendors.Alibaba), aigc.WithApiKey("test-key"))
+	if err != nil || model.GetModelId() != "text-embedding-v4" {
+		t.Errorf("unexpected model: %v", err)
+	}
+}
@@ -11,15 +11,15 @@ var (
 	errDimensionMismatch = errors.New("dimension mismatch")
 )
 
+// The kernels dot and squaredL2 are implemented in assembly on amd64 with
+// AVX2 and FMA, see vector_amd64.go, or by the unrolled loops of
+// vector_kernel.go.
+
 func VectorMagnitude(v []float32) (float32, error) {
 	if len(v) == 0 {
 		return 0, errZeroDimension
 	}
-	var sum float32
-	for _, x := range v {
-		sum += x * x
-	}
-	return sum, nil
+	return float32(math.Sqrt(float64(dot(v, v)))), nil
 }
 
 func VectorNormalize(v []float32, result []float32) error {
@@ -40,16 +40,15 @@ func VectorNormalize(v []float32, result []float32) error {
 		return errZeroVector
 	}
 
-	var inverse float32 = 1.0 / magnitude
-
-	// BCE hint, see https://go101.org/optimizations/5-bce.html
-	result = result[:len(v)]
-	for i := 0; i < len(v); i++ {
-		result[i] = v[i] * inverse
-	}
+	scale(v, 1.0/magnitude, result)
 	return nil
 }
 
+// VectorNormalizeInPlace scales the vector to unit length.
+func VectorNormalizeInPlace(v []float32) error {
+	return VectorNormalize(v, v)
+}
+
 func VectorAdd(v1, v2 []float32, result []float32) error {
 	if len(v1) != len(v2) {
 		return errDimensionMismatch
@@ -88,54 +87,29 @@ func VectorDotProduct(v1, v2 []float32) (float32, error) {
 	if len(v1) != len(v2) {
 		return 0, errDimensionMismatch
 	}
-	var sum float32
-
-	// BCE hint, see https://go101.org/optimizations/5-bce.html
-	v2 = v2[:len(v1)]
-	for i := 0; i < len(v1); i++ {
-		sum += v1[i] * v2[i]
-	}
-	return sum, nil
+	return dot(v1, v2), nil
 }
 
 func VectorEuclideanDistance(v1, v2 []float32) (float32, error) {
 	if len(v1) != len(v2) {
 		return 0, errDimensionMismatch
 	}
-	var sum float32
-
-	// BCE hint, see https://go101.org/optimizations/5-bce.html
-	v2 = v2[:len(v1)]
-	for i := 0; i < len(v1); i++ {
-		d := v1[i] - v2[i]
-		sum += d * d
-	}
-	return float32(math.Sqrt(float64(sum))), nil
+	return float32(math.Sqrt(float64(squaredL2(v1, v2)))), nil
 }
 
 func VectorCosineSimilarity(v1, v2 []float32) (float32, error) {
-	var x, y float32
-	var dot float32
-	var norm1, norm2 float32
-
 	if len(v1) != len(v2) {
 		return 0, errDimensionMismatch
 	}
 
-	// BCE hint, see https://go101.org/optimizations/5-bce.html
-	v2 = v2[:len(v1)]
-	for i := 0; i < len(v1); i++ {
-		x = v1[i]
-		y = v2[i]
-		dot += x * y
-		norm1 += x * x
-		norm2 += y * y
-	}
-
+	var norm1 = dot(v1, v1)
+	var norm2 = dot(v2, v2)
 	if norm1 == 0 || norm2 == 0 {
 		return 0, errZeroVector
 	}
+	return cosine(dot(v1, v2), norm1, norm2), nil
+}
 
-	var cosine = dot / (float32(math.Sqrt(float64(norm1))) * float32(math.Sqrt(float64(norm2))))
-	return cosine, nil
+func cosine(dot float32, squaredNorm1 float32, squaredNorm2 float32) float32 {
+	return float32(float64(dot) / math.Sqrt(float64(squaredNorm1)*float64(squaredNorm2)))
 }
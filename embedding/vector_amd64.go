@@ -0,0 +1,65 @@
+//go:build amd64 && !purego
+
+package embedding
+
+// Kernels of at least avx2MinLength elements use AVX2 and FMA if the CPU and
+// the OS support them, shorter vectors are not worth the call.
+const avx2MinLength = 16
+
+var useAVX2 = detectAVX2()
+
+//go:noescape
+func dotAVX2(a, b *float32, n int) float32
+
+//go:noescape
+func squaredL2AVX2(a, b *float32, n int) float32
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func xgetbv() (eax, edx uint32)
+
+// detectAVX2 reports whether AVX2 and FMA are supported, and the OS saves
+// the YMM registers.
+func detectAVX2() bool {
+	var maxLeaf, _, _, _ = cpuid(0, 0)
+	if maxLeaf < 7 {
+		return false
+	}
+
+	var _, _, ecx1, _ = cpuid(1, 0)
+	const (
+		fma     = 1 << 12
+		osxsave = 1 << 27
+		avx     = 1 << 28
+	)
+	if ecx1&(fma|osxsave|avx) != fma|osxsave|avx {
+		return false
+	}
+
+	// XMM and YMM states are enabled by the OS
+	var xcr0, _ = xgetbv()
+	if xcr0&0x6 != 0x6 {
+		return false
+	}
+
+	var _, ebx7, _, _ = cpuid(7, 0)
+	const avx2 = 1 << 5
+	return ebx7&avx2 != 0
+}
+
+// dot returns the dot product, a and b have the same length.
+func dot(a, b []float32) float32 {
+	if useAVX2 && len(a) >= avx2MinLength {
+		return dotAVX2(&a[0], &b[0], len(a))
+	}
+	return dotGeneric(a, b)
+}
+
+// squaredL2 returns the squared euclidean distance, a and b have the same
+// length.
+func squaredL2(a, b []float32) float32 {
+	if useAVX2 && len(a) >= avx2MinLength {
+		return squaredL2AVX2(&a[0], &b[0], len(a))
+	}
+	return squaredL2Generic(a, b)
+}
@@ -0,0 +1,201 @@
+package embedding
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The batch functions score a query against a matrix of vectors stored
+// contiguously in row-major order, len(matrix) = len(scores) * len(query).
+
+// VectorMatch is a row of the matrix and its score.
+type VectorMatch struct {
+	Index int
+	Score float32
+}
+
+func checkMatrix(query []float32, matrix []float32, scores []float32) error {
+	if len(query) == 0 {
+		return errZeroDimension
+	}
+	if len(matrix) != len(scores)*len(query) {
+		return errDimensionMismatch
+	}
+	return nil
+}
+
+// VectorBatchDotProduct writes the dot products of the query and the rows.
+func VectorBatchDotProduct(query []float32, matrix []float32, scores []float32) error {
+	var err = checkMatrix(query, matrix, scores)
+	if err != nil {
+		return err
+	}
+	var dim = len(query)
+	for i := range scores {
+		scores[i] = dot(query, matrix[i*dim:(i+1)*dim])
+	}
+	return nil
+}
+
+// VectorBatchCosineSimilarity writes the cosine similarities of the query and
+// the rows. Zero rows score 0.
+func VectorBatchCosineSimilarity(query []float32, matrix []float32, scores []float32) error {
+	var err = checkMatrix(query, matrix, scores)
+	if err != nil {
+		return err
+	}
+	var queryNorm = dot(query, query)
+	if queryNorm == 0 {
+		return errZeroVector
+	}
+	var dim = len(query)
+	for i := range scores {
+		var row = matrix[i*dim : (i+1)*dim]
+		var rowNorm = dot(row, row)
+		if rowNorm == 0 {
+			scores[i] = 0
+			continue
+		}
+		scores[i] = cosine(dot(query, row), queryNorm, rowNorm)
+	}
+	return nil
+}
+
+// VectorBatchEuclideanDistance writes the euclidean distances of the query
+// and the rows.
+func VectorBatchEuclideanDistance(query []float32, matrix []float32, scores []float32) error {
+	var err = checkMatrix(query, matrix, scores)
+	if err != nil {
+		return err
+	}
+	var dim = len(query)
+	for i := range scores {
+		scores[i] = float32(math.Sqrt(float64(squaredL2(query, matrix[i*dim:(i+1)*dim]))))
+	}
+	return nil
+}
+
+// VectorTopK returns the k largest scores, or the k smallest if largest is
+// false, best first. Ties are ordered by index.
+func VectorTopK(scores []float32, k int, largest bool) []VectorMatch {
+	if k <= 0 || len(scores) == 0 {
+		return nil
+	}
+	if k > len(scores) {
+		k = len(scores)
+	}
+
+	// better reports whether a ranks before b
+	var better = func(a, b VectorMatch) bool {
+		if a.Score != b.Score {
+			return (a.Score > b.Score) == largest
+		}
+		return a.Index < b.Index
+	}
+
+	// heap keeps the k best matches, the worst of them at the root
+	var heap = make([]VectorMatch, 0, k)
+	var down = func(i int) {
+		for {
+			var worst = i
+			var left, right = 2*i + 1, 2*i + 2
+			if left < len(heap) && better(heap[worst], heap[left]) {
+				worst = left
+			}
+			if right < len(heap) && better(heap[worst], heap[right]) {
+				worst = right
+			}
+			if worst == i {
+				return
+			}
+			heap[i], heap[worst] = heap[worst], heap[i]
+			i = worst
+		}
+	}
+
+	for i, score := range scores {
+		var match = VectorMatch{Index: i, Score: score}
+		if len(heap) < k {
+			heap = append(heap, match)
+			for j := len(heap) - 1; j > 0; {
+				var parent = (j - 1) / 2
+				if !better(heap[parent], heap[j]) {
+					break
+				}
+				heap[parent], heap[j] = heap[j], heap[parent]
+				j = parent
+			}
+			continue
+		}
+		if better(match, heap[0]) {
+			heap[0] = match
+			down(0)
+		}
+	}
+
+	sort.Slice(heap, func(i, j int) bool { return better(heap[i], heap[j]) })
+	return heap
+}
+
+// VectorSearch returns the k rows of the matrix nearest to the query by the
+// distance type, most similar first. Scores are cosine similarities or
+// euclidean distances.
+func VectorSearch(query []float32, matrix []float32, k int, distanceType VectorDistanceType) ([]VectorMatch, error) {
+	var err error
+	if len(query) == 0 {
+		return nil, errZeroDimension
+	}
+	if len(matrix)%len(query) != 0 {
+		return nil, errDimensionMismatch
+	}
+
+	var scores = make([]float32, len(matrix)/len(query))
+	switch distanceType {
+	case EuclideanDistance:
+		err = VectorBatchEuclideanDistance(query, matrix, scores)
+		if err != nil {
+			return nil, err
+		}
+		return VectorTopK(scores, k, false), nil
+	default:
+		err = VectorBatchCosineSimilarity(query, matrix, scores)
+		if err != nil {
+			return nil, err
+		}
+		return VectorTopK(scores, k, true), nil
+	}
+}
+
+// VectorRescore scores the candidates, e.g. the matches of a quantized
+// search, by the full precision rows of the matrix, and returns the k best of
+// them, most similar first.
+func VectorRescore(query []float32, matrix []float32, candidates []VectorMatch, k int, distanceType VectorDistanceType) ([]VectorMatch, error) {
+	if len(query) == 0 {
+		return nil, errZeroDimension
+	}
+	if len(matrix)%len(query) != 0 {
+		return nil, errDimensionMismatch
+	}
+
+	var queryNorm = dot(query, query)
+	if distanceType != EuclideanDistance && queryNorm == 0 {
+		return nil, errZeroVector
+	}
+
+	var dim = len(query)
+	var rows = len(matrix) / dim
+	var scores = make([]float32, len(candidates))
+	for i, candidate := range candidates {
+		if candidate.Index < 0 || candidate.Index >= rows {
+			return nil, fmt.Errorf("candidate index %d out of range", candidate.Index)
+		}
+		scores[i] = scoreVector(query, queryNorm, matrix[candidate.Index*dim:(candidate.Index+1)*dim], distanceType)
+	}
+
+	var top = VectorTopK(scores, k, distanceType != EuclideanDistance)
+	for i := range top {
+		top[i].Index = candidates[top[i].Index].Index
+	}
+	return top, nil
+}
@@ -0,0 +1,62 @@
+package embedding
+
+// Pure Go kernels. The loops are unrolled by 4 with independent accumulators,
+// so the additions are not serialized on one register.
+
+func dotGeneric(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	var i = 0
+
+	// BCE hint, see https://go101.org/optimizations/5-bce.html
+	b = b[:len(a)]
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+func squaredL2Generic(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	var i = 0
+
+	// BCE hint, see https://go101.org/optimizations/5-bce.html
+	b = b[:len(a)]
+	for ; i+4 <= len(a); i += 4 {
+		var d0 = a[i] - b[i]
+		var d1 = a[i+1] - b[i+1]
+		var d2 = a[i+2] - b[i+2]
+		var d3 = a[i+3] - b[i+3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(a); i++ {
+		var d = a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// scale writes v * s to result, result may be v.
+func scale(v []float32, s float32, result []float32) {
+	var i = 0
+
+	// BCE hint, see https://go101.org/optimizations/5-bce.html
+	result = result[:len(v)]
+	for ; i+4 <= len(v); i += 4 {
+		result[i] = v[i] * s
+		result[i+1] = v[i+1] * s
+		result[i+2] = v[i+2] * s
+		result[i+3] = v[i+3] * s
+	}
+	for ; i < len(v); i++ {
+		result[i] = v[i] * s
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !amd64 || purego
+
+package embedding
+
+// dot returns the dot product, a and b have the same length.
+func dot(a, b []float32) float32 {
+	return dotGeneric(a, b)
+}
+
+// squaredL2 returns the squared euclidean distance, a and b have the same
+// length.
+func squaredL2(a, b []float32) float32 {
+	return squaredL2Generic(a, b)
+}
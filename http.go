@@ -26,6 +26,8 @@ type transportNode struct {
 type HttpClient struct {
 	Proxy   string
 	Retries int
+	// If set, used instead of the shared transport of Proxy
+	Transport http.RoundTripper
 
 	client *http.Client
 	inited bool
@@ -33,7 +35,7 @@ type HttpClient struct {
 
 type roundtripRetryer struct {
 	Retries   int
-	transport *http.Transport
+	transport http.RoundTripper
 }
 
 func dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
@@ -123,11 +125,15 @@ func (c *HttpClient) Client() (*http.Client, error) {
 	}
 
 	var err error
-	var transport *http.Transport
-
-	transport, err = GetHttpTransport(c.Proxy)
-	if err != nil {
-		return nil, fmt.Errorf("[HttpClient.Client] %w", err)
+	var transport http.RoundTripper
+
+	if c.Transport != nil {
+		transport = c.Transport
+	} else {
+		transport, err = GetHttpTransport(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("[HttpClient.Client] %w", err)
+		}
 	}
 
 	c.client = &http.Client{Transport: roundtripRetryer{transport: transport}}
@@ -167,3 +173,71 @@ func HttpResponseText(response *http.Response) string {
 
 	return errorText.String()
 }
+
+// Send sends a request and returns the status code and the body of the
+// response. Status codes other than 200 and 202 (accepted jobs) are errors.
+// A nil body sends no body. The logs receive the request and response
+// bodies if they are set.
+func (c *HttpClient) Send(ctx context.Context, method string, url string, header http.Header, body []byte,
+	requestLog func([]byte), responseLog func([]byte)) (int, []byte, error) {
+	var err error
+	var reader io.Reader
+	var httpRequest *http.Request
+	var httpResponse *http.Response
+
+	if body != nil {
+		reader = bytes.NewReader(body)
+		if requestLog != nil {
+			requestLog(body)
+		}
+	}
+
+	httpRequest, err = http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("[HttpClient.Send] create http request %w", err)
+	}
+	for key, values := range header {
+		httpRequest.Header[key] = values
+	}
+
+	httpResponse, err = c.Do(httpRequest)
+	if err != nil {
+		return 0, nil, fmt.Errorf("[HttpClient.Send] do http request %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK && httpResponse.StatusCode != http.StatusAccepted {
+		return httpResponse.StatusCode, nil, fmt.Errorf("[HttpClient.Send] http error %s %s",
+			httpResponse.Status, HttpResponseText(httpResponse))
+	}
+
+	var responseBody []byte
+	responseBody, err = io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return httpResponse.StatusCode, nil, fmt.Errorf("[HttpClient.Send] read http response %w", err)
+	}
+	if responseLog != nil {
+		responseLog(responseBody)
+	}
+	return httpResponse.StatusCode, responseBody, nil
+}
+
+// PostJson posts a json body and returns the body of the response, status
+// codes other than 200 are errors.
+func (c *HttpClient) PostJson(ctx context.Context, url string, header http.Header, body []byte,
+	requestLog func([]byte), responseLog func([]byte)) ([]byte, error) {
+	var jsonHeader = make(http.Header, len(header)+1)
+	for key, values := range header {
+		jsonHeader[key] = values
+	}
+	jsonHeader.Set("Content-Type", "application/json")
+
+	var status, responseBody, err = c.Send(ctx, http.MethodPost, url, jsonHeader, body, requestLog, responseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[HttpClient.PostJson] %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("[HttpClient.PostJson] unexpected http status %d", status)
+	}
+	return responseBody, nil
+}
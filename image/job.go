@@ -0,0 +1,75 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultPollInterval = 2 * time.Second
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is an image generation job of a queue-based vendor.
+type Job struct {
+	Id     string
+	Status JobStatus
+	// Progress from 0 to 1, if the vendor reports it
+	Progress float64
+	// Failure reason if the job failed
+	Error string
+	// Only set if the job succeeded
+	Response *ModelResponse
+}
+
+// JobModel is implemented by queue-based vendors. Generate submits a job and
+// waits for it, Submit and Fetch can be used to keep the job id and fetch
+// the result later.
+type JobModel interface {
+	Model
+	Submit(ctx context.Context, request *ModelRequest) (string, error)
+	Fetch(ctx context.Context, jobId string) (*Job, error)
+}
+
+// WaitJob polls the job until it is finished or the context is done.
+func WaitJob(ctx context.Context, model JobModel, jobId string, interval time.Duration) (*ModelResponse, error) {
+	var err error
+	var job *Job
+	var timer *time.Timer
+
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	for {
+		job, err = model.Fetch(ctx, jobId)
+		if err != nil {
+			return nil, fmt.Errorf("[WaitJob] %w", err)
+		}
+		switch job.Status {
+		case JobSucceeded:
+			return job.Response, nil
+		case JobFailed:
+			return nil, fmt.Errorf("[WaitJob] job %s failed: %s", jobId, job.Error)
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(interval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(interval)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("[WaitJob] job %s: %w", jobId, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
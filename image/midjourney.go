@@ -0,0 +1,239 @@
+package image
+
+// midjourney-proxy documentation:
+// https://github.com/novicezk/midjourney-proxy/blob/main/docs/api.md
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+type midJourneySubmitRequest struct {
+	Prompt string `json:"prompt"`
+	// Init images as data urls
+	Base64Array []string `json:"base64Array,omitempty"`
+}
+
+type midJourneySubmitResponse struct {
+	// 1: submitted, 21: already exists, 22: queued, others are errors
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+	// Task id
+	Result string `json:"result"`
+}
+
+type midJourneyTask struct {
+	Id string `json:"id"`
+	// "NOT_START" | "SUBMITTED" | "IN_PROGRESS" | "FAILURE" | "SUCCESS"
+	Status string `json:"status"`
+	// e.g. "50%"
+	Progress   string `json:"progress"`
+	ImageUrl   string `json:"imageUrl"`
+	FailReason string `json:"failReason"`
+}
+
+type midJourneyProxyModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func (r *midJourneySubmitRequest) load(request *ModelRequest) error {
+	var builder strings.Builder
+
+	if request.Prompt == "" {
+		return errors.New("[midJourneySubmitRequest.load] prompt is empty")
+	}
+	if len(request.Mask) > 0 {
+		return errors.New("[midJourneySubmitRequest.load] midjourney does not support inpainting")
+	}
+
+	// Parameters are appended to the prompt
+	builder.WriteString(request.Prompt)
+	if request.NegativePrompt != "" {
+		builder.WriteString(" --no ")
+		builder.WriteString(request.NegativePrompt)
+	}
+	if ratio := request.aspectRatio(nil); ratio != "" {
+		builder.WriteString(" --ar ")
+		builder.WriteString(ratio)
+	}
+	if request.Seed.Valid {
+		builder.WriteString(" --seed ")
+		builder.WriteString(strconv.FormatInt(request.Seed.Value, 10))
+	}
+	if request.Style != "" {
+		builder.WriteString(" --style ")
+		builder.WriteString(request.Style)
+	}
+	r.Prompt = builder.String()
+
+	r.Base64Array = nil
+	if len(request.Image) > 0 {
+		var mediaType = http.DetectContentType(request.Image)
+		r.Base64Array = []string{"data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(request.Image)}
+	}
+	return nil
+}
+
+func (t *midJourneyTask) dump(job *Job) error {
+	job.Id = t.Id
+	job.Error = t.FailReason
+	job.Response = nil
+	job.Progress = 0
+	if p, err := strconv.ParseFloat(strings.TrimSuffix(t.Progress, "%"), 64); err == nil {
+		job.Progress = p / 100
+	}
+
+	switch t.Status {
+	case "NOT_START", "SUBMITTED":
+		job.Status = JobPending
+	case "IN_PROGRESS":
+		job.Status = JobRunning
+	case "FAILURE":
+		job.Status = JobFailed
+	case "SUCCESS":
+		job.Status = JobSucceeded
+		job.Progress = 1
+		job.Response = &ModelResponse{Images: []Image{{Url: t.ImageUrl}}}
+	default:
+		return fmt.Errorf("[midJourneyTask.dump] unknown status: %s", t.Status)
+	}
+	return nil
+}
+
+func (m *midJourneyProxyModel) header() http.Header {
+	var header = http.Header{}
+	header.Set("mj-api-secret", m.ApiKey)
+	return header
+}
+
+func (m *midJourneyProxyModel) GetModelId() string {
+	return m.ModelId
+}
+
+// Submit submits an imagine task, returns the task id.
+func (m *midJourneyProxyModel) Submit(ctx context.Context, request *ModelRequest) (string, error) {
+	var err error
+	var body []byte
+	var submit midJourneySubmitRequest
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = submit.load(request)
+	if err != nil {
+		return "", fmt.Errorf("[midJourneyProxyModel.Submit] %w", err)
+	}
+	err = aigc.EncodeJson(buffer, &submit)
+	if err != nil {
+		return "", fmt.Errorf("[midJourneyProxyModel.Submit] %w", err)
+	}
+
+	var header = m.header()
+	header.Set("Content-Type", "application/json")
+	_, body, err = m.client.Send(ctx, http.MethodPost, m.Endpoint+"/mj/submit/imagine", header,
+		buffer.Bytes(), m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return "", fmt.Errorf("[midJourneyProxyModel.Submit] %w", err)
+	}
+
+	var response midJourneySubmitResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return "", fmt.Errorf("[midJourneyProxyModel.Submit] %w", err)
+	}
+	switch response.Code {
+	case 1, 21, 22:
+	default:
+		return "", fmt.Errorf("[midJourneyProxyModel.Submit] submit error %d %s", response.Code,
+			response.Description)
+	}
+	if response.Result == "" {
+		return "", errors.New("[midJourneyProxyModel.Submit] no task id")
+	}
+	return response.Result, nil
+}
+
+// Fetch fetches the task, the image of a succeeded task is the url of the 2x2
+// grid.
+func (m *midJourneyProxyModel) Fetch(ctx context.Context, jobId string) (*Job, error) {
+	var err error
+	var body []byte
+
+	_, body, err = m.client.Send(ctx, http.MethodGet, m.Endpoint+"/mj/task/"+url.PathEscape(jobId)+"/fetch",
+		m.header(), nil, nil, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[midJourneyProxyModel.Fetch] %w", err)
+	}
+
+	var task midJourneyTask
+	err = json.Unmarshal(body, &task)
+	if err != nil {
+		return nil, fmt.Errorf("[midJourneyProxyModel.Fetch] %w", err)
+	}
+	var job Job
+	err = task.dump(&job)
+	if err != nil {
+		return nil, fmt.Errorf("[midJourneyProxyModel.Fetch] %w", err)
+	}
+	if job.Id == "" {
+		job.Id = jobId
+	}
+	return &job, nil
+}
+
+// Generate submits the task and waits for it. Count is ignored.
+func (m *midJourneyProxyModel) Generate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var jobId string
+	var response *ModelResponse
+
+	jobId, err = m.Submit(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("[midJourneyProxyModel.Generate] %w", err)
+	}
+	response, err = WaitJob(ctx, m, jobId, request.pollInterval())
+	if err != nil {
+		return nil, fmt.Errorf("[midJourneyProxyModel.Generate] %w", err)
+	}
+	return response, nil
+}
+
+func newMidJourneyProxyModel(modelId string, opts *aigc.ModelOptions) (*midJourneyProxyModel, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("midjourney proxy endpoint is required")
+	}
+
+	var model = &midJourneyProxyModel{
+		ModelId:     modelId,
+		Endpoint:    strings.TrimSuffix(opts.Endpoint, "/"),
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
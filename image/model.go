@@ -0,0 +1,49 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+type Model interface {
+	GetModelId() string
+	Generate(ctx context.Context, request *ModelRequest) (*ModelResponse, error)
+}
+
+func NewModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (Model, error) {
+	var opts aigc.ModelOptions
+
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	switch modelId {
+	case Models.OpenAIDallE2, Models.OpenAIDallE3:
+		if opts.VendorId == aigc.Vendors.Microsoft {
+			return newAzureOpenAIDallEModel(string(modelId), &opts)
+		}
+		if opts.VendorId == aigc.Vendors.OpenAI {
+			return newOpenAIDallEModel(string(modelId), &opts)
+		}
+		if opts.VendorId == "" {
+			opts.VendorId = aigc.Vendors.OpenAI
+			return newOpenAIDallEModel(string(modelId), &opts)
+		}
+	case Models.StabilityImageUltra, Models.StabilityImageCore,
+		Models.StabilitySD3Large, Models.StabilitySD3Turbo, Models.StabilitySD3Medium:
+		if opts.VendorId == "" || opts.VendorId == aigc.Vendors.StabilityAI {
+			opts.VendorId = aigc.Vendors.StabilityAI
+			return newStabilityModel(string(modelId), &opts)
+		}
+	}
+
+	if opts.VendorId == aigc.Vendors.MidJourney {
+		return newMidJourneyProxyModel(string(modelId), &opts)
+	}
+
+	if opts.VendorId != "" {
+		return nil, fmt.Errorf("model can not be created, vendor:%s, model: %s", opts.VendorId, modelId)
+	}
+	return nil, fmt.Errorf("model can not be created: %s", modelId)
+}
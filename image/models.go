@@ -0,0 +1,35 @@
+package image
+
+import "github.com/Pooh-Mucho/go-aigc"
+
+var Models = struct {
+	// OpenAI DALL-E models
+	OpenAIDallE2 aigc.ModelId
+	OpenAIDallE3 aigc.ModelId
+
+	// Stability AI models
+	// https://platform.stability.ai/docs/api-reference
+	StabilityImageUltra aigc.ModelId
+	StabilityImageCore  aigc.ModelId
+	StabilitySD3Large   aigc.ModelId
+	StabilitySD3Turbo   aigc.ModelId
+	StabilitySD3Medium  aigc.ModelId
+
+	// MidJourney by a midjourney-proxy compatible service
+	// https://github.com/novicezk/midjourney-proxy
+	MidJourney aigc.ModelId
+}{
+	// OpenAI DALL-E models
+	OpenAIDallE2: "dall-e-2",
+	OpenAIDallE3: "dall-e-3",
+
+	// Stability AI models
+	StabilityImageUltra: "stable-image-ultra",
+	StabilityImageCore:  "stable-image-core",
+	StabilitySD3Large:   "sd3-large",
+	StabilitySD3Turbo:   "sd3-large-turbo",
+	StabilitySD3Medium:  "sd3-medium",
+
+	// MidJourney by a midjourney-proxy compatible service
+	MidJourney: "midjourney",
+}
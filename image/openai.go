@@ -0,0 +1,338 @@
+package image
+
+// OpenAI documentation:
+// https://platform.openai.com/docs/api-reference/images
+
+// Azure OpenAI documentation:
+// https://learn.microsoft.com/en-us/azure/ai-services/openai/dall-e-quickstart
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	// Base url of the OpenAI API, Endpoint option replaces it
+	openaiDefaultEndpoint = "https://api.openai.com/v1"
+
+	azureOpenAIDefaultApiVersion = "2024-06-01"
+)
+
+type dallERequest struct {
+	// OpenAI should set Model. Azure API should not.
+	Model string `json:"model,omitempty"`
+	// A text description of the desired image(s). The maximum length is 1000
+	// characters for dall-e-2 and 4000 characters for dall-e-3.
+	Prompt string `json:"prompt"`
+	// The number of images to generate. Must be between 1 and 10. For
+	// dall-e-3, only n=1 is supported.
+	N int `json:"n,omitempty"`
+	// dall-e-2: "256x256" | "512x512" | "1024x1024"
+	// dall-e-3: "1024x1024" | "1792x1024" | "1024x1792"
+	Size string `json:"size,omitempty"`
+	// "standard" | "hd", only for dall-e-3
+	Quality string `json:"quality,omitempty"`
+	// "vivid" | "natural", only for dall-e-3
+	Style string `json:"style,omitempty"`
+	// "url" | "b64_json". URLs are only valid for 60 minutes.
+	ResponseFormat string `json:"response_format,omitempty"`
+
+	// For edits, the image to edit and the mask, sent as multipart/form-data.
+	Image []byte `json:"-"`
+	Mask  []byte `json:"-"`
+}
+
+type dallEResponse struct {
+	Created int64 `json:"created"`
+	Data    []struct {
+		B64Json       string `json:"b64_json,omitempty"`
+		Url           string `json:"url,omitempty"`
+		RevisedPrompt string `json:"revised_prompt,omitempty"`
+	} `json:"data"`
+}
+
+type openaiDallEModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+type azureOpenAIDallEModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	ApiVersion  string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func (r *dallERequest) load(modelId string, request *ModelRequest) error {
+	if request.Prompt == "" {
+		return errors.New("[dallERequest.load] prompt is empty")
+	}
+
+	r.Prompt = request.Prompt
+	r.N = request.count()
+	r.Quality = request.Quality
+	r.Style = request.Style
+	r.ResponseFormat = "b64_json"
+	r.Size = ""
+	if request.Width > 0 && request.Height > 0 {
+		r.Size = strconv.Itoa(request.Width) + "x" + strconv.Itoa(request.Height)
+	}
+
+	if modelId == string(Models.OpenAIDallE3) && r.N > 1 {
+		return errors.New("[dallERequest.load] dall-e-3 only supports 1 image per request")
+	}
+
+	r.Image = request.Image
+	r.Mask = request.Mask
+	if len(r.Mask) > 0 && len(r.Image) == 0 {
+		return errors.New("[dallERequest.load] mask without image")
+	}
+	if len(r.Image) > 0 && modelId == string(Models.OpenAIDallE3) {
+		return errors.New("[dallERequest.load] dall-e-3 does not support image edits")
+	}
+	return nil
+}
+
+// edit reports whether the request is sent to the edits API.
+func (r *dallERequest) edit() bool {
+	return len(r.Image) > 0
+}
+
+func (r *dallERequest) fields() []aigc.MultipartField {
+	var fields = []aigc.MultipartField{
+		{Name: "image", FileName: "image.png", ContentType: string(ImagePng), Data: r.Image},
+		{Name: "prompt", Value: r.Prompt},
+		{Name: "n", Value: strconv.Itoa(r.N)},
+		{Name: "response_format", Value: r.ResponseFormat},
+	}
+	if len(r.Mask) > 0 {
+		fields = append(fields, aigc.MultipartField{
+			Name: "mask", FileName: "mask.png", ContentType: string(ImagePng), Data: r.Mask,
+		})
+	}
+	if r.Model != "" {
+		fields = append(fields, aigc.MultipartField{Name: "model", Value: r.Model})
+	}
+	if r.Size != "" {
+		fields = append(fields, aigc.MultipartField{Name: "size", Value: r.Size})
+	}
+	return fields
+}
+
+// encode writes the request body, returns the content type.
+func (r *dallERequest) encode(buffer *bytes.Buffer) (string, error) {
+	if r.edit() {
+		return aigc.EncodeMultipart(buffer, r.fields()...)
+	}
+	return "application/json", aigc.EncodeJson(buffer, r)
+}
+
+func (r *dallEResponse) dump(response *ModelResponse) error {
+	var err error
+	response.Images = nil
+	for _, data := range r.Data {
+		var image = Image{MediaType: ImagePng, Url: data.Url, RevisedPrompt: data.RevisedPrompt}
+		if data.B64Json != "" {
+			image.Data, err = base64.StdEncoding.DecodeString(data.B64Json)
+			if err != nil {
+				return fmt.Errorf("[dallEResponse.dump] %w", err)
+			}
+		}
+		response.Images = append(response.Images, image)
+	}
+	if len(response.Images) == 0 {
+		return errors.New("[dallEResponse.dump] no image")
+	}
+	return nil
+}
+
+// generate sends the dall-e request to the url.
+func generateDallE(ctx context.Context, client *aigc.HttpClient, url string, header http.Header,
+	request *dallERequest, requestLog func([]byte), responseLog func([]byte)) (*ModelResponse, error) {
+	var err error
+	var contentType string
+	var body []byte
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	contentType, err = request.encode(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("[generateDallE] %w", err)
+	}
+	header.Set("Content-Type", contentType)
+
+	_, body, err = client.Send(ctx, http.MethodPost, url, header, buffer.Bytes(), requestLog, responseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[generateDallE] %w", err)
+	}
+
+	var dallE dallEResponse
+	err = json.Unmarshal(body, &dallE)
+	if err != nil {
+		return nil, fmt.Errorf("[generateDallE] %w", err)
+	}
+	var response = ModelResponse{}
+	err = dallE.dump(&response)
+	if err != nil {
+		return nil, fmt.Errorf("[generateDallE] %w", err)
+	}
+	return &response, nil
+}
+
+func (m *openaiDallEModel) getModelUrl(edit bool) string {
+	var endpoint = m.Endpoint
+	if endpoint == "" {
+		endpoint = openaiDefaultEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if edit {
+		return endpoint + "/images/edits"
+	}
+	return endpoint + "/images/generations"
+}
+
+func (m *openaiDallEModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *openaiDallEModel) Generate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var response *ModelResponse
+	var dallE dallERequest
+
+	err = dallE.load(m.ModelId, request)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiDallEModel.Generate] %w", err)
+	}
+	dallE.Model = m.ModelId
+
+	var header = http.Header{}
+	header.Set("Authorization", "Bearer "+m.ApiKey)
+
+	response, err = generateDallE(ctx, &m.client, m.getModelUrl(dallE.edit()), header, &dallE,
+		m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiDallEModel.Generate] %w", err)
+	}
+	return response, nil
+}
+
+func (m *azureOpenAIDallEModel) getModelUrl() string {
+	var builder strings.Builder
+
+	builder.WriteString(m.Endpoint)
+	if !strings.HasSuffix(m.Endpoint, "/") {
+		builder.WriteByte('/')
+	}
+	builder.WriteString("openai/deployments/")
+	builder.WriteString(m.ModelId)
+	builder.WriteString("/images/generations?api-version=")
+	if m.ApiVersion != "" {
+		builder.WriteString(m.ApiVersion)
+	} else {
+		builder.WriteString(azureOpenAIDefaultApiVersion)
+	}
+	return builder.String()
+}
+
+func (m *azureOpenAIDallEModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *azureOpenAIDallEModel) Generate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var response *ModelResponse
+	var dallE dallERequest
+
+	err = dallE.load(m.ModelId, request)
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAIDallEModel.Generate] %w", err)
+	}
+	if dallE.edit() {
+		return nil, errors.New("[azureOpenAIDallEModel.Generate] azure does not support image edits")
+	}
+
+	var header = http.Header{}
+	header.Set("api-key", m.ApiKey)
+
+	response, err = generateDallE(ctx, &m.client, m.getModelUrl(), header, &dallE, m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[azureOpenAIDallEModel.Generate] %w", err)
+	}
+	return response, nil
+}
+
+func newOpenAIDallEModel(modelId string, opts *aigc.ModelOptions) (*openaiDallEModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("openai api key is required")
+	}
+	if opts.ApiVersion != "" {
+		return nil, errors.New("openai api version is not supported")
+	}
+
+	var model = &openaiDallEModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
+
+func newAzureOpenAIDallEModel(modelId string, opts *aigc.ModelOptions) (*azureOpenAIDallEModel, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("azure endpoint is required")
+	}
+	if opts.ApiKey == "" {
+		return nil, errors.New("azure api key is required")
+	}
+
+	var model = &azureOpenAIDallEModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		ApiVersion:  opts.ApiVersion,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
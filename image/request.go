@@ -0,0 +1,111 @@
+package image
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+type MediaType string
+
+const (
+	ImagePng  MediaType = "image/png"
+	ImageJpeg MediaType = "image/jpeg"
+	ImageWebp MediaType = "image/webp"
+)
+
+// ModelRequest is a text-to-image request. It is an image-to-image request if
+// Image is set, or an inpainting request if Mask is set too. Parameters not
+// supported by the vendor are ignored, see the comments of the fields.
+type ModelRequest struct {
+	Prompt string
+	// Not supported by DALL-E
+	NegativePrompt string
+	// Image size in pixels. DALL-E supports fixed sizes, e.g. 1024x1024.
+	// Stability and MidJourney use the nearest aspect ratio.
+	Width  int
+	Height int
+	// Aspect ratio, e.g. "16:9". Not supported by DALL-E, overrides Width
+	// and Height for others.
+	AspectRatio string
+	// Not supported by DALL-E. Stability generates the images of a request
+	// by the consecutive seeds from Seed.
+	Seed aigc.Nullable[int64]
+	// Number of images, default is 1. DALL-E 3 and MidJourney (always a 2x2
+	// grid) generate 1 image per request.
+	Count int
+	// DALL-E 3: "vivid" | "natural"
+	// Stability: style preset, e.g. "photographic", "anime", "digital-art"
+	// MidJourney: "raw"
+	Style string
+	// DALL-E 3: "standard" | "hd"
+	Quality string
+	// Output format, default is png. Not supported by DALL-E and MidJourney.
+	MediaType MediaType
+
+	// Init image for image-to-image and inpainting. DALL-E edits the
+	// transparent areas of the image if Mask is not set.
+	Image []byte
+	// How much the init image is changed, from 0 to 1. Only for Stability.
+	Strength aigc.Nullable[float64]
+	// Inpainting mask, white areas are repainted. Only for DALL-E 2 and
+	// Stability.
+	Mask []byte
+
+	// Interval of polling the job of queue-based vendors, default is 2s
+	PollInterval time.Duration
+}
+
+// aspectRatio returns the AspectRatio of the request, or the supported ratio
+// nearest to Width:Height. Width:Height is reduced if supported is empty.
+// Empty if neither is set.
+func (r *ModelRequest) aspectRatio(supported []string) string {
+	if r.AspectRatio != "" {
+		return r.AspectRatio
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return ""
+	}
+
+	if len(supported) == 0 {
+		var a, b = r.Width, r.Height
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return strconv.Itoa(r.Width/a) + ":" + strconv.Itoa(r.Height/a)
+	}
+
+	var nearest string
+	var nearestDiff = math.Inf(1)
+	var ratio = math.Log(float64(r.Width) / float64(r.Height))
+	for _, s := range supported {
+		var w, h int
+		if _, err := fmt.Sscanf(s, "%d:%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+			continue
+		}
+		var diff = math.Abs(math.Log(float64(w)/float64(h)) - ratio)
+		if diff < nearestDiff {
+			nearest, nearestDiff = s, diff
+		}
+	}
+	return nearest
+}
+
+func (r *ModelRequest) count() int {
+	if r.Count <= 0 {
+		return 1
+	}
+	return r.Count
+}
+
+func (r *ModelRequest) pollInterval() time.Duration {
+	if r.PollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return r.PollInterval
+}
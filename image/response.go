@@ -0,0 +1,16 @@
+package image
+
+type Image struct {
+	// Image bytes, or Url if the vendor only returns urls
+	Data      []byte
+	MediaType MediaType
+	Url       string
+	// DALL-E 3 revises the prompt
+	RevisedPrompt string
+	// Stability returns the seed of the image
+	Seed int64
+}
+
+type ModelResponse struct {
+	Images []Image
+}
@@ -0,0 +1,280 @@
+package image
+
+// Stability AI documentation:
+// https://platform.stability.ai/docs/api-reference
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	stabilityDefaultEndpoint = "https://api.stability.ai"
+
+	// Maximum seed of the API
+	stabilityMaxSeed = 4294967294
+)
+
+// Aspect ratios supported by the generate APIs
+var stabilityAspectRatios = []string{"16:9", "1:1", "21:9", "2:3", "3:2", "4:5", "5:4", "9:16", "9:21"}
+
+type stabilityRequest struct {
+	// Path of the API, e.g. "/v2beta/stable-image/generate/sd3"
+	path string
+
+	Prompt         string
+	NegativePrompt string
+	// "sd3-large" | "sd3-large-turbo" | "sd3-medium", only for sd3
+	Model       string
+	AspectRatio string
+	// 0 means random
+	Seed int64
+	// "png" | "jpeg" | "webp"
+	OutputFormat string
+	StylePreset  string
+
+	// "text-to-image" | "image-to-image", only for sd3
+	Mode     string
+	Image    []byte
+	Strength aigc.Nullable[float64]
+	Mask     []byte
+}
+
+type stabilityResponse struct {
+	// Base64 encoded image
+	Image string `json:"image"`
+	// "SUCCESS" | "CONTENT_FILTERED"
+	FinishReason string `json:"finish_reason"`
+	Seed         int64  `json:"seed"`
+}
+
+type stabilityModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func (r *stabilityRequest) load(modelId string, request *ModelRequest) error {
+	if request.Prompt == "" {
+		return errors.New("[stabilityRequest.load] prompt is empty")
+	}
+
+	r.Prompt = request.Prompt
+	r.NegativePrompt = request.NegativePrompt
+	r.StylePreset = request.Style
+	r.Seed = 0
+	if request.Seed.Valid {
+		r.Seed = request.Seed.Value
+	}
+
+	switch request.MediaType {
+	case "", ImagePng:
+		r.OutputFormat = "png"
+	case ImageJpeg:
+		r.OutputFormat = "jpeg"
+	case ImageWebp:
+		r.OutputFormat = "webp"
+	default:
+		return fmt.Errorf("[stabilityRequest.load] unsupported media type: %s", request.MediaType)
+	}
+
+	r.Image = request.Image
+	r.Mask = request.Mask
+	r.Strength = request.Strength
+	if len(r.Mask) > 0 && len(r.Image) == 0 {
+		return errors.New("[stabilityRequest.load] mask without image")
+	}
+
+	r.Model = ""
+	r.Mode = ""
+	r.AspectRatio = ""
+	switch {
+	case len(r.Mask) > 0:
+		// Inpainting keeps the size of the init image
+		r.path = "/v2beta/stable-image/edit/inpaint"
+		return nil
+	case modelId == string(Models.StabilityImageUltra):
+		r.path = "/v2beta/stable-image/generate/ultra"
+	case modelId == string(Models.StabilityImageCore):
+		if len(r.Image) > 0 {
+			return errors.New("[stabilityRequest.load] stable-image-core does not support image-to-image")
+		}
+		r.path = "/v2beta/stable-image/generate/core"
+	default:
+		r.path = "/v2beta/stable-image/generate/sd3"
+		r.Model = modelId
+		r.Mode = "text-to-image"
+		if len(r.Image) > 0 {
+			r.Mode = "image-to-image"
+		}
+	}
+
+	if len(r.Image) > 0 {
+		// The output has the aspect ratio of the init image, strength is
+		// required.
+		if !r.Strength.Valid {
+			r.Strength = aigc.NewNullable(0.5)
+		}
+	} else {
+		r.AspectRatio = request.aspectRatio(stabilityAspectRatios)
+	}
+	return nil
+}
+
+func (r *stabilityRequest) fields() []aigc.MultipartField {
+	var fields = []aigc.MultipartField{
+		{Name: "prompt", Value: r.Prompt},
+		{Name: "output_format", Value: r.OutputFormat},
+	}
+	var add = func(name string, value string) {
+		if value != "" {
+			fields = append(fields, aigc.MultipartField{Name: name, Value: value})
+		}
+	}
+	add("negative_prompt", r.NegativePrompt)
+	add("model", r.Model)
+	add("mode", r.Mode)
+	add("aspect_ratio", r.AspectRatio)
+	add("style_preset", r.StylePreset)
+	if r.Seed != 0 {
+		add("seed", strconv.FormatInt(r.Seed, 10))
+	}
+	if len(r.Image) > 0 {
+		fields = append(fields, aigc.MultipartField{Name: "image", FileName: "image", Data: r.Image})
+		if r.Strength.Valid && len(r.Mask) == 0 {
+			add("strength", strconv.FormatFloat(r.Strength.Value, 'f', -1, 64))
+		}
+	}
+	if len(r.Mask) > 0 {
+		fields = append(fields, aigc.MultipartField{Name: "mask", FileName: "mask", Data: r.Mask})
+	}
+	return fields
+}
+
+func (r *stabilityResponse) dump(format string, image *Image) error {
+	var err error
+	if r.FinishReason == "CONTENT_FILTERED" {
+		return errors.New("[stabilityResponse.dump] image is content filtered")
+	}
+	image.Data, err = base64.StdEncoding.DecodeString(r.Image)
+	if err != nil {
+		return fmt.Errorf("[stabilityResponse.dump] %w", err)
+	}
+	image.MediaType = MediaType("image/" + format)
+	image.Seed = r.Seed
+	return nil
+}
+
+func (m *stabilityModel) getModelUrl(path string) string {
+	var endpoint = m.Endpoint
+	if endpoint == "" {
+		endpoint = stabilityDefaultEndpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + path
+}
+
+func (m *stabilityModel) GetModelId() string {
+	return m.ModelId
+}
+
+// stabilitySeed returns the seed of the i-th image of a fixed seed, the seeds
+// are consecutive and wrap around in [1, stabilityMaxSeed].
+func stabilitySeed(seed int64, i int) int64 {
+	return (seed-1+int64(i))%stabilityMaxSeed + 1
+}
+
+// Generate generates the images one by one, the API returns 1 image per
+// request.
+func (m *stabilityModel) Generate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var contentType string
+	var body []byte
+	var stability stabilityRequest
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = stability.load(m.ModelId, request)
+	if err != nil {
+		return nil, fmt.Errorf("[stabilityModel.Generate] %w", err)
+	}
+
+	var header = http.Header{}
+	header.Set("Authorization", "Bearer "+m.ApiKey)
+	header.Set("Accept", "application/json")
+
+	var url = m.getModelUrl(stability.path)
+	var seed = stability.Seed
+	var response = &ModelResponse{}
+	for i := 0; i < request.count(); i++ {
+		// The same seed generates the same image, each image of a fixed seed
+		// has its own one
+		if i == 0 || seed != 0 {
+			if seed != 0 {
+				stability.Seed = stabilitySeed(seed, i)
+			}
+			buffer.Reset()
+			contentType, err = aigc.EncodeMultipart(buffer, stability.fields()...)
+			if err != nil {
+				return nil, fmt.Errorf("[stabilityModel.Generate] %w", err)
+			}
+			header.Set("Content-Type", contentType)
+		}
+
+		_, body, err = m.client.Send(ctx, http.MethodPost, url, header, buffer.Bytes(),
+			m.RequestLog, m.ResponseLog)
+		if err != nil {
+			return nil, fmt.Errorf("[stabilityModel.Generate] %w", err)
+		}
+
+		var result stabilityResponse
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			return nil, fmt.Errorf("[stabilityModel.Generate] %w", err)
+		}
+		var image Image
+		err = result.dump(stability.OutputFormat, &image)
+		if err != nil {
+			return nil, fmt.Errorf("[stabilityModel.Generate] %w", err)
+		}
+		response.Images = append(response.Images, image)
+	}
+	return response, nil
+}
+
+func newStabilityModel(modelId string, opts *aigc.ModelOptions) (*stabilityModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("stability api key is required")
+	}
+
+	var model = &stabilityModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,288 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var imagePng = []byte("\x89PNG\r\n\x1a\nimage")
+
+func Test_OpenAI_DallE_Generate(t *testing.T) {
+	var body map[string]any
+	var path string
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"created": 1, "data": [{"b64_json": %q, "revised_prompt": "A red fox."}]}`,
+			base64.StdEncoding.EncodeToString(imagePng))
+	}))
+	defer server.Close()
+
+	var model, err = image.NewModel(image.Models.OpenAIDallE3,
+		aigc.WithEndpoint(server.URL+"/v1"),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Generate(context.Background(), &image.ModelRequest{
+		Prompt:  "A fox",
+		Width:   1792,
+		Height:  1024,
+		Quality: "hd",
+		Style:   "natural",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Images) != 1 || string(response.Images[0].Data) != string(imagePng) ||
+		response.Images[0].RevisedPrompt != "A red fox." {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if path != "/v1/images/generations" || body["model"] != "dall-e-3" || body["size"] != "1792x1024" ||
+		body["quality"] != "hd" || body["style"] != "natural" || body["response_format"] != "b64_json" {
+		t.Errorf("unexpected request: %s %v", path, body)
+	}
+
+	_, err = model.Generate(context.Background(), &image.ModelRequest{Prompt: "A fox", Count: 2})
+	if err == nil {
+		t.Error("dall-e-3 generates 2 images")
+	}
+}
+
+func Test_OpenAI_DallE_Edit(t *testing.T) {
+	var received *http.Request
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+		}
+		received = r
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"created": 1, "data": [{"b64_json": %q}, {"b64_json": %q}]}`,
+			base64.StdEncoding.EncodeToString(imagePng), base64.StdEncoding.EncodeToString(imagePng))
+	}))
+	defer server.Close()
+
+	var model, err = image.NewModel(image.Models.OpenAIDallE2,
+		aigc.WithEndpoint(server.URL),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Generate(context.Background(), &image.ModelRequest{
+		Prompt: "Add a hat",
+		Count:  2,
+		Image:  imagePng,
+		Mask:   imagePng,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Images) != 2 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	var form = received.MultipartForm
+	if received.URL.Path != "/images/edits" || form.Value["prompt"][0] != "Add a hat" ||
+		form.Value["n"][0] != "2" || len(form.File["image"]) != 1 || len(form.File["mask"]) != 1 {
+		t.Errorf("unexpected request: %s %v", received.URL.Path, form.Value)
+	}
+}
+
+func Test_Stability_Generate(t *testing.T) {
+	var requests []*http.Request
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"image": %q, "finish_reason": "SUCCESS", "seed": %d}`,
+			base64.StdEncoding.EncodeToString(imagePng), 42+len(requests))
+	}))
+	defer server.Close()
+
+	var model, err = image.NewModel(image.Models.StabilitySD3Large,
+		aigc.WithEndpoint(server.URL),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := model.Generate(context.Background(), &image.ModelRequest{
+		Prompt:         "A lighthouse",
+		NegativePrompt: "people",
+		Width:          1920,
+		Height:         1080,
+		Seed:           aigc.NewNullable[int64](7),
+		Count:          2,
+		Style:          "photographic",
+		MediaType:      image.ImageWebp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Images) != 2 || response.Images[0].MediaType != image.ImageWebp ||
+		response.Images[1].Seed != 44 || string(response.Images[0].Data) != string(imagePng) {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("unexpected requests: %d", len(requests))
+	}
+	var r = requests[0]
+	var form = r.MultipartForm.Value
+	if r.URL.Path != "/v2beta/stable-image/generate/sd3" || r.Header.Get("Accept") != "application/json" ||
+		r.Header.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("unexpected request: %s %v", r.URL.Path, r.Header)
+	}
+	if form["model"][0] != "sd3-large" || form["mode"][0] != "text-to-image" || form["aspect_ratio"][0] != "16:9" ||
+		form["negative_prompt"][0] != "people" || form["seed"][0] != "7" || form["output_format"][0] != "webp" ||
+		form["style_preset"][0] != "photographic" {
+		t.Errorf("unexpected form: %v", form)
+	}
+	// The images of a fixed seed are not duplicates
+	if seed := requests[1].MultipartForm.Value["seed"]; len(seed) != 1 || seed[0] != "8" {
+		t.Errorf("unexpected seed of the second image: %v", seed)
+	}
+
+	// Inpainting
+	requests = nil
+	_, err = model.Generate(context.Background(), &image.ModelRequest{
+		Prompt: "A boat",
+		Image:  imagePng,
+		Mask:   imagePng,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests[0].URL.Path != "/v2beta/stable-image/edit/inpaint" || len(requests[0].MultipartForm.File["mask"]) != 1 {
+		t.Errorf("unexpected inpaint request: %s", requests[0].URL.Path)
+	}
+}
+
+func Test_Stability_Content_Filtered(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"image": "", "finish_reason": "CONTENT_FILTERED", "seed": 1}`)
+	}))
+	defer server.Close()
+
+	var model, err = image.NewModel(image.Models.StabilityImageCore,
+		aigc.WithEndpoint(server.URL),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = model.Generate(context.Background(), &image.ModelRequest{Prompt: "A fox"}); err == nil {
+		t.Error("content filtered image is returned")
+	}
+}
+
+func Test_MidJourney_Job(t *testing.T) {
+	var fetches atomic.Int32
+	var submit map[string]any
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("mj-api-secret") != "test-secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/mj/submit/imagine":
+			if err := json.NewDecoder(r.Body).Decode(&submit); err != nil {
+				t.Error(err)
+			}
+			io.WriteString(w, `{"code": 1, "description": "Submitted", "result": "1234"}`)
+		case "/mj/task/1234/fetch":
+			switch fetches.Add(1) {
+			case 1:
+				io.WriteString(w, `{"id": "1234", "status": "SUBMITTED", "progress": "0%"}`)
+			case 2:
+				io.WriteString(w, `{"id": "1234", "status": "IN_PROGRESS", "progress": "50%"}`)
+			default:
+				io.WriteString(w, `{"id": "1234", "status": "SUCCESS", "progress": "100%",
+					"imageUrl": "https://cdn.example.com/1234.png"}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var model, err = image.NewModel(image.Models.MidJourney,
+		aigc.WithVendor(aigc.Vendors.MidJourney),
+		aigc.WithEndpoint(server.URL),
+		aigc.WithApiKey("test-secret"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := model.(image.JobModel); !ok {
+		t.Fatal("midjourney model is not a job model")
+	}
+
+	response, err := model.Generate(context.Background(), &image.ModelRequest{
+		Prompt:         "A castle",
+		NegativePrompt: "fog",
+		Width:          1920,
+		Height:         1080,
+		Seed:           aigc.NewNullable[int64](9),
+		PollInterval:   time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Images) != 1 || response.Images[0].Url != "https://cdn.example.com/1234.png" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if submit["prompt"] != "A castle --no fog --ar 16:9 --seed 9" {
+		t.Errorf("unexpected prompt: %v", submit["prompt"])
+	}
+	if fetches.Load() != 3 {
+		t.Errorf("unexpected fetches: %d", fetches.Load())
+	}
+}
+
+func Test_MidJourney_Job_Failed(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id": "1234", "status": "FAILURE", "failReason": "banned prompt"}`)
+	}))
+	defer server.Close()
+
+	var model, err = image.NewModel(image.Models.MidJourney,
+		aigc.WithVendor(aigc.Vendors.MidJourney),
+		aigc.WithEndpoint(server.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job *image.Job
+	job, err = model.(image.JobModel).Fetch(context.Background(), "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != image.JobFailed || job.Error != "banned prompt" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if _, err = image.WaitJob(context.Background(), model.(image.JobModel), "1234", time.Millisecond); err == nil {
+		t.Error("failed job is not an error")
+	}
+}
@@ -0,0 +1,99 @@
+// Package bedrock is the Amazon Bedrock runtime client shared by the chat and
+// embedding models.
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// credentialsProvider is an implementation of aws.CredentialsProvider
+type credentialsProvider struct {
+	AccessKey string
+	SecretKey string
+}
+
+type Client struct {
+	Region    string
+	AccessKey string
+	SecretKey string
+	Proxy     string
+	Retries   int
+	// If set, used instead of the shared transport of Proxy
+	Transport http.RoundTripper
+	// If set, replaces the regional endpoint, e.g. a VPC endpoint
+	Endpoint string
+
+	client *bedrockruntime.Client
+}
+
+func (p *credentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	_ = ctx
+	return aws.Credentials{
+		AccessKeyID:     p.AccessKey,
+		SecretAccessKey: p.SecretKey,
+		SessionToken:    "",
+		Source:          "",
+		CanExpire:       false,
+		Expires:         time.Time{},
+	}, nil
+}
+
+func (c *Client) Client() (*bedrockruntime.Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	var err error
+	var transport http.RoundTripper
+	var bedrockOpts bedrockruntime.Options
+
+	if c.Transport != nil {
+		transport = c.Transport
+	} else {
+		transport, err = aigc.GetHttpTransport(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("[Client.Client] %w", err)
+		}
+	}
+
+	bedrockOpts = bedrockruntime.Options{
+		Region:      c.Region,
+		Credentials: &credentialsProvider{AccessKey: c.AccessKey, SecretKey: c.SecretKey},
+		HTTPClient:  &http.Client{Transport: transport},
+	}
+
+	if c.Retries > 0 {
+		bedrockOpts.RetryMaxAttempts = c.Retries
+	}
+	if c.Endpoint != "" {
+		bedrockOpts.BaseEndpoint = aws.String(c.Endpoint)
+	}
+
+	c.client = bedrockruntime.New(bedrockOpts)
+
+	return c.client, nil
+}
+
+func (c *Client) InvokeModel(
+	ctx context.Context,
+	params *bedrockruntime.InvokeModelInput,
+) (*bedrockruntime.InvokeModelOutput, error) {
+	var err error
+	var client *bedrockruntime.Client
+
+	client, err = c.Client()
+	if err != nil {
+		return nil, fmt.Errorf("[Client.InvokeModel] %w", err)
+	}
+
+	return client.InvokeModel(ctx, params)
+}
@@ -1,5 +1,7 @@
 package aigc
 
+import "net/http"
+
 type ModelId string
 
 type ModelOptions struct {
@@ -14,6 +16,17 @@ type ModelOptions struct {
 	Retries     int
 	RequestLog  func([]byte)
 	ResponseLog func([]byte)
+	// Beta features of the vendor API. E.G. "prompt-caching-2024-07-31" for
+	// the anthropic-beta header.
+	Betas []string
+	// Replaces the default transport of http clients, e.g. for recording and
+	// replaying http traffic in tests. Proxy is ignored if it is set.
+	HttpTransport http.RoundTripper
+	// Counts the tokens of prompts where the model needs the prompt size,
+	// e.g. to choose the context length of Ollama models. Default is
+	// Tokenizer.FastEstimate, use an exact tokenizer of the model for long
+	// prompts.
+	TokenCounter func(text string) int
 }
 
 /*
@@ -93,3 +106,21 @@ func WithResponseLog(responseLog func([]byte)) func(*ModelOptions) {
 		o.ResponseLog = responseLog
 	}
 }
+
+func WithBetas(betas ...string) func(*ModelOptions) {
+	return func(o *ModelOptions) {
+		o.Betas = append(o.Betas, betas...)
+	}
+}
+
+func WithHttpTransport(transport http.RoundTripper) func(*ModelOptions) {
+	return func(o *ModelOptions) {
+		o.HttpTransport = transport
+	}
+}
+
+func WithTokenCounter(counter func(text string) int) func(*ModelOptions) {
+	return func(o *ModelOptions) {
+		o.TokenCounter = counter
+	}
+}
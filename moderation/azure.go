@@ -0,0 +1,228 @@
+package moderation
+
+// Azure AI Content Safety documentation:
+// https://learn.microsoft.com/en-us/rest/api/contentsafety/text-operations/analyze-text
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	azureContentSafetyDefaultApiVersion = "2024-09-01"
+
+	azureDefaultSeverityThreshold = 4
+	azureMaxSeverity              = 7
+
+	// Maximum characters (UTF-16 code units) of a text:analyze request
+	azureMaxTextLength = 10000
+)
+
+type azureAnalyzeTextRequest struct {
+	// Maximum 10k characters
+	Text string `json:"text"`
+	// "FourSeverityLevels" (0, 2, 4, 6) | "EightSeverityLevels" (0-7)
+	OutputType string `json:"outputType"`
+}
+
+type azureAnalyzeTextResponse struct {
+	CategoriesAnalysis []struct {
+		// "Hate" | "SelfHarm" | "Sexual" | "Violence"
+		Category string `json:"category"`
+		Severity int    `json:"severity"`
+	} `json:"categoriesAnalysis"`
+}
+
+type azureContentSafetyModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	ApiVersion  string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func azureCategory(category string) Category {
+	switch category {
+	case "Hate":
+		return CategoryHate
+	case "SelfHarm":
+		return CategorySelfHarm
+	case "Sexual":
+		return CategorySexual
+	case "Violence":
+		return CategoryViolence
+	}
+	return Category(strings.ToLower(category))
+}
+
+func (r *azureAnalyzeTextResponse) dump(threshold int, result *Result) {
+	result.Flagged = false
+	result.Categories = nil
+	for _, analysis := range r.CategoriesAnalysis {
+		var score = CategoryScore{
+			Category: azureCategory(analysis.Category),
+			Score:    float64(analysis.Severity) / azureMaxSeverity,
+			Flagged:  analysis.Severity >= threshold,
+			Severity: analysis.Severity,
+		}
+		result.Flagged = result.Flagged || score.Flagged
+		result.Categories = append(result.Categories, score)
+	}
+}
+
+// merge keeps the highest severity of each category of the responses.
+func (r *azureAnalyzeTextResponse) merge(other *azureAnalyzeTextResponse) {
+next:
+	for _, analysis := range other.CategoriesAnalysis {
+		for i := range r.CategoriesAnalysis {
+			if r.CategoriesAnalysis[i].Category == analysis.Category {
+				r.CategoriesAnalysis[i].Severity = max(r.CategoriesAnalysis[i].Severity, analysis.Severity)
+				continue next
+			}
+		}
+		r.CategoriesAnalysis = append(r.CategoriesAnalysis, analysis)
+	}
+}
+
+// azureSplitText splits a text into parts of at most limit UTF-16 code units.
+// A part ends at the last space of the part if there is one in its second
+// half, otherwise words are cut.
+func azureSplitText(text string, limit int) []string {
+	var parts []string
+	for {
+		var length, end, space int
+		for i, r := range text {
+			// Runes outside the BMP are surrogate pairs
+			if r > 0xFFFF {
+				length += 2
+			} else {
+				length++
+			}
+			if length > limit {
+				end = i
+				break
+			}
+			if unicode.IsSpace(r) && length > limit/2 {
+				space = i + utf8.RuneLen(r)
+			}
+		}
+		if end == 0 {
+			return append(parts, text)
+		}
+		if space > 0 {
+			end = space
+		}
+		parts = append(parts, text[:end])
+		text = text[end:]
+	}
+}
+
+func (m *azureContentSafetyModel) getModelUrl() string {
+	var builder strings.Builder
+
+	builder.WriteString(m.Endpoint)
+	if !strings.HasSuffix(m.Endpoint, "/") {
+		builder.WriteByte('/')
+	}
+	builder.WriteString("contentsafety/text:analyze?api-version=")
+	if m.ApiVersion != "" {
+		builder.WriteString(m.ApiVersion)
+	} else {
+		builder.WriteString(azureContentSafetyDefaultApiVersion)
+	}
+	return builder.String()
+}
+
+func (m *azureContentSafetyModel) GetModelId() string {
+	return m.ModelId
+}
+
+// Moderate analyzes the inputs one by one, the API accepts 1 text of at most
+// 10k characters per request. Longer inputs are analyzed in parts, the
+// result has the highest severity of each category of the parts.
+func (m *azureContentSafetyModel) Moderate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var body []byte
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	if len(request.Inputs) == 0 {
+		return nil, errors.New("[azureContentSafetyModel.Moderate] inputs are empty")
+	}
+
+	var threshold = request.SeverityThreshold
+	if threshold <= 0 {
+		threshold = azureDefaultSeverityThreshold
+	}
+
+	var url = m.getModelUrl()
+	var header = http.Header{}
+	header.Set("Ocp-Apim-Subscription-Key", m.ApiKey)
+
+	var response = &ModelResponse{ModelId: m.ModelId, Results: make([]Result, len(request.Inputs))}
+	for i, input := range request.Inputs {
+		var analysis azureAnalyzeTextResponse
+		for _, text := range azureSplitText(input, azureMaxTextLength) {
+			buffer.Reset()
+			err = aigc.EncodeJson(buffer, &azureAnalyzeTextRequest{Text: text, OutputType: "EightSeverityLevels"})
+			if err != nil {
+				return nil, fmt.Errorf("[azureContentSafetyModel.Moderate] %w", err)
+			}
+
+			body, err = m.client.PostJson(ctx, url, header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+			if err != nil {
+				return nil, fmt.Errorf("[azureContentSafetyModel.Moderate] %w", err)
+			}
+
+			var part azureAnalyzeTextResponse
+			err = json.Unmarshal(body, &part)
+			if err != nil {
+				return nil, fmt.Errorf("[azureContentSafetyModel.Moderate] %w", err)
+			}
+			analysis.merge(&part)
+		}
+		analysis.dump(threshold, &response.Results[i])
+	}
+	return response, nil
+}
+
+func newAzureContentSafetyModel(modelId string, opts *aigc.ModelOptions) (*azureContentSafetyModel, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("azure endpoint is required")
+	}
+	if opts.ApiKey == "" {
+		return nil, errors.New("azure api key is required")
+	}
+
+	var model = &azureContentSafetyModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		ApiVersion:  opts.ApiVersion,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,215 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+type Action int
+
+const (
+	// Flagged requests and responses are refused with FlaggedError
+	ActionBlock Action = 0
+	// Results are added to ModelResponse.ContentFilters, nothing is refused
+	ActionAnnotate Action = 1
+)
+
+type Options struct {
+	Action Action
+	// Moderate the user messages of the request, default is true
+	CheckInput bool
+	// Moderate the messages of the response, default is true
+	CheckOutput bool
+	// Passed to the moderation model, see ModelRequest.SeverityThreshold
+	SeverityThreshold int
+}
+
+type OptionFunc func(*Options)
+
+func WithAction(action Action) func(*Options) {
+	return func(o *Options) {
+		o.Action = action
+	}
+}
+
+func WithCheckInput(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.CheckInput = enabled
+	}
+}
+
+func WithCheckOutput(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.CheckOutput = enabled
+	}
+}
+
+func WithSeverityThreshold(threshold int) func(*Options) {
+	return func(o *Options) {
+		o.SeverityThreshold = threshold
+	}
+}
+
+// FlaggedError is returned by moderated chat models if the prompt or the
+// completion is flagged.
+type FlaggedError struct {
+	Source chat.ContentFilterSource
+	Result Result
+}
+
+func (e *FlaggedError) Error() string {
+	var categories = e.Result.FlaggedCategories()
+	var names = make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = string(category)
+	}
+	return fmt.Sprintf("%s is flagged: %s", e.Source, strings.Join(names, ", "))
+}
+
+// chatModel is a chat.Model wrapper moderates the requests and responses.
+type chatModel struct {
+	Model     chat.Model
+	Moderator Model
+	Options   Options
+}
+
+// messageText joins the text blocks of the message.
+func messageText(message *chat.Message) string {
+	var texts []string
+	for _, content := range message.Contents {
+		if content.Type == chat.ContentTypeText && content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// inputs returns the texts of all user messages and their indexes. The
+// history is owned by the caller, so earlier messages are moderated again
+// instead of being trusted by their positions.
+func inputs(request *chat.ModelRequest) ([]string, []int) {
+	var texts []string
+	var indexes []int
+	for i := range request.Messages {
+		if request.Messages[i].Role != chat.RoleUser {
+			continue
+		}
+		if text := messageText(&request.Messages[i]); text != "" {
+			texts = append(texts, text)
+			indexes = append(indexes, i)
+		}
+	}
+	return texts, indexes
+}
+
+func contentFilter(source chat.ContentFilterSource, promptIndex int, result *Result) chat.ContentFilter {
+	var filter = chat.ContentFilter{Source: source, PromptIndex: promptIndex, Filtered: result.Flagged}
+	for _, score := range result.Categories {
+		filter.Categories = append(filter.Categories, chat.ContentFilterCategory{
+			Category: string(score.Category),
+			Filtered: score.Flagged,
+			Score:    score.Score,
+		})
+	}
+	return filter
+}
+
+func (m *chatModel) moderate(ctx context.Context, texts []string) (*ModelResponse, error) {
+	var response, err = m.Moderator.Moderate(ctx, &ModelRequest{
+		Inputs:            texts,
+		SeverityThreshold: m.Options.SeverityThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results) != len(texts) {
+		return nil, fmt.Errorf("%d results for %d inputs", len(response.Results), len(texts))
+	}
+	return response, nil
+}
+
+func (m *chatModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *chatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	var err error
+	var filters []chat.ContentFilter
+	var moderation *ModelResponse
+	var response *chat.ModelResponse
+
+	if m.Options.CheckInput {
+		var texts, indexes = inputs(request)
+		if len(texts) > 0 {
+			moderation, err = m.moderate(ctx, texts)
+			if err != nil {
+				return nil, fmt.Errorf("[moderation.chatModel.Complete] %w", err)
+			}
+			for i := range moderation.Results {
+				var result = &moderation.Results[i]
+				if result.Flagged && m.Options.Action == ActionBlock {
+					return nil, &FlaggedError{Source: chat.ContentFilterPrompt, Result: *result}
+				}
+				filters = append(filters, contentFilter(chat.ContentFilterPrompt, indexes[i], result))
+			}
+		}
+	}
+
+	response, err = m.Model.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Options.CheckOutput {
+		var texts []string
+		for i := range response.Messages {
+			if text := messageText(&response.Messages[i]); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) > 0 {
+			moderation, err = m.moderate(ctx, []string{strings.Join(texts, "\n")})
+			if err != nil {
+				return nil, fmt.Errorf("[moderation.chatModel.Complete] %w", err)
+			}
+			var result = &moderation.Results[0]
+			if result.Flagged && m.Options.Action == ActionBlock {
+				return nil, &FlaggedError{Source: chat.ContentFilterCompletion, Result: *result}
+			}
+			filters = append(filters, contentFilter(chat.ContentFilterCompletion, 0, result))
+		}
+	}
+
+	response.ContentFilters = append(response.ContentFilters, filters...)
+	return response, nil
+}
+
+// NewChatModel wraps a chat.Model, the user messages of the request and the
+// completion are classified by the moderator. By default, flagged requests
+// are not sent and flagged completions are dropped, both return FlaggedError.
+// With ActionAnnotate, the results are added to the ContentFilters of the
+// response instead.
+func NewChatModel(model chat.Model, moderator Model, options ...OptionFunc) (chat.Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	if moderator == nil {
+		return nil, errors.New("moderator is required")
+	}
+
+	var m = &chatModel{
+		Model:     model,
+		Moderator: moderator,
+		Options:   Options{CheckInput: true, CheckOutput: true},
+	}
+	for _, fn := range options {
+		fn(&m.Options)
+	}
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeywordRule flags the category if an input contains any keyword (case
+// insensitive) or matches any regular expression.
+type KeywordRule struct {
+	Category Category
+	Keywords []string
+	Patterns []string
+}
+
+type keywordRule struct {
+	category Category
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// KeywordModel is a local classifier by keywords and regular expressions, it
+// needs no vendor and scores 1 for a matched category.
+type KeywordModel struct {
+	rules []keywordRule
+}
+
+func (m *KeywordModel) GetModelId() string {
+	return "keyword"
+}
+
+func (m *KeywordModel) classify(input string, result *Result) {
+	var lower = strings.ToLower(input)
+
+	result.Flagged = false
+	result.Categories = make([]CategoryScore, 0, len(m.rules))
+	for _, rule := range m.rules {
+		var matched = false
+		for _, keyword := range rule.keywords {
+			if strings.Contains(lower, keyword) {
+				matched = true
+				break
+			}
+		}
+		for i := 0; !matched && i < len(rule.patterns); i++ {
+			matched = rule.patterns[i].MatchString(input)
+		}
+
+		var score = CategoryScore{Category: rule.category, Flagged: matched}
+		if matched {
+			score.Score = 1
+		}
+		result.Flagged = result.Flagged || matched
+		result.Categories = append(result.Categories, score)
+	}
+}
+
+func (m *KeywordModel) Moderate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	if len(request.Inputs) == 0 {
+		return nil, errors.New("[KeywordModel.Moderate] inputs are empty")
+	}
+	var response = &ModelResponse{ModelId: m.GetModelId(), Results: make([]Result, len(request.Inputs))}
+	for i, input := range request.Inputs {
+		m.classify(input, &response.Results[i])
+	}
+	return response, nil
+}
+
+// NewKeywordModel compiles the rules, rules of the same category are merged.
+func NewKeywordModel(rules ...KeywordRule) (*KeywordModel, error) {
+	var model = &KeywordModel{}
+	var index = make(map[Category]int)
+
+	for _, rule := range rules {
+		if rule.Category == "" {
+			return nil, errors.New("keyword rule category is required")
+		}
+		var i, ok = index[rule.Category]
+		if !ok {
+			i = len(model.rules)
+			index[rule.Category] = i
+			model.rules = append(model.rules, keywordRule{category: rule.Category})
+		}
+		for _, keyword := range rule.Keywords {
+			if keyword != "" {
+				model.rules[i].keywords = append(model.rules[i].keywords, strings.ToLower(keyword))
+			}
+		}
+		for _, pattern := range rule.Patterns {
+			var re, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("keyword rule %s: %w", rule.Category, err)
+			}
+			model.rules[i].patterns = append(model.rules[i].patterns, re)
+		}
+	}
+	if len(model.rules) == 0 {
+		return nil, errors.New("keyword rules are required")
+	}
+	return model, nil
+}
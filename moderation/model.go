@@ -0,0 +1,45 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// Model classifies texts into harmful content categories.
+type Model interface {
+	GetModelId() string
+	Moderate(ctx context.Context, request *ModelRequest) (*ModelResponse, error)
+}
+
+func NewModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (Model, error) {
+	var opts aigc.ModelOptions
+
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	switch modelId {
+	case Models.OpenAIOmniModerationLatest, Models.OpenAITextModerationLatest:
+		if opts.VendorId == aigc.Vendors.OpenAI {
+			return newOpenAIModerationModel(string(modelId), &opts)
+		}
+		if opts.VendorId == "" {
+			opts.VendorId = aigc.Vendors.OpenAI
+			return newOpenAIModerationModel(string(modelId), &opts)
+		}
+	case Models.AzureContentSafety:
+		if opts.VendorId == aigc.Vendors.Microsoft {
+			return newAzureContentSafetyModel(string(modelId), &opts)
+		}
+		if opts.VendorId == "" {
+			opts.VendorId = aigc.Vendors.Microsoft
+			return newAzureContentSafetyModel(string(modelId), &opts)
+		}
+	}
+
+	if opts.VendorId != "" {
+		return nil, fmt.Errorf("model can not be created, vendor:%s, model: %s", opts.VendorId, modelId)
+	}
+	return nil, fmt.Errorf("model can not be created: %s", modelId)
+}
@@ -0,0 +1,21 @@
+package moderation
+
+import "github.com/Pooh-Mucho/go-aigc"
+
+var Models = struct {
+	// OpenAI moderation models
+	// https://platform.openai.com/docs/guides/moderation
+	OpenAIOmniModerationLatest aigc.ModelId
+	OpenAITextModerationLatest aigc.ModelId
+
+	// Azure AI Content Safety text analysis
+	// https://learn.microsoft.com/en-us/azure/ai-services/content-safety/
+	AzureContentSafety aigc.ModelId
+}{
+	// OpenAI moderation models
+	OpenAIOmniModerationLatest: "omni-moderation-latest",
+	OpenAITextModerationLatest: "text-moderation-latest",
+
+	// Azure AI Content Safety text analysis
+	AzureContentSafety: "azure-content-safety",
+}
@@ -0,0 +1,159 @@
+package moderation
+
+// OpenAI documentation:
+// https://platform.openai.com/docs/api-reference/moderations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const openaiModerationEndpoint = "https://api.openai.com/v1/moderations"
+
+type openaiModerationRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openaiModerationResult struct {
+	Flagged bool `json:"flagged"`
+	// Whether the category is flagged, e.g. "hate": false
+	Categories map[string]bool `json:"categories"`
+	// Confidence of the category, e.g. "hate": 0.0012
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+type openaiModerationResponse struct {
+	Id      string                   `json:"id"`
+	Model   string                   `json:"model"`
+	Results []openaiModerationResult `json:"results"`
+}
+
+type openaiModerationModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+func (r *openaiModerationRequest) load(modelId string, request *ModelRequest) error {
+	if len(request.Inputs) == 0 {
+		return errors.New("[openaiModerationRequest.load] inputs are empty")
+	}
+	r.Model = modelId
+	r.Input = request.Inputs
+	return nil
+}
+
+func (r *openaiModerationResponse) dump(response *ModelResponse) error {
+	response.Id = r.Id
+	response.ModelId = r.Model
+	response.Results = make([]Result, len(r.Results))
+	for i, result := range r.Results {
+		var categories = make([]string, 0, len(result.CategoryScores))
+		for category := range result.CategoryScores {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+
+		response.Results[i].Flagged = result.Flagged
+		for _, category := range categories {
+			response.Results[i].Categories = append(response.Results[i].Categories, CategoryScore{
+				Category: Category(category),
+				Score:    result.CategoryScores[category],
+				Flagged:  result.Categories[category],
+			})
+		}
+	}
+	return nil
+}
+
+func (m *openaiModerationModel) getModelUrl() string {
+	if m.Endpoint == "" {
+		return openaiModerationEndpoint
+	}
+	return m.Endpoint
+}
+
+func (m *openaiModerationModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *openaiModerationModel) Moderate(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var body []byte
+	var openaiRequest openaiModerationRequest
+	var openaiResponse openaiModerationResponse
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = openaiRequest.load(m.ModelId, request)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiModerationModel.Moderate] %w", err)
+	}
+	err = aigc.EncodeJson(buffer, &openaiRequest)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiModerationModel.Moderate] %w", err)
+	}
+
+	var header = http.Header{}
+	header.Set("Authorization", "Bearer "+m.ApiKey)
+	body, err = m.client.PostJson(ctx, m.getModelUrl(), header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiModerationModel.Moderate] %w", err)
+	}
+
+	err = json.Unmarshal(body, &openaiResponse)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiModerationModel.Moderate] %w", err)
+	}
+	if len(openaiResponse.Results) != len(request.Inputs) {
+		return nil, fmt.Errorf("[openaiModerationModel.Moderate] %d results for %d inputs",
+			len(openaiResponse.Results), len(request.Inputs))
+	}
+
+	var response = &ModelResponse{}
+	err = openaiResponse.dump(response)
+	if err != nil {
+		return nil, fmt.Errorf("[openaiModerationModel.Moderate] %w", err)
+	}
+	return response, nil
+}
+
+func newOpenAIModerationModel(modelId string, opts *aigc.ModelOptions) (*openaiModerationModel, error) {
+	if opts.ApiKey == "" {
+		return nil, errors.New("openai api key is required")
+	}
+	if opts.ApiVersion != "" {
+		return nil, errors.New("openai api version is not supported")
+	}
+
+	var model = &openaiModerationModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,10 @@
+package moderation
+
+type ModelRequest struct {
+	// Texts to classify, each text has a result in the response
+	Inputs []string
+	// Azure Content Safety reports severities from 0 to 7, a category is
+	// flagged if its severity is at or above the threshold. Default is 4
+	// (medium).
+	SeverityThreshold int
+}
@@ -0,0 +1,76 @@
+package moderation
+
+type Category string
+
+// Categories of OpenAI moderation. Azure Content Safety reports Hate,
+// SelfHarm, Sexual and Violence. The keyword classifier reports the
+// categories of its rules, any name can be used.
+const (
+	CategoryHarassment            Category = "harassment"
+	CategoryHarassmentThreatening Category = "harassment/threatening"
+	CategoryHate                  Category = "hate"
+	CategoryHateThreatening       Category = "hate/threatening"
+	CategoryIllicit               Category = "illicit"
+	CategoryIllicitViolent        Category = "illicit/violent"
+	CategorySelfHarm              Category = "self-harm"
+	CategorySelfHarmIntent        Category = "self-harm/intent"
+	CategorySelfHarmInstructions  Category = "self-harm/instructions"
+	CategorySexual                Category = "sexual"
+	CategorySexualMinors          Category = "sexual/minors"
+	CategoryViolence              Category = "violence"
+	CategoryViolenceGraphic       Category = "violence/graphic"
+)
+
+type CategoryScore struct {
+	Category Category
+	// Confidence from 0 to 1. Azure severity is scaled by 7, the keyword
+	// classifier scores 1 for a match.
+	Score   float64
+	Flagged bool
+	// Azure severity from 0 to 7, zero for other models
+	Severity int
+}
+
+// Result is the classification of an input text.
+type Result struct {
+	Flagged    bool
+	Categories []CategoryScore
+}
+
+type ModelResponse struct {
+	Id      string
+	ModelId string
+	// Results in the order of the inputs
+	Results []Result
+}
+
+// Category returns the score of the category.
+func (r *Result) Category(category Category) (CategoryScore, bool) {
+	for _, score := range r.Categories {
+		if score.Category == category {
+			return score, true
+		}
+	}
+	return CategoryScore{}, false
+}
+
+// FlaggedCategories returns the flagged categories.
+func (r *Result) FlaggedCategories() []Category {
+	var categories []Category
+	for _, score := range r.Categories {
+		if score.Flagged {
+			categories = append(categories, score.Category)
+		}
+	}
+	return categories
+}
+
+// Flagged reports whether any result is flagged.
+func (r *ModelResponse) Flagged() bool {
+	for i := range r.Results {
+		if r.Results[i].Flagged {
+			return true
+		}
+	}
+	return false
+}
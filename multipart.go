@@ -0,0 +1,62 @@
+package aigc
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// MultipartField is a field of a multipart/form-data request body, it is a
+// file if FileName is set.
+type MultipartField struct {
+	Name  string
+	Value string
+
+	// For file fields
+	FileName    string
+	ContentType string // default is "application/octet-stream"
+	Data        []byte
+}
+
+var multipartQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// EncodeMultipart writes the fields to the buffer as a multipart/form-data
+// body, returns the Content-Type header value with the boundary.
+func EncodeMultipart(buffer *bytes.Buffer, fields ...MultipartField) (string, error) {
+	var err error
+	var writer = multipart.NewWriter(buffer)
+
+	for _, field := range fields {
+		if field.FileName == "" {
+			err = writer.WriteField(field.Name, field.Value)
+			if err != nil {
+				return "", fmt.Errorf("[EncodeMultipart] %w", err)
+			}
+			continue
+		}
+
+		var header = make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			multipartQuoteEscaper.Replace(field.Name), multipartQuoteEscaper.Replace(field.FileName)))
+		if field.ContentType != "" {
+			header.Set("Content-Type", field.ContentType)
+		} else {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return "", fmt.Errorf("[EncodeMultipart] %w", err)
+		}
+		if _, err = part.Write(field.Data); err != nil {
+			return "", fmt.Errorf("[EncodeMultipart] %w", err)
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		return "", fmt.Errorf("[EncodeMultipart] %w", err)
+	}
+	return writer.FormDataContentType(), nil
+}
@@ -0,0 +1,224 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+// ErrBudgetExceeded is returned by budget guarded models when the tenant has
+// reached its spend limit.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context carries the tenant id, used by budget guarded
+// models to charge the requests.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant id set by WithTenant, or empty string.
+func TenantFromContext(ctx context.Context) string {
+	var tenant, _ = ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// Budget tracks the spend of tenants. A limit <= 0 means unlimited.
+//
+// The limit is checked before a request is sent, so the last request may
+// exceed the limit.
+type Budget struct {
+	Catalog      Catalog
+	DefaultLimit float64
+
+	lock   sync.Mutex
+	limits map[string]float64
+	spent  map[string]float64
+}
+
+func (b *Budget) SetLimit(tenant string, limit float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.limits[tenant] = limit
+}
+
+func (b *Budget) Limit(tenant string) float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.limit(tenant)
+}
+
+func (b *Budget) limit(tenant string) float64 {
+	var limit, ok = b.limits[tenant]
+	if ok {
+		return limit
+	}
+	return b.DefaultLimit
+}
+
+func (b *Budget) Spent(tenant string) float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.spent[tenant]
+}
+
+// Remaining returns the remaining budget of the tenant, or -1 if unlimited.
+func (b *Budget) Remaining(tenant string) float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var limit = b.limit(tenant)
+	if limit <= 0 {
+		return -1
+	}
+	if b.spent[tenant] >= limit {
+		return 0
+	}
+	return limit - b.spent[tenant]
+}
+
+// Check returns ErrBudgetExceeded if the tenant has reached its limit.
+func (b *Budget) Check(tenant string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var limit = b.limit(tenant)
+	if limit > 0 && b.spent[tenant] >= limit {
+		return fmt.Errorf("%w: tenant '%s' spent %.6f USD, limit %.6f USD",
+			ErrBudgetExceeded, tenant, b.spent[tenant], limit)
+	}
+	return nil
+}
+
+func (b *Budget) Charge(tenant string, cost Cost) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.spent[tenant] += cost.Total()
+}
+
+// Reset clears the spend of the tenant, e.g. at the start of a billing period.
+func (b *Budget) Reset(tenant string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.spent, tenant)
+}
+
+func NewBudget(catalog Catalog, defaultLimit float64) *Budget {
+	if catalog == nil {
+		catalog = DefaultCatalog
+	}
+	return &Budget{
+		Catalog:      catalog,
+		DefaultLimit: defaultLimit,
+		limits:       make(map[string]float64),
+		spent:        make(map[string]float64),
+	}
+}
+
+// budgetChatModel is a chat.Model wrapper charges the tenant of the context.
+type budgetChatModel struct {
+	Model  chat.Model
+	Budget *Budget
+}
+
+func (m *budgetChatModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *budgetChatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	var tenant = TenantFromContext(ctx)
+
+	var err = m.Budget.Check(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("[budgetChatModel.Complete] %w", err)
+	}
+
+	response, err := m.Model.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cost, err := m.Budget.Catalog.ResponseCost(m.Model.GetModelId(), response)
+	if err != nil {
+		return nil, fmt.Errorf("[budgetChatModel.Complete] %w", err)
+	}
+	m.Budget.Charge(tenant, cost)
+	return response, nil
+}
+
+// NewBudgetChatModel wraps a chat.Model, requests are refused with
+// ErrBudgetExceeded once the tenant (see WithTenant) reaches its limit.
+func NewBudgetChatModel(model chat.Model, budget *Budget) (chat.Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	if budget == nil {
+		return nil, errors.New("budget is required")
+	}
+	if _, ok := budget.Catalog.Lookup(model.GetModelId()); !ok {
+		return nil, fmt.Errorf("unknown model price: %s", model.GetModelId())
+	}
+	return &budgetChatModel{Model: model, Budget: budget}, nil
+}
+
+// budgetEmbeddingModel is an embedding.Model wrapper charges the tenant of the
+// context.
+type budgetEmbeddingModel struct {
+	Model  embedding.Model
+	Budget *Budget
+}
+
+func (m *budgetEmbeddingModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *budgetEmbeddingModel) GetDistanceType() embedding.VectorDistanceType {
+	return m.Model.GetDistanceType()
+}
+
+func (m *budgetEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	return m.Model.Distance(vector1, vector2)
+}
+
+func (m *budgetEmbeddingModel) Embedding(ctx context.Context, request *embedding.ModelRequest) (*embedding.ModelResponse, error) {
+	var tenant = TenantFromContext(ctx)
+
+	var err = m.Budget.Check(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("[budgetEmbeddingModel.Embedding] %w", err)
+	}
+
+	response, err := m.Model.Embedding(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cost, err := m.Budget.Catalog.EmbeddingCost(m.Model.GetModelId(), response)
+	if err != nil {
+		return nil, fmt.Errorf("[budgetEmbeddingModel.Embedding] %w", err)
+	}
+	m.Budget.Charge(tenant, cost)
+	return response, nil
+}
+
+// NewBudgetEmbeddingModel wraps an embedding.Model, requests are refused with
+// ErrBudgetExceeded once the tenant (see WithTenant) reaches its limit.
+func NewBudgetEmbeddingModel(model embedding.Model, budget *Budget) (embedding.Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	if budget == nil {
+		return nil, errors.New("budget is required")
+	}
+	if _, ok := budget.Catalog.Lookup(model.GetModelId()); !ok {
+		return nil, fmt.Errorf("unknown model price: %s", model.GetModelId())
+	}
+	return &budgetEmbeddingModel{Model: model, Budget: budget}, nil
+}
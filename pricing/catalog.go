@@ -0,0 +1,159 @@
+package pricing
+
+import (
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+// Price is the price of a model in USD per 1 million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+	// Price of input tokens served from the vendor's prompt cache. Zero means
+	// the model has no discount for cached tokens, Input price is used.
+	CachedInput float64
+	// Price of input tokens written to the vendor's prompt cache, Anthropic
+	// charges 25% more than Input. Zero means Input price is used.
+	CacheCreationInput float64
+}
+
+// Catalog maps model ids to prices.
+type Catalog map[aigc.ModelId]Price
+
+// DefaultCatalog contains list prices of every model in chat.Models and
+// embedding.Models, as published by the vendors in September 2024. Qwen
+// prices are converted from CNY. Self-hosted models (BAAI, Nomic, Mixedbread)
+// are free.
+//
+// Prices change frequently, copy and override the catalog if needed.
+var DefaultCatalog = Catalog{
+	// OpenAI GPT models
+	chat.Models.OpenAIGpt4oMini:                  {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+	chat.Models.OpenAIGpt4oMini_20240718:         {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+	chat.Models.OpenAIGpt4o:                      {Input: 2.50, Output: 10.00, CachedInput: 1.25},
+	chat.Models.OpenAIGpt4o_20240513:             {Input: 5.00, Output: 15.00},
+	chat.Models.OpenAIGpt4o_20240806:             {Input: 2.50, Output: 10.00, CachedInput: 1.25},
+	chat.Models.OpenAIGpt4Turbo:                  {Input: 10.00, Output: 30.00},
+	chat.Models.OpenAIGpt4Turbo_20240409:         {Input: 10.00, Output: 30.00},
+	chat.Models.OpenAIGpt4Turbo_Preview_20240125: {Input: 10.00, Output: 30.00},
+	chat.Models.OpenAIGpt4Turbo_Preview_20231106: {Input: 10.00, Output: 30.00},
+	chat.Models.OpenAIGpt4:                       {Input: 30.00, Output: 60.00},
+	chat.Models.OpenAIGpt4_20230613:              {Input: 30.00, Output: 60.00},
+	chat.Models.OpenAIGpt4_32k_20230613:          {Input: 60.00, Output: 120.00},
+	chat.Models.OpenAIGpt4_20230314:              {Input: 30.00, Output: 60.00},
+	chat.Models.OpenAIGpt35_Turbo:                {Input: 0.50, Output: 1.50},
+	chat.Models.OpenAIGpt35_Turbo_20240125:       {Input: 0.50, Output: 1.50},
+	chat.Models.OpenAIGpt35_Turbo_20231106:       {Input: 1.00, Output: 2.00},
+	chat.Models.OpenAIGpt35_Turbo_16k_20230613:   {Input: 3.00, Output: 4.00},
+	chat.Models.OpenAIGpt35_Turbo_20230613:       {Input: 1.50, Output: 2.00},
+
+	// OpenAI GPT audio models, text token prices. Audio tokens cost 100 USD
+	// input and 200 USD output, they are not separated in the token usage
+	chat.Models.OpenAIGpt4oAudioPreview:          {Input: 2.50, Output: 10.00},
+	chat.Models.OpenAIGpt4oAudioPreview_20241001: {Input: 2.50, Output: 10.00},
+
+	// OpenAI o-series reasoning models, reasoning tokens are output tokens
+	chat.Models.OpenAIO1Preview:          {Input: 15.00, Output: 60.00, CachedInput: 7.50},
+	chat.Models.OpenAIO1Preview_20240912: {Input: 15.00, Output: 60.00, CachedInput: 7.50},
+	chat.Models.OpenAIO1Mini:             {Input: 3.00, Output: 12.00, CachedInput: 1.50},
+	chat.Models.OpenAIO1Mini_20240912:    {Input: 3.00, Output: 12.00, CachedInput: 1.50},
+
+	// Anthropic Claude models
+	chat.Models.AnthropicClaude35Sonnet_20240620: {Input: 3.00, Output: 15.00, CachedInput: 0.30, CacheCreationInput: 3.75},
+	chat.Models.AnthropicClaude3Opus_20240229:    {Input: 15.00, Output: 75.00, CachedInput: 1.50, CacheCreationInput: 18.75},
+	chat.Models.AnthropicClaude3Sonnet_20240229:  {Input: 3.00, Output: 15.00},
+	chat.Models.AnthropicClaude3Haiku_20240307:   {Input: 0.25, Output: 1.25, CachedInput: 0.03, CacheCreationInput: 0.30},
+
+	// Bedrock Anthropic Claude models
+	chat.Models.BedrockAnthropicClaude35Sonnet_20240620: {Input: 3.00, Output: 15.00},
+	chat.Models.BedrockAnthropicClaude3Opus_20240229:    {Input: 15.00, Output: 75.00},
+	chat.Models.BedrockAnthropicClaude3Sonnet_20240229:  {Input: 3.00, Output: 15.00},
+	chat.Models.BedrockAnthropicClaude3Haiku_20240307:   {Input: 0.25, Output: 1.25},
+	chat.Models.BedrockAnthropicInstant:                 {Input: 0.80, Output: 2.40},
+
+	// Bedrock Llama3 models
+	chat.Models.BedrockLlama31_405B: {Input: 5.32, Output: 16.00},
+	chat.Models.BedrockLlama31_70B:  {Input: 0.99, Output: 0.99},
+	chat.Models.BedrockLlama31_8B:   {Input: 0.22, Output: 0.22},
+
+	// Qwen models on Aliyun DashScope
+	chat.Models.QwenMax:            {Input: 2.80, Output: 8.40},
+	chat.Models.QwenMax_20240428:   {Input: 5.60, Output: 16.80},
+	chat.Models.QwenMax_20240403:   {Input: 5.60, Output: 16.80},
+	chat.Models.QwenMax_20240107:   {Input: 5.60, Output: 16.80},
+	chat.Models.QwenMaxLongContext: {Input: 5.60, Output: 16.80},
+	chat.Models.QwenPlus:           {Input: 0.56, Output: 1.68},
+	chat.Models.QwenPlus_20240806:  {Input: 0.56, Output: 1.68},
+	chat.Models.QwenPlus_20240723:  {Input: 0.56, Output: 1.68},
+	chat.Models.QwenPlus_20240624:  {Input: 0.56, Output: 1.68},
+	chat.Models.QwenPlus_20240206:  {Input: 0.56, Output: 1.68},
+	chat.Models.QwenTurbo:          {Input: 0.042, Output: 0.084},
+	chat.Models.QwenTurbo_20240624: {Input: 0.28, Output: 0.84},
+	chat.Models.QwenTurbo_20240206: {Input: 0.28, Output: 0.84},
+	chat.Models.QwenVLMax:          {Input: 2.80, Output: 2.80},
+	chat.Models.QwenVLMax_20240809: {Input: 2.80, Output: 2.80},
+	chat.Models.Qwen2_72B_Instruct: {Input: 0.70, Output: 1.40},
+	chat.Models.Qwen2_57B_Instruct: {Input: 0.49, Output: 0.98},
+	chat.Models.Qwen2_7B_Instruct:  {Input: 0.14, Output: 0.28},
+	chat.Models.QwQ_32B_Preview:    {Input: 0.49, Output: 0.98},
+
+	// OpenAI Embedding models
+	embedding.Models.OpenAITextEmbeddingAda_002: {Input: 0.10},
+	embedding.Models.OpenAITextEmbedding3Small:  {Input: 0.02},
+	embedding.Models.OpenAITextEmbedding3Large:  {Input: 0.13},
+
+	// Bedrock embedding models
+	embedding.Models.AmazonTitanEmbedTextV2:    {Input: 0.02},
+	embedding.Models.CohereEmbedEnglishV3:      {Input: 0.10},
+	embedding.Models.CohereEmbedMultilingualV3: {Input: 0.10},
+
+	// Hosted embedding models, Gemini embeddings are free of charge
+	embedding.Models.DashScopeTextEmbeddingV3: {Input: 0.098},
+	embedding.Models.GoogleTextEmbedding004:   {},
+	embedding.Models.Voyage3:                  {Input: 0.06},
+	embedding.Models.Voyage3Lite:              {Input: 0.02},
+	embedding.Models.Voyage3Large:             {Input: 0.18},
+	embedding.Models.VoyageCode3:              {Input: 0.18},
+	embedding.Models.JinaEmbeddingsV3:         {Input: 0.02},
+
+	// Self-hosted embedding models
+	embedding.Models.BaaiBgeM3:           {},
+	embedding.Models.BaaiBgeRerankerV2M3: {},
+	embedding.Models.NomicEmbedText:      {},
+	embedding.Models.NomicEmbedTextV1:    {},
+	embedding.Models.NomicEmbedTextV15:   {},
+	embedding.Models.MxbaiEmbedLarge:     {},
+	embedding.Models.MxbaiEmbedLargeV1:   {},
+}
+
+// Lookup returns the price of a model. Model ids returned by Model.GetModelId
+// are accepted, e.g. Azure deployment ids such as "gpt-35-turbo".
+func (c Catalog) Lookup(modelId string) (Price, bool) {
+	var price, ok = c[aigc.ModelId(modelId)]
+	if ok {
+		return price, true
+	}
+	// Azure API model id is different from OpenAI model id, see newAzureGptModel
+	if strings.Contains(modelId, "-35-") {
+		price, ok = c[aigc.ModelId(strings.Replace(modelId, "-35-", "-3.5-", 1))]
+		if ok {
+			return price, true
+		}
+	}
+	return Price{}, false
+}
+
+// Copy returns a copy of the catalog, so it can be modified without changing
+// the original one.
+func (c Catalog) Copy() Catalog {
+	var z = make(Catalog, len(c))
+	for k, v := range c {
+		z[k] = v
+	}
+	return z
+}
@@ -0,0 +1,110 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+const tokensPerPriceUnit = 1_000_000
+
+// Cost is an amount of money in USD.
+type Cost struct {
+	// Cost of input tokens not served from the prompt cache
+	Input float64
+	// Cost of input tokens served from the prompt cache
+	CachedInput float64
+	// Cost of input tokens written to the prompt cache
+	CacheCreationInput float64
+	Output             float64
+}
+
+func (c Cost) Total() float64 {
+	return c.Input + c.CachedInput + c.CacheCreationInput + c.Output
+}
+
+func (c Cost) Add(other Cost) Cost {
+	return Cost{
+		Input:              c.Input + other.Input,
+		CachedInput:        c.CachedInput + other.CachedInput,
+		CacheCreationInput: c.CacheCreationInput + other.CacheCreationInput,
+		Output:             c.Output + other.Output,
+	}
+}
+
+// UsageCost returns the cost of the token usage of a model.
+func (c Catalog) UsageCost(modelId string, usage chat.TokenUsage) (Cost, error) {
+	var price, ok = c.Lookup(modelId)
+	if !ok {
+		return Cost{}, fmt.Errorf("[Catalog.UsageCost] unknown model price: %s", modelId)
+	}
+	return price.cost(usage), nil
+}
+
+// ResponseCost returns the cost of a chat response. Responses served from a
+// response cache cost nothing.
+func (c Catalog) ResponseCost(modelId string, response *chat.ModelResponse) (Cost, error) {
+	if response == nil || response.Cache.Hit {
+		return Cost{}, nil
+	}
+	var cost, err = c.UsageCost(modelId, response.Usage)
+	if err != nil {
+		return Cost{}, fmt.Errorf("[Catalog.ResponseCost] %w", err)
+	}
+	return cost, nil
+}
+
+// EmbeddingCost returns the cost of an embedding response. Responses served
+// from a response cache cost nothing.
+func (c Catalog) EmbeddingCost(modelId string, response *embedding.ModelResponse) (Cost, error) {
+	if response == nil || response.Cache.Hit {
+		return Cost{}, nil
+	}
+	var cost, err = c.UsageCost(modelId, chat.TokenUsage{InputTokens: response.Tokens})
+	if err != nil {
+		return Cost{}, fmt.Errorf("[Catalog.EmbeddingCost] %w", err)
+	}
+	return cost, nil
+}
+
+// ToolExecutorCost returns the total cost of all roundtrips executed by the
+// executor.
+func (c Catalog) ToolExecutorCost(executor *chat.ToolExecutor) (Cost, error) {
+	if executor.Model == nil {
+		return Cost{}, errors.New("[Catalog.ToolExecutorCost] Model is not set")
+	}
+
+	var total Cost
+	var modelId = executor.Model.GetModelId()
+	for i := 0; i < executor.Roundtrips(); i++ {
+		var cost, err = c.ResponseCost(modelId, executor.GetRoundtrip(i).Response)
+		if err != nil {
+			return Cost{}, fmt.Errorf("[Catalog.ToolExecutorCost] %w", err)
+		}
+		total = total.Add(cost)
+	}
+	return total, nil
+}
+
+func (p Price) cost(usage chat.TokenUsage) Cost {
+	var cached = min(usage.CachedInputTokens, usage.InputTokens)
+	var created = min(usage.CacheCreationInputTokens, usage.InputTokens-cached)
+	var cachedPrice = p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	var creationPrice = p.CacheCreationInput
+	if creationPrice == 0 {
+		creationPrice = p.Input
+	}
+	return Cost{
+		Input:              float64(usage.InputTokens-cached-created) * p.Input / tokensPerPriceUnit,
+		CachedInput:        float64(cached) * cachedPrice / tokensPerPriceUnit,
+		CacheCreationInput: float64(created) * creationPrice / tokensPerPriceUnit,
+		Output:             float64(usage.OutputTokens) * p.Output / tokensPerPriceUnit,
+	}
+}
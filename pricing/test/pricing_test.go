@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"github.com/Pooh-Mucho/go-aigc/pricing"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type fixedUsageChatModel struct {
+	modelId string
+	usage   chat.TokenUsage
+	calls   int
+}
+
+func (m *fixedUsageChatModel) GetModelId() string {
+	return m.modelId
+}
+
+func (m *fixedUsageChatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	m.calls++
+	return &chat.ModelResponse{
+		Messages:     []chat.Message{{Role: chat.RoleAssistant, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "ok"}}}},
+		FinishReason: "stop",
+		Usage:        m.usage,
+	}, nil
+}
+
+func almostEqual(x, y float64) bool {
+	return math.Abs(x-y) < 1e-12
+}
+
+func Test_Pricing_Catalog_Covers_Models(t *testing.T) {
+	for _, models := range []any{chat.Models, embedding.Models} {
+		var rv = reflect.ValueOf(models)
+		for i := 0; i < rv.NumField(); i++ {
+			var modelId = rv.Field(i).Interface().(aigc.ModelId)
+			if _, ok := pricing.DefaultCatalog.Lookup(string(modelId)); !ok {
+				t.Errorf("%s (%s) has no price", rv.Type().Field(i).Name, modelId)
+			}
+		}
+	}
+
+	// Azure deployment id
+	if _, ok := pricing.DefaultCatalog.Lookup("gpt-35-turbo"); !ok {
+		t.Error("gpt-35-turbo has no price")
+	}
+}
+
+func Test_Pricing_Response_Cost(t *testing.T) {
+	var response = &chat.ModelResponse{Usage: chat.TokenUsage{
+		InputTokens:       2_000_000,
+		CachedInputTokens: 1_000_000,
+		OutputTokens:      500_000,
+	}}
+
+	var cost, err = pricing.DefaultCatalog.ResponseCost(string(chat.Models.OpenAIGpt4oMini), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(cost.Input, 0.15) || !almostEqual(cost.CachedInput, 0.075) || !almostEqual(cost.Output, 0.30) {
+		t.Fatalf("unexpected cost: %+v", cost)
+	}
+	if !almostEqual(cost.Total(), 0.525) {
+		t.Fatalf("unexpected total: %f", cost.Total())
+	}
+
+	// Cached responses are free
+	response.Cache.Hit = true
+	cost, _ = pricing.DefaultCatalog.ResponseCost(string(chat.Models.OpenAIGpt4oMini), response)
+	if cost.Total() != 0 {
+		t.Fatalf("cached response cost %f", cost.Total())
+	}
+
+	_, err = pricing.DefaultCatalog.ResponseCost("unknown-model", &chat.ModelResponse{})
+	if err == nil {
+		t.Fatal("unknown model has a price")
+	}
+}
+
+func Test_Pricing_Cache_Creation_Cost(t *testing.T) {
+	var usage = chat.TokenUsage{
+		InputTokens:              3_000_000,
+		CachedInputTokens:        1_000_000,
+		CacheCreationInputTokens: 1_000_000,
+	}
+
+	var cost, err = pricing.DefaultCatalog.UsageCost(string(chat.Models.AnthropicClaude35Sonnet_20240620), usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(cost.Input, 3.00) || !almostEqual(cost.CachedInput, 0.30) ||
+		!almostEqual(cost.CacheCreationInput, 3.75) {
+		t.Fatalf("unexpected cost: %+v", cost)
+	}
+
+	// No cache write price, input price is used
+	cost, _ = pricing.DefaultCatalog.UsageCost(string(chat.Models.OpenAIGpt4oMini), usage)
+	if !almostEqual(cost.CacheCreationInput, 0.15) {
+		t.Fatalf("unexpected cost: %+v", cost)
+	}
+}
+
+func Test_Pricing_ToolExecutor_Cost(t *testing.T) {
+	var model = &fixedUsageChatModel{
+		modelId: string(chat.Models.AnthropicClaude3Haiku),
+		usage:   chat.TokenUsage{InputTokens: 1000, OutputTokens: 1000},
+	}
+	var executor = chat.ToolExecutor{
+		Model: model,
+		InitialRequest: &chat.ModelRequest{Messages: []chat.Message{{
+			Role:     chat.RoleUser,
+			Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "hello"}},
+		}}},
+	}
+	var _, err = executor.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cost, err := pricing.DefaultCatalog.ToolExecutorCost(&executor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(cost.Total(), 0.0015) {
+		t.Fatalf("unexpected cost: %+v", cost)
+	}
+}
+
+func Test_Pricing_Budget_Guard(t *testing.T) {
+	var inner = &fixedUsageChatModel{
+		modelId: string(chat.Models.OpenAIGpt4o),
+		usage:   chat.TokenUsage{InputTokens: 100_000, OutputTokens: 100_000}, // 1.25 USD
+	}
+	var budget = pricing.NewBudget(nil, 0)
+	budget.SetLimit("alice", 2)
+
+	var model, err = pricing.NewBudgetChatModel(inner, budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request = &chat.ModelRequest{Messages: []chat.Message{{
+		Role:     chat.RoleUser,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "hello"}},
+	}}}
+
+	var alice = pricing.WithTenant(context.Background(), "alice")
+	for i := 0; i < 2; i++ {
+		if _, err = model.Complete(alice, request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = model.Complete(alice, request)
+	if !errors.Is(err, pricing.ErrBudgetExceeded) {
+		t.Fatalf("expected budget exceeded, got %v", err)
+	}
+	if inner.calls != 2 || !almostEqual(budget.Spent("alice"), 2.5) || budget.Remaining("alice") != 0 {
+		t.Fatalf("calls: %d, spent: %f", inner.calls, budget.Spent("alice"))
+	}
+
+	// Other tenants are not limited
+	var bob = pricing.WithTenant(context.Background(), "bob")
+	if _, err = model.Complete(bob, request); err != nil {
+		t.Fatal(err)
+	}
+
+	budget.Reset("alice")
+	if _, err = model.Complete(alice, request); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pricing.NewBudgetChatModel(&fixedUsageChatModel{modelId: "unknown"}, budget)
+	if err == nil {
+		t.Fatal("model without price is guarded")
+	}
+}
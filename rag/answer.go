@@ -0,0 +1,133 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+)
+
+// Answer is the response of a question and its sources.
+type Answer struct {
+	Response *chat.ModelResponse
+	// Chunks in the prompt, Sources[i] is cited by the marker [i+1]
+	Sources []Chunk
+	// Indexes of the sources cited by the response, in the order of their
+	// first citations
+	Cited []int
+}
+
+// citationPattern matches markers, e.g. "[1]" and "[1, 3]".
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Request creates a chat request asks the question with the chunks as
+// passages, the passages are marked by [1], [2] and so on, in the order of
+// the chunks.
+func (p *Pipeline) Request(query string, chunks []Chunk) *chat.ModelRequest {
+	var b strings.Builder
+	b.WriteString("Passages:\n")
+	for i, chunk := range chunks {
+		fmt.Fprintf(&b, "\n[%d] %s\n", i+1, chunkTitle(&chunk))
+		b.WriteString(chunk.Text)
+		b.WriteString("\n")
+	}
+	b.WriteString("\nQuestion: ")
+	b.WriteString(query)
+
+	return &chat.ModelRequest{
+		Messages: []chat.Message{
+			{Role: chat.RoleSystem, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: p.opts.Instructions}}},
+			{Role: chat.RoleUser, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: b.String()}}},
+		},
+	}
+}
+
+// chunkTitle returns the title of a passage, the "title" metadata or the
+// document id, and the section headers.
+func chunkTitle(chunk *Chunk) string {
+	var title = chunk.Metadata["title"]
+	if title == "" {
+		title = chunk.DocumentId
+	}
+	if len(chunk.Headers) > 0 {
+		title += " > " + strings.Join(chunk.Headers, " > ")
+	}
+	return title
+}
+
+// Answer retrieves the chunks relevant to the question, and asks the model
+// to answer by them. A nil filter accepts all chunks.
+func (p *Pipeline) Answer(ctx context.Context, model chat.Model, query string, filter vectorindex.Filter) (*Answer, error) {
+	var chunks, err = p.Retrieve(ctx, query, filter)
+	if err != nil {
+		return nil, fmt.Errorf("[Pipeline.Answer] %w", err)
+	}
+	response, err := model.Complete(ctx, p.Request(query, chunks))
+	if err != nil {
+		return nil, fmt.Errorf("[Pipeline.Answer] %w", err)
+	}
+
+	var answer = &Answer{Response: response, Sources: chunks}
+	var cited = make(map[int]bool)
+	for _, message := range response.Messages {
+		for _, content := range message.Contents {
+			if content.Type != chat.ContentTypeText {
+				continue
+			}
+			for _, match := range citationPattern.FindAllStringSubmatch(content.Text, -1) {
+				for _, s := range strings.Split(match[1], ",") {
+					var n, _ = strconv.Atoi(strings.TrimSpace(s))
+					if n >= 1 && n <= len(chunks) && !cited[n-1] {
+						cited[n-1] = true
+						answer.Cited = append(answer.Cited, n-1)
+					}
+				}
+			}
+		}
+	}
+	return answer, nil
+}
+
+// Tool returns a tool searches the ingested documents, for ToolExecutor
+// agents. The tool takes a "query" argument, and returns the retrieved
+// chunks with their ids, document ids, texts and scores.
+func (p *Pipeline) Tool(name string, description string) chat.Tool {
+	return chat.Tool{
+		Name:        name,
+		Description: description,
+		Parameters: chat.ToolParameters{
+			Properties: []aigc.JsonSchemaProperty{
+				{Name: "query", Type: aigc.JsonSchema{Type: aigc.JsonString, Description: "The search query"}},
+			},
+			Required: []string{"query"},
+		},
+		Function: func(arguments map[string]any) (any, error) {
+			var query, _ = arguments["query"].(string)
+			if strings.TrimSpace(query) == "" {
+				return nil, errors.New("[Pipeline.Tool] query is required")
+			}
+			var chunks, err = p.Retrieve(context.Background(), query, nil)
+			if err != nil {
+				return nil, err
+			}
+			var results = make([]any, len(chunks))
+			for i, chunk := range chunks {
+				results[i] = map[string]any{
+					"id":       chunk.Id,
+					"document": chunkTitle(&chunk),
+					"text":     chunk.Text,
+					"score":    chunk.Score,
+				}
+			}
+			return results, nil
+		},
+	}
+}
@@ -0,0 +1,123 @@
+package rag
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/rerank"
+	"github.com/Pooh-Mucho/go-aigc/textsplit"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+)
+
+const (
+	defaultTopK      = 4
+	defaultBatchSize = 64
+
+	defaultInstructions = "Answer the question using the passages. Cite the passages supporting each " +
+		"statement by their markers, e.g. [1] or [1][3]. If the passages do not contain the answer, say " +
+		"that you do not know instead of guessing."
+)
+
+type Options struct {
+	// Splits the documents into chunks. Default is a recursive splitter of
+	// 512 tokens.
+	Splitter textsplit.Splitter
+	// Number of chunks retrieved for a query. Default is 4.
+	TopK int
+	// Number of candidates searched in the index before reranking and MMR.
+	// Default is 4 times TopK if either is enabled, otherwise TopK.
+	Candidates int
+	// Reranks the candidates by the relevance to the query, nil means the
+	// candidates are ranked by the vector similarity.
+	Reranker rerank.Model
+	// Balance of relevance and diversity of the maximal marginal relevance,
+	// from 0 to 1, 1 is relevance only. Zero disables MMR.
+	MMRLambda float64
+	// System instructions of the answer requests. Default asks to answer by
+	// the passages and cite them by the markers.
+	Instructions string
+	// Number of chunks embedded in a request. Default is 64.
+	BatchSize int
+	// Indexes the texts of the chunks for hybrid keyword and vector search,
+	// nil means vector search only.
+	Keywords *vectorindex.BM25Index
+	// Options of the hybrid search, e.g. vectorindex.WithWeightedFusion.
+	HybridOptions []vectorindex.OptionFunc
+}
+
+type OptionFunc func(*Options)
+
+func WithSplitter(splitter textsplit.Splitter) func(*Options) {
+	return func(o *Options) {
+		o.Splitter = splitter
+	}
+}
+
+func WithTopK(k int) func(*Options) {
+	return func(o *Options) {
+		o.TopK = k
+	}
+}
+
+func WithCandidates(n int) func(*Options) {
+	return func(o *Options) {
+		o.Candidates = n
+	}
+}
+
+func WithReranker(reranker rerank.Model) func(*Options) {
+	return func(o *Options) {
+		o.Reranker = reranker
+	}
+}
+
+func WithMMR(lambda float64) func(*Options) {
+	return func(o *Options) {
+		o.MMRLambda = lambda
+	}
+}
+
+func WithInstructions(instructions string) func(*Options) {
+	return func(o *Options) {
+		o.Instructions = instructions
+	}
+}
+
+func WithBatchSize(size int) func(*Options) {
+	return func(o *Options) {
+		o.BatchSize = size
+	}
+}
+
+func WithHybrid(keywords *vectorindex.BM25Index, options ...vectorindex.OptionFunc) func(*Options) {
+	return func(o *Options) {
+		o.Keywords = keywords
+		o.HybridOptions = options
+	}
+}
+
+func newOptions(options []OptionFunc) Options {
+	var opts Options
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.Splitter == nil {
+		opts.Splitter = textsplit.NewRecursiveSplitter(nil)
+	}
+	if opts.TopK <= 0 {
+		opts.TopK = defaultTopK
+	}
+	if opts.MMRLambda < 0 || opts.MMRLambda > 1 {
+		opts.MMRLambda = 0
+	}
+	if opts.Candidates < opts.TopK {
+		opts.Candidates = opts.TopK
+		if opts.Reranker != nil || opts.MMRLambda > 0 {
+			opts.Candidates = 4 * opts.TopK
+		}
+	}
+	if opts.Instructions == "" {
+		opts.Instructions = defaultInstructions
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	return opts
+}
@@ -0,0 +1,336 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"github.com/Pooh-Mucho/go-aigc/rerank"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+)
+
+// Reserved metadata keys of the indexed chunks.
+const (
+	metadataText     = "rag:text"
+	metadataDocument = "rag:document"
+	metadataHeaders  = "rag:headers"
+)
+
+// Document is a source document to ingest.
+type Document struct {
+	Id   string
+	Text string
+	// Metadata copied to each chunk, e.g. the title and the url. Keys
+	// starting with "rag:" are reserved.
+	Metadata vectorindex.Metadata
+}
+
+// Chunk is a retrieved part of a document.
+type Chunk struct {
+	// Id of the chunk, the document id and the number of the chunk, e.g.
+	// "guide.md#3"
+	Id         string
+	DocumentId string
+	Text       string
+	// Titles of the Markdown sections containing the chunk, see
+	// textsplit.Chunk
+	Headers  []string
+	Metadata vectorindex.Metadata
+	// Relevance to the query, higher is more relevant. It is the rerank
+	// score if the chunks are reranked, the fused score of a hybrid search,
+	// otherwise the vector score.
+	Score float64
+}
+
+// Index stores the vectors of the chunks, a vectorindex.Index or a
+// vectorindex.Store. The stale chunks of the documents are removed by the
+// Delete method of either.
+type Index interface {
+	DistanceType() embedding.VectorDistanceType
+	Add(id string, vector []float32, metadata vectorindex.Metadata) error
+	Get(id string) ([]float32, vectorindex.Metadata, bool)
+	Search(query []float32, k int, filter vectorindex.Filter) ([]vectorindex.Match, error)
+}
+
+// Pipeline ingests documents into a vector index, and answers questions by
+// the chunks retrieved from it. The texts of the chunks are kept in the
+// metadata of the index, so a persistent vectorindex.Store keeps the whole
+// corpus. All methods are safe for concurrent use.
+type Pipeline struct {
+	model  embedding.Model
+	index  Index
+	hybrid *vectorindex.HybridSearch
+	opts   Options
+}
+
+func NewPipeline(model embedding.Model, index Index, options ...OptionFunc) *Pipeline {
+	var p = &Pipeline{model: model, index: index, opts: newOptions(options)}
+	if p.opts.Keywords != nil {
+		p.hybrid = vectorindex.NewHybridSearch(index, p.opts.Keywords, p.opts.HybridOptions...)
+	}
+	return p
+}
+
+// Ingest splits the documents into chunks, embeds and indexes them, returns
+// the number of chunks. Ingesting a document again replaces its chunks, the
+// extra chunks of the previous version are deleted if the document is
+// shorter.
+func (p *Pipeline) Ingest(ctx context.Context, documents ...Document) (int, error) {
+	var ids []string
+	var texts []string
+	var metadata []vectorindex.Metadata
+	var total = 0
+	// Number of chunks of the documents, the chunks after them are stale
+	var counts = make(map[string]int, len(documents))
+
+	var flush = func() error {
+		if len(texts) == 0 {
+			return nil
+		}
+		var response, err = p.model.Embedding(ctx, &embedding.ModelRequest{
+			Documents: texts,
+			InputType: embedding.InputDocument,
+		})
+		if err != nil {
+			return err
+		}
+		if len(response.Embeddings) != len(texts) {
+			return fmt.Errorf("%d embeddings of %d chunks", len(response.Embeddings), len(texts))
+		}
+		for i := range texts {
+			err = p.index.Add(ids[i], response.Embeddings[i], metadata[i])
+			if err != nil {
+				return err
+			}
+			if p.opts.Keywords != nil {
+				err = p.opts.Keywords.Add(ids[i], texts[i], metadata[i])
+				if err != nil {
+					return err
+				}
+			}
+		}
+		total += len(texts)
+		ids, texts, metadata = ids[:0], texts[:0], metadata[:0]
+		return nil
+	}
+
+	for _, document := range documents {
+		if document.Id == "" {
+			return total, errors.New("[Pipeline.Ingest] document id is empty")
+		}
+		var chunks = p.opts.Splitter.Split(document.Text)
+		counts[document.Id] = len(chunks)
+		for n, chunk := range chunks {
+			var m = make(vectorindex.Metadata, len(document.Metadata)+3)
+			for k, v := range document.Metadata {
+				m[k] = v
+			}
+			m[metadataText] = chunk.Text
+			m[metadataDocument] = document.Id
+			if len(chunk.Headers) > 0 {
+				m[metadataHeaders] = strings.Join(chunk.Headers, "\n")
+			}
+
+			ids = append(ids, document.Id+"#"+strconv.Itoa(n))
+			texts = append(texts, chunk.Text)
+			metadata = append(metadata, m)
+			if len(texts) >= p.opts.BatchSize {
+				if err := flush(); err != nil {
+					return total, fmt.Errorf("[Pipeline.Ingest] %w", err)
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return total, fmt.Errorf("[Pipeline.Ingest] %w", err)
+	}
+	for documentId, n := range counts {
+		if _, err := p.deleteChunks(documentId, n); err != nil {
+			return total, fmt.Errorf("[Pipeline.Ingest] %w", err)
+		}
+	}
+	return total, nil
+}
+
+// Delete removes the chunks of a document from the index and the keyword
+// index, returns the number of chunks removed.
+func (p *Pipeline) Delete(documentId string) (int, error) {
+	var n, err = p.deleteChunks(documentId, 0)
+	if err != nil {
+		return n, fmt.Errorf("[Pipeline.Delete] %w", err)
+	}
+	return n, nil
+}
+
+// deleteChunks removes the chunks of a document from the number start. The
+// chunks are numbered from 0 without gaps, so it stops at the first missing
+// chunk.
+func (p *Pipeline) deleteChunks(documentId string, start int) (int, error) {
+	var deleted = 0
+	for n := start; ; n++ {
+		var id = documentId + "#" + strconv.Itoa(n)
+		if _, _, ok := p.index.Get(id); !ok {
+			return deleted, nil
+		}
+		switch index := p.index.(type) {
+		case interface{ Delete(id string) bool }:
+			index.Delete(id)
+		case interface {
+			Delete(id string) (bool, error)
+		}:
+			if _, err := index.Delete(id); err != nil {
+				return deleted, err
+			}
+		default:
+			return deleted, errors.New("index does not support deleting chunks")
+		}
+		if p.opts.Keywords != nil {
+			p.opts.Keywords.Delete(id)
+		}
+		deleted++
+	}
+}
+
+// Retrieve returns the chunks most relevant to the query, most relevant
+// first. The candidates of the vector or hybrid search are reranked and
+// diversified by MMR if they are enabled. A nil filter accepts all chunks.
+func (p *Pipeline) Retrieve(ctx context.Context, query string, filter vectorindex.Filter) ([]Chunk, error) {
+	var response, err = p.model.Embedding(ctx, &embedding.ModelRequest{Document: query, InputType: embedding.InputQuery})
+	if err != nil {
+		return nil, fmt.Errorf("[Pipeline.Retrieve] %w", err)
+	}
+	var matches []vectorindex.Match
+	if p.hybrid != nil {
+		matches, err = p.hybrid.Search(query, response.Embedding, p.opts.Candidates, filter)
+	} else {
+		matches, err = p.index.Search(response.Embedding, p.opts.Candidates, filter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[Pipeline.Retrieve] %w", err)
+	}
+
+	var chunks = make([]Chunk, len(matches))
+	for i, match := range matches {
+		chunks[i] = newChunk(match.Id, match.Metadata, float64(match.Score))
+		if p.hybrid == nil && p.index.DistanceType() == embedding.EuclideanDistance {
+			// Scores of euclidean indexes are distances, negated so higher is
+			// more relevant
+			chunks[i].Score = -chunks[i].Score
+		}
+	}
+
+	if p.opts.Reranker != nil && len(chunks) > 1 {
+		chunks, err = p.rerank(ctx, query, chunks)
+		if err != nil {
+			return nil, fmt.Errorf("[Pipeline.Retrieve] %w", err)
+		}
+	}
+	if p.opts.MMRLambda > 0 && len(chunks) > p.opts.TopK {
+		return p.mmr(chunks), nil
+	}
+	if len(chunks) > p.opts.TopK {
+		chunks = chunks[:p.opts.TopK]
+	}
+	return chunks, nil
+}
+
+func newChunk(id string, metadata vectorindex.Metadata, score float64) Chunk {
+	var chunk = Chunk{
+		Id:         id,
+		DocumentId: metadata[metadataDocument],
+		Text:       metadata[metadataText],
+		Metadata:   make(vectorindex.Metadata, len(metadata)),
+		Score:      score,
+	}
+	if headers := metadata[metadataHeaders]; headers != "" {
+		chunk.Headers = strings.Split(headers, "\n")
+	}
+	for k, v := range metadata {
+		if !strings.HasPrefix(k, "rag:") {
+			chunk.Metadata[k] = v
+		}
+	}
+	return chunk
+}
+
+// rerank orders the chunks by the scores of the reranker.
+func (p *Pipeline) rerank(ctx context.Context, query string, chunks []Chunk) ([]Chunk, error) {
+	var request = &rerank.ModelRequest{Query: query, Documents: make([]string, len(chunks))}
+	for i := range chunks {
+		request.Documents[i] = chunks[i].Text
+	}
+	var response, err = p.opts.Reranker.Rerank(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]Chunk, 0, len(response.Results))
+	for _, r := range response.Results {
+		if r.Index < 0 || r.Index >= len(chunks) {
+			return nil, fmt.Errorf("rerank result index %d out of range", r.Index)
+		}
+		var chunk = chunks[r.Index]
+		chunk.Score = r.Score
+		result = append(result, chunk)
+	}
+	return result, nil
+}
+
+// mmr selects TopK chunks by the maximal marginal relevance, each next chunk
+// maximizes lambda * relevance - (1 - lambda) * the maximum similarity to
+// the selected chunks. Relevance is the score scaled to [0, 1].
+func (p *Pipeline) mmr(chunks []Chunk) []Chunk {
+	var lambda = p.opts.MMRLambda
+	var vectors = make([][]float32, len(chunks))
+	var relevance = make([]float64, len(chunks))
+
+	var low, high = chunks[0].Score, chunks[0].Score
+	for i := range chunks {
+		vectors[i], _, _ = p.index.Get(chunks[i].Id)
+		low, high = min(low, chunks[i].Score), max(high, chunks[i].Score)
+	}
+	for i := range chunks {
+		relevance[i] = 1
+		if high > low {
+			relevance[i] = (chunks[i].Score - low) / (high - low)
+		}
+	}
+
+	// Maximum similarity of each candidate to the selected chunks
+	var similarity = make([]float64, len(chunks))
+	var selected = make([]bool, len(chunks))
+	var result = make([]Chunk, 0, p.opts.TopK)
+	for len(result) < p.opts.TopK {
+		var best = -1
+		var bestScore float64
+		for i := range chunks {
+			if selected[i] {
+				continue
+			}
+			var score = lambda*relevance[i] - (1-lambda)*similarity[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		selected[best] = true
+		result = append(result, chunks[best])
+
+		for i := range chunks {
+			if selected[i] || vectors[i] == nil || vectors[best] == nil {
+				continue
+			}
+			if s, err := embedding.VectorCosineSimilarity(vectors[i], vectors[best]); err == nil {
+				similarity[i] = max(similarity[i], float64(s))
+			}
+		}
+	}
+	return result
+}
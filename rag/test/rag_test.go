@@ -0,0 +1,343 @@
+package test
+
+import (
+	"context"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"github.com/Pooh-Mucho/go-aigc/rag"
+	"github.com/Pooh-Mucho/go-aigc/rerank"
+	"github.com/Pooh-Mucho/go-aigc/textsplit"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+	"hash/fnv"
+	"strings"
+	"testing"
+)
+
+// wordsEmbeddingModel embeds the words of a text into hashed buckets, texts
+// sharing words are similar.
+type wordsEmbeddingModel struct {
+	requests int
+}
+
+func (m *wordsEmbeddingModel) GetModelId() string {
+	return "words"
+}
+
+func (m *wordsEmbeddingModel) GetDistanceType() embedding.VectorDistanceType {
+	return embedding.CosineDistance
+}
+
+func (m *wordsEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	var similarity, err = embedding.VectorCosineSimilarity(vector1, vector2)
+	return 1 - similarity, err
+}
+
+func (m *wordsEmbeddingModel) Embedding(ctx context.Context, request *embedding.ModelRequest) (*embedding.ModelResponse, error) {
+	m.requests++
+	var documents = request.Documents
+	if len(documents) == 0 {
+		documents = []string{request.Document}
+	}
+	var response = &embedding.ModelResponse{}
+	for _, document := range documents {
+		var vector = make([]float32, 64)
+		vector[0] = 0.01
+		for _, word := range strings.Fields(strings.ToLower(strings.Trim(document, ".?"))) {
+			var h = fnv.New32a()
+			h.Write([]byte(strings.Trim(word, ".,?")))
+			vector[h.Sum32()%64] += 1
+		}
+		response.Embeddings = append(response.Embeddings, vector)
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+// lengthRerankModel ranks shorter documents first.
+type lengthRerankModel struct{}
+
+func (m *lengthRerankModel) GetModelId() string {
+	return "length"
+}
+
+func (m *lengthRerankModel) Rerank(ctx context.Context, request *rerank.ModelRequest) (*rerank.ModelResponse, error) {
+	var response = &rerank.ModelResponse{}
+	for i, document := range request.Documents {
+		response.Results = append(response.Results, rerank.Result{Index: i, Score: 1 / float64(len(document))})
+	}
+	for i := 1; i < len(response.Results); i++ {
+		for j := i; j > 0 && response.Results[j].Score > response.Results[j-1].Score; j-- {
+			response.Results[j], response.Results[j-1] = response.Results[j-1], response.Results[j]
+		}
+	}
+	return response, nil
+}
+
+// citingChatModel answers with a fixed text and records the request.
+type citingChatModel struct {
+	request *chat.ModelRequest
+}
+
+func (m *citingChatModel) GetModelId() string {
+	return "citing"
+}
+
+func (m *citingChatModel) Complete(ctx context.Context, request *chat.ModelRequest) (*chat.ModelResponse, error) {
+	m.request = request
+	return &chat.ModelResponse{
+		Messages: []chat.Message{{Role: chat.RoleAssistant, Contents: []chat.ContentBlock{
+			{Type: chat.ContentTypeText, Text: "Paris is the capital [2]. It is on the Seine [2, 1][9]."},
+		}}},
+		FinishReason: "stop",
+	}, nil
+}
+
+var documents = []rag.Document{
+	{Id: "france", Text: "Paris is the capital of France.", Metadata: vectorindex.Metadata{"title": "France"}},
+	{Id: "germany", Text: "Berlin is the capital of Germany."},
+	{Id: "seine", Text: "The Seine river flows through Paris."},
+	{Id: "pasta", Text: "Boil the pasta in salted water for ten minutes."},
+}
+
+func newPipeline(t *testing.T, model embedding.Model, options ...rag.OptionFunc) *rag.Pipeline {
+	t.Helper()
+	var pipeline = rag.NewPipeline(model, vectorindex.NewFlatIndex(embedding.CosineDistance, 0), options...)
+	var n, err = pipeline.Ingest(context.Background(), documents...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(documents) {
+		t.Fatalf("%d chunks", n)
+	}
+	return pipeline
+}
+
+func Test_Pipeline_Retrieve(t *testing.T) {
+	var model = &wordsEmbeddingModel{}
+	var pipeline = newPipeline(t, model, rag.WithTopK(2), rag.WithBatchSize(3))
+	if model.requests != 2 {
+		t.Errorf("%d embedding requests of batch size 3", model.requests)
+	}
+
+	var chunks, err = pipeline.Retrieve(context.Background(), "What is the capital of France?", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 || chunks[0].Id != "france#0" || chunks[0].DocumentId != "france" {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	if chunks[0].Text != "Paris is the capital of France." || chunks[0].Metadata["title"] != "France" {
+		t.Errorf("unexpected chunk: %+v", chunks[0])
+	}
+	if _, ok := chunks[0].Metadata["rag:text"]; ok {
+		t.Error("reserved metadata is returned")
+	}
+	if chunks[0].Score < chunks[1].Score {
+		t.Error("chunks are not sorted")
+	}
+
+	chunks, err = pipeline.Retrieve(context.Background(), "capital", vectorindex.MetadataEquals(vectorindex.Metadata{"title": "France"}))
+	if err != nil || len(chunks) != 1 || chunks[0].DocumentId != "france" {
+		t.Errorf("unexpected filtered chunks: %+v, %v", chunks, err)
+	}
+}
+
+func Test_Pipeline_Rerank(t *testing.T) {
+	var pipeline = newPipeline(t, &wordsEmbeddingModel{}, rag.WithTopK(2), rag.WithReranker(&lengthRerankModel{}))
+	var chunks, err = pipeline.Retrieve(context.Background(), "capital of France", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shortest candidates of all 4, ranked by the reranker
+	if len(chunks) != 2 || chunks[0].DocumentId != "france" || chunks[1].DocumentId != "germany" {
+		t.Errorf("unexpected chunks: %+v", chunks)
+	}
+	if chunks[0].Score != 1/float64(len(chunks[0].Text)) {
+		t.Errorf("score is not the rerank score: %v", chunks[0].Score)
+	}
+}
+
+func Test_Pipeline_MMR(t *testing.T) {
+	var model = &wordsEmbeddingModel{}
+	var pipeline = newPipeline(t, model, rag.WithTopK(2))
+	var copies = rag.Document{Id: "france-copy", Text: documents[0].Text}
+	if _, err := pipeline.Ingest(context.Background(), copies); err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks, err = pipeline.Retrieve(context.Background(), "capital of France", nil)
+	if err != nil || len(chunks) != 2 || chunks[0].Text != chunks[1].Text {
+		t.Fatalf("duplicates are not the top chunks: %+v, %v", chunks, err)
+	}
+
+	pipeline = rag.NewPipeline(model, vectorindex.NewFlatIndex(embedding.CosineDistance, 0), rag.WithTopK(2), rag.WithMMR(0.3))
+	if _, err = pipeline.Ingest(context.Background(), append(documents, copies)...); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err = pipeline.Retrieve(context.Background(), "capital of France", nil)
+	if err != nil || len(chunks) != 2 || chunks[0].Text == chunks[1].Text {
+		t.Errorf("duplicates are not diversified: %+v, %v", chunks, err)
+	}
+}
+
+func Test_Pipeline_Answer(t *testing.T) {
+	var pipeline = newPipeline(t, &wordsEmbeddingModel{}, rag.WithTopK(2),
+		rag.WithSplitter(textsplit.NewMarkdownSplitter()))
+	var model = &citingChatModel{}
+	var answer, err = pipeline.Answer(context.Background(), model, "capital of France", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(answer.Sources) != 2 || answer.Response == nil {
+		t.Fatalf("unexpected answer: %+v", answer)
+	}
+	if len(answer.Cited) != 2 || answer.Cited[0] != 1 || answer.Cited[1] != 0 {
+		t.Errorf("unexpected citations: %v", answer.Cited)
+	}
+
+	var prompt = model.request.Messages[1].Contents[0].Text
+	if model.request.Messages[0].Role != chat.RoleSystem || !strings.Contains(prompt, "[1] France\nParis is the capital of France.") ||
+		!strings.HasSuffix(prompt, "Question: capital of France") {
+		t.Errorf("unexpected prompt: %q", prompt)
+	}
+}
+
+func Test_Pipeline_Tool(t *testing.T) {
+	var pipeline = newPipeline(t, &wordsEmbeddingModel{}, rag.WithTopK(1))
+	var tool = pipeline.Tool("search_documents", "Search the documents")
+	if tool.Name != "search_documents" || len(tool.Parameters.Properties) != 1 || tool.Parameters.Required[0] != "query" {
+		t.Fatalf("unexpected tool: %+v", tool)
+	}
+
+	var result, err = tool.Function(map[string]any{"query": "how to cook pasta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results, ok = result.([]any)
+	if !ok || len(results) != 1 || results[0].(map[string]any)["id"] != "pasta#0" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	if _, err = tool.Function(map[string]any{}); err == nil {
+		t.Error("empty query is accepted")
+	}
+}
+
+// identifierBlindEmbeddingModel embeds the words of a text like
+// wordsEmbeddingModel, but misses identifiers like real embeddings, the words
+// with digits are ignored.
+type identifierBlindEmbeddingModel struct{}
+
+func (m *identifierBlindEmbeddingModel) GetModelId() string {
+	return "identifier-blind"
+}
+
+func (m *identifierBlindEmbeddingModel) GetDistanceType() embedding.VectorDistanceType {
+	return embedding.CosineDistance
+}
+
+func (m *identifierBlindEmbeddingModel) Distance(vector1, vector2 []float32) (float32, error) {
+	var similarity, err = embedding.VectorCosineSimilarity(vector1, vector2)
+	return 1 - similarity, err
+}
+
+func (m *identifierBlindEmbeddingModel) Embedding(ctx context.Context, request *embedding.ModelRequest) (*embedding.ModelResponse, error) {
+	var documents = request.Documents
+	if len(documents) == 0 {
+		documents = []string{request.Document}
+	}
+	var response = &embedding.ModelResponse{}
+	for _, document := range documents {
+		var vector = make([]float32, 256)
+		vector[0] = 0.01
+		for _, word := range strings.Fields(strings.ToLower(strings.Trim(document, ".?"))) {
+			if strings.ContainsAny(word, "0123456789") {
+				continue
+			}
+			var h = fnv.New32a()
+			h.Write([]byte(strings.Trim(word, ".,?")))
+			vector[h.Sum32()%256] += 1
+		}
+		response.Embeddings = append(response.Embeddings, vector)
+	}
+	response.Embedding = response.Embeddings[0]
+	return response, nil
+}
+
+func Test_Pipeline_Hybrid(t *testing.T) {
+	var keywords = vectorindex.NewBM25Index()
+	var pipeline = rag.NewPipeline(&identifierBlindEmbeddingModel{}, vectorindex.NewFlatIndex(embedding.CosineDistance, 0),
+		rag.WithTopK(1), rag.WithHybrid(keywords, vectorindex.WithWeightedFusion(0.3)))
+	var hybridDocuments = append(documents[:len(documents):len(documents)],
+		rag.Document{Id: "errors", Text: "Error E1234 means the disk is full."})
+	if _, err := pipeline.Ingest(context.Background(), hybridDocuments...); err != nil {
+		t.Fatal(err)
+	}
+	if keywords.Len() != len(hybridDocuments) {
+		t.Fatalf("%d chunks of keywords", keywords.Len())
+	}
+
+	var chunks, err = pipeline.Retrieve(context.Background(), "E1234", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Id != "errors#0" || chunks[0].Text != "Error E1234 means the disk is full." {
+		t.Errorf("unexpected chunks: %+v", chunks)
+	}
+	chunks, err = pipeline.Retrieve(context.Background(), "the seine river", nil)
+	if err != nil || len(chunks) != 1 || chunks[0].DocumentId != "seine" {
+		t.Errorf("unexpected chunks: %+v, %v", chunks, err)
+	}
+}
+
+func Test_Pipeline_Reingest(t *testing.T) {
+	var keywords = vectorindex.NewBM25Index()
+	var index = vectorindex.NewFlatIndex(embedding.CosineDistance, 0)
+	var pipeline = rag.NewPipeline(&wordsEmbeddingModel{}, index, rag.WithHybrid(keywords),
+		rag.WithSplitter(textsplit.NewSentenceSplitter(textsplit.WithChunkSize(12))))
+	var text = "Paris is the capital of France. The Seine river flows through Paris. Boil the pasta in salted water."
+	var n, err = pipeline.Ingest(context.Background(), rag.Document{Id: "notes", Text: text})
+	if err != nil || n != 3 {
+		t.Fatalf("%d chunks, %v", n, err)
+	}
+
+	// The shorter version replaces the first chunk, and the stale chunks
+	// are deleted
+	if _, err = pipeline.Ingest(context.Background(), rag.Document{Id: "notes", Text: "Berlin is the capital of Germany."}); err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 1 || keywords.Len() != 1 {
+		t.Fatalf("stale chunks: %d vectors, %d keywords", index.Len(), keywords.Len())
+	}
+	var chunks, _ = pipeline.Retrieve(context.Background(), "how to cook pasta", nil)
+	if len(chunks) != 1 || chunks[0].Id != "notes#0" || !strings.Contains(chunks[0].Text, "Berlin") {
+		t.Errorf("unexpected chunks: %+v", chunks)
+	}
+
+	if n, err = pipeline.Delete("notes"); err != nil || n != 1 {
+		t.Errorf("Delete: %d, %v", n, err)
+	}
+	if index.Len() != 0 || keywords.Len() != 0 {
+		t.Errorf("undeleted chunks: %d vectors, %d keywords", index.Len(), keywords.Len())
+	}
+}
+
+func Test_Pipeline_Reingest_Store(t *testing.T) {
+	var store, err = vectorindex.OpenStore(t.TempDir(), "words", vectorindex.NewFlatIndex(embedding.CosineDistance, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var pipeline = rag.NewPipeline(&wordsEmbeddingModel{}, store,
+		rag.WithSplitter(textsplit.NewSentenceSplitter(textsplit.WithChunkSize(12))))
+	var text = "Paris is the capital of France. The Seine river flows through Paris. Boil the pasta in salted water."
+	if _, err = pipeline.Ingest(context.Background(), rag.Document{Id: "notes", Text: text}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pipeline.Ingest(context.Background(), rag.Document{Id: "notes", Text: "Berlin is the capital of Germany."}); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 1 {
+		t.Errorf("stale chunks: %d vectors", store.Len())
+	}
+}
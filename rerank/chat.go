@@ -0,0 +1,140 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+// Documents scored in one chat completion
+const chatRerankBatchSize = 20
+
+// Maximum score of the prompt, scores are normalized to 0..1
+const chatRerankMaxScore = 10
+
+const chatRerankSystemPrompt = `You are a search relevance judge. Rate how relevant each document is to the query, from 0 (irrelevant) to 10 (answers the query exactly).
+Reply with a JSON array only, one object per document, for example: [{"index": 0, "score": 7}, {"index": 1, "score": 0}]`
+
+// chatRerankModel uses a chat model as the reranker. It is slower and more
+// expensive than a cross-encoder, but needs no extra deployment.
+type chatRerankModel struct {
+	Model chat.Model
+}
+
+type chatRerankScore struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+func chatRerankPrompt(query string, documents []string) string {
+	var builder strings.Builder
+	builder.WriteString("Query: ")
+	builder.WriteString(query)
+	builder.WriteString("\n\nDocuments:\n")
+	for i, document := range documents {
+		builder.WriteString("<document index=\"")
+		builder.WriteString(strconv.Itoa(i))
+		builder.WriteString("\">\n")
+		builder.WriteString(document)
+		builder.WriteString("\n</document>\n")
+	}
+	return builder.String()
+}
+
+// parseChatRerankScores parses the JSON array of the reply, text around the
+// array is ignored. Documents not scored by the model score 0.
+func parseChatRerankScores(text string, documents int) ([]float64, error) {
+	var start = strings.IndexByte(text, '[')
+	var end = strings.LastIndexByte(text, ']')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no scores in the reply: %q", text)
+	}
+
+	var scores []chatRerankScore
+	var err = json.Unmarshal([]byte(text[start:end+1]), &scores)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scores in the reply: %w", err)
+	}
+
+	var result = make([]float64, documents)
+	for _, score := range scores {
+		if score.Index < 0 || score.Index >= documents {
+			continue
+		}
+		result[score.Index] = min(max(score.Score, 0), chatRerankMaxScore) / chatRerankMaxScore
+	}
+	return result, nil
+}
+
+func (m *chatRerankModel) GetModelId() string {
+	return m.Model.GetModelId()
+}
+
+func (m *chatRerankModel) score(ctx context.Context, query string, documents []string) ([]float64, int, error) {
+	var response, err = m.Model.Complete(ctx, &chat.ModelRequest{
+		Messages: []chat.Message{
+			{Role: chat.RoleSystem, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: chatRerankSystemPrompt}}},
+			{Role: chat.RoleUser, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: chatRerankPrompt(query, documents)}}},
+		},
+		Temperature: aigc.NewNullable(0.0),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var text strings.Builder
+	for _, message := range response.Messages {
+		for _, content := range message.Contents {
+			if content.Type == chat.ContentTypeText {
+				text.WriteString(content.Text)
+			}
+		}
+	}
+	scores, err := parseChatRerankScores(text.String(), len(documents))
+	if err != nil {
+		return nil, 0, err
+	}
+	return scores, response.Usage.InputTokens + response.Usage.OutputTokens, nil
+}
+
+// Rerank scores the documents in batches, one chat completion per batch.
+func (m *chatRerankModel) Rerank(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	if request.Query == "" {
+		return nil, errors.New("[chatRerankModel.Rerank] query is empty")
+	}
+	if len(request.Documents) == 0 {
+		return nil, errors.New("[chatRerankModel.Rerank] documents are empty")
+	}
+
+	var response = &ModelResponse{Results: make([]Result, 0, len(request.Documents))}
+	for start := 0; start < len(request.Documents); start += chatRerankBatchSize {
+		var end = min(start+chatRerankBatchSize, len(request.Documents))
+		var scores, tokens, err = m.score(ctx, request.Query, request.Documents[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("[chatRerankModel.Rerank] %w", err)
+		}
+		for i, score := range scores {
+			response.Results = append(response.Results, Result{Index: start + i, Score: score})
+		}
+		response.Tokens += tokens
+	}
+	response.Results = sortResults(response.Results, request.topN())
+	return response, nil
+}
+
+// NewChatModelReranker returns a reranker asks the chat model to score the
+// documents, for setups without a rerank model.
+func NewChatModelReranker(model chat.Model) (Model, error) {
+	if model == nil {
+		return nil, errors.New("model is required")
+	}
+	return &chatRerankModel{Model: model}, nil
+}
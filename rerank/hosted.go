@@ -0,0 +1,195 @@
+package rerank
+
+// Cohere documentation:
+// https://docs.cohere.com/reference/rerank
+
+// Jina documentation:
+// https://jina.ai/reranker/#apiform
+
+// Voyage documentation:
+// https://docs.voyageai.com/reference/reranker-api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// Paths of the hosted rerank APIs. The APIs share the request and response
+// shapes but differ in a few names.
+var hostedPaths = map[aigc.VendorId]string{
+	aigc.Vendors.Cohere: "/v2/rerank",
+	aigc.Vendors.Jina:   "/v1/rerank",
+	aigc.Vendors.Voyage: "/v1/rerank",
+}
+
+type hostedRerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	// Cohere and Jina
+	TopN int `json:"top_n,omitempty"`
+	// Voyage
+	TopK int `json:"top_k,omitempty"`
+	// Jina returns the documents by default
+	ReturnDocuments *bool `json:"return_documents,omitempty"`
+}
+
+type hostedRerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+type hostedRerankResponse struct {
+	// Cohere and Jina
+	Results []hostedRerankResult `json:"results"`
+	// Voyage
+	Data []hostedRerankResult `json:"data"`
+	// Jina and Voyage
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+	// Cohere
+	Meta struct {
+		BilledUnits struct {
+			SearchUnits int `json:"search_units"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+type hostedRerankModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	vendor aigc.VendorId
+	client aigc.HttpClient
+}
+
+func (r *hostedRerankRequest) load(vendor aigc.VendorId, request *ModelRequest) error {
+	if request.Query == "" {
+		return errors.New("[hostedRerankRequest.load] query is empty")
+	}
+	if len(request.Documents) == 0 {
+		return errors.New("[hostedRerankRequest.load] documents are empty")
+	}
+	r.Query = request.Query
+	r.Documents = request.Documents
+	r.TopN, r.TopK, r.ReturnDocuments = 0, 0, nil
+	switch vendor {
+	case aigc.Vendors.Voyage:
+		r.TopK = request.TopN
+	case aigc.Vendors.Jina:
+		var returnDocuments = false
+		r.TopN = request.TopN
+		r.ReturnDocuments = &returnDocuments
+	default:
+		r.TopN = request.TopN
+	}
+	return nil
+}
+
+func (r *hostedRerankResponse) dump(documents int, response *ModelResponse) error {
+	var results = r.Results
+	if len(results) == 0 {
+		results = r.Data
+	}
+	response.Results = make([]Result, 0, len(results))
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= documents {
+			return fmt.Errorf("[hostedRerankResponse.dump] invalid index %d", result.Index)
+		}
+		response.Results = append(response.Results, Result{Index: result.Index, Score: result.RelevanceScore})
+	}
+	response.Tokens = r.Usage.TotalTokens
+	if response.Tokens == 0 {
+		response.Tokens = r.Meta.BilledUnits.SearchUnits
+	}
+	return nil
+}
+
+func (m *hostedRerankModel) getModelUrl() string {
+	if m.Endpoint == "" {
+		return aigc.VendorEndpoint(m.vendor, hostedPaths[m.vendor])
+	}
+	return m.Endpoint
+}
+
+func (m *hostedRerankModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *hostedRerankModel) Rerank(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var body []byte
+	var hostedRequest hostedRerankRequest
+	var hostedResponse hostedRerankResponse
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	err = hostedRequest.load(m.vendor, request)
+	if err != nil {
+		return nil, fmt.Errorf("[hostedRerankModel.Rerank] %w", err)
+	}
+	hostedRequest.Model = m.ModelId
+
+	err = aigc.EncodeJson(buffer, &hostedRequest)
+	if err != nil {
+		return nil, fmt.Errorf("[hostedRerankModel.Rerank] %w", err)
+	}
+
+	var header = http.Header{}
+	if m.ApiKey != "" {
+		header.Set("Authorization", "Bearer "+m.ApiKey)
+	}
+	body, err = m.client.PostJson(ctx, m.getModelUrl(), header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[hostedRerankModel.Rerank] %w", err)
+	}
+
+	err = json.Unmarshal(body, &hostedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("[hostedRerankModel.Rerank] %w", err)
+	}
+	var response = &ModelResponse{}
+	err = hostedResponse.dump(len(request.Documents), response)
+	if err != nil {
+		return nil, fmt.Errorf("[hostedRerankModel.Rerank] %w", err)
+	}
+	response.Results = sortResults(response.Results, request.topN())
+	return response, nil
+}
+
+func newHostedRerankModel(vendor aigc.VendorId, modelId string, opts *aigc.ModelOptions) (*hostedRerankModel, error) {
+	// Self-hosted services may not need an api key
+	if opts.ApiKey == "" && opts.Endpoint == "" {
+		return nil, errors.New("api key is required")
+	}
+
+	var model = &hostedRerankModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+		vendor:      vendor,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
@@ -0,0 +1,52 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// Model scores the relevance of the documents to the query.
+type Model interface {
+	GetModelId() string
+	Rerank(ctx context.Context, request *ModelRequest) (*ModelResponse, error)
+}
+
+func NewModel(modelId aigc.ModelId, options ...aigc.ModelOptionFunc) (Model, error) {
+	var opts aigc.ModelOptions
+
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	if opts.VendorId == "" {
+		switch modelId {
+		case Models.CohereRerankEnglishV3, Models.CohereRerankMultilingualV3:
+			opts.VendorId = aigc.Vendors.Cohere
+		case Models.JinaRerankerV2BaseMultilingual:
+			opts.VendorId = aigc.Vendors.Jina
+		case Models.VoyageRerank2, Models.VoyageRerank2Lite:
+			opts.VendorId = aigc.Vendors.Voyage
+		case Models.BaaiBgeRerankerV2M3:
+			opts.VendorId = aigc.Vendors.HuggingFace
+		}
+	}
+
+	// Self-hosted services often implement the Cohere or Jina API, any model
+	// id is accepted.
+	switch opts.VendorId {
+	case aigc.Vendors.Cohere:
+		return newHostedRerankModel(opts.VendorId, string(modelId), &opts)
+	case aigc.Vendors.Jina:
+		return newHostedRerankModel(opts.VendorId, string(modelId), &opts)
+	case aigc.Vendors.Voyage:
+		return newHostedRerankModel(opts.VendorId, string(modelId), &opts)
+	case aigc.Vendors.HuggingFace:
+		return newTeiRerankModel(string(modelId), &opts)
+	}
+
+	if opts.VendorId != "" {
+		return nil, fmt.Errorf("model can not be created, vendor:%s, model: %s", opts.VendorId, modelId)
+	}
+	return nil, fmt.Errorf("model can not be created: %s", modelId)
+}
@@ -0,0 +1,37 @@
+package rerank
+
+import "github.com/Pooh-Mucho/go-aigc"
+
+var Models = struct {
+	// Cohere rerank models
+	// https://docs.cohere.com/reference/rerank
+	CohereRerankEnglishV3      aigc.ModelId
+	CohereRerankMultilingualV3 aigc.ModelId
+
+	// Jina reranker models
+	// https://jina.ai/reranker/
+	JinaRerankerV2BaseMultilingual aigc.ModelId
+
+	// Voyage rerank models
+	// https://docs.voyageai.com/docs/reranker
+	VoyageRerank2     aigc.ModelId
+	VoyageRerank2Lite aigc.ModelId
+
+	// BAAI reranker models, served by text-embeddings-inference
+	// https://huggingface.co/BAAI/bge-reranker-v2-m3
+	BaaiBgeRerankerV2M3 aigc.ModelId
+}{
+	// Cohere rerank models
+	CohereRerankEnglishV3:      "rerank-english-v3.0",
+	CohereRerankMultilingualV3: "rerank-multilingual-v3.0",
+
+	// Jina reranker models
+	JinaRerankerV2BaseMultilingual: "jina-reranker-v2-base-multilingual",
+
+	// Voyage rerank models
+	VoyageRerank2:     "rerank-2",
+	VoyageRerank2Lite: "rerank-2-lite",
+
+	// BAAI reranker models, served by text-embeddings-inference
+	BaaiBgeRerankerV2M3: "bge-reranker-v2-m3",
+}
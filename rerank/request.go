@@ -0,0 +1,16 @@
+package rerank
+
+type ModelRequest struct {
+	Query string
+	// Candidate documents, the results refer to them by index
+	Documents []string
+	// Number of the most relevant results to return, zero means all
+	TopN int
+}
+
+func (r *ModelRequest) topN() int {
+	if r.TopN <= 0 || r.TopN > len(r.Documents) {
+		return len(r.Documents)
+	}
+	return r.TopN
+}
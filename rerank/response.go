@@ -0,0 +1,35 @@
+package rerank
+
+import (
+	"sort"
+)
+
+type Result struct {
+	// Index of the document in the request
+	Index int
+	// Relevance score, higher is more relevant. The range depends on the
+	// model, usually from 0 to 1.
+	Score float64
+}
+
+type ModelResponse struct {
+	// Results sorted by score in descending order
+	Results []Result
+	// Tokens or search units billed by the vendor, zero if not reported
+	Tokens int
+}
+
+// sortResults sorts the results by score in descending order, ties are kept
+// in the order of the documents, and keeps the top n.
+func sortResults(results []Result, topN int) []Result {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Index < results[j].Index
+	})
+	if topN > 0 && len(results) > topN {
+		results = results[:topN]
+	}
+	return results
+}
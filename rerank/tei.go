@@ -0,0 +1,128 @@
+package rerank
+
+// text-embeddings-inference documentation:
+// https://huggingface.github.io/text-embeddings-inference/#/Text%20Embeddings%20Inference/rerank
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+type teiRerankRequest struct {
+	Query string   `json:"query"`
+	Texts []string `json:"texts"`
+	// Scores are sigmoid of the logits if false
+	RawScores bool `json:"raw_scores"`
+	// Truncate the inputs longer than the model max length instead of an
+	// error
+	Truncate bool `json:"truncate"`
+}
+
+// The response is an array of the results, sorted by score.
+type teiRerankResult struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// teiRerankModel is a cross-encoder reranker, e.g. bge-reranker-v2-m3,
+// served by text-embeddings-inference.
+type teiRerankModel struct {
+	ModelId     string
+	Endpoint    string
+	ApiKey      string
+	Proxy       string
+	Retries     int
+	RequestLog  func([]byte)
+	ResponseLog func([]byte)
+
+	client aigc.HttpClient
+}
+
+// http://host:port/rerank
+func (m *teiRerankModel) getModelUrl() string {
+	var url = m.Endpoint
+	if strings.HasSuffix(url, "/rerank") {
+		return url
+	}
+	if strings.HasSuffix(url, "/") {
+		return url + "rerank"
+	}
+	return url + "/rerank"
+}
+
+func (m *teiRerankModel) GetModelId() string {
+	return m.ModelId
+}
+
+func (m *teiRerankModel) Rerank(ctx context.Context, request *ModelRequest) (*ModelResponse, error) {
+	var err error
+	var body []byte
+	var results []teiRerankResult
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	if request.Query == "" {
+		return nil, errors.New("[teiRerankModel.Rerank] query is empty")
+	}
+	if len(request.Documents) == 0 {
+		return nil, errors.New("[teiRerankModel.Rerank] documents are empty")
+	}
+
+	err = aigc.EncodeJson(buffer, &teiRerankRequest{Query: request.Query, Texts: request.Documents, Truncate: true})
+	if err != nil {
+		return nil, fmt.Errorf("[teiRerankModel.Rerank] %w", err)
+	}
+
+	var header = http.Header{}
+	if m.ApiKey != "" {
+		header.Set("Authorization", "Bearer "+m.ApiKey)
+	}
+	body, err = m.client.PostJson(ctx, m.getModelUrl(), header, buffer.Bytes(), m.RequestLog, m.ResponseLog)
+	if err != nil {
+		return nil, fmt.Errorf("[teiRerankModel.Rerank] %w", err)
+	}
+
+	err = json.Unmarshal(body, &results)
+	if err != nil {
+		return nil, fmt.Errorf("[teiRerankModel.Rerank] %w", err)
+	}
+	var response = &ModelResponse{Results: make([]Result, 0, len(results))}
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(request.Documents) {
+			return nil, fmt.Errorf("[teiRerankModel.Rerank] invalid index %d", result.Index)
+		}
+		response.Results = append(response.Results, Result{Index: result.Index, Score: result.Score})
+	}
+	response.Results = sortResults(response.Results, request.topN())
+	return response, nil
+}
+
+func newTeiRerankModel(modelId string, opts *aigc.ModelOptions) (*teiRerankModel, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("text-embeddings-inference endpoint is required")
+	}
+
+	var model = &teiRerankModel{
+		ModelId:     modelId,
+		Endpoint:    opts.Endpoint,
+		ApiKey:      opts.ApiKey,
+		Proxy:       opts.Proxy,
+		Retries:     opts.Retries,
+		RequestLog:  opts.RequestLog,
+		ResponseLog: opts.ResponseLog,
+	}
+	model.client = aigc.HttpClient{
+		Proxy:     opts.Proxy,
+		Retries:   opts.Retries,
+		Transport: opts.HttpTransport,
+	}
+	return model, nil
+}
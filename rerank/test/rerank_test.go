@@ -0,0 +1,168 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/rerank"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var Documents = []string{
+	"Llamas are members of the camelid family",
+	"The capital of France is Paris",
+	"Llamas weigh between 280 and 450 pounds",
+}
+
+const Query = "How heavy is a llama?"
+
+func checkResults(t *testing.T, results []rerank.Result, indexes ...int) {
+	t.Helper()
+	if len(results) != len(indexes) {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	for i, index := range indexes {
+		if results[i].Index != index {
+			t.Fatalf("unexpected results: %+v", results)
+		}
+		if i > 0 && results[i].Score > results[i-1].Score {
+			t.Fatalf("results are not sorted: %+v", results)
+		}
+	}
+}
+
+func Test_Hosted_Rerank(t *testing.T) {
+	var cases = []struct {
+		name    string
+		modelId aigc.ModelId
+		reply   string
+		topKey  string
+	}{
+		{"Cohere", rerank.Models.CohereRerankEnglishV3,
+			`{"id": "1", "results": [{"index": 2, "relevance_score": 0.9}, {"index": 0, "relevance_score": 0.3}],
+			  "meta": {"billed_units": {"search_units": 1}}}`, "top_n"},
+		{"Jina", rerank.Models.JinaRerankerV2BaseMultilingual,
+			`{"model": "jina-reranker-v2-base-multilingual", "usage": {"total_tokens": 42},
+			  "results": [{"index": 0, "relevance_score": 0.3}, {"index": 2, "relevance_score": 0.9}]}`, "top_n"},
+		{"Voyage", rerank.Models.VoyageRerank2,
+			`{"object": "list", "model": "rerank-2", "usage": {"total_tokens": 42},
+			  "data": [{"index": 2, "relevance_score": 0.9}, {"index": 0, "relevance_score": 0.3}]}`, "top_k"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var body map[string]any
+			var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer test-key" {
+					t.Errorf("unexpected authorization: %s", r.Header.Get("Authorization"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Error(err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, c.reply)
+			}))
+			defer server.Close()
+
+			var model, err = rerank.NewModel(c.modelId, aigc.WithEndpoint(server.URL), aigc.WithApiKey("test-key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := model.Rerank(context.Background(), &rerank.ModelRequest{
+				Query:     Query,
+				Documents: Documents,
+				TopN:      2,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResults(t, response.Results, 2, 0)
+			if response.Tokens == 0 {
+				t.Error("tokens are not reported")
+			}
+			if body["model"] != string(c.modelId) || body["query"] != Query || body[c.topKey] != float64(2) {
+				t.Errorf("unexpected request: %v", body)
+			}
+		})
+	}
+}
+
+func Test_Tei_Rerank(t *testing.T) {
+	var path string
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		var body struct {
+			Query string   `json:"query"`
+			Texts []string `json:"texts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if body.Query != Query || len(body.Texts) != len(Documents) {
+			t.Errorf("unexpected request: %+v", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `[{"index": 2, "score": 0.98}, {"index": 0, "score": 0.12}, {"index": 1, "score": 0.0001}]`)
+	}))
+	defer server.Close()
+
+	var model, err = rerank.NewModel(rerank.Models.BaaiBgeRerankerV2M3, aigc.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := model.Rerank(context.Background(), &rerank.ModelRequest{Query: Query, Documents: Documents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, response.Results, 2, 0, 1)
+	if path != "/rerank" {
+		t.Errorf("unexpected path: %s", path)
+	}
+}
+
+func Test_Chat_Model_Reranker(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+	defer server.Close()
+
+	var model, err = chat.NewModel(chat.Models.OpenAIGpt4o,
+		aigc.WithVendor(aigc.Vendors.OpenAI),
+		aigc.WithEndpoint(server.Endpoint()),
+		aigc.WithApiKey("test-key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reranker, err := rerank.NewChatModelReranker(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Enqueue(vendortest.TextReply(
+		"Scores:\n```json\n[{\"index\": 0, \"score\": 3}, {\"index\": 2, \"score\": 10}]\n```").WithUsage(100, 20))
+	response, err := reranker.Rerank(context.Background(), &rerank.ModelRequest{Query: Query, Documents: Documents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, response.Results, 2, 0, 1)
+	if response.Results[0].Score != 1 || response.Results[1].Score != 0.3 || response.Results[2].Score != 0 {
+		t.Errorf("unexpected scores: %+v", response.Results)
+	}
+	if response.Tokens != 120 {
+		t.Errorf("unexpected tokens: %d", response.Tokens)
+	}
+
+	var sent, _ = server.LastRequest()
+	if !strings.Contains(string(sent.Body), Documents[2]) {
+		t.Errorf("documents are not sent: %s", sent.Body)
+	}
+
+	server.Enqueue(vendortest.TextReply("I can not rate these documents."))
+	if _, err = reranker.Rerank(context.Background(), &rerank.ModelRequest{Query: Query, Documents: Documents}); err == nil {
+		t.Error("reply without scores is accepted")
+	}
+}
@@ -0,0 +1,93 @@
+package textsplit
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Language string
+
+const (
+	LanguageGo         Language = "go"
+	LanguagePython     Language = "python"
+	LanguageJavaScript Language = "javascript"
+	LanguageTypeScript Language = "typescript"
+	LanguageJava       Language = "java"
+	LanguageCSharp     Language = "csharp"
+	LanguageC          Language = "c"
+	LanguageCpp        Language = "cpp"
+	LanguageRust       Language = "rust"
+)
+
+// codeDeclarations are the separators start top level declarations.
+var codeDeclarations = map[Language][]string{
+	LanguageGo: {"\nfunc ", "\ntype ", "\nvar ", "\nconst "},
+	LanguagePython: {"\nclass ", "\ndef ", "\nasync def ", "\n    def ", "\n    async def ",
+		"\n\tdef "},
+	LanguageJavaScript: {"\nfunction ", "\nasync function ", "\nclass ", "\nconst ", "\nlet ",
+		"\nexport "},
+	LanguageTypeScript: {"\nfunction ", "\nasync function ", "\nclass ", "\nconst ", "\nlet ",
+		"\nexport ", "\ninterface ", "\ntype ", "\nenum "},
+	LanguageJava: {"\nclass ", "\ninterface ", "\nenum ", "\npublic ", "\nprotected ", "\nprivate ",
+		"\n    public ", "\n    protected ", "\n    private ", "\n    static "},
+	LanguageCSharp: {"\nnamespace ", "\nclass ", "\ninterface ", "\nenum ", "\nstruct ", "\npublic ",
+		"\ninternal ", "\n    public ", "\n    protected ", "\n    private ", "\n    internal "},
+	LanguageC: {"\nstruct ", "\nenum ", "\ntypedef ", "\nstatic ", "\nvoid ", "\nint ", "\nchar ",
+		"\n#define ", "\n#if"},
+	LanguageCpp: {"\nnamespace ", "\nclass ", "\nstruct ", "\nenum ", "\ntemplate", "\ntypedef ",
+		"\nstatic ", "\nvoid ", "\nint ", "\n#define ", "\n#if"},
+	LanguageRust: {"\nfn ", "\npub fn ", "\nasync fn ", "\npub async fn ", "\nstruct ", "\npub struct ",
+		"\nenum ", "\npub enum ", "\nimpl", "\ntrait ", "\npub trait ", "\nmod ", "\npub mod "},
+}
+
+// isCommentLine reports whether the line is a comment, a decorator or an
+// annotation, which belongs to the declaration after it.
+func isCommentLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "//") || strings.HasPrefix(line, "/*") || strings.HasPrefix(line, "*") ||
+		strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#define") && !strings.HasPrefix(line, "#if") ||
+		strings.HasPrefix(line, "@") || strings.HasPrefix(line, "#[")
+}
+
+// declarationBoundaries splits before declarations, and moves the
+// boundaries before the comments of the declarations.
+func declarationBoundaries(separators []string) boundaryFunc {
+	var declarations = separatorBoundaries(separators...)
+	return func(text string) []int {
+		var boundaries = declarations(text)
+		var result = boundaries[:0]
+		for _, b := range boundaries {
+			// b is the start of a line, move it to the start of the comments
+			for b > 0 {
+				var start = strings.LastIndexByte(text[:b-1], '\n') + 1
+				if !isCommentLine(text[start : b-1]) {
+					break
+				}
+				b = start
+			}
+			if b > 0 && (len(result) == 0 || b > result[len(result)-1]) {
+				result = append(result, b)
+			}
+		}
+		return result
+	}
+}
+
+// NewCodeSplitter creates a splitter for source code. Code is split between
+// top level declarations, which are kept with their comments, then blank
+// lines, lines, words and characters.
+func NewCodeSplitter(language Language, options ...OptionFunc) (Splitter, error) {
+	var declarations, ok = codeDeclarations[language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", language)
+	}
+	return &splitter{
+		opts: newOptions(options),
+		levels: []boundaryFunc{
+			declarationBoundaries(declarations),
+			separatorBoundaries("\n\n"),
+			separatorBoundaries("\n"),
+			separatorBoundaries(" "),
+		},
+	}, nil
+}
@@ -0,0 +1,120 @@
+package textsplit
+
+import (
+	"strings"
+)
+
+// markdownLines calls fn with the start, the end including the line break,
+// and whether the line is in a fenced code block, for each line of the text.
+// Fence lines are in the code blocks.
+func markdownLines(text string, fn func(start int, end int, fenced bool)) {
+	var fence string // the opening fence, empty if not in a code block
+	for start := 0; start < len(text); {
+		var end = strings.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start + 1
+		}
+
+		var line = strings.TrimLeft(text[start:end], " ")
+		var fenced = fence != ""
+		if fence == "" {
+			if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+				fence = line[:3]
+				fenced = true
+			}
+		} else if strings.HasPrefix(line, fence) && strings.TrimSpace(strings.TrimLeft(line, fence[:1])) == "" {
+			fence = ""
+		}
+
+		fn(start, end, fenced)
+		start = end
+	}
+}
+
+// markdownHeader returns the level and the title of an ATX header line, e.g.
+// "## Title", level 0 if the line is not a header.
+func markdownHeader(line string) (int, string) {
+	line = strings.TrimRight(line, "\r\n")
+	var level = 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0, ""
+	}
+	var title = strings.TrimSpace(line[level:])
+	title = strings.TrimSpace(strings.TrimRight(title, "#"))
+	return level, title
+}
+
+// markdownSections splits the text at headers out of code blocks. The
+// headers of a section are the titles of its header and the enclosing
+// headers.
+func markdownSections(text string) []section {
+	var sections []section
+	var current = section{}
+	var levels []int
+	var titles []string
+
+	markdownLines(text, func(start int, end int, fenced bool) {
+		if fenced {
+			return
+		}
+		var level, title = markdownHeader(text[start:end])
+		if level == 0 {
+			return
+		}
+
+		if start > current.start {
+			current.end = start
+			sections = append(sections, current)
+		}
+		for len(levels) > 0 && levels[len(levels)-1] >= level {
+			levels = levels[:len(levels)-1]
+			titles = titles[:len(titles)-1]
+		}
+		levels = append(levels, level)
+		titles = append(titles, title)
+		current = section{start: start, headers: append([]string(nil), titles...)}
+	})
+
+	current.end = len(text)
+	if current.end > current.start {
+		sections = append(sections, current)
+	}
+	return sections
+}
+
+// markdownBlocks splits after blank lines out of code blocks, so code blocks
+// are kept in a chunk if they fit.
+func markdownBlocks(text string) []int {
+	var boundaries []int
+	markdownLines(text, func(start int, end int, fenced bool) {
+		if fenced || end >= len(text) {
+			return
+		}
+		if strings.TrimSpace(text[start:end]) == "" {
+			boundaries = append(boundaries, end)
+		}
+	})
+	return boundaries
+}
+
+// NewMarkdownSplitter creates a splitter for Markdown documents. Chunks do
+// not cross sections, and have the titles of their sections as Headers.
+// Sections are split between paragraphs, lists and code blocks, then lines,
+// sentences, words and characters.
+func NewMarkdownSplitter(options ...OptionFunc) Splitter {
+	return &splitter{
+		opts: newOptions(options),
+		levels: []boundaryFunc{
+			markdownBlocks,
+			separatorBoundaries("\n"),
+			sentenceBoundaries,
+			separatorBoundaries(" "),
+		},
+		sections: markdownSections,
+	}
+}
@@ -0,0 +1,64 @@
+package textsplit
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+const (
+	defaultChunkSize = 512
+)
+
+// LengthFunc returns the number of tokens of a text.
+type LengthFunc func(text string) int
+
+type Options struct {
+	// Maximum number of tokens of a chunk, default is 512. A chunk may exceed
+	// it slightly, because tokens of pieces are counted separately.
+	ChunkSize int
+	// Number of tokens shared by adjacent chunks, default is 0. It is at most
+	// half of the chunk size.
+	ChunkOverlap int
+	// Counts the tokens, default is aigc.Tokenizer.FastEstimate. Use the
+	// tokenizer of the embedding model for exact counts.
+	Length LengthFunc
+}
+
+type OptionFunc func(*Options)
+
+func WithChunkSize(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.ChunkSize = tokens
+	}
+}
+
+func WithChunkOverlap(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.ChunkOverlap = tokens
+	}
+}
+
+func WithLength(length LengthFunc) func(*Options) {
+	return func(o *Options) {
+		o.Length = length
+	}
+}
+
+func newOptions(options []OptionFunc) Options {
+	var opts Options
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	if opts.ChunkOverlap < 0 {
+		opts.ChunkOverlap = 0
+	}
+	if opts.ChunkOverlap > opts.ChunkSize/2 {
+		opts.ChunkOverlap = opts.ChunkSize / 2
+	}
+	if opts.Length == nil {
+		opts.Length = aigc.Tokenizer.FastEstimate
+	}
+	return opts
+}
@@ -0,0 +1,87 @@
+package textsplit
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// isSentenceEnd reports whether r ends a sentence. CJK punctuations end a
+// sentence by themselves, ASCII ones only if followed by a space.
+func isSentenceEnd(r rune) (end bool, cjk bool) {
+	switch r {
+	case '。', '！', '？', '；', '…', '．', '｡':
+		return true, true
+	case '.', '!', '?', ';':
+		return true, false
+	}
+	return false, false
+}
+
+// isClosing reports whether r closes a quotation or a bracket, which belongs
+// to the sentence before it.
+func isClosing(r rune) bool {
+	switch r {
+	case '"', '\'', ')', ']', '”', '’', '」', '』', '）', '》', '〉', '】':
+		return true
+	}
+	return false
+}
+
+// sentenceBoundaries splits after sentence ends and line breaks, e.g.
+// "Hello. 你好。" splits after "." and "。".
+func sentenceBoundaries(text string) []int {
+	var boundaries []int
+	for i := 0; i < len(text); {
+		var r, size = utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		if r == '\n' {
+			if i < len(text) {
+				boundaries = append(boundaries, i)
+			}
+			continue
+		}
+		var end, cjk = isSentenceEnd(r)
+		if !end {
+			continue
+		}
+
+		// Consecutive ends and closings, e.g. "?!" and "。」"
+		var j = i
+		for j < len(text) {
+			var next, nextSize = utf8.DecodeRuneInString(text[j:])
+			if e, _ := isSentenceEnd(next); !e && !isClosing(next) {
+				break
+			}
+			if e, c := isSentenceEnd(next); e && c {
+				cjk = true
+			}
+			j += nextSize
+		}
+		if j >= len(text) {
+			break
+		}
+		if !cjk {
+			// "3.14" and "example.com" are not sentence ends
+			var next, _ = utf8.DecodeRuneInString(text[j:])
+			if !unicode.IsSpace(next) {
+				i = j
+				continue
+			}
+		}
+		boundaries = append(boundaries, j)
+		i = j
+	}
+	return boundaries
+}
+
+// NewSentenceSplitter creates a splitter merges sentences into chunks.
+// Sentences end at ".", "!", "?" and ";" followed by spaces, at CJK
+// punctuations "。", "！", "？", "；" and "…", and at line breaks. Sentences
+// longer than the chunk size are split between words, then characters.
+func NewSentenceSplitter(options ...OptionFunc) Splitter {
+	return &splitter{
+		opts:   newOptions(options),
+		levels: []boundaryFunc{sentenceBoundaries, separatorBoundaries(" ")},
+	}
+}
@@ -0,0 +1,232 @@
+package textsplit
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a part of the source text.
+type Chunk struct {
+	Text string
+	// Byte offsets in the source text, Text is source[Start:End]
+	Start int
+	End   int
+	// Number of tokens counted by the length function
+	Tokens int
+	// Titles of the Markdown sections containing the chunk, outermost first.
+	// Only for Markdown splitters.
+	Headers []string
+}
+
+type Splitter interface {
+	// Split splits the text into chunks in the order of the text. Leading
+	// and trailing whitespaces of chunks are trimmed, and blank chunks are
+	// dropped.
+	Split(text string) []Chunk
+}
+
+// boundaryFunc returns the positions where the text can be split, in
+// ascending order and between 0 and len(text) exclusive.
+type boundaryFunc func(text string) []int
+
+type section struct {
+	start   int
+	end     int
+	headers []string
+}
+
+// splitter splits the text recursively: a part longer than the chunk size is
+// split at the boundaries of the first level, parts still too long are split
+// at the boundaries of the next level, and so on, parts without boundaries
+// are split between characters. Then adjacent parts are merged into chunks
+// up to the chunk size.
+type splitter struct {
+	opts     Options
+	levels   []boundaryFunc
+	sections func(text string) []section // nil means a single section
+}
+
+type piece struct {
+	start  int
+	end    int
+	tokens int
+}
+
+func (s *splitter) Split(text string) []Chunk {
+	var chunks []Chunk
+	var sections = []section{{start: 0, end: len(text)}}
+	if s.sections != nil {
+		sections = s.sections(text)
+	}
+	for _, sec := range sections {
+		var pieces = s.split(text, sec.start, sec.end, 0, nil)
+		chunks = s.merge(text, pieces, sec.headers, chunks)
+	}
+	return chunks
+}
+
+func (s *splitter) split(text string, start int, end int, level int, pieces []piece) []piece {
+	var tokens = s.opts.Length(text[start:end])
+	if tokens <= s.opts.ChunkSize {
+		if strings.TrimSpace(text[start:end]) == "" {
+			return pieces
+		}
+		return append(pieces, piece{start: start, end: end, tokens: tokens})
+	}
+	if level >= len(s.levels) {
+		return s.splitCharacters(text, start, end, pieces)
+	}
+
+	var boundaries = s.levels[level](text[start:end])
+	var from = start
+	for _, b := range boundaries {
+		if start+b > from {
+			pieces = s.split(text, from, start+b, level+1, pieces)
+			from = start + b
+		}
+	}
+	return s.split(text, from, end, level+1, pieces)
+}
+
+// splitCharacters splits between characters, each part has the longest
+// prefix fits the chunk size.
+func (s *splitter) splitCharacters(text string, start int, end int, pieces []piece) []piece {
+	var ends []int // ends of the runes
+	for i := start; i < end; {
+		var _, size = utf8.DecodeRuneInString(text[i:end])
+		i += size
+		ends = append(ends, i)
+	}
+	for len(ends) > 0 {
+		var n = sort.Search(len(ends), func(i int) bool {
+			return s.opts.Length(text[start:ends[i]]) > s.opts.ChunkSize
+		})
+		if n == 0 {
+			// A single rune exceeds the chunk size
+			n = 1
+		}
+		pieces = append(pieces, piece{start: start, end: ends[n-1], tokens: s.opts.Length(text[start:ends[n-1]])})
+		start = ends[n-1]
+		ends = ends[n:]
+	}
+	return pieces
+}
+
+// merge merges adjacent pieces into chunks up to the chunk size, adjacent
+// chunks share pieces up to the chunk overlap.
+func (s *splitter) merge(text string, pieces []piece, headers []string, chunks []Chunk) []Chunk {
+	var size, overlap = s.opts.ChunkSize, s.opts.ChunkOverlap
+	var i = 0
+	for i < len(pieces) {
+		var j, tokens = i, 0
+		for j < len(pieces) && (j == i || tokens+pieces[j].tokens <= size) {
+			tokens += pieces[j].tokens
+			j++
+		}
+		chunks = s.appendChunk(chunks, text, pieces[i].start, pieces[j-1].end, headers)
+		if j == len(pieces) {
+			break
+		}
+
+		// The next chunk starts with the last pieces of this chunk
+		var k, shared = j, 0
+		for k > i+1 && shared+pieces[k-1].tokens <= overlap {
+			shared += pieces[k-1].tokens
+			k--
+		}
+		for k < j && shared+pieces[j].tokens > size {
+			shared -= pieces[k].tokens
+			k++
+		}
+		i = k
+	}
+	return chunks
+}
+
+func (s *splitter) appendChunk(chunks []Chunk, text string, start int, end int, headers []string) []Chunk {
+	for start < end {
+		var r, size = utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		var r, size = utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	if start == end {
+		return chunks
+	}
+	return append(chunks, Chunk{
+		Text:    text[start:end],
+		Start:   start,
+		End:     end,
+		Tokens:  s.opts.Length(text[start:end]),
+		Headers: headers,
+	})
+}
+
+// separatorBoundaries returns a boundary func splits at the separators. The
+// leading newlines of a separator end the part before it, the rest starts the
+// part after it, e.g. "\n\n" ends a paragraph, and "\nfunc " starts a
+// function.
+func separatorBoundaries(separators ...string) boundaryFunc {
+	return func(text string) []int {
+		var boundaries []int
+		for _, separator := range separators {
+			if separator == "" {
+				continue
+			}
+			var newlines = len(separator) - len(strings.TrimLeft(separator, "\n"))
+			for from := 0; ; {
+				var i = strings.Index(text[from:], separator)
+				if i < 0 {
+					break
+				}
+				var b = from + i + newlines
+				if b > 0 && b < len(text) {
+					boundaries = append(boundaries, b)
+				}
+				from += i + len(separator)
+			}
+		}
+		if len(separators) > 1 {
+			sort.Ints(boundaries)
+		}
+		return boundaries
+	}
+}
+
+var defaultSeparators = []string{"\n\n", "\n", " "}
+
+// NewRecursiveSplitter creates a splitter tries the separators in order, then
+// characters. The default separators are paragraphs, lines and spaces.
+//
+// Sentence ends are tried before spaces, or after the separators if spaces
+// are not one of them, so CJK texts without spaces are split by sentences.
+func NewRecursiveSplitter(separators []string, options ...OptionFunc) Splitter {
+	if separators == nil {
+		separators = defaultSeparators
+	}
+	var levels []boundaryFunc
+	var sentences = false
+	for _, separator := range separators {
+		if separator == " " && !sentences {
+			levels = append(levels, sentenceBoundaries)
+			sentences = true
+		}
+		if separator != "" {
+			levels = append(levels, separatorBoundaries(separator))
+		}
+	}
+	if !sentences {
+		levels = append(levels, sentenceBoundaries)
+	}
+	return &splitter{opts: newOptions(options), levels: levels}
+}
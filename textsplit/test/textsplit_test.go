@@ -0,0 +1,214 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/textsplit"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// words counts the words, an exact length function for the tests.
+func words(text string) int {
+	return len(strings.Fields(text))
+}
+
+// runes counts the characters.
+func runes(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+func checkChunks(t *testing.T, source string, chunks []textsplit.Chunk, size int, length func(string) int) {
+	t.Helper()
+	if len(chunks) == 0 {
+		t.Fatal("no chunks")
+	}
+	for i, chunk := range chunks {
+		if source[chunk.Start:chunk.End] != chunk.Text {
+			t.Errorf("chunk %d: offsets [%d, %d) do not match the text %q", i, chunk.Start, chunk.End, chunk.Text)
+		}
+		if chunk.Tokens != length(chunk.Text) || chunk.Tokens > size {
+			t.Errorf("chunk %d: %d tokens, size %d: %q", i, chunk.Tokens, size, chunk.Text)
+		}
+		if strings.TrimSpace(chunk.Text) != chunk.Text || chunk.Text == "" {
+			t.Errorf("chunk %d is not trimmed: %q", i, chunk.Text)
+		}
+		if i > 0 && chunk.Start < chunks[i-1].Start {
+			t.Errorf("chunk %d is out of order", i)
+		}
+	}
+}
+
+func Test_Recursive_Paragraphs(t *testing.T) {
+	var text = "The first paragraph has some words.\n\n" +
+		"The second paragraph is a bit longer than the first one, it has more words.\n\n" +
+		"Third."
+	var splitter = textsplit.NewRecursiveSplitter(nil, textsplit.WithChunkSize(16), textsplit.WithLength(words))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 16, words)
+
+	if len(chunks) != 2 {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	// The long paragraph is split into sentences, which are merged with the
+	// short paragraph after it
+	if chunks[0].Text != "The first paragraph has some words." {
+		t.Errorf("unexpected first chunk: %q", chunks[0].Text)
+	}
+	if !strings.HasSuffix(chunks[1].Text, "Third.") {
+		t.Errorf("unexpected last chunk: %q", chunks[1].Text)
+	}
+}
+
+func Test_Recursive_Overlap(t *testing.T) {
+	var text = strings.Repeat("alpha beta gamma delta epsilon ", 20)
+	var splitter = textsplit.NewRecursiveSplitter(nil,
+		textsplit.WithChunkSize(10), textsplit.WithChunkOverlap(3), textsplit.WithLength(words))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 10, words)
+
+	for i := 1; i < len(chunks); i++ {
+		var shared = chunks[i-1].End - chunks[i].Start
+		if shared <= 0 || words(text[chunks[i].Start:chunks[i-1].End]) != 3 {
+			t.Fatalf("chunks %d and %d do not overlap by 3 words: %q, %q", i-1, i, chunks[i-1].Text, chunks[i].Text)
+		}
+	}
+	if chunks[len(chunks)-1].End != len(strings.TrimSpace(text)) {
+		t.Error("text is not fully covered")
+	}
+}
+
+func Test_Recursive_Characters(t *testing.T) {
+	var text = strings.Repeat("x", 25)
+	var splitter = textsplit.NewRecursiveSplitter(nil, textsplit.WithChunkSize(10), textsplit.WithLength(runes))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 10, runes)
+	if len(chunks) != 3 || chunks[2].Text != "xxxxx" {
+		t.Errorf("unexpected chunks: %+v", chunks)
+	}
+}
+
+func Test_Recursive_FastEstimate(t *testing.T) {
+	var text = strings.Repeat("Retrieval augmented generation needs chunks of a bounded size. ", 200)
+	var chunks = textsplit.NewRecursiveSplitter(nil, textsplit.WithChunkSize(100)).Split(text)
+	if len(chunks) < 10 {
+		t.Fatalf("unexpected number of chunks: %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if text[chunk.Start:chunk.End] != chunk.Text || chunk.Tokens > 110 {
+			t.Errorf("chunk %d: %d tokens", i, chunk.Tokens)
+		}
+	}
+}
+
+func Test_Sentence_CJK(t *testing.T) {
+	var text = "今天的天气真的非常好。我们一起去公园散步吧！你觉得这个主意怎么样？“好啊，就这么办。”他说。"
+	var splitter = textsplit.NewSentenceSplitter(textsplit.WithChunkSize(12), textsplit.WithLength(runes))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 12, runes)
+
+	var want = []string{
+		"今天的天气真的非常好。",
+		"我们一起去公园散步吧！",
+		"你觉得这个主意怎么样？",
+		"“好啊，就这么办。”",
+		"他说。",
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	for i := range want {
+		if chunks[i].Text != want[i] {
+			t.Errorf("sentence %d: %q, want %q", i, chunks[i].Text, want[i])
+		}
+	}
+}
+
+func Test_Sentence_Latin(t *testing.T) {
+	var text = "Version 3.14 is out. See example.com for details!\nThanks"
+	var splitter = textsplit.NewSentenceSplitter(textsplit.WithChunkSize(4), textsplit.WithLength(words))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 4, words)
+
+	var want = []string{"Version 3.14 is out.", "See example.com for details!", "Thanks"}
+	if len(chunks) != len(want) {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	for i := range want {
+		if chunks[i].Text != want[i] {
+			t.Errorf("sentence %d: %q, want %q", i, chunks[i].Text, want[i])
+		}
+	}
+}
+
+func Test_Markdown_Sections(t *testing.T) {
+	var text = "# Guide\n\nIntro text.\n\n" +
+		"## Install\n\nRun the installer.\n\n" +
+		"```sh\n# not a header\nmake install\n```\n\n" +
+		"### Linux\n\nUse the package manager.\n\n" +
+		"## Usage\n\nCall the API.\n"
+	var splitter = textsplit.NewMarkdownSplitter(textsplit.WithChunkSize(100), textsplit.WithLength(words))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 100, words)
+
+	var want = [][]string{{"Guide"}, {"Guide", "Install"}, {"Guide", "Install", "Linux"}, {"Guide", "Usage"}}
+	if len(chunks) != len(want) {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	for i := range want {
+		if strings.Join(chunks[i].Headers, "/") != strings.Join(want[i], "/") {
+			t.Errorf("chunk %d: headers %v, want %v", i, chunks[i].Headers, want[i])
+		}
+	}
+	if !strings.Contains(chunks[1].Text, "make install\n```") {
+		t.Errorf("code block is not kept in its section: %q", chunks[1].Text)
+	}
+}
+
+func Test_Markdown_CodeBlock(t *testing.T) {
+	var code = "```go\nfunc main() {\n\n\tprintln(1)\n}\n```"
+	var text = "# Code\n\nSome words before the code block.\n\n" + code + "\n\nAnd after."
+	var splitter = textsplit.NewMarkdownSplitter(textsplit.WithChunkSize(8), textsplit.WithLength(words))
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 8, words)
+
+	var found = false
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Text, code) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("code block is split at the blank line: %+v", chunks)
+	}
+}
+
+func Test_Code_Go(t *testing.T) {
+	var text = "package main\n\nimport \"fmt\"\n\n" +
+		"// hello prints a greeting\n// to the world.\nfunc hello() {\n\tfmt.Println(\"hello\")\n}\n\n" +
+		"type point struct {\n\tx, y int\n}\n\n" +
+		"func main() {\n\thello()\n}\n"
+	var splitter, err = textsplit.NewCodeSplitter(textsplit.LanguageGo, textsplit.WithChunkSize(16), textsplit.WithLength(words))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks = splitter.Split(text)
+	checkChunks(t, text, chunks, 16, words)
+
+	var hello = false
+	for _, chunk := range chunks {
+		if strings.HasPrefix(chunk.Text, "// hello prints a greeting") && strings.Contains(chunk.Text, "func hello()") {
+			hello = true
+		}
+		if strings.Contains(chunk.Text, "func main") && !strings.HasPrefix(chunk.Text, "func main") &&
+			!strings.HasPrefix(chunk.Text, "type point") {
+			t.Errorf("func main is not split at the declaration: %q", chunk.Text)
+		}
+	}
+	if !hello {
+		t.Errorf("comments are separated from the function: %+v", chunks)
+	}
+
+	if _, err = textsplit.NewCodeSplitter("cobol"); err == nil {
+		t.Error("unknown language is accepted")
+	}
+}
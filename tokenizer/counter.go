@@ -0,0 +1,177 @@
+package tokenizer
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+// Counter counts the tokens of chat requests, including the overheads of the
+// chat format, tool schemas and images. The counts of exact tokenizers are
+// close to the prompt tokens reported by the vendors, but not equal, as the
+// vendors do not publish all details of their formats.
+type Counter struct {
+	count func(text string) int
+	opts  Options
+}
+
+// NewCounter creates a counter counts texts by the count function, e.g.
+// Tokenizer.Count. Default is aigc.Tokenizer.FastEstimate.
+func NewCounter(count func(text string) int, options ...OptionFunc) *Counter {
+	if count == nil {
+		count = aigc.Tokenizer.FastEstimate
+	}
+	return &Counter{count: count, opts: newOptions(options)}
+}
+
+// Count returns the number of tokens of a text.
+func (c *Counter) Count(text string) int {
+	return c.count(text)
+}
+
+// countJson counts the tokens of the JSON encoding of a value.
+func (c *Counter) countJson(value any) int {
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+	if aigc.EncodeJson(buffer, value) != nil {
+		return 0
+	}
+	return c.count(string(bytes.TrimSpace(buffer.Bytes())))
+}
+
+// CountContent returns the number of tokens of a content block. Audio is
+// counted by its transcript, and PDF documents without the text are not
+// counted.
+func (c *Counter) CountContent(content *chat.ContentBlock) int {
+	switch content.Type {
+	case chat.ContentTypeText, chat.ContentTypeReasoning:
+		return c.count(content.Text) + c.count(content.Refusal)
+	case chat.ContentTypeImage:
+		var width, height = imageSize(content)
+		return c.opts.ImageTokens(width, height)
+	case chat.ContentTypeDocument:
+		var tokens = c.count(content.Title)
+		if content.Text != "" {
+			tokens += c.count(content.Text)
+		} else if content.DocumentType == chat.DocumentText {
+			tokens += c.count(string(content.Data))
+		}
+		return tokens
+	case chat.ContentTypeAudio:
+		return c.count(content.Transcript)
+	case chat.ContentTypeToolCall:
+		return c.opts.ToolCallTokens + c.count(content.ToolCallId) + c.count(content.ToolName) +
+			c.countJson(content.Arguments)
+	case chat.ContentTypeToolResult:
+		var tokens = c.opts.ToolCallTokens + c.count(content.ToolCallId)
+		if s, ok := content.Result.(string); ok {
+			return tokens + c.count(s)
+		}
+		return tokens + c.countJson(content.Result)
+	}
+	return 0
+}
+
+// CountMessage returns the number of tokens of a message, including the
+// message overhead.
+func (c *Counter) CountMessage(message *chat.Message) int {
+	var tokens = c.opts.MessageTokens + c.count(string(message.Role))
+	if message.Name != "" {
+		tokens += c.opts.NameTokens + c.count(message.Name)
+	}
+	for i := range message.Contents {
+		tokens += c.CountContent(&message.Contents[i])
+	}
+	return tokens
+}
+
+// CountTools returns the number of tokens of the tool definitions, which are
+// counted as their JSON schemas.
+func (c *Counter) CountTools(tools []chat.Tool) int {
+	var tokens = 0
+	for i := range tools {
+		tokens += c.opts.ToolTokens + c.countJson(struct {
+			Name        string              `json:"name"`
+			Description string              `json:"description,omitempty"`
+			Parameters  chat.ToolParameters `json:"parameters"`
+		}{tools[i].Name, tools[i].Description, tools[i].Parameters})
+	}
+	return tokens
+}
+
+// CountRequest returns the number of prompt tokens of a request, the
+// messages, the tools and the reply priming.
+func (c *Counter) CountRequest(request *chat.ModelRequest) int {
+	var tokens = c.opts.ReplyTokens + c.CountTools(request.Tools)
+	for i := range request.Messages {
+		tokens += c.CountMessage(&request.Messages[i])
+	}
+	return tokens
+}
+
+// imageSize returns the size of a PNG or JPEG image in the data, zeros if it
+// is unknown.
+func imageSize(content *chat.ContentBlock) (int, int) {
+	var config image.Config
+	var err error
+	if len(content.Data) == 0 {
+		return 0, 0
+	}
+	switch content.MediaType {
+	case chat.ImagePng:
+		config, err = png.DecodeConfig(bytes.NewReader(content.Data))
+	case chat.ImageJpeg:
+		config, err = jpeg.DecodeConfig(bytes.NewReader(content.Data))
+	default:
+		return 0, 0
+	}
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
+
+// OpenAIImageTokens returns the tokens of an image in high detail. The image
+// is scaled to fit in 2048x2048, then its shorter side is scaled down to 768,
+// each 512x512 tile costs 170 tokens plus 85 tokens of the image. Unknown
+// sizes are counted as 1024x1024, 765 tokens.
+func OpenAIImageTokens(width int, height int) int {
+	if width <= 0 || height <= 0 {
+		width, height = 1024, 1024
+	}
+	var w, h = float64(width), float64(height)
+	if long := max(w, h); long > 2048 {
+		w, h = w*2048/long, h*2048/long
+	}
+	if short := min(w, h); short > 768 {
+		w, h = w*768/short, h*768/short
+	}
+	var tiles = math.Ceil(w/512) * math.Ceil(h/512)
+	return 85 + 170*int(tiles)
+}
+
+// AnthropicImageTokens returns the tokens of an image of Claude models,
+// width*height/750. The image is scaled to fit in 1568 pixels on the long
+// side and 1.15 megapixels. Unknown sizes are counted as 1092x1092, about
+// 1600 tokens.
+func AnthropicImageTokens(width int, height int) int {
+	if width <= 0 || height <= 0 {
+		width, height = 1092, 1092
+	}
+	var w, h = float64(width), float64(height)
+	if long := max(w, h); long > 1568 {
+		w, h = w*1568/long, h*1568/long
+	}
+	if pixels := w * h; pixels > 1_150_000 {
+		var scale = math.Sqrt(1_150_000 / pixels)
+		w, h = w*scale, h*scale
+	}
+	return int(math.Ceil(w * h / 750))
+}
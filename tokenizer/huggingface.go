@@ -0,0 +1,255 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// hfTokenizerJson is the tokenizer.json file of Hugging Face tokenizers.
+type hfTokenizerJson struct {
+	AddedTokens []struct {
+		Id      int    `json:"id"`
+		Content string `json:"content"`
+	} `json:"added_tokens"`
+	Normalizer   *hfComponent `json:"normalizer"`
+	PreTokenizer *hfComponent `json:"pre_tokenizer"`
+	Model        struct {
+		Type         string            `json:"type"`
+		Vocab        map[string]int    `json:"vocab"`
+		Merges       []json.RawMessage `json:"merges"`
+		IgnoreMerges bool              `json:"ignore_merges"`
+		UnkToken     string            `json:"unk_token"`
+	} `json:"model"`
+}
+
+// hfComponent is a normalizer or a pre-tokenizer.
+type hfComponent struct {
+	Type    string `json:"type"`
+	Pattern struct {
+		Regex string `json:"Regex"`
+	} `json:"pattern"`
+	Behavior       string        `json:"behavior"`
+	Invert         bool          `json:"invert"`
+	AddPrefixSpace bool          `json:"add_prefix_space"`
+	UseRegex       *bool         `json:"use_regex"`
+	PreTokenizers  []hfComponent `json:"pretokenizers"`
+	Normalizers    []hfComponent `json:"normalizers"`
+}
+
+var hfPatterns = map[string]func(text string, fn func(piece string)){
+	cl100kPattern: cl100kSplit(3),
+	qwen2Pattern:  cl100kSplit(1),
+	o200kPattern:  o200kSplit,
+	gpt2Pattern:   gpt2Split,
+}
+
+// byteLevelDecoder maps the characters of byte-level vocabularies to the
+// bytes. Printable bytes are themselves, others are shifted from U+0100 up,
+// e.g. "Ġ" is the space.
+var byteLevelDecoder = func() map[rune]byte {
+	var decoder = make(map[rune]byte, 256)
+	var n = 0
+	for b := 0; b < 256; b++ {
+		if b >= '!' && b <= '~' || b >= 0xA1 && b <= 0xAC || b >= 0xAE && b <= 0xFF {
+			decoder[rune(b)] = byte(b)
+		} else {
+			decoder[rune(256+n)] = byte(b)
+			n++
+		}
+	}
+	return decoder
+}()
+
+func decodeByteLevel(token string) (string, bool) {
+	var b = make([]byte, 0, len(token))
+	for _, r := range token {
+		var c, ok = byteLevelDecoder[r]
+		if !ok {
+			return "", false
+		}
+		b = append(b, c)
+	}
+	return string(b), true
+}
+
+// LoadHuggingFace loads a tokenizer.json file of a byte-level BPE tokenizer,
+// e.g. Llama 3 and Qwen2. Added tokens are special tokens. The NFC normalizer
+// of Qwen2 is not applied, texts are expected to be in NFC already, as most
+// texts are. The post-processor is not applied either, e.g. the BOS token of
+// Llama 3 is not added.
+func LoadHuggingFace(path string) (*Tokenizer, error) {
+	var data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[LoadHuggingFace] %w", err)
+	}
+	var file hfTokenizerJson
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("[LoadHuggingFace] %s: %w", path, err)
+	}
+	t, err := newHuggingFace(&file)
+	if err != nil {
+		return nil, fmt.Errorf("[LoadHuggingFace] %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func newHuggingFace(file *hfTokenizerJson) (*Tokenizer, error) {
+	if file.Model.Type != "" && file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported model %q", file.Model.Type)
+	}
+	if len(file.Model.Vocab) == 0 {
+		return nil, errors.New("empty vocabulary")
+	}
+	if err := checkNormalizer(file.Normalizer); err != nil {
+		return nil, err
+	}
+	var split, err = hfPreTokenizer(file.PreTokenizer)
+	if err != nil {
+		return nil, err
+	}
+
+	var vocab = make(map[string]int, len(file.Model.Vocab))
+	for token, id := range file.Model.Vocab {
+		var b, ok = decodeByteLevel(token)
+		if !ok {
+			return nil, fmt.Errorf("token %q is not byte-level", token)
+		}
+		vocab[b] = id
+	}
+
+	var t = newTokenizer(vocab, split)
+	t.ignoreMerges = file.Model.IgnoreMerges
+	t.merges = make(map[[2]int]int, len(file.Model.Merges))
+	for rank, raw := range file.Model.Merges {
+		var left, right, err = parseMerge(raw)
+		if err != nil {
+			return nil, fmt.Errorf("merge %d: %w", rank, err)
+		}
+		var l, ok1 = vocab[left]
+		var r, ok2 = vocab[right]
+		if _, ok3 := vocab[left+right]; !ok1 || !ok2 || !ok3 {
+			return nil, fmt.Errorf("merge %d: token is not in the vocabulary", rank)
+		}
+		if _, ok := t.merges[[2]int{l, r}]; !ok {
+			t.merges[[2]int{l, r}] = rank
+		}
+	}
+
+	if file.Model.UnkToken != "" {
+		if id, ok := file.Model.Vocab[file.Model.UnkToken]; ok {
+			t.unknown = id
+		}
+	}
+	for _, added := range file.AddedTokens {
+		if added.Content != "" {
+			t.addSpecial(added.Content, added.Id)
+		}
+	}
+	return t, nil
+}
+
+// parseMerge parses a merge, "a b" or ["a", "b"], into the bytes of the pair.
+func parseMerge(raw json.RawMessage) (string, string, error) {
+	var left, right string
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		var ok bool
+		left, right, ok = strings.Cut(s, " ")
+		if !ok {
+			return "", "", fmt.Errorf("invalid merge %q", s)
+		}
+	} else {
+		var pair []string
+		if err := json.Unmarshal(raw, &pair); err != nil || len(pair) != 2 {
+			return "", "", fmt.Errorf("invalid merge %s", raw)
+		}
+		left, right = pair[0], pair[1]
+	}
+
+	var ok1, ok2 bool
+	left, ok1 = decodeByteLevel(left)
+	right, ok2 = decodeByteLevel(right)
+	if !ok1 || !ok2 {
+		return "", "", errors.New("merge is not byte-level")
+	}
+	return left, right, nil
+}
+
+func checkNormalizer(c *hfComponent) error {
+	if c == nil {
+		return nil
+	}
+	switch c.Type {
+	case "NFC":
+		return nil
+	case "Sequence":
+		for i := range c.Normalizers {
+			if err := checkNormalizer(&c.Normalizers[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported normalizer %q", c.Type)
+}
+
+// hfPreTokenizer returns the split function of a pre-tokenizer, a Split of a
+// known pattern followed by a ByteLevel without the regular expression, or a
+// ByteLevel with the GPT-2 expression.
+func hfPreTokenizer(c *hfComponent) (func(text string, fn func(piece string)), error) {
+	if c == nil {
+		return nil, errors.New("pre-tokenizer is required")
+	}
+	var components = []hfComponent{*c}
+	if c.Type == "Sequence" {
+		components = c.PreTokenizers
+	}
+
+	var split func(text string, fn func(piece string))
+	var byteLevel, prefixSpace = false, false
+	for _, component := range components {
+		switch component.Type {
+		case "Split":
+			var fn, ok = hfPatterns[component.Pattern.Regex]
+			if !ok || component.Invert || component.Behavior != "Isolated" || split != nil {
+				return nil, fmt.Errorf("unsupported split pattern %q", component.Pattern.Regex)
+			}
+			split = fn
+		case "ByteLevel":
+			if component.UseRegex == nil || *component.UseRegex {
+				if split != nil {
+					return nil, errors.New("unsupported pre-tokenizer: split twice")
+				}
+				split = gpt2Split
+			}
+			byteLevel = true
+			prefixSpace = component.AddPrefixSpace
+		default:
+			return nil, fmt.Errorf("unsupported pre-tokenizer %q", component.Type)
+		}
+	}
+	if !byteLevel {
+		return nil, errors.New("pre-tokenizer is not byte-level")
+	}
+	if split == nil {
+		split = func(text string, fn func(piece string)) {
+			if text != "" {
+				fn(text)
+			}
+		}
+	}
+	if prefixSpace {
+		var inner = split
+		split = func(text string, fn func(piece string)) {
+			if text != "" && text[0] != ' ' {
+				text = " " + text
+			}
+			inner(text, fn)
+		}
+	}
+	return split, nil
+}
@@ -0,0 +1,79 @@
+package tokenizer
+
+// ImageTokensFunc returns the number of tokens of an image, width and height
+// are zero if the size of the image is unknown, e.g. image urls.
+type ImageTokensFunc func(width int, height int) int
+
+// Options of counters. The defaults are the overheads of the OpenAI chat
+// format.
+type Options struct {
+	// Tokens of each message, e.g. "<|im_start|>", the role and
+	// "<|im_end|>". Default is 3.
+	MessageTokens int
+	// Tokens of the name of a message. Default is 1.
+	NameTokens int
+	// Tokens priming the reply of the assistant. Default is 3.
+	ReplyTokens int
+	// Tokens of each tool definition besides its JSON schema. Default is 8.
+	ToolTokens int
+	// Tokens of each tool call and tool result besides the arguments and the
+	// result. Default is 4.
+	ToolCallTokens int
+	// Counts the tokens of images. Default is OpenAIImageTokens.
+	ImageTokens ImageTokensFunc
+}
+
+type OptionFunc func(*Options)
+
+func WithMessageTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.MessageTokens = tokens
+	}
+}
+
+func WithNameTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.NameTokens = tokens
+	}
+}
+
+func WithReplyTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.ReplyTokens = tokens
+	}
+}
+
+func WithToolTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.ToolTokens = tokens
+	}
+}
+
+func WithToolCallTokens(tokens int) func(*Options) {
+	return func(o *Options) {
+		o.ToolCallTokens = tokens
+	}
+}
+
+func WithImageTokens(imageTokens ImageTokensFunc) func(*Options) {
+	return func(o *Options) {
+		o.ImageTokens = imageTokens
+	}
+}
+
+func newOptions(options []OptionFunc) Options {
+	var opts = Options{
+		MessageTokens:  3,
+		NameTokens:     1,
+		ReplyTokens:    3,
+		ToolTokens:     8,
+		ToolCallTokens: 4,
+	}
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.ImageTokens == nil {
+		opts.ImageTokens = OpenAIImageTokens
+	}
+	return opts
+}
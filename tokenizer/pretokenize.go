@@ -0,0 +1,305 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The pre-tokenizers split the text as the regular expressions of the
+// vocabularies. The expressions use look-ahead, which the regexp package
+// does not support, so they are matched by hand. Alternatives are tried in
+// order at each position, the first one matches wins.
+
+// cl100kPattern is the expression of cl100k_base and Llama 3. Qwen2 is the
+// same except that numbers are split into single digits.
+const cl100kPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+const qwen2Pattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+// o200kPattern is the expression of o200k_base, in a single line.
+const o200kPattern = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+// gpt2Pattern is the expression of GPT-2, and the ByteLevel pre-tokenizer of
+// Hugging Face.
+const gpt2Pattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// runeAt decodes the rune at i, -1 at the end of the text.
+func runeAt(text string, i int) (rune, int) {
+	if i >= len(text) {
+		return -1, 0
+	}
+	if text[i] < utf8.RuneSelf {
+		return rune(text[i]), 1
+	}
+	return utf8.DecodeRuneInString(text[i:])
+}
+
+func isLetter(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	}
+	return unicode.IsLetter(r)
+}
+
+func isNumber(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= '0' && r <= '9'
+	}
+	return unicode.IsNumber(r)
+}
+
+func isSpace(r rune) bool {
+	return r >= 0 && unicode.IsSpace(r)
+}
+
+// isOther matches [^\s\p{L}\p{N}].
+func isOther(r rune) bool {
+	return r >= 0 && !isSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+// isPrefix matches [^\r\n\p{L}\p{N}].
+func isPrefix(r rune) bool {
+	return r >= 0 && r != '\r' && r != '\n' && !isLetter(r) && !isNumber(r)
+}
+
+// isUpper matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}].
+func isUpper(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= 'A' && r <= 'Z'
+	}
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLower matches [\p{Ll}\p{Lm}\p{Lo}\p{M}].
+func isLower(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= 'a' && r <= 'z'
+	}
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// skip returns the end of the run of runes matching the class from i.
+func skip(text string, i int, class func(rune) bool) int {
+	for {
+		var r, size = runeAt(text, i)
+		if !class(r) {
+			return i
+		}
+		i += size
+	}
+}
+
+// contraction returns the length of the contraction at i, e.g. "'s", zero if
+// there is none.
+func contraction(text string, i int, fold bool) int {
+	if i >= len(text) || text[i] != '\'' {
+		return 0
+	}
+	for _, suffix := range contractions {
+		var end = i + 1 + len(suffix)
+		if end > len(text) {
+			continue
+		}
+		var s = text[i+1 : end]
+		if s == suffix || fold && strings.EqualFold(s, suffix) {
+			return 1 + len(suffix)
+		}
+	}
+	return 0
+}
+
+// matchOthers matches " ?[^\s\p{L}\p{N}]+" followed by the trailing bytes,
+// returns i if it does not match.
+func matchOthers(text string, i int, trailing string) int {
+	var j = i
+	if j < len(text) && text[j] == ' ' {
+		j++
+	}
+	if r, _ := runeAt(text, j); !isOther(r) {
+		// Without the optional space neither, a space is not in the class
+		return i
+	}
+	j = skip(text, j, isOther)
+	for j < len(text) && strings.IndexByte(trailing, text[j]) >= 0 {
+		j++
+	}
+	return j
+}
+
+// matchSpaces matches "\s*[\r\n]+|\s+(?!\S)|\s+" if newlines is set, or
+// "\s+(?!\S)|\s+", at a space.
+func matchSpaces(text string, i int, newlines bool) int {
+	var j = i
+	var newline = -1
+	for {
+		var r, size = runeAt(text, j)
+		if !isSpace(r) {
+			break
+		}
+		if r == '\r' || r == '\n' {
+			newline = j
+		}
+		j += size
+	}
+	if newlines && newline >= 0 {
+		return newline + 1
+	}
+	if j >= len(text) {
+		return j
+	}
+	// The last space goes with the word after it
+	var _, size = utf8.DecodeLastRuneInString(text[i:j])
+	if j-size > i {
+		return j - size
+	}
+	return j
+}
+
+// splitWith calls fn with the pieces of the text, match returns the end of
+// the piece at i.
+func splitWith(text string, fn func(piece string), match func(text string, i int) int) {
+	for i := 0; i < len(text); {
+		var end = match(text, i)
+		if end <= i {
+			// Not reachable for valid patterns, a rune per piece
+			var _, size = runeAt(text, i)
+			end = i + size
+		}
+		fn(text[i:end])
+		i = end
+	}
+}
+
+// cl100kSplit returns the pre-tokenizer of cl100k_base, numbers are split
+// into up to the digits.
+func cl100kSplit(digits int) func(text string, fn func(piece string)) {
+	var match = func(text string, i int) int {
+		if n := contraction(text, i, true); n > 0 {
+			return i + n
+		}
+
+		var r, size = runeAt(text, i)
+		if isLetter(r) {
+			return skip(text, i, isLetter)
+		}
+		if isPrefix(r) {
+			if next, _ := runeAt(text, i+size); isLetter(next) {
+				return skip(text, i+size, isLetter)
+			}
+		}
+
+		if isNumber(r) {
+			var j = i
+			for n := 0; n < digits; n++ {
+				var d, dsize = runeAt(text, j)
+				if !isNumber(d) {
+					break
+				}
+				j += dsize
+			}
+			return j
+		}
+
+		if end := matchOthers(text, i, "\r\n"); end > i {
+			return end
+		}
+		return matchSpaces(text, i, true)
+	}
+
+	return func(text string, fn func(piece string)) {
+		splitWith(text, fn, match)
+	}
+}
+
+// o200kLower matches "[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+",
+// returns -1 if it does not match.
+func o200kLower(text string, i int) int {
+	var j = i
+	var last = -1 // end of the last lower rune in the upper run
+	for {
+		var r, size = runeAt(text, j)
+		if !isUpper(r) {
+			break
+		}
+		j += size
+		if isLower(r) {
+			last = j
+		}
+	}
+	if r, _ := runeAt(text, j); isLower(r) {
+		return skip(text, j, isLower)
+	}
+	// The upper run gives back runes until it ends with a lower one
+	return last
+}
+
+// o200kUpper matches "[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*",
+// returns -1 if it does not match.
+func o200kUpper(text string, i int) int {
+	var j = skip(text, i, isUpper)
+	if j == i {
+		return -1
+	}
+	return skip(text, j, isLower)
+}
+
+func o200kSplit(text string, fn func(piece string)) {
+	splitWith(text, fn, func(text string, i int) int {
+		var r, size = runeAt(text, i)
+		for _, word := range []func(string, int) int{o200kLower, o200kUpper} {
+			var end = -1
+			if isPrefix(r) {
+				end = word(text, i+size)
+			}
+			if end < 0 {
+				end = word(text, i)
+			}
+			if end >= 0 {
+				return end + contraction(text, end, true)
+			}
+		}
+
+		if isNumber(r) {
+			var j = i
+			for n := 0; n < 3; n++ {
+				var d, dsize = runeAt(text, j)
+				if !isNumber(d) {
+					break
+				}
+				j += dsize
+			}
+			return j
+		}
+
+		if end := matchOthers(text, i, "\r\n/"); end > i {
+			return end
+		}
+		return matchSpaces(text, i, true)
+	})
+}
+
+func gpt2Split(text string, fn func(piece string)) {
+	splitWith(text, fn, func(text string, i int) int {
+		if n := contraction(text, i, false); n > 0 {
+			return i + n
+		}
+
+		var j = i
+		if text[j] == ' ' {
+			j++
+		}
+		var r, _ = runeAt(text, j)
+		switch {
+		case isLetter(r):
+			return skip(text, j, isLetter)
+		case isNumber(r):
+			return skip(text, j, isNumber)
+		case isOther(r):
+			return skip(text, j, isOther)
+		}
+		return matchSpaces(text, i, false)
+	})
+}
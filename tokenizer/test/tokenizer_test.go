@@ -0,0 +1,296 @@
+package test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/tokenizer"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTiktoken writes a rank file of the single bytes and the tokens.
+func writeTiktoken(t *testing.T, tokens ...string) string {
+	t.Helper()
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, token := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	var path = filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pieces decodes the tokens one by one.
+func pieces(t *testing.T, tok *tokenizer.Tokenizer, ids []int) []string {
+	t.Helper()
+	var result []string
+	for _, id := range ids {
+		var s, err = tok.Decode([]int{id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func checkPieces(t *testing.T, encoding tokenizer.Encoding, text string, want []string) {
+	t.Helper()
+	// Each expected piece is a token, the pieces are tokens if and only if
+	// the pre-tokenizer splits the text into them
+	var tok, err = tokenizer.LoadTiktoken(writeTiktoken(t, want...), encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids = tok.Encode(text)
+	var got = pieces(t, tok, ids)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("pieces of %q:\n got %q\nwant %q", text, got, want)
+	}
+	if tok.Count(text) != len(ids) {
+		t.Errorf("count %d, encoded %d tokens", tok.Count(text), len(ids))
+	}
+	decoded, err := tok.Decode(ids)
+	if err != nil || decoded != text {
+		t.Errorf("decoded %q, %v", decoded, err)
+	}
+}
+
+func Test_Tiktoken_Cl100kPieces(t *testing.T) {
+	checkPieces(t, tokenizer.Cl100kBase, "Hello world's  123456 ok!!\n\n  x\tYOU'LL",
+		[]string{"Hello", " world", "'s", " ", " ", "123", "456", " ok", "!!\n\n", " ", " x", "\tYOU", "'LL"})
+	checkPieces(t, tokenizer.Cl100kBase, "你好，世界。\n", []string{"你好", "，世界", "。\n"})
+}
+
+func Test_Tiktoken_O200kPieces(t *testing.T) {
+	checkPieces(t, tokenizer.O200kBase, "HelloWorld I'm CAPS don't 12345 a/b !\n/x",
+		[]string{"Hello", "World", " I'm", " CAPS", " don't", " ", "123", "45", " a", "/b", " !\n/", "x"})
+}
+
+func Test_Tiktoken_Merges(t *testing.T) {
+	var tok, err = tokenizer.LoadTiktoken(writeTiktoken(t, "ab", "bc", "abc"), tokenizer.Cl100kBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "ab" has the lowest rank, then "abc", "abcb" is not a token
+	var ids = tok.Encode("abcb")
+	if fmt.Sprint(ids) != fmt.Sprint([]int{258, 'b'}) {
+		t.Errorf("unexpected tokens: %v", ids)
+	}
+	if ids = tok.Encode("bcab"); fmt.Sprint(ids) != "[257 256]" {
+		t.Errorf("unexpected tokens: %v", ids)
+	}
+
+	var text = "abc<|endoftext|>"
+	if ids = tok.Encode(text); len(ids) < 3 {
+		t.Errorf("special token is not encoded as text: %v", ids)
+	}
+	ids = tok.EncodeSpecial(text)
+	if fmt.Sprint(ids) != "[258 100257]" {
+		t.Errorf("special token is not encoded: %v", ids)
+	}
+	if decoded, _ := tok.Decode(ids); decoded != text {
+		t.Errorf("decoded %q", decoded)
+	}
+	if _, err = tok.Decode([]int{500}); err == nil {
+		t.Error("unknown id is decoded")
+	}
+	if _, err = tokenizer.LoadTiktoken(writeTiktoken(t), "p50k_base"); err == nil {
+		t.Error("unknown encoding is loaded")
+	}
+}
+
+// byteLevel encodes bytes to the characters of byte-level vocabularies.
+func byteLevel(s string) string {
+	var b strings.Builder
+	var n = 0
+	var encoder = map[byte]rune{}
+	for c := 0; c < 256; c++ {
+		if c >= '!' && c <= '~' || c >= 0xA1 && c <= 0xAC || c >= 0xAE && c <= 0xFF {
+			encoder[byte(c)] = rune(c)
+		} else {
+			encoder[byte(c)] = rune(256 + n)
+			n++
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(encoder[s[i]])
+	}
+	return b.String()
+}
+
+func writeHuggingFace(t *testing.T, pattern string, merges [][2]string) string {
+	t.Helper()
+	var vocab = map[string]int{}
+	for c := 0; c < 256; c++ {
+		vocab[byteLevel(string([]byte{byte(c)}))] = c
+	}
+	var jsonMerges []any
+	for i, merge := range merges {
+		var left, right = byteLevel(merge[0]), byteLevel(merge[1])
+		vocab[left+right] = 256 + i
+		if i%2 == 0 {
+			jsonMerges = append(jsonMerges, left+" "+right)
+		} else {
+			jsonMerges = append(jsonMerges, []string{left, right})
+		}
+	}
+
+	var file = map[string]any{
+		"added_tokens": []any{
+			map[string]any{"id": 1000, "content": "<|begin_of_text|>", "special": true},
+		},
+		"normalizer": nil,
+		"pre_tokenizer": map[string]any{
+			"type": "Sequence",
+			"pretokenizers": []any{
+				map[string]any{"type": "Split", "pattern": map[string]any{"Regex": pattern}, "behavior": "Isolated", "invert": false},
+				map[string]any{"type": "ByteLevel", "add_prefix_space": false, "trim_offsets": true, "use_regex": false},
+			},
+		},
+		"model": map[string]any{"type": "BPE", "vocab": vocab, "merges": jsonMerges},
+	}
+	var data, err = json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var path = filepath.Join(t.TempDir(), "tokenizer.json")
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const llama3Pattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+func Test_HuggingFace_Merges(t *testing.T) {
+	var path = writeHuggingFace(t, llama3Pattern, [][2]string{
+		{" ", "w"}, {"o", "r"}, {" w", "or"}, {"l", "d"}, {" wor", "ld"},
+	})
+	var tok, err = tokenizer.LoadHuggingFace(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Merges apply by their ranks: " w", "or", " wor", "ld", " world"
+	var ids = tok.Encode("hello world")
+	if fmt.Sprint(pieces(t, tok, ids)) != fmt.Sprint([]string{"h", "e", "l", "l", "o", " world"}) {
+		t.Errorf("unexpected tokens: %q", pieces(t, tok, ids))
+	}
+
+	ids = tok.EncodeSpecial("<|begin_of_text|> world")
+	if fmt.Sprint(ids) != "[1000 260]" {
+		t.Errorf("unexpected tokens: %v", ids)
+	}
+	if decoded, _ := tok.Decode(ids); decoded != "<|begin_of_text|> world" {
+		t.Errorf("decoded %q", decoded)
+	}
+	if id, ok := tok.SpecialToken("<|begin_of_text|>"); !ok || id != 1000 {
+		t.Errorf("special token: %d, %v", id, ok)
+	}
+
+	if _, err = tokenizer.LoadHuggingFace(writeHuggingFace(t, `\w+`, nil)); err == nil {
+		t.Error("unknown pattern is loaded")
+	}
+}
+
+func Test_Counter_Request(t *testing.T) {
+	var words = func(text string) int {
+		return len(strings.Fields(text))
+	}
+	var counter = tokenizer.NewCounter(words)
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 1024, 1024))); err != nil {
+		t.Fatal(err)
+	}
+
+	var request = chat.ModelRequest{
+		Messages: []chat.Message{
+			{Role: chat.RoleSystem, Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: "be brief"}}},
+			{Role: chat.RoleUser, Name: "bob", Contents: []chat.ContentBlock{
+				{Type: chat.ContentTypeText, Text: "what is in the image"},
+				{Type: chat.ContentTypeImage, MediaType: chat.ImagePng, Data: buffer.Bytes()},
+			}},
+		},
+	}
+	// 3 per message, 1 word of the role, 1 + 1 of the name, 765 of the image,
+	// and 3 of the reply
+	var want = (3 + 1 + 2) + (3 + 1 + 2 + 5 + 765) + 3
+	if tokens := counter.CountRequest(&request); tokens != want {
+		t.Errorf("tokens %d, want %d", tokens, want)
+	}
+
+	request.Tools = []chat.Tool{{
+		Name:        "get_weather",
+		Description: "Get the weather of a city",
+		Parameters: chat.ToolParameters{
+			Properties: []aigc.JsonSchemaProperty{{Name: "city", Type: aigc.JsonSchema{Type: "string"}}},
+			Required:   []string{"city"},
+		},
+	}}
+	if tokens := counter.CountRequest(&request); tokens <= want+8 {
+		t.Errorf("tools are not counted: %d", tokens)
+	}
+
+	// The default count function
+	if tokens := tokenizer.NewCounter(nil).CountRequest(&request); tokens <= 0 {
+		t.Errorf("unexpected tokens: %d", tokens)
+	}
+}
+
+func Test_ImageTokens(t *testing.T) {
+	var cases = []struct {
+		width, height, openai int
+	}{
+		{1024, 1024, 765},
+		{2048, 4096, 1105},
+		{512, 512, 255},
+		{0, 0, 765},
+	}
+	for _, c := range cases {
+		if tokens := tokenizer.OpenAIImageTokens(c.width, c.height); tokens != c.openai {
+			t.Errorf("%dx%d: %d tokens, want %d", c.width, c.height, tokens, c.openai)
+		}
+	}
+	if tokens := tokenizer.AnthropicImageTokens(1000, 1000); tokens != 1334 {
+		t.Errorf("1000x1000: %d tokens", tokens)
+	}
+	if tokens := tokenizer.AnthropicImageTokens(4000, 4000); tokens > 1600 {
+		t.Errorf("large image is not scaled: %d tokens", tokens)
+	}
+}
+
+func Benchmark_Tokenizer_Count(b *testing.B) {
+	var path = filepath.Join(b.TempDir(), "test.tiktoken")
+	var lines strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&lines, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	if err := os.WriteFile(path, []byte(lines.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	var tok, err = tokenizer.LoadTiktoken(path, tokenizer.O200kBase)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var text = strings.Repeat("The quick brown fox jumps over the lazy dog. 敏捷的棕色狐狸跳过了懒狗。\n", 100)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tok.Count(text)
+	}
+}
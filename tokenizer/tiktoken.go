@@ -0,0 +1,117 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Encoding is the name of a tiktoken vocabulary.
+type Encoding string
+
+const (
+	// Vocabulary of gpt-4, gpt-3.5-turbo and text-embedding-3 models
+	Cl100kBase Encoding = "cl100k_base"
+	// Vocabulary of gpt-4o, gpt-4.1, o1, o3 and o4 models
+	O200kBase Encoding = "o200k_base"
+)
+
+var tiktokenSpecials = map[Encoding]map[string]int{
+	Cl100kBase: {
+		"<|endoftext|>":   100257,
+		"<|fim_prefix|>":  100258,
+		"<|fim_middle|>":  100259,
+		"<|fim_suffix|>":  100260,
+		"<|endofprompt|>": 100276,
+	},
+	O200kBase: {
+		"<|endoftext|>":   199999,
+		"<|endofprompt|>": 200018,
+	},
+}
+
+// EncodingForModel returns the tiktoken vocabulary of an OpenAI model id,
+// empty if the model is unknown.
+func EncodingForModel(modelId string) Encoding {
+	switch {
+	case strings.HasPrefix(modelId, "gpt-4o"),
+		strings.HasPrefix(modelId, "gpt-4.1"),
+		strings.HasPrefix(modelId, "gpt-4.5"),
+		strings.HasPrefix(modelId, "chatgpt-4o"),
+		strings.HasPrefix(modelId, "o1"),
+		strings.HasPrefix(modelId, "o3"),
+		strings.HasPrefix(modelId, "o4"):
+		return O200kBase
+	case strings.HasPrefix(modelId, "gpt-4"),
+		strings.HasPrefix(modelId, "gpt-3.5"),
+		strings.HasPrefix(modelId, "text-embedding-3"),
+		strings.HasPrefix(modelId, "text-embedding-ada-002"):
+		return Cl100kBase
+	}
+	return ""
+}
+
+// LoadTiktoken loads a tiktoken rank file, e.g. cl100k_base.tiktoken, of the
+// encoding. Each line of the file is a base64 encoded token and its rank.
+func LoadTiktoken(path string, encoding Encoding) (*Tokenizer, error) {
+	var split func(text string, fn func(piece string))
+	switch encoding {
+	case Cl100kBase:
+		split = cl100kSplit(3)
+	case O200kBase:
+		split = o200kSplit
+	default:
+		return nil, fmt.Errorf("[LoadTiktoken] unknown encoding %q", encoding)
+	}
+
+	var data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[LoadTiktoken] %w", err)
+	}
+	vocab, err := parseTiktoken(data)
+	if err != nil {
+		return nil, fmt.Errorf("[LoadTiktoken] %s: %w", path, err)
+	}
+
+	var t = newTokenizer(vocab, split)
+	for token, id := range tiktokenSpecials[encoding] {
+		t.addSpecial(token, id)
+	}
+	return t, nil
+}
+
+func parseTiktoken(data []byte) (map[string]int, error) {
+	var vocab = make(map[string]int, bytes.Count(data, []byte{'\n'})+1)
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	var line = 0
+	for scanner.Scan() {
+		line++
+		var fields = strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: invalid format", line)
+		}
+		var token, err = base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil || rank < 0 {
+			return nil, fmt.Errorf("line %d: invalid rank %q", line, fields[1])
+		}
+		vocab[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(vocab) == 0 {
+		return nil, fmt.Errorf("empty vocabulary")
+	}
+	return vocab, nil
+}
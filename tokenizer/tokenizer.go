@@ -0,0 +1,227 @@
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Tokenizer is a byte-level BPE tokenizer. The text is split into pieces by
+// the pre-tokenizer of the vocabulary, then the bytes of each piece are
+// merged into tokens. It is safe for concurrent use.
+type Tokenizer struct {
+	// Ids of the tokens, keyed by the bytes of the tokens
+	vocab map[string]int
+	// Bytes of the tokens, indexed by the ids, empty for unused ids
+	tokens []string
+	// Ranks of the merges of Hugging Face vocabularies, keyed by the ids of
+	// the pairs. Tiktoken vocabularies merge by the ranks of the merged
+	// tokens, which are the ids, and have no merges.
+	merges map[[2]int]int
+	// The piece is a token if it is in the vocabulary, without merging
+	ignoreMerges bool
+	// Id of the unknown token, -1 if unknown bytes are dropped
+	unknown int
+
+	// Special tokens, and the special tokens by the first byte, longest
+	// first
+	specials      map[string]int
+	specialIds    map[int]string
+	specialPrefix map[byte][]string
+
+	split func(text string, fn func(piece string))
+}
+
+func newTokenizer(vocab map[string]int, split func(text string, fn func(piece string))) *Tokenizer {
+	var t = &Tokenizer{
+		vocab:      vocab,
+		unknown:    -1,
+		specials:   make(map[string]int),
+		specialIds: make(map[int]string),
+		split:      split,
+	}
+	var size = 0
+	for _, id := range vocab {
+		size = max(size, id+1)
+	}
+	t.tokens = make([]string, size)
+	for token, id := range vocab {
+		t.tokens[id] = token
+	}
+	return t
+}
+
+func (t *Tokenizer) addSpecial(token string, id int) {
+	t.specials[token] = id
+	t.specialIds[id] = token
+
+	if t.specialPrefix == nil {
+		t.specialPrefix = make(map[byte][]string)
+	}
+	var tokens = append(t.specialPrefix[token[0]], token)
+	sort.Slice(tokens, func(i, j int) bool {
+		return len(tokens[i]) > len(tokens[j])
+	})
+	t.specialPrefix[token[0]] = tokens
+}
+
+// SpecialToken returns the id of a special token, e.g. "<|endoftext|>".
+func (t *Tokenizer) SpecialToken(token string) (int, bool) {
+	var id, ok = t.specials[token]
+	return id, ok
+}
+
+// Encode encodes the text into token ids. Special tokens in the text are
+// encoded as ordinary text, as untrusted user input should be.
+func (t *Tokenizer) Encode(text string) []int {
+	var ids []int
+	t.split(text, func(piece string) {
+		ids = t.encodePiece(piece, ids)
+	})
+	return ids
+}
+
+// EncodeSpecial encodes the text into token ids, special tokens in the text
+// are encoded as themselves, e.g. "<|im_start|>" of chat templates.
+func (t *Tokenizer) EncodeSpecial(text string) []int {
+	var ids []int
+	var from = 0
+	for i := 0; i < len(text); i++ {
+		var token = t.matchSpecial(text[i:])
+		if token == "" {
+			continue
+		}
+		t.split(text[from:i], func(piece string) {
+			ids = t.encodePiece(piece, ids)
+		})
+		ids = append(ids, t.specials[token])
+		i += len(token) - 1
+		from = i + 1
+	}
+	t.split(text[from:], func(piece string) {
+		ids = t.encodePiece(piece, ids)
+	})
+	return ids
+}
+
+func (t *Tokenizer) matchSpecial(text string) string {
+	for _, token := range t.specialPrefix[text[0]] {
+		if strings.HasPrefix(text, token) {
+			return token
+		}
+	}
+	return ""
+}
+
+// Count returns the number of tokens of the text, special tokens are counted
+// as ordinary text.
+func (t *Tokenizer) Count(text string) int {
+	var n = 0
+	var ids []int
+	t.split(text, func(piece string) {
+		if _, ok := t.vocab[piece]; ok {
+			n++
+			return
+		}
+		ids = t.encodePiece(piece, ids[:0])
+		n += len(ids)
+	})
+	return n
+}
+
+// Decode decodes the token ids into the text. The text may end in the middle
+// of a UTF-8 sequence if the ids are a prefix of an encoded text.
+func (t *Tokenizer) Decode(ids []int) (string, error) {
+	var b strings.Builder
+	for _, id := range ids {
+		if id >= 0 && id < len(t.tokens) && t.tokens[id] != "" {
+			b.WriteString(t.tokens[id])
+			continue
+		}
+		var token, ok = t.specialIds[id]
+		if !ok {
+			return "", fmt.Errorf("[Tokenizer.Decode] unknown token id %d", id)
+		}
+		b.WriteString(token)
+	}
+	return b.String(), nil
+}
+
+func (t *Tokenizer) encodePiece(piece string, ids []int) []int {
+	if len(piece) == 0 {
+		return ids
+	}
+	if t.merges == nil || t.ignoreMerges {
+		// Tiktoken merges always reach a token of the vocabulary
+		if id, ok := t.vocab[piece]; ok {
+			return append(ids, id)
+		}
+	}
+
+	// Parts of the piece are piece[bounds[i]:bounds[i+1]], ranks[i] is the
+	// rank of merging the part i and i+1
+	var bounds = make([]int, len(piece)+1)
+	var ranks = make([]int, len(piece))
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for i := 0; i+2 < len(bounds); i++ {
+		ranks[i] = t.rank(piece, bounds[i], bounds[i+1], bounds[i+2])
+	}
+	ranks[len(ranks)-1] = math.MaxInt
+
+	for len(bounds) > 2 {
+		var best = -1
+		var bestRank = math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if ranks[i] < bestRank {
+				best, bestRank = i, ranks[i]
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+		ranks = append(ranks[:best], ranks[best+1:]...)
+		if best+2 < len(bounds) {
+			ranks[best] = t.rank(piece, bounds[best], bounds[best+1], bounds[best+2])
+		} else {
+			ranks[best] = math.MaxInt
+		}
+		if best > 0 {
+			ranks[best-1] = t.rank(piece, bounds[best-1], bounds[best], bounds[best+1])
+		}
+	}
+
+	for i := 0; i+1 < len(bounds); i++ {
+		if id, ok := t.vocab[piece[bounds[i]:bounds[i+1]]]; ok {
+			ids = append(ids, id)
+		} else if t.unknown >= 0 {
+			ids = append(ids, t.unknown)
+		}
+	}
+	return ids
+}
+
+// rank returns the rank of merging piece[start:middle] and piece[middle:end],
+// math.MaxInt if they can not be merged.
+func (t *Tokenizer) rank(piece string, start int, middle int, end int) int {
+	if t.merges == nil {
+		if id, ok := t.vocab[piece[start:end]]; ok {
+			return id
+		}
+		return math.MaxInt
+	}
+
+	var left, ok1 = t.vocab[piece[start:middle]]
+	var right, ok2 = t.vocab[piece[middle:end]]
+	if !ok1 || !ok2 {
+		return math.MaxInt
+	}
+	if rank, ok := t.merges[[2]int{left, right}]; ok {
+		return rank
+	}
+	return math.MaxInt
+}
@@ -0,0 +1,209 @@
+package vectorindex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+const (
+	bm25DefaultK1 = 1.2
+	bm25DefaultB  = 0.75
+)
+
+// BM25Index is an inverted index of texts ranked by Okapi BM25. It finds
+// exact terms, e.g. error codes and product numbers, that embeddings tend to
+// miss. Match.Score of a search is the BM25 score, higher is more relevant.
+// All methods are safe for concurrent use.
+type BM25Index struct {
+	k1       float64
+	b        float64
+	tokenize func(text string) []string
+
+	lock        sync.RWMutex
+	ids         []string // by slot, empty for free slots
+	metadata    []Metadata
+	lengths     []int32
+	terms       [][]string // distinct terms of the slots
+	free        []int32
+	slots       map[string]int32
+	postings    map[string]map[int32]int32 // term frequencies by slot
+	totalLength int64
+}
+
+// NewBM25Index creates a BM25 index, see WithBM25 and WithTokenizer.
+func NewBM25Index(options ...OptionFunc) *BM25Index {
+	var opts Options
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.BM25K1 <= 0 {
+		opts.BM25K1 = bm25DefaultK1
+	}
+	if !opts.BM25B.Valid || opts.BM25B.Value < 0 || opts.BM25B.Value > 1 {
+		opts.BM25B.Set(bm25DefaultB)
+	}
+	if opts.Tokenizer == nil {
+		opts.Tokenizer = KeywordTokens
+	}
+	return &BM25Index{
+		k1:       opts.BM25K1,
+		b:        opts.BM25B.Value,
+		tokenize: opts.Tokenizer,
+		slots:    make(map[string]int32),
+		postings: make(map[string]map[int32]int32),
+	}
+}
+
+// Len returns the number of texts.
+func (x *BM25Index) Len() int {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return len(x.slots)
+}
+
+// Add indexes a text, or replaces the text with the same id.
+func (x *BM25Index) Add(id string, text string, metadata Metadata) error {
+	if id == "" {
+		return fmt.Errorf("[BM25Index.Add] %w", errEmptyId)
+	}
+	var frequencies = make(map[string]int32)
+	var length int32
+	for _, term := range x.tokenize(text) {
+		frequencies[term]++
+		length++
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	if slot, ok := x.slots[id]; ok {
+		x.remove(slot)
+	}
+	var slot int32
+	if n := len(x.free); n > 0 {
+		slot = x.free[n-1]
+		x.free = x.free[:n-1]
+	} else {
+		slot = int32(len(x.ids))
+		x.ids = append(x.ids, "")
+		x.metadata = append(x.metadata, nil)
+		x.lengths = append(x.lengths, 0)
+		x.terms = append(x.terms, nil)
+	}
+
+	var terms = make([]string, 0, len(frequencies))
+	for term, tf := range frequencies {
+		var posting = x.postings[term]
+		if posting == nil {
+			posting = make(map[int32]int32)
+			x.postings[term] = posting
+		}
+		posting[slot] = tf
+		terms = append(terms, term)
+	}
+	x.slots[id] = slot
+	x.ids[slot] = id
+	x.metadata[slot] = cloneMetadata(metadata)
+	x.lengths[slot] = length
+	x.terms[slot] = terms
+	x.totalLength += int64(length)
+	return nil
+}
+
+// Delete removes a text, reports whether the id exists.
+func (x *BM25Index) Delete(id string) bool {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	var slot, ok = x.slots[id]
+	if ok {
+		x.remove(slot)
+	}
+	return ok
+}
+
+// remove frees a slot, the caller holds the write lock.
+func (x *BM25Index) remove(slot int32) {
+	for _, term := range x.terms[slot] {
+		var posting = x.postings[term]
+		delete(posting, slot)
+		if len(posting) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	delete(x.slots, x.ids[slot])
+	x.totalLength -= int64(x.lengths[slot])
+	x.ids[slot] = ""
+	x.metadata[slot] = nil
+	x.lengths[slot] = 0
+	x.terms[slot] = nil
+	x.free = append(x.free, slot)
+}
+
+// Search returns at most k texts containing the terms of the query, most
+// relevant first. A nil filter accepts all texts.
+func (x *BM25Index) Search(query string, k int, filter Filter) ([]Match, error) {
+	var terms = x.tokenize(query)
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	if k <= 0 || len(x.slots) == 0 {
+		return nil, nil
+	}
+
+	var n = float64(len(x.slots))
+	var averageLength = float64(x.totalLength) / n
+	if averageLength == 0 {
+		averageLength = 1
+	}
+	var accumulated = make(map[int32]float64)
+	var seen = make(map[string]bool, len(terms))
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		var posting = x.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		var df = float64(len(posting))
+		var idf = math.Log(1 + (n-df+0.5)/(df+0.5))
+		for slot, tf := range posting {
+			var norm = x.k1 * (1 - x.b + x.b*float64(x.lengths[slot])/averageLength)
+			accumulated[slot] += idf * float64(tf) * (x.k1 + 1) / (float64(tf) + norm)
+		}
+	}
+
+	// Slots in order, so ties are ranked by the order of the slots
+	var positions = make([]int32, 0, len(accumulated))
+	for slot := range accumulated {
+		if filter == nil || filter(x.ids[slot], x.metadata[slot]) {
+			positions = append(positions, slot)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	var scores = make([]float32, len(positions))
+	for i, slot := range positions {
+		scores[i] = float32(accumulated[slot])
+	}
+
+	var top = embedding.VectorTopK(scores, k, true)
+	var matches = make([]Match, len(top))
+	for i, t := range top {
+		var slot = positions[t.Index]
+		matches[i] = Match{
+			Id:       x.ids[slot],
+			Score:    t.Score,
+			Metadata: cloneMetadata(x.metadata[slot]),
+		}
+	}
+	return matches, nil
+}
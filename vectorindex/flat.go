@@ -0,0 +1,169 @@
+package vectorindex
+
+import (
+	"fmt"
+	"sync"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+// FlatIndex is an exact index, a search scores the query against all
+// vectors.
+type FlatIndex struct {
+	distanceType embedding.VectorDistanceType
+	dimension    int
+
+	lock      sync.RWMutex
+	ids       []string
+	matrix    []float32 // row-major, the rows are in the order of ids
+	metadata  []Metadata
+	positions map[string]int
+}
+
+// NewFlatIndex creates an exact index. Dimension zero means the dimension of
+// the first vector added.
+func NewFlatIndex(distanceType embedding.VectorDistanceType, dimension int) *FlatIndex {
+	return &FlatIndex{
+		distanceType: normalizeDistanceType(distanceType),
+		dimension:    dimension,
+		positions:    make(map[string]int),
+	}
+}
+
+func (x *FlatIndex) DistanceType() embedding.VectorDistanceType {
+	return x.distanceType
+}
+
+func (x *FlatIndex) Dimension() int {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return x.dimension
+}
+
+func (x *FlatIndex) Len() int {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return len(x.ids)
+}
+
+func (x *FlatIndex) row(i int) []float32 {
+	return x.matrix[i*x.dimension : (i+1)*x.dimension]
+}
+
+func (x *FlatIndex) Add(id string, vector []float32, metadata Metadata) error {
+	if id == "" {
+		return fmt.Errorf("[FlatIndex.Add] %w", errEmptyId)
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	var prepared, err = prepare(x.distanceType, x.dimension, vector)
+	if err != nil {
+		return fmt.Errorf("[FlatIndex.Add] %w", err)
+	}
+	x.dimension = len(prepared)
+
+	if i, ok := x.positions[id]; ok {
+		copy(x.row(i), prepared)
+		x.metadata[i] = cloneMetadata(metadata)
+		return nil
+	}
+	x.positions[id] = len(x.ids)
+	x.ids = append(x.ids, id)
+	x.matrix = append(x.matrix, prepared...)
+	x.metadata = append(x.metadata, cloneMetadata(metadata))
+	return nil
+}
+
+// Delete moves the last vector to the position of the deleted one.
+func (x *FlatIndex) Delete(id string) bool {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	var i, ok = x.positions[id]
+	if !ok {
+		return false
+	}
+	var last = len(x.ids) - 1
+	if i != last {
+		x.ids[i] = x.ids[last]
+		x.metadata[i] = x.metadata[last]
+		copy(x.row(i), x.row(last))
+		x.positions[x.ids[i]] = i
+	}
+	delete(x.positions, id)
+	x.ids = x.ids[:last]
+	x.metadata[last] = nil
+	x.metadata = x.metadata[:last]
+	x.matrix = x.matrix[:last*x.dimension]
+	return true
+}
+
+func (x *FlatIndex) Get(id string) ([]float32, Metadata, bool) {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	var i, ok = x.positions[id]
+	if !ok {
+		return nil, nil, false
+	}
+	var vector = make([]float32, x.dimension)
+	copy(vector, x.row(i))
+	return vector, cloneMetadata(x.metadata[i]), true
+}
+
+func (x *FlatIndex) Search(query []float32, k int, filter Filter) ([]Match, error) {
+	var err error
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	if k <= 0 || len(x.ids) == 0 {
+		return nil, nil
+	}
+	query, err = prepare(x.distanceType, x.dimension, query)
+	if err != nil {
+		return nil, fmt.Errorf("[FlatIndex.Search] %w", err)
+	}
+
+	var scores []float32
+	var positions []int // positions of the scores, nil if all vectors are scored
+
+	if filter == nil {
+		scores = make([]float32, len(x.ids))
+		if x.distanceType == embedding.EuclideanDistance {
+			err = embedding.VectorBatchEuclideanDistance(query, x.matrix, scores)
+		} else {
+			err = embedding.VectorBatchDotProduct(query, x.matrix, scores)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("[FlatIndex.Search] %w", err)
+		}
+	} else {
+		for i, id := range x.ids {
+			if !filter(id, x.metadata[i]) {
+				continue
+			}
+			positions = append(positions, i)
+			scores = append(scores, score(x.distanceType, distance(x.distanceType, query, x.row(i))))
+		}
+	}
+
+	var top = embedding.VectorTopK(scores, k, x.distanceType != embedding.EuclideanDistance)
+	var matches = make([]Match, len(top))
+	for i, t := range top {
+		var position = t.Index
+		if positions != nil {
+			position = positions[t.Index]
+		}
+		matches[i] = Match{
+			Id:       x.ids[position],
+			Score:    t.Score,
+			Metadata: cloneMetadata(x.metadata[position]),
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,475 @@
+package vectorindex
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+const (
+	hnswDefaultM              = 16
+	hnswDefaultEfConstruction = 200
+	hnswDefaultEfSearch       = 64
+)
+
+type hnswNode struct {
+	id        string
+	vector    []float32
+	metadata  Metadata
+	neighbors [][]int32 // by layer, from 0 to the level of the node
+	deleted   bool
+}
+
+// HNSWIndex is an approximate index, a hierarchical navigable small world
+// graph (Malkov and Yashunin, 2016).
+//
+// Deleted vectors are marked and still used to navigate the graph, but never
+// returned. Replacing a vector deletes the old one. The graph is rebuilt
+// without the deleted vectors when they outnumber the live ones, so the
+// memory is at most twice the live vectors. A rebuild costs as much as adding
+// all live vectors again, and blocks the other calls meanwhile.
+type HNSWIndex struct {
+	distanceType   embedding.VectorDistanceType
+	dimension      int
+	m              int
+	efConstruction int
+	efSearch       int
+	levelFactor    float64
+
+	lock        sync.RWMutex
+	random      *rand.Rand
+	nodes       []hnswNode
+	ids         map[string]int32
+	entry       int32 // -1 if the graph is empty
+	maxLevel    int
+	deleted     int // number of deleted nodes
+	visitedPool sync.Pool
+}
+
+// NewHNSWIndex creates an approximate index. Dimension zero means the
+// dimension of the first vector added.
+func NewHNSWIndex(distanceType embedding.VectorDistanceType, dimension int, options ...OptionFunc) *HNSWIndex {
+	var opts Options
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.M <= 1 {
+		opts.M = hnswDefaultM
+	}
+	if opts.EfConstruction <= 0 {
+		opts.EfConstruction = hnswDefaultEfConstruction
+	}
+	if opts.EfSearch <= 0 {
+		opts.EfSearch = hnswDefaultEfSearch
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+
+	var index = &HNSWIndex{
+		distanceType:   normalizeDistanceType(distanceType),
+		dimension:      dimension,
+		m:              opts.M,
+		efConstruction: max(opts.EfConstruction, opts.M),
+		efSearch:       opts.EfSearch,
+		levelFactor:    1 / math.Log(float64(opts.M)),
+		random:         rand.New(rand.NewSource(opts.Seed)),
+		ids:            make(map[string]int32),
+		entry:          -1,
+	}
+	index.visitedPool.New = func() any { return &hnswVisited{} }
+	return index
+}
+
+func (x *HNSWIndex) DistanceType() embedding.VectorDistanceType {
+	return x.distanceType
+}
+
+func (x *HNSWIndex) Dimension() int {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return x.dimension
+}
+
+func (x *HNSWIndex) Len() int {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return len(x.ids)
+}
+
+// Deleted returns the number of deleted vectors still kept in the graph.
+func (x *HNSWIndex) Deleted() int {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return x.deleted
+}
+
+// maxNeighbors returns the maximum number of neighbors on the layer.
+func (x *HNSWIndex) maxNeighbors(layer int) int {
+	if layer == 0 {
+		return 2 * x.m
+	}
+	return x.m
+}
+
+func (x *HNSWIndex) distance(v []float32, node int32) float32 {
+	return distance(x.distanceType, v, x.nodes[node].vector)
+}
+
+func (x *HNSWIndex) Add(id string, vector []float32, metadata Metadata) error {
+	if id == "" {
+		return fmt.Errorf("[HNSWIndex.Add] %w", errEmptyId)
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	var prepared, err = prepare(x.distanceType, x.dimension, vector)
+	if err != nil {
+		return fmt.Errorf("[HNSWIndex.Add] %w", err)
+	}
+	x.dimension = len(prepared)
+
+	if old, ok := x.ids[id]; ok {
+		x.markDeleted(old)
+	}
+	var level = int(-math.Log(1-x.random.Float64()) * x.levelFactor)
+	x.insert(id, prepared, cloneMetadata(metadata), level)
+	if x.deleted > len(x.ids) {
+		x.rebuild()
+	}
+	return nil
+}
+
+// insert links a prepared vector into the graph at the level, the caller
+// holds the write lock.
+func (x *HNSWIndex) insert(id string, prepared []float32, metadata Metadata, level int) {
+	var node = int32(len(x.nodes))
+	x.nodes = append(x.nodes, hnswNode{
+		id:        id,
+		vector:    prepared,
+		metadata:  metadata,
+		neighbors: make([][]int32, level+1),
+	})
+	x.ids[id] = node
+
+	if x.entry < 0 {
+		x.entry = node
+		x.maxLevel = level
+		return
+	}
+
+	// Greedy descent to the level of the node
+	var entry = x.entry
+	for layer := x.maxLevel; layer > level; layer-- {
+		entry = x.greedy(prepared, entry, layer)
+	}
+
+	// Connect the node to the nearest neighbors of each layer
+	var entries = []hnswCandidate{{node: entry, distance: x.distance(prepared, entry)}}
+	for layer := min(level, x.maxLevel); layer >= 0; layer-- {
+		var candidates = x.searchLayer(prepared, entries, x.efConstruction, layer, nil)
+		var neighbors = x.selectNeighbors(candidates, x.m)
+		x.nodes[node].neighbors[layer] = neighbors
+		for _, neighbor := range neighbors {
+			x.connect(neighbor, node, layer)
+		}
+		entries = candidates
+	}
+
+	if level > x.maxLevel {
+		x.entry = node
+		x.maxLevel = level
+	}
+}
+
+// markDeleted marks a node deleted, the caller holds the write lock.
+func (x *HNSWIndex) markDeleted(node int32) {
+	x.nodes[node].deleted = true
+	x.nodes[node].metadata = nil
+	x.deleted++
+}
+
+// Compact rebuilds the graph without the deleted vectors. It costs as much as
+// adding all live vectors again, and blocks the other calls meanwhile.
+func (x *HNSWIndex) Compact() {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.deleted > 0 {
+		x.rebuild()
+	}
+}
+
+// rebuild inserts the live nodes into a new graph at their levels, the
+// caller holds the write lock.
+func (x *HNSWIndex) rebuild() {
+	var nodes = x.nodes
+	x.nodes = make([]hnswNode, 0, len(x.ids))
+	x.ids = make(map[string]int32, len(x.ids))
+	x.entry = -1
+	x.maxLevel = 0
+	x.deleted = 0
+	for i := range nodes {
+		if !nodes[i].deleted {
+			x.insert(nodes[i].id, nodes[i].vector, nodes[i].metadata, len(nodes[i].neighbors)-1)
+		}
+	}
+}
+
+// connect adds a link from the node to the neighbor, and prunes the links of
+// the node if there are too many.
+func (x *HNSWIndex) connect(node int32, neighbor int32, layer int) {
+	var links = append(x.nodes[node].neighbors[layer], neighbor)
+	if len(links) <= x.maxNeighbors(layer) {
+		x.nodes[node].neighbors[layer] = links
+		return
+	}
+
+	var candidates = make([]hnswCandidate, len(links))
+	for i, link := range links {
+		candidates[i] = hnswCandidate{node: link, distance: x.distance(x.nodes[node].vector, link)}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	x.nodes[node].neighbors[layer] = x.selectNeighbors(candidates, x.maxNeighbors(layer))
+}
+
+// selectNeighbors selects at most m neighbors from candidates sorted by
+// distance. A candidate is skipped if it is closer to a selected neighbor
+// than to the base, which keeps links in diverse directions. Skipped
+// candidates fill the remaining slots.
+func (x *HNSWIndex) selectNeighbors(candidates []hnswCandidate, m int) []int32 {
+	var selected = make([]int32, 0, m)
+	var skipped []int32
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		var diverse = true
+		for _, s := range selected {
+			if x.distance(x.nodes[c.node].vector, s) < c.distance {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c.node)
+		} else {
+			skipped = append(skipped, c.node)
+		}
+	}
+	for _, s := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// greedy returns the node nearest to the vector on the layer, by walking
+// from the entry to nearer neighbors.
+func (x *HNSWIndex) greedy(vector []float32, entry int32, layer int) int32 {
+	var nearest = x.distance(vector, entry)
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range x.nodes[entry].neighbors[layer] {
+			var d = x.distance(vector, neighbor)
+			if d < nearest {
+				nearest, entry, changed = d, neighbor, true
+			}
+		}
+	}
+	return entry
+}
+
+// searchLayer returns at most ef nodes nearest to the vector on the layer,
+// sorted by distance. The graph is traversed through all nodes, but only the
+// nodes accepted by accept are returned, nil accepts all.
+func (x *HNSWIndex) searchLayer(vector []float32, entries []hnswCandidate, ef int, layer int,
+	accept func(node int32) bool) []hnswCandidate {
+
+	var visited = x.visitedPool.Get().(*hnswVisited)
+	defer x.visitedPool.Put(visited)
+	visited.reset(len(x.nodes))
+
+	var candidates = &hnswHeap{}            // nearest first
+	var results = &hnswHeap{farthest: true} // farthest first
+
+	for _, e := range entries {
+		visited.visit(e.node)
+		heap.Push(candidates, e)
+		if accept == nil || accept(e.node) {
+			heap.Push(results, e)
+		}
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		var c = heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.distance > results.top().distance {
+			break
+		}
+		for _, neighbor := range x.nodes[c.node].neighbors[layer] {
+			if !visited.visit(neighbor) {
+				continue
+			}
+
+			var d = x.distance(vector, neighbor)
+			if results.Len() >= ef && d >= results.top().distance {
+				continue
+			}
+			heap.Push(candidates, hnswCandidate{node: neighbor, distance: d})
+			if accept == nil || accept(neighbor) {
+				heap.Push(results, hnswCandidate{node: neighbor, distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	var nearest = make([]hnswCandidate, results.Len())
+	for i := len(nearest) - 1; i >= 0; i-- {
+		nearest[i] = heap.Pop(results).(hnswCandidate)
+	}
+	return nearest
+}
+
+func (x *HNSWIndex) Delete(id string) bool {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	var node, ok = x.ids[id]
+	if !ok {
+		return false
+	}
+	x.markDeleted(node)
+	delete(x.ids, id)
+	if x.deleted > len(x.ids) {
+		x.rebuild()
+	}
+	return true
+}
+
+func (x *HNSWIndex) Get(id string) ([]float32, Metadata, bool) {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	var node, ok = x.ids[id]
+	if !ok {
+		return nil, nil, false
+	}
+	var vector = make([]float32, len(x.nodes[node].vector))
+	copy(vector, x.nodes[node].vector)
+	return vector, cloneMetadata(x.nodes[node].metadata), true
+}
+
+func (x *HNSWIndex) Search(query []float32, k int, filter Filter) ([]Match, error) {
+	var err error
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	if k <= 0 || len(x.ids) == 0 {
+		return nil, nil
+	}
+	query, err = prepare(x.distanceType, x.dimension, query)
+	if err != nil {
+		return nil, fmt.Errorf("[HNSWIndex.Search] %w", err)
+	}
+
+	var entry = x.entry
+	for layer := x.maxLevel; layer > 0; layer-- {
+		entry = x.greedy(query, entry, layer)
+	}
+
+	var accept = func(node int32) bool {
+		var n = &x.nodes[node]
+		return !n.deleted && (filter == nil || filter(n.id, n.metadata))
+	}
+	var entries = []hnswCandidate{{node: entry, distance: x.distance(query, entry)}}
+	var nearest = x.searchLayer(query, entries, max(x.efSearch, k), 0, accept)
+	if len(nearest) > k {
+		nearest = nearest[:k]
+	}
+
+	var matches = make([]Match, len(nearest))
+	for i, c := range nearest {
+		matches[i] = Match{
+			Id:       x.nodes[c.node].id,
+			Score:    score(x.distanceType, c.distance),
+			Metadata: cloneMetadata(x.nodes[c.node].metadata),
+		}
+	}
+	return matches, nil
+}
+
+// hnswVisited is a set of nodes visited by a search. A node is visited if
+// its mark is the current epoch, so the set is cleared without touching the
+// marks.
+type hnswVisited struct {
+	marks []uint32
+	epoch uint32
+}
+
+func (v *hnswVisited) reset(size int) {
+	if len(v.marks) < size {
+		v.marks = append(v.marks, make([]uint32, size-len(v.marks))...)
+	}
+	v.epoch++
+	if v.epoch == 0 {
+		clear(v.marks)
+		v.epoch = 1
+	}
+}
+
+// visit marks the node, reports whether it is not visited before.
+func (v *hnswVisited) visit(node int32) bool {
+	if v.marks[node] == v.epoch {
+		return false
+	}
+	v.marks[node] = v.epoch
+	return true
+}
+
+type hnswCandidate struct {
+	node     int32
+	distance float32
+}
+
+// hnswHeap is a heap of candidates, the nearest at the top, or the farthest
+// if farthest is true.
+type hnswHeap struct {
+	items    []hnswCandidate
+	farthest bool
+}
+
+func (h *hnswHeap) Len() int { return len(h.items) }
+
+func (h *hnswHeap) Less(i, j int) bool {
+	if h.farthest {
+		return h.items[i].distance > h.items[j].distance
+	}
+	return h.items[i].distance < h.items[j].distance
+}
+
+func (h *hnswHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *hnswHeap) Push(v any) { h.items = append(h.items, v.(hnswCandidate)) }
+
+func (h *hnswHeap) Pop() any {
+	var last = h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func (h *hnswHeap) top() hnswCandidate { return h.items[0] }
@@ -0,0 +1,180 @@
+package vectorindex
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+// FusionType is the method to fuse the keyword and vector results of a
+// hybrid search.
+type FusionType string
+
+const (
+	// FusionRRF scores a result by the reciprocal rank fusion, the sum of
+	// 1 / (RRFConstant + rank) of its ranks in the keyword and vector
+	// results. It ignores the scales of the scores.
+	FusionRRF FusionType = "rrf"
+	// FusionWeighted scales the scores of both results to [0, 1] by their
+	// minimums and maximums, and sums them by VectorWeight.
+	FusionWeighted FusionType = "weighted"
+)
+
+const (
+	hybridDefaultRRFConstant  = 60
+	hybridDefaultVectorWeight = 0.5
+)
+
+// VectorSearcher is the vector side of a hybrid search, an Index or a Store.
+type VectorSearcher interface {
+	DistanceType() embedding.VectorDistanceType
+	Search(query []float32, k int, filter Filter) ([]Match, error)
+}
+
+// HybridSearch searches a vector index and a BM25 index of the same ids, and
+// fuses their results. Match.Score of a search is the fused score, higher is
+// more relevant.
+type HybridSearch struct {
+	vectors  VectorSearcher
+	keywords *BM25Index
+	opts     Options
+}
+
+// NewHybridSearch creates a hybrid search, see WithRRF, WithWeightedFusion
+// and WithHybridCandidates.
+func NewHybridSearch(vectors VectorSearcher, keywords *BM25Index, options ...OptionFunc) *HybridSearch {
+	var opts Options
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.Fusion != FusionWeighted {
+		opts.Fusion = FusionRRF
+	}
+	if opts.RRFConstant <= 0 {
+		opts.RRFConstant = hybridDefaultRRFConstant
+	}
+	if !opts.VectorWeight.Valid || opts.VectorWeight.Value < 0 || opts.VectorWeight.Value > 1 {
+		opts.VectorWeight.Set(hybridDefaultVectorWeight)
+	}
+	return &HybridSearch{vectors: vectors, keywords: keywords, opts: opts}
+}
+
+// Search returns at most k results of the query text and its vector, most
+// relevant first. An empty text searches by the vector only, a nil vector by
+// the text only. A nil filter accepts all results.
+func (h *HybridSearch) Search(query string, vector []float32, k int, filter Filter) ([]Match, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	if query == "" && vector == nil {
+		return nil, errors.New("[HybridSearch.Search] query is empty")
+	}
+	var candidates = h.opts.HybridCandidates
+	if candidates < k {
+		candidates = 4 * k
+	}
+
+	var vectorMatches, keywordMatches []Match
+	var err error
+	if vector != nil {
+		vectorMatches, err = h.vectors.Search(vector, candidates, filter)
+		if err != nil {
+			return nil, fmt.Errorf("[HybridSearch.Search] %w", err)
+		}
+	}
+	if query != "" {
+		keywordMatches, err = h.keywords.Search(query, candidates, filter)
+		if err != nil {
+			return nil, fmt.Errorf("[HybridSearch.Search] %w", err)
+		}
+	}
+
+	var vectorScores, keywordScores []float64
+	if h.opts.Fusion == FusionWeighted {
+		var largest = h.vectors.DistanceType() != embedding.EuclideanDistance
+		vectorScores = scaleScores(normalizeScores(vectorMatches, largest), h.opts.VectorWeight.Value)
+		keywordScores = scaleScores(normalizeScores(keywordMatches, true), 1-h.opts.VectorWeight.Value)
+	} else {
+		vectorScores = reciprocalRanks(len(vectorMatches), h.opts.RRFConstant)
+		keywordScores = reciprocalRanks(len(keywordMatches), h.opts.RRFConstant)
+	}
+
+	// Results in the order of their first appearance, vector results first,
+	// so ties are ranked by the vector ranks
+	var fused = make([]Match, 0, len(vectorMatches)+len(keywordMatches))
+	var scores = make([]float64, 0, cap(fused))
+	var positions = make(map[string]int, cap(fused))
+	var add = func(matches []Match, matchScores []float64) {
+		for i, match := range matches {
+			if p, ok := positions[match.Id]; ok {
+				scores[p] += matchScores[i]
+				continue
+			}
+			positions[match.Id] = len(fused)
+			fused = append(fused, Match{Id: match.Id, Metadata: match.Metadata})
+			scores = append(scores, matchScores[i])
+		}
+	}
+	add(vectorMatches, vectorScores)
+	add(keywordMatches, keywordScores)
+
+	var order = make([]int, len(fused))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if len(order) > k {
+		order = order[:k]
+	}
+	var matches = make([]Match, len(order))
+	for i, p := range order {
+		matches[i] = fused[p]
+		matches[i].Score = float32(scores[p])
+	}
+	return matches, nil
+}
+
+// reciprocalRanks returns the RRF scores of n ranked results.
+func reciprocalRanks(n int, constant float64) []float64 {
+	var scores = make([]float64, n)
+	for i := range scores {
+		scores[i] = 1 / (constant + float64(i+1))
+	}
+	return scores
+}
+
+// normalizeScores scales the scores of the matches to [0, 1], 1 is the most
+// relevant. Largest is false if lower scores are more relevant. All scores
+// are 1 if they are equal.
+func normalizeScores(matches []Match, largest bool) []float64 {
+	var scores = make([]float64, len(matches))
+	if len(matches) == 0 {
+		return scores
+	}
+	var low, high = matches[0].Score, matches[0].Score
+	for _, match := range matches {
+		low, high = min(low, match.Score), max(high, match.Score)
+	}
+	for i, match := range matches {
+		switch {
+		case high == low:
+			scores[i] = 1
+		case largest:
+			scores[i] = float64(match.Score-low) / float64(high-low)
+		default:
+			scores[i] = float64(high-match.Score) / float64(high-low)
+		}
+	}
+	return scores
+}
+
+func scaleScores(scores []float64, weight float64) []float64 {
+	for i := range scores {
+		scores[i] *= weight
+	}
+	return scores
+}
@@ -0,0 +1,123 @@
+package vectorindex
+
+import (
+	"errors"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+var (
+	errEmptyId           = errors.New("id is empty")
+	errZeroDimension     = errors.New("vector has zero dimension")
+	errZeroVector        = errors.New("zero vector can not be indexed by cosine distance")
+	errDimensionMismatch = errors.New("dimension mismatch")
+)
+
+// Metadata of an indexed vector, e.g. the source and the offset of the
+// document.
+type Metadata map[string]string
+
+// Filter reports whether a vector can be returned by a search.
+type Filter func(id string, metadata Metadata) bool
+
+// MetadataEquals returns a filter accepts vectors whose metadata have all
+// the key-value pairs.
+func MetadataEquals(pairs Metadata) Filter {
+	return func(id string, metadata Metadata) bool {
+		for k, v := range pairs {
+			if value, ok := metadata[k]; !ok || value != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type Match struct {
+	Id string
+	// Cosine similarity, higher is more similar, or euclidean distance,
+	// lower is more similar, by the distance type of the index.
+	Score    float32
+	Metadata Metadata
+}
+
+// Index is a set of vectors keyed by document ids.
+//
+// The distance type of an index should be the GetDistanceType of the
+// embedding.Model produces the vectors. Vectors of cosine indexes are stored
+// normalized. All methods are safe for concurrent use.
+type Index interface {
+	DistanceType() embedding.VectorDistanceType
+	// Dimension of the vectors, zero before the first vector is added
+	Dimension() int
+	// Number of vectors
+	Len() int
+	// Add adds a vector, or replaces the vector with the same id.
+	Add(id string, vector []float32, metadata Metadata) error
+	// Delete removes a vector, reports whether the id exists.
+	Delete(id string) bool
+	// Get returns a copy of the vector and its metadata.
+	Get(id string) ([]float32, Metadata, bool)
+	// Search returns at most k vectors nearest to the query, most similar
+	// first. A nil filter accepts all vectors.
+	Search(query []float32, k int, filter Filter) ([]Match, error)
+}
+
+// prepare checks the dimension of the vector, and returns a copy, normalized
+// for cosine distance.
+func prepare(distanceType embedding.VectorDistanceType, dimension int, vector []float32) ([]float32, error) {
+	if len(vector) == 0 {
+		return nil, errZeroDimension
+	}
+	if dimension != 0 && len(vector) != dimension {
+		return nil, errDimensionMismatch
+	}
+	var result = make([]float32, len(vector))
+	if distanceType == embedding.EuclideanDistance {
+		copy(result, vector)
+		return result, nil
+	}
+	if err := embedding.VectorNormalize(vector, result); err != nil {
+		return nil, errZeroVector
+	}
+	return result, nil
+}
+
+// distance returns the distance of prepared vectors, lower is more similar:
+// 1 - cosine similarity or euclidean distance.
+func distance(distanceType embedding.VectorDistanceType, v1, v2 []float32) float32 {
+	if distanceType == embedding.EuclideanDistance {
+		var d, _ = embedding.VectorEuclideanDistance(v1, v2)
+		return d
+	}
+	var dot, _ = embedding.VectorDotProduct(v1, v2)
+	return 1 - dot
+}
+
+// score converts a distance to the score of a match.
+func score(distanceType embedding.VectorDistanceType, distance float32) float32 {
+	if distanceType == embedding.EuclideanDistance {
+		return distance
+	}
+	return 1 - distance
+}
+
+func normalizeDistanceType(distanceType embedding.VectorDistanceType) embedding.VectorDistanceType {
+	if distanceType == embedding.EuclideanDistance {
+		return embedding.EuclideanDistance
+	}
+	return embedding.CosineDistance
+}
+
+func cloneMetadata(metadata Metadata) Metadata {
+	if metadata == nil {
+		return nil
+	}
+	var result = make(Metadata, len(metadata))
+	for k, v := range metadata {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package vectorindex
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeywordTokens splits a text into the lowercase terms of a BM25 index.
+//
+// Runs of letters and digits are words. Words joined by '-', '_', '.' or '/'
+// are kept as a compound term followed by its parts, so identifiers like
+// "ERR_CONN_RESET" and "SKU-4821-B" match exactly as well as by their parts.
+// Han, Kana and Hangul runs, written without spaces, are split into
+// overlapping bigrams, a single character is a term by itself.
+func KeywordTokens(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	var compound = false
+	var cjk []rune
+
+	var flushWord = func() {
+		if word.Len() == 0 {
+			return
+		}
+		var s = word.String()
+		tokens = append(tokens, s)
+		if compound {
+			for _, part := range strings.FieldsFunc(s, isKeywordConnector) {
+				tokens = append(tokens, part)
+			}
+		}
+		word.Reset()
+		compound = false
+	}
+	var flushCJK = func() {
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word.WriteRune(unicode.ToLower(r))
+		case isKeywordConnector(r) && word.Len() > 0 && isWordStart(text[i+utf8.RuneLen(r):]):
+			word.WriteRune(r)
+			compound = true
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isKeywordConnector(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == '/'
+}
+
+// isWordStart reports whether s starts with a letter or digit of a word.
+func isWordStart(s string) bool {
+	var r, _ = utf8.DecodeRuneInString(s)
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isCJK(r)
+}
+
+func isCJK(r rune) bool {
+	// U+30FC is the prolonged sound mark of Katakana words
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == '\u30fc'
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package vectorindex
+
+import (
+	"os"
+)
+
+// mapFile reads the file into memory, memory mapping is only used on unix.
+func mapFile(path string) ([]byte, func() error, error) {
+	var data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
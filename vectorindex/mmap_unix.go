@@ -0,0 +1,35 @@
+//go:build unix
+
+package vectorindex
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the file into memory read-only, the returned function unmaps
+// it. Segments are scanned from the mapping without reading them into the
+// heap.
+func mapFile(path string) ([]byte, func() error, error) {
+	var file, err = os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var info os.FileInfo
+	info, err = file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+
+	var data []byte
+	data, err = syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
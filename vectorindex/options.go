@@ -0,0 +1,119 @@
+package vectorindex
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+)
+
+// Options of HNSW indexes, stores, BM25 indexes and hybrid searches.
+type Options struct {
+	// Maximum number of neighbors of a node on the upper layers of a HNSW
+	// index, twice on the bottom layer. Default is 16.
+	M int
+	// Size of the candidate list when inserting. Default is 200.
+	EfConstruction int
+	// Size of the candidate list when searching, at least k. Default is 64.
+	EfSearch int
+	// Seed of the random levels, zero means 1. Indexes built with the same
+	// seed from the same sequence of vectors are identical.
+	Seed int64
+
+	// Size of a segment file of a store, a new segment is started when the
+	// current one exceeds the size. Default is 64 MiB.
+	SegmentSize int64
+	// Sync the segment file after each write of a store. Writes are only
+	// flushed to the OS by default, and may be lost if the machine crashes.
+	Sync bool
+
+	// Term frequency saturation of a BM25 index. Default is 1.2.
+	BM25K1 float64
+	// Document length normalization of a BM25 index, from 0 to 1, 1 scales
+	// the term frequencies by the document lengths fully, 0 disables the
+	// normalization. Default is 0.75.
+	BM25B aigc.Nullable[float64]
+	// Splits texts into the terms of a BM25 index. Default is KeywordTokens.
+	Tokenizer func(text string) []string
+
+	// Fuses the results of a hybrid search. Default is FusionRRF.
+	Fusion FusionType
+	// Constant of the reciprocal rank fusion, larger values flatten the
+	// weights of the top ranks. Default is 60.
+	RRFConstant float64
+	// Weight of the vector scores of FusionWeighted, from 0 to 1, the
+	// keyword scores have 1 - VectorWeight, 0 ranks by the keywords only.
+	// Default is 0.5.
+	VectorWeight aigc.Nullable[float64]
+	// Number of results searched by each side of a hybrid search, at least
+	// k. Default is 4 times k.
+	HybridCandidates int
+}
+
+type OptionFunc func(*Options)
+
+func WithM(m int) func(*Options) {
+	return func(o *Options) {
+		o.M = m
+	}
+}
+
+func WithEfConstruction(ef int) func(*Options) {
+	return func(o *Options) {
+		o.EfConstruction = ef
+	}
+}
+
+func WithEfSearch(ef int) func(*Options) {
+	return func(o *Options) {
+		o.EfSearch = ef
+	}
+}
+
+func WithSeed(seed int64) func(*Options) {
+	return func(o *Options) {
+		o.Seed = seed
+	}
+}
+
+func WithSegmentSize(size int64) func(*Options) {
+	return func(o *Options) {
+		o.SegmentSize = size
+	}
+}
+
+func WithSync(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.Sync = enabled
+	}
+}
+
+func WithBM25(k1 float64, b float64) func(*Options) {
+	return func(o *Options) {
+		o.BM25K1 = k1
+		o.BM25B.Set(b)
+	}
+}
+
+func WithTokenizer(tokenizer func(text string) []string) func(*Options) {
+	return func(o *Options) {
+		o.Tokenizer = tokenizer
+	}
+}
+
+func WithRRF(constant float64) func(*Options) {
+	return func(o *Options) {
+		o.Fusion = FusionRRF
+		o.RRFConstant = constant
+	}
+}
+
+func WithWeightedFusion(vectorWeight float64) func(*Options) {
+	return func(o *Options) {
+		o.Fusion = FusionWeighted
+		o.VectorWeight.Set(vectorWeight)
+	}
+}
+
+func WithHybridCandidates(n int) func(*Options) {
+	return func(o *Options) {
+		o.HybridCandidates = n
+	}
+}
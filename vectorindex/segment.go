@@ -0,0 +1,204 @@
+package vectorindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// A segment file is a header followed by records:
+//
+//	header:  magic [8]byte | version uint32 | reserved uint32
+//	record:  length uint32 | crc32c(payload) uint32 | payload [length]byte
+//	payload: op byte | id string
+//	         put only: metadata count uvarint | (key string | value string)...
+//	                   dimension uvarint | vector [dimension]float32
+//
+// Strings are prefixed by their uvarint lengths, integers and floats are
+// little endian. Records are only appended, a later record of the same id
+// overrides the earlier ones.
+
+const (
+	segmentMagic      = "AIGCVEC\x00"
+	segmentVersion    = 1
+	segmentHeaderSize = 16
+	recordHeaderSize  = 8
+
+	recordPut    byte = 1
+	recordDelete byte = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrCorrupted is returned if a segment has an invalid header or a
+	// record fails the checksum verification.
+	ErrCorrupted = errors.New("store is corrupted")
+	// ErrModelMismatch is returned if a store is opened with a different
+	// model, distance type or dimension from the ones it is created with.
+	ErrModelMismatch = errors.New("store is created by a different embedding model")
+)
+
+type segmentRecord struct {
+	op       byte
+	id       string
+	metadata Metadata
+	vector   []float32
+}
+
+func segmentHeader() []byte {
+	var header = make([]byte, segmentHeaderSize)
+	copy(header, segmentMagic)
+	binary.LittleEndian.PutUint32(header[8:], segmentVersion)
+	return header
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// encodeRecord writes the record with its length and checksum.
+func encodeRecord(buffer *bytes.Buffer, record *segmentRecord) {
+	var payload = make([]byte, recordHeaderSize, recordHeaderSize+64+len(record.vector)*4)
+	payload = append(payload, record.op)
+	payload = appendString(payload, record.id)
+	if record.op == recordPut {
+		payload = binary.AppendUvarint(payload, uint64(len(record.metadata)))
+		for k, v := range record.metadata {
+			payload = appendString(payload, k)
+			payload = appendString(payload, v)
+		}
+		payload = binary.AppendUvarint(payload, uint64(len(record.vector)))
+		for _, f := range record.vector {
+			payload = binary.LittleEndian.AppendUint32(payload, math.Float32bits(f))
+		}
+	}
+	binary.LittleEndian.PutUint32(payload[0:], uint32(len(payload)-recordHeaderSize))
+	binary.LittleEndian.PutUint32(payload[4:], crc32.Checksum(payload[recordHeaderSize:], crcTable))
+	buffer.Write(payload)
+}
+
+// segmentReader decodes a payload, the first error stops the reading.
+type segmentReader struct {
+	data []byte
+	err  error
+}
+
+func (r *segmentReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	var v, n = binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("invalid uvarint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *segmentReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)) {
+		r.err = errors.New("unexpected end of record")
+		return nil
+	}
+	var b = r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *segmentReader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+func decodeRecord(payload []byte) (segmentRecord, error) {
+	var record segmentRecord
+	var r = segmentReader{data: payload}
+
+	var op = r.bytes(1)
+	if r.err != nil {
+		return record, r.err
+	}
+	record.op = op[0]
+	record.id = r.string()
+
+	switch record.op {
+	case recordDelete:
+	case recordPut:
+		var count = r.uvarint()
+		if count > 0 && r.err == nil {
+			record.metadata = make(Metadata, min(count, 64))
+			for i := uint64(0); i < count && r.err == nil; i++ {
+				var k = r.string()
+				record.metadata[k] = r.string()
+			}
+		}
+		var dimension = r.uvarint()
+		var data = r.bytes(dimension * 4)
+		if r.err == nil {
+			record.vector = make([]float32, dimension)
+			for i := range record.vector {
+				record.vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+			}
+		}
+	default:
+		return record, fmt.Errorf("unknown record type %d", record.op)
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.err = errors.New("unexpected data at the end of record")
+	}
+	return record, r.err
+}
+
+// scanSegment verifies the header and the records of a segment, and calls fn
+// with the offset, the size and the decoded record of each record.
+//
+// It returns the end of the last complete record. If the segment ends with an
+// incomplete record, e.g. the process crashed while appending, truncated is
+// true and the incomplete record is ignored.
+func scanSegment(data []byte, fn func(offset int64, size int, record *segmentRecord) error) (end int64, truncated bool, err error) {
+	if len(data) < segmentHeaderSize {
+		return 0, true, nil
+	}
+	if string(data[:8]) != segmentMagic {
+		return 0, false, fmt.Errorf("%w: invalid segment header", ErrCorrupted)
+	}
+	if v := binary.LittleEndian.Uint32(data[8:]); v != segmentVersion {
+		return 0, false, fmt.Errorf("%w: unsupported segment version %d", ErrCorrupted, v)
+	}
+
+	var offset = int64(segmentHeaderSize)
+	for offset < int64(len(data)) {
+		var rest = data[offset:]
+		if len(rest) < recordHeaderSize {
+			return offset, true, nil
+		}
+		var length = int64(binary.LittleEndian.Uint32(rest[0:]))
+		if int64(len(rest))-recordHeaderSize < length {
+			return offset, true, nil
+		}
+		var payload = rest[recordHeaderSize : recordHeaderSize+length]
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(rest[4:]) {
+			return offset, false, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorrupted, offset)
+		}
+		var record, err = decodeRecord(payload)
+		if err != nil {
+			return offset, false, fmt.Errorf("%w: %s at offset %d", ErrCorrupted, err.Error(), offset)
+		}
+		if fn != nil {
+			err = fn(offset, int(recordHeaderSize+length), &record)
+			if err != nil {
+				return offset, false, err
+			}
+		}
+		offset += recordHeaderSize + length
+	}
+	return offset, false, nil
+}
@@ -0,0 +1,792 @@
+package vectorindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+)
+
+const (
+	storeManifestName       = "MANIFEST.json"
+	storeManifestVersion    = 1
+	storeDefaultSegmentSize = 64 << 20
+)
+
+var errStoreClosed = errors.New("store is closed")
+
+// storeManifest lists the segments of a store, and records the model the
+// vectors are embedded by. It is replaced atomically by rename.
+type storeManifest struct {
+	Version      int                          `json:"version"`
+	ModelId      string                       `json:"model_id"`
+	DistanceType embedding.VectorDistanceType `json:"distance_type"`
+	Dimension    int                          `json:"dimension"`
+	Segments     []string                     `json:"segments"`
+	NextSegment  int                          `json:"next_segment"`
+}
+
+// recordRef is the location of the latest put record of an id.
+type recordRef struct {
+	segment string
+	offset  int64
+	size    int
+}
+
+type StoreStats struct {
+	// Number of segment files
+	Segments int
+	// Number of records in the segments, including overridden and deleted
+	Records int
+	// Number of vectors
+	Vectors int
+	// Total size of the segment files
+	Bytes int64
+}
+
+// Store makes an index durable. Vectors and metadata are appended to segment
+// files under a directory, and loaded into the index when the store is
+// opened:
+//
+//	<dir>/MANIFEST.json
+//	<dir>/00000001.seg
+//	<dir>/00000002.seg
+//
+// Overridden and deleted vectors stay in the segments until Compact.
+type Store struct {
+	dir         string
+	index       Index
+	segmentSize int64
+	sync        bool
+
+	lock       sync.Mutex
+	manifest   storeManifest
+	active     *os.File // the last segment, opened for appending
+	activeSize int64
+	latest     map[string]recordRef
+	records    int
+	bytes      int64 // size of the segments except the active one
+}
+
+// OpenStore opens or creates the store under dir, and loads the vectors into
+// the index, which should be empty.
+//
+// The model id, usually embedding.Model.GetModelId, the distance type and the
+// dimension of the index are recorded when the store is created. Opening the
+// store with a different one returns ErrModelMismatch.
+func OpenStore(dir string, modelId string, index Index, options ...OptionFunc) (*Store, error) {
+	var err error
+	var opts Options
+
+	if dir == "" {
+		return nil, errors.New("store directory is required")
+	}
+	if modelId == "" {
+		return nil, errors.New("model id is required")
+	}
+	if index == nil {
+		return nil, errors.New("index is required")
+	}
+	for _, fn := range options {
+		fn(&opts)
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = storeDefaultSegmentSize
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("[OpenStore] %w", err)
+	}
+
+	var s = &Store{
+		dir:         dir,
+		index:       index,
+		segmentSize: opts.SegmentSize,
+		sync:        opts.Sync,
+		latest:      make(map[string]recordRef),
+	}
+
+	var found bool
+	found, err = readManifest(dir, &s.manifest)
+	if err != nil {
+		return nil, fmt.Errorf("[OpenStore] %w", err)
+	}
+	if !found {
+		s.manifest = storeManifest{
+			Version:      storeManifestVersion,
+			ModelId:      modelId,
+			DistanceType: index.DistanceType(),
+			Dimension:    index.Dimension(),
+			NextSegment:  1,
+		}
+	}
+	if s.manifest.ModelId != modelId {
+		return nil, fmt.Errorf("[OpenStore] %w: store model %s, model %s",
+			ErrModelMismatch, s.manifest.ModelId, modelId)
+	}
+	if s.manifest.DistanceType != index.DistanceType() {
+		return nil, fmt.Errorf("[OpenStore] %w: store distance type %s, index distance type %s",
+			ErrModelMismatch, s.manifest.DistanceType, index.DistanceType())
+	}
+	if s.manifest.Dimension != 0 && index.Dimension() != 0 && s.manifest.Dimension != index.Dimension() {
+		return nil, fmt.Errorf("[OpenStore] %w: store dimension %d, index dimension %d",
+			ErrModelMismatch, s.manifest.Dimension, index.Dimension())
+	}
+
+	for i, name := range s.manifest.Segments {
+		err = s.load(name, i == len(s.manifest.Segments)-1)
+		if err != nil {
+			return nil, fmt.Errorf("[OpenStore] %w", err)
+		}
+	}
+
+	// Continue appending to the last segment if it is not full
+	var segments = s.manifest.Segments
+	if len(segments) > 0 {
+		var last = segments[len(segments)-1]
+		var info os.FileInfo
+		info, err = os.Stat(s.path(last))
+		if err != nil {
+			return nil, fmt.Errorf("[OpenStore] %w", err)
+		}
+		if info.Size() < s.segmentSize {
+			s.active, err = os.OpenFile(s.path(last), os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				return nil, fmt.Errorf("[OpenStore] %w", err)
+			}
+			s.activeSize = info.Size()
+			s.bytes -= info.Size()
+		}
+	}
+	if s.active == nil {
+		err = s.roll()
+		if err != nil {
+			return nil, fmt.Errorf("[OpenStore] %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) path(segment string) string {
+	return filepath.Join(s.dir, segment)
+}
+
+// load applies the records of a segment to the index. An incomplete record
+// at the end of the last segment is truncated.
+func (s *Store) load(name string, last bool) error {
+	var data, unmap, err = mapFile(s.path(name))
+	if err != nil {
+		return err
+	}
+
+	var end int64
+	var truncated bool
+	end, truncated, err = scanSegment(data, func(offset int64, size int, record *segmentRecord) error {
+		s.records++
+		if record.op == recordDelete {
+			s.index.Delete(record.id)
+			delete(s.latest, record.id)
+			return nil
+		}
+		s.latest[record.id] = recordRef{segment: name, offset: offset, size: size}
+		return s.index.Add(record.id, record.vector, record.metadata)
+	})
+	if unmapErr := unmap(); err == nil {
+		err = unmapErr
+	}
+	if err != nil {
+		return fmt.Errorf("segment %s: %w", name, err)
+	}
+
+	if truncated {
+		if !last {
+			return fmt.Errorf("%w: segment %s is incomplete", ErrCorrupted, name)
+		}
+		if end < segmentHeaderSize {
+			// The header was not written completely
+			err = os.WriteFile(s.path(name), segmentHeader(), 0o644)
+			end = segmentHeaderSize
+		} else {
+			err = os.Truncate(s.path(name), end)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	s.bytes += end
+	return nil
+}
+
+// roll starts a new segment and makes it active.
+func (s *Store) roll() error {
+	var name = fmt.Sprintf("%08d.seg", s.manifest.NextSegment)
+	var file, err = createSegment(s.path(name))
+	if err != nil {
+		return err
+	}
+
+	s.manifest.NextSegment++
+	s.manifest.Segments = append(s.manifest.Segments, name)
+	err = writeManifest(s.dir, &s.manifest)
+	if err != nil {
+		file.Close()
+		s.manifest.Segments = s.manifest.Segments[:len(s.manifest.Segments)-1]
+		os.Remove(s.path(name))
+		return err
+	}
+
+	if s.active != nil {
+		s.active.Close()
+		s.bytes += s.activeSize
+	}
+	s.active = file
+	s.activeSize = segmentHeaderSize
+	return nil
+}
+
+// append writes the record to the active segment.
+func (s *Store) append(record *segmentRecord) (recordRef, error) {
+	var err error
+	var buffer = aigc.AllocBuffer()
+	defer aigc.FreeBuffer(buffer)
+
+	if s.active == nil {
+		return recordRef{}, errStoreClosed
+	}
+	if s.activeSize >= s.segmentSize {
+		err = s.roll()
+		if err != nil {
+			return recordRef{}, err
+		}
+	}
+
+	encodeRecord(buffer, record)
+	var ref = recordRef{
+		segment: s.manifest.Segments[len(s.manifest.Segments)-1],
+		offset:  s.activeSize,
+		size:    buffer.Len(),
+	}
+	_, err = s.active.Write(buffer.Bytes())
+	if err == nil && s.sync {
+		err = s.active.Sync()
+	}
+	if err != nil {
+		// Drop the partial written record
+		s.active.Truncate(s.activeSize)
+		return recordRef{}, err
+	}
+	s.activeSize += int64(ref.size)
+	s.records++
+	return ref, nil
+}
+
+func (s *Store) Index() Index {
+	return s.index
+}
+
+func (s *Store) DistanceType() embedding.VectorDistanceType {
+	return s.index.DistanceType()
+}
+
+func (s *Store) ModelId() string {
+	return s.manifest.ModelId
+}
+
+func (s *Store) Len() int {
+	return s.index.Len()
+}
+
+// Get returns a copy of the vector and its metadata.
+func (s *Store) Get(id string) ([]float32, Metadata, bool) {
+	return s.index.Get(id)
+}
+
+// Add writes the vector to the store and adds it to the index, or replaces
+// the vector with the same id.
+func (s *Store) Add(id string, vector []float32, metadata Metadata) error {
+	var err error
+	if id == "" {
+		return fmt.Errorf("[Store.Add] %w", errEmptyId)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, err = prepare(s.manifest.DistanceType, s.manifest.Dimension, vector)
+	if err != nil {
+		return fmt.Errorf("[Store.Add] %w", err)
+	}
+	if s.manifest.Dimension == 0 {
+		s.manifest.Dimension = len(vector)
+		err = writeManifest(s.dir, &s.manifest)
+		if err != nil {
+			s.manifest.Dimension = 0
+			return fmt.Errorf("[Store.Add] %w", err)
+		}
+	}
+
+	var ref recordRef
+	ref, err = s.append(&segmentRecord{op: recordPut, id: id, metadata: metadata, vector: vector})
+	if err != nil {
+		return fmt.Errorf("[Store.Add] %w", err)
+	}
+	s.latest[id] = ref
+
+	err = s.index.Add(id, vector, metadata)
+	if err != nil {
+		return fmt.Errorf("[Store.Add] %w", err)
+	}
+	return nil
+}
+
+// Delete removes the vector from the store and the index, reports whether
+// the id exists.
+func (s *Store) Delete(id string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.latest[id]; !ok {
+		return false, nil
+	}
+	var _, err = s.append(&segmentRecord{op: recordDelete, id: id})
+	if err != nil {
+		return false, fmt.Errorf("[Store.Delete] %w", err)
+	}
+	delete(s.latest, id)
+	s.index.Delete(id)
+	return true, nil
+}
+
+// Search searches the index.
+func (s *Store) Search(query []float32, k int, filter Filter) ([]Match, error) {
+	return s.index.Search(query, k, filter)
+}
+
+func (s *Store) Stats() StoreStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return StoreStats{
+		Segments: len(s.manifest.Segments),
+		Records:  s.records,
+		Vectors:  len(s.latest),
+		Bytes:    s.bytes + s.activeSize,
+	}
+}
+
+// Verify verifies the checksums of all records.
+func (s *Store) Verify() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, name := range s.manifest.Segments {
+		var err = verifySegment(s.path(name))
+		if err != nil {
+			return fmt.Errorf("[Store.Verify] segment %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Compact rewrites the vectors into new segments without the overridden and
+// deleted records, and removes the old segments. An index with a Compact
+// method, e.g. HNSWIndex, is compacted as well.
+func (s *Store) Compact() error {
+	var err error
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.active == nil {
+		return fmt.Errorf("[Store.Compact] %w", errStoreClosed)
+	}
+
+	var old = s.manifest.Segments
+	var compacted = s.manifest
+	compacted.Segments = nil
+	var latest = make(map[string]recordRef, len(s.latest))
+
+	var writer = segmentWriter{dir: s.dir, manifest: &compacted, segmentSize: s.segmentSize}
+	for _, name := range old {
+		var data, unmap, mapErr = mapFile(s.path(name))
+		if mapErr != nil {
+			writer.abort()
+			return fmt.Errorf("[Store.Compact] %w", mapErr)
+		}
+		_, _, err = scanSegment(data, func(offset int64, size int, record *segmentRecord) error {
+			var ref, ok = s.latest[record.id]
+			if record.op != recordPut || !ok || ref.segment != name || ref.offset != offset {
+				return nil
+			}
+			var newRef, writeErr = writer.write(data[offset : offset+int64(size)])
+			latest[record.id] = newRef
+			return writeErr
+		})
+		if unmapErr := unmap(); err == nil {
+			err = unmapErr
+		}
+		if err != nil {
+			writer.abort()
+			return fmt.Errorf("[Store.Compact] segment %s: %w", name, err)
+		}
+	}
+
+	var active *os.File
+	active, err = writer.finish()
+	if err != nil {
+		writer.abort()
+		return fmt.Errorf("[Store.Compact] %w", err)
+	}
+	err = writeManifest(s.dir, &compacted)
+	if err != nil {
+		active.Close()
+		writer.abort()
+		return fmt.Errorf("[Store.Compact] %w", err)
+	}
+
+	// The new manifest is written, old segments are garbage from now on
+	s.active.Close()
+	for _, name := range old {
+		os.Remove(s.path(name))
+	}
+	s.manifest = compacted
+	s.latest = latest
+	s.active = active
+	s.activeSize = writer.size
+	s.records = len(latest)
+	s.bytes = writer.bytes
+	if index, ok := s.index.(interface{ Compact() }); ok {
+		index.Compact()
+	}
+	return nil
+}
+
+// Snapshot writes a consistent copy of the store into dir, which must not
+// contain a store. Segments are hard linked if possible, otherwise copied.
+func (s *Store) Snapshot(dir string) error {
+	var err error
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.active == nil {
+		return fmt.Errorf("[Store.Snapshot] %w", errStoreClosed)
+	}
+	err = prepareEmptyStoreDir(dir)
+	if err != nil {
+		return fmt.Errorf("[Store.Snapshot] %w", err)
+	}
+	err = s.active.Sync()
+	if err != nil {
+		return fmt.Errorf("[Store.Snapshot] %w", err)
+	}
+
+	var last = len(s.manifest.Segments) - 1
+	for i, name := range s.manifest.Segments {
+		if i == last {
+			// The active segment is still growing, copy what is written
+			err = copyFile(s.path(name), filepath.Join(dir, name), s.activeSize)
+		} else {
+			err = linkOrCopyFile(s.path(name), filepath.Join(dir, name))
+		}
+		if err != nil {
+			return fmt.Errorf("[Store.Snapshot] %w", err)
+		}
+	}
+	err = writeManifest(dir, &s.manifest)
+	if err != nil {
+		return fmt.Errorf("[Store.Snapshot] %w", err)
+	}
+	return nil
+}
+
+// Close closes the store, the index is kept.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	var err = s.active.Sync()
+	if closeErr := s.active.Close(); err == nil {
+		err = closeErr
+	}
+	s.active = nil
+	if err != nil {
+		return fmt.Errorf("[Store.Close] %w", err)
+	}
+	return nil
+}
+
+// RestoreSnapshot verifies the snapshot and copies it into dir, which must
+// not contain a store. Segments are hard linked if possible. The restored
+// store is opened by OpenStore.
+func RestoreSnapshot(snapshot string, dir string) error {
+	var err error
+	var manifest storeManifest
+	var found bool
+
+	found, err = readManifest(snapshot, &manifest)
+	if err != nil {
+		return fmt.Errorf("[RestoreSnapshot] %w", err)
+	}
+	if !found {
+		return fmt.Errorf("[RestoreSnapshot] no store in %s", snapshot)
+	}
+	for _, name := range manifest.Segments {
+		err = verifySegment(filepath.Join(snapshot, name))
+		if err != nil {
+			return fmt.Errorf("[RestoreSnapshot] segment %s: %w", name, err)
+		}
+	}
+
+	err = prepareEmptyStoreDir(dir)
+	if err != nil {
+		return fmt.Errorf("[RestoreSnapshot] %w", err)
+	}
+	for i, name := range manifest.Segments {
+		if i == len(manifest.Segments)-1 {
+			// The last segment will be appended by the restored store
+			err = copyFile(filepath.Join(snapshot, name), filepath.Join(dir, name), -1)
+		} else {
+			err = linkOrCopyFile(filepath.Join(snapshot, name), filepath.Join(dir, name))
+		}
+		if err != nil {
+			return fmt.Errorf("[RestoreSnapshot] %w", err)
+		}
+	}
+	// The manifest is written last, an interrupted restore leaves no store
+	err = writeManifest(dir, &manifest)
+	if err != nil {
+		return fmt.Errorf("[RestoreSnapshot] %w", err)
+	}
+	return nil
+}
+
+// segmentWriter writes records into new segments for compaction.
+type segmentWriter struct {
+	dir         string
+	manifest    *storeManifest
+	segmentSize int64
+
+	file    *os.File
+	size    int64 // size of the current segment
+	bytes   int64 // size of the finished segments
+	created []string
+}
+
+func (w *segmentWriter) next() error {
+	if w.file != nil {
+		var err = w.file.Sync()
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
+		w.file = nil
+		w.bytes += w.size
+		if err != nil {
+			return err
+		}
+	}
+
+	var name = fmt.Sprintf("%08d.seg", w.manifest.NextSegment)
+	var file, err = createSegment(filepath.Join(w.dir, name))
+	if err != nil {
+		return err
+	}
+	w.manifest.NextSegment++
+	w.manifest.Segments = append(w.manifest.Segments, name)
+	w.created = append(w.created, name)
+	w.file = file
+	w.size = segmentHeaderSize
+	return nil
+}
+
+func (w *segmentWriter) write(record []byte) (recordRef, error) {
+	if w.file == nil || w.size >= w.segmentSize {
+		var err = w.next()
+		if err != nil {
+			return recordRef{}, err
+		}
+	}
+	var _, err = w.file.Write(record)
+	if err != nil {
+		return recordRef{}, err
+	}
+	var ref = recordRef{segment: w.created[len(w.created)-1], offset: w.size, size: len(record)}
+	w.size += int64(len(record))
+	return ref, nil
+}
+
+// finish syncs the last segment and returns it for appending.
+func (w *segmentWriter) finish() (*os.File, error) {
+	if w.file == nil {
+		var err = w.next()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err = w.file.Sync()
+	if err != nil {
+		return nil, err
+	}
+	var file = w.file
+	w.file = nil
+	return file, nil
+}
+
+// abort removes the created segments.
+func (w *segmentWriter) abort() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	for _, name := range w.created {
+		os.Remove(filepath.Join(w.dir, name))
+	}
+}
+
+// createSegment creates a segment file with the header, opened for
+// appending.
+func createSegment(path string) (*os.File, error) {
+	var file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.Write(segmentHeader())
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return file, nil
+}
+
+func verifySegment(path string) error {
+	var data, unmap, err = mapFile(path)
+	if err != nil {
+		return err
+	}
+	var truncated bool
+	_, truncated, err = scanSegment(data, nil)
+	if unmapErr := unmap(); err == nil {
+		err = unmapErr
+	}
+	if err == nil && truncated {
+		err = fmt.Errorf("%w: segment is incomplete", ErrCorrupted)
+	}
+	return err
+}
+
+func readManifest(dir string, manifest *storeManifest) (bool, error) {
+	var data, err = os.ReadFile(filepath.Join(dir, storeManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return false, fmt.Errorf("%w: invalid manifest %s", ErrCorrupted, err.Error())
+	}
+	if manifest.Version != storeManifestVersion {
+		return false, fmt.Errorf("unsupported store version %d", manifest.Version)
+	}
+	return true, nil
+}
+
+// writeManifest writes to a temporary file then renames, so readers never
+// see a partial written manifest.
+func writeManifest(dir string, manifest *storeManifest) error {
+	var err error
+	var data []byte
+	var temp *os.File
+
+	data, err = json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err = os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	err = os.Rename(temp.Name(), filepath.Join(dir, storeManifestName))
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
+
+func prepareEmptyStoreDir(dir string) error {
+	if dir == "" {
+		return errors.New("store directory is required")
+	}
+	var err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(filepath.Join(dir, storeManifestName))
+	if err == nil {
+		return fmt.Errorf("store already exists in %s", dir)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// copyFile copies the first n bytes of src to dst, all if n < 0.
+func copyFile(src string, dst string, n int64) error {
+	var in, err = os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out *os.File
+	out, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		_, err = io.Copy(out, in)
+	} else {
+		_, err = io.CopyN(out, in, n)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func linkOrCopyFile(src string, dst string) error {
+	if os.Link(src, dst) == nil {
+		return nil
+	}
+	return copyFile(src, dst, -1)
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func matchIds(matches []vectorindex.Match) []string {
+	var ids = make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.Id
+	}
+	return ids
+}
+
+func Test_KeywordTokens(t *testing.T) {
+	var tests = []struct {
+		text   string
+		tokens []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"Error ERR_CONN_RESET at SKU-4821-B.", []string{"error", "err_conn_reset", "err", "conn", "reset", "at",
+			"sku-4821-b", "sku", "4821", "b"}},
+		{"v1.2 - done", []string{"v1.2", "v1", "2", "done"}},
+		{"检索增强生成", []string{"检索", "索增", "增强", "强生", "生成"}},
+		{"使用GPT4模型。日", []string{"使用", "gpt4", "模型", "日"}},
+		{"コーヒー 와 한국어", []string{"コー", "ーヒ", "ヒー", "와", "한국", "국어"}},
+	}
+	for _, test := range tests {
+		var tokens = vectorindex.KeywordTokens(test.text)
+		if !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("%q: %q, want %q", test.text, tokens, test.tokens)
+		}
+	}
+}
+
+func Test_BM25Index_Search(t *testing.T) {
+	var index = vectorindex.NewBM25Index()
+	var texts = []string{
+		"the quick brown fox",
+		"the lazy dog sleeps all day long",
+		"quick quick fox jumps",
+		"a lazy fox",
+	}
+	for i, text := range texts {
+		var metadata = vectorindex.Metadata{"parity": strconv.Itoa(i % 2)}
+		if err := index.Add(strconv.Itoa(i), text, metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if index.Len() != 4 {
+		t.Fatalf("Len: %d", index.Len())
+	}
+
+	var matches, err = index.Search("Quick fox", 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"2", "0", "3"}) {
+		t.Errorf("quick fox: %v", ids)
+	}
+	if matches[0].Score <= matches[1].Score || matches[0].Metadata["parity"] != "0" {
+		t.Errorf("unexpected matches: %+v", matches)
+	}
+
+	// Shorter texts rank higher by the length normalization
+	matches, _ = index.Search("lazy", 10, nil)
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"3", "1"}) {
+		t.Errorf("lazy: %v", ids)
+	}
+	matches, _ = index.Search("lazy", 10, vectorindex.MetadataEquals(vectorindex.Metadata{"parity": "1"}))
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"3", "1"}) {
+		t.Errorf("filtered lazy: %v", ids)
+	}
+	matches, _ = index.Search("lazy", 10, vectorindex.MetadataEquals(vectorindex.Metadata{"parity": "0"}))
+	if len(matches) != 0 {
+		t.Errorf("filtered lazy: %v", matchIds(matches))
+	}
+	// No length normalization, ties are ranked by the order of the texts
+	var unnormalized = vectorindex.NewBM25Index(vectorindex.WithBM25(1.2, 0))
+	for i, text := range texts {
+		unnormalized.Add(strconv.Itoa(i), text, nil)
+	}
+	matches, _ = unnormalized.Search("lazy", 10, nil)
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"1", "3"}) || matches[0].Score != matches[1].Score {
+		t.Errorf("unnormalized lazy: %v", ids)
+	}
+	if matches, _ = index.Search("cat", 10, nil); len(matches) != 0 {
+		t.Errorf("cat: %v", matchIds(matches))
+	}
+
+	if !index.Delete("2") || index.Delete("2") {
+		t.Error("Delete")
+	}
+	if err = index.Add("0", "a sleeping cat", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = index.Add("4", "quick", nil); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ = index.Search("quick fox cat", 10, nil)
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"4", "0", "3"}) && !reflect.DeepEqual(ids, []string{"0", "4", "3"}) {
+		t.Errorf("after updates: %v", ids)
+	}
+	if index.Len() != 4 {
+		t.Errorf("Len: %d", index.Len())
+	}
+	if err = index.Add("", "text", nil); err == nil {
+		t.Error("empty id is accepted")
+	}
+}
+
+func Test_HybridSearch(t *testing.T) {
+	var vectors = vectorindex.NewFlatIndex(embedding.CosineDistance, 2)
+	var keywords = vectorindex.NewBM25Index()
+	var documents = []struct {
+		id     string
+		text   string
+		vector []float32
+	}{
+		{"refused", "connection refused by the server", []float32{1, 0}},
+		{"reset", "connection reset by peer", []float32{0.7, 0.7}},
+		{"code", "error E1234 on startup", []float32{0, 1}},
+	}
+	for _, d := range documents {
+		if err := vectors.Add(d.id, d.vector, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := keywords.Add(d.id, d.text, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var query = []float32{1, 0.1}
+
+	var tests = []struct {
+		name    string
+		options []vectorindex.OptionFunc
+		ids     []string
+	}{
+		// The keyword rank of "code" outweighs its vector rank
+		{"rrf", nil, []string{"code", "refused"}},
+		{"vector weighted", []vectorindex.OptionFunc{vectorindex.WithWeightedFusion(0.9)}, []string{"refused", "reset"}},
+		{"keyword weighted", []vectorindex.OptionFunc{vectorindex.WithWeightedFusion(0.3)}, []string{"code", "refused"}},
+	}
+	for _, test := range tests {
+		var search = vectorindex.NewHybridSearch(vectors, keywords, test.options...)
+		var matches, err = search.Search("E1234", query, 2, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := matchIds(matches); !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%s: %v, want %v", test.name, ids, test.ids)
+		}
+		if matches[0].Score < matches[1].Score {
+			t.Errorf("%s: scores are not sorted", test.name)
+		}
+	}
+
+	// Keywords only by the zero vector weight
+	var search = vectorindex.NewHybridSearch(vectors, keywords, vectorindex.WithWeightedFusion(0))
+	var matches, _ = search.Search("E1234", query, 2, nil)
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"code", "refused"}) || matches[0].Score != 1 || matches[1].Score != 0 {
+		t.Errorf("keyword only weighted: %v, %+v", ids, matches)
+	}
+
+	search = vectorindex.NewHybridSearch(vectors, keywords, vectorindex.WithRRF(10))
+	matches, _ = search.Search("connection", nil, 3, nil)
+	if ids := matchIds(matches); len(ids) != 2 || ids[0] == "code" || ids[1] == "code" {
+		t.Errorf("keywords only: %v", ids)
+	}
+	matches, _ = search.Search("", query, 3, nil)
+	if ids := matchIds(matches); !reflect.DeepEqual(ids, []string{"refused", "reset", "code"}) {
+		t.Errorf("vector only: %v", ids)
+	}
+	if matches[0].Score != 1.0/11 {
+		t.Errorf("rrf score: %v", matches[0].Score)
+	}
+	if _, err := search.Search("", nil, 3, nil); err == nil {
+		t.Error("empty query is accepted")
+	}
+}
@@ -0,0 +1,292 @@
+package test
+
+import (
+	"fmt"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func randomVectors(seed int64, n, dim int) [][]float32 {
+	var r = rand.New(rand.NewSource(seed))
+	var vectors = make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = r.Float32()*2 - 1
+		}
+	}
+	return vectors
+}
+
+// clusteredVectors returns vectors around random centroids, closer to real
+// embeddings than uniform random vectors.
+func clusteredVectors(seed int64, n, dim, clusters int) [][]float32 {
+	var r = rand.New(rand.NewSource(seed))
+	var centroids = randomVectors(seed+1, clusters, dim)
+	var vectors = make([][]float32, n)
+	for i := range vectors {
+		var centroid = centroids[r.Intn(clusters)]
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = centroid[j] + float32(r.NormFloat64())*0.3
+		}
+	}
+	return vectors
+}
+
+// adder is an index or a store.
+type adder interface {
+	Add(id string, vector []float32, metadata vectorindex.Metadata) error
+}
+
+func fill(t testing.TB, index adder, vectors [][]float32) {
+	for i, v := range vectors {
+		var metadata = vectorindex.Metadata{"parity": strconv.Itoa(i % 2)}
+		if err := index.Add(strconv.Itoa(i), v, metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recall returns the fraction of the exact top k found by the approximate
+// index.
+func recall(t testing.TB, exact, approximate vectorindex.Index, queries [][]float32, k int,
+	filter vectorindex.Filter) float64 {
+
+	var found, total int
+	for _, q := range queries {
+		want, err := exact.Search(q, k, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := approximate.Search(q, k, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids = make(map[string]bool)
+		for _, m := range got {
+			ids[m.Id] = true
+		}
+		for _, m := range want {
+			if ids[m.Id] {
+				found++
+			}
+		}
+		total += len(want)
+	}
+	return float64(found) / float64(total)
+}
+
+func Test_FlatIndex_Cosine(t *testing.T) {
+	var index = vectorindex.NewFlatIndex(embedding.CosineDistance, 0)
+	var err error
+
+	err = index.Add("x", []float32{1, 0}, vectorindex.Metadata{"axis": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = index.Add("y", []float32{0, 2}, vectorindex.Metadata{"axis": "y"})
+	_ = index.Add("xy", []float32{1, 1}, nil)
+
+	if index.Dimension() != 2 || index.Len() != 3 {
+		t.Fatalf("unexpected dimension %d, len %d", index.Dimension(), index.Len())
+	}
+	if err = index.Add("z", []float32{1, 2, 3}, nil); err == nil {
+		t.Error("dimension mismatch is accepted")
+	}
+	if err = index.Add("zero", []float32{0, 0}, nil); err == nil {
+		t.Error("zero vector is accepted")
+	}
+
+	matches, err := index.Search([]float32{3, 0.1}, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].Id != "x" || matches[1].Id != "xy" {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	if matches[0].Score < 0.99 || matches[0].Metadata["axis"] != "x" {
+		t.Errorf("unexpected best match: %+v", matches[0])
+	}
+
+	matches, _ = index.Search([]float32{3, 0.1}, 2, vectorindex.MetadataEquals(vectorindex.Metadata{"axis": "y"}))
+	if len(matches) != 1 || matches[0].Id != "y" {
+		t.Errorf("unexpected filtered matches: %+v", matches)
+	}
+
+	// Replace and delete
+	_ = index.Add("x", []float32{-1, 0}, nil)
+	if index.Len() != 3 {
+		t.Errorf("replacing changes the length: %d", index.Len())
+	}
+	if !index.Delete("y") || index.Delete("y") {
+		t.Error("unexpected delete results")
+	}
+	matches, _ = index.Search([]float32{1, 0}, 10, nil)
+	if len(matches) != 2 || matches[0].Id != "xy" || matches[1].Id != "x" {
+		t.Errorf("unexpected matches after update: %+v", matches)
+	}
+
+	vector, _, ok := index.Get("xy")
+	if !ok || vector[0] < 0.707 || vector[0] > 0.708 {
+		t.Errorf("vector is not normalized: %v", vector)
+	}
+}
+
+func Test_FlatIndex_Euclidean(t *testing.T) {
+	var index = vectorindex.NewFlatIndex(embedding.EuclideanDistance, 2)
+	_ = index.Add("a", []float32{0, 0}, nil)
+	_ = index.Add("b", []float32{3, 4}, nil)
+	_ = index.Add("c", []float32{1, 1}, nil)
+
+	matches, err := index.Search([]float32{0, 0}, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches[0].Id != "a" || matches[1].Id != "c" || matches[2].Id != "b" || matches[2].Score != 5 {
+		t.Errorf("unexpected matches: %+v", matches)
+	}
+	if _, err = index.Search([]float32{0, 0, 0}, 1, nil); err == nil {
+		t.Error("dimension mismatch is accepted")
+	}
+}
+
+func Test_HNSWIndex_Recall(t *testing.T) {
+	for _, distanceType := range []embedding.VectorDistanceType{embedding.CosineDistance, embedding.EuclideanDistance} {
+		var vectors = randomVectors(1, 3000, 32)
+		var exact = vectorindex.NewFlatIndex(distanceType, 32)
+		var approximate = vectorindex.NewHNSWIndex(distanceType, 32)
+		fill(t, exact, vectors)
+		fill(t, approximate, vectors)
+
+		var queries = randomVectors(2, 50, 32)
+		if r := recall(t, exact, approximate, queries, 10, nil); r < 0.9 {
+			t.Errorf("%s: recall@10 is %.3f", distanceType, r)
+		}
+		var even = vectorindex.MetadataEquals(vectorindex.Metadata{"parity": "0"})
+		if r := recall(t, exact, approximate, queries, 10, even); r < 0.9 {
+			t.Errorf("%s: filtered recall@10 is %.3f", distanceType, r)
+		}
+	}
+}
+
+func Test_HNSWIndex_Delete(t *testing.T) {
+	var vectors = randomVectors(3, 500, 16)
+	var index = vectorindex.NewHNSWIndex(embedding.CosineDistance, 0, vectorindex.WithM(8))
+	fill(t, index, vectors)
+
+	// The vector itself is the nearest until it is deleted
+	matches, _ := index.Search(vectors[42], 1, nil)
+	if len(matches) != 1 || matches[0].Id != "42" {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	if !index.Delete("42") || index.Len() != 499 {
+		t.Fatal("unexpected delete")
+	}
+	matches, _ = index.Search(vectors[42], 5, nil)
+	for _, m := range matches {
+		if m.Id == "42" {
+			t.Error("deleted vector is returned")
+		}
+	}
+	if _, _, ok := index.Get("42"); ok {
+		t.Error("deleted vector is found")
+	}
+
+	// Replacing moves the id to the new vector
+	_ = index.Add("7", vectors[100], nil)
+	matches, _ = index.Search(vectors[100], 2, nil)
+	if len(matches) != 2 || matches[0].Score < 0.999 || matches[1].Score < 0.999 {
+		t.Errorf("unexpected matches of replaced vector: %+v", matches)
+	}
+	matches, _ = index.Search(vectors[7], 3, nil)
+	for _, m := range matches {
+		if m.Id == "7" && m.Score > 0.999 {
+			t.Error("replaced vector is returned")
+		}
+	}
+
+	if err := index.Add("", vectors[0], nil); err == nil {
+		t.Error("empty id is accepted")
+	}
+}
+
+func Test_HNSWIndex_Compact(t *testing.T) {
+	var vectors = randomVectors(5, 400, 16)
+	var exact = vectorindex.NewFlatIndex(embedding.CosineDistance, 16)
+	var index = vectorindex.NewHNSWIndex(embedding.CosineDistance, 16)
+	fill(t, exact, vectors[:200])
+	fill(t, index, vectors[:200])
+
+	// Replacing the vectors again and again keeps at most as many deleted
+	// vectors as live ones
+	for round := 0; round < 5; round++ {
+		fill(t, index, vectors[:200])
+		if index.Len() != 200 || index.Deleted() > index.Len() {
+			t.Fatalf("round %d: %d vectors, %d deleted", round, index.Len(), index.Deleted())
+		}
+	}
+	for i := 0; i < 50; i++ {
+		index.Delete(strconv.Itoa(i))
+		exact.Delete(strconv.Itoa(i))
+	}
+	index.Compact()
+	if index.Len() != 150 || index.Deleted() != 0 {
+		t.Fatalf("compacted: %d vectors, %d deleted", index.Len(), index.Deleted())
+	}
+	var queries = vectors[200:250]
+	if r := recall(t, exact, index, queries, 10, nil); r < 0.9 {
+		t.Errorf("recall@10 after compaction is %.3f", r)
+	}
+	var even = vectorindex.MetadataEquals(vectorindex.Metadata{"parity": "0"})
+	if r := recall(t, exact, index, queries, 10, even); r < 0.9 {
+		t.Errorf("filtered recall@10 after compaction is %.3f", r)
+	}
+	if _, metadata, ok := index.Get("60"); !ok || metadata["parity"] != "0" {
+		t.Errorf("unexpected vector after compaction: %v, %v", metadata, ok)
+	}
+}
+
+func benchmarkRecall(b *testing.B, n, dim int, options ...vectorindex.OptionFunc) {
+	var vectors = clusteredVectors(4, n+100, dim, 100)
+	var queries = vectors[n:]
+	vectors = vectors[:n]
+	var exact = vectorindex.NewFlatIndex(embedding.CosineDistance, dim)
+	var approximate = vectorindex.NewHNSWIndex(embedding.CosineDistance, dim, options...)
+	fill(b, exact, vectors)
+	fill(b, approximate, vectors)
+	var r = recall(b, exact, approximate, queries, 10, nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = approximate.Search(queries[i%len(queries)], 10, nil)
+	}
+	b.ReportMetric(r, "recall@10")
+}
+
+func Benchmark_FlatIndex_Search(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			var index = vectorindex.NewFlatIndex(embedding.CosineDistance, 128)
+			fill(b, index, randomVectors(4, n, 128))
+			var queries = randomVectors(5, 100, 128)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = index.Search(queries[i%len(queries)], 10, nil)
+			}
+		})
+	}
+}
+
+func Benchmark_HNSWIndex_Recall(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		for _, ef := range []int{16, 64, 256} {
+			b.Run(fmt.Sprintf("n=%d/ef=%d", n, ef), func(b *testing.B) {
+				benchmarkRecall(b, n, 128, vectorindex.WithEfSearch(ef))
+			})
+		}
+	}
+}
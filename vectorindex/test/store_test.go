@@ -0,0 +1,204 @@
+package test
+
+import (
+	"errors"
+	"github.com/Pooh-Mucho/go-aigc/embedding"
+	"github.com/Pooh-Mucho/go-aigc/vectorindex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const storeModelId = "text-embedding-3-small"
+
+func openStore(t *testing.T, dir string, options ...vectorindex.OptionFunc) *vectorindex.Store {
+	var store, err = vectorindex.OpenStore(dir, storeModelId, vectorindex.NewFlatIndex(embedding.CosineDistance, 0), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	var files, err = filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func Test_Store_Reopen(t *testing.T) {
+	var dir = t.TempDir()
+	var vectors = randomVectors(1, 100, 8)
+
+	var store = openStore(t, dir)
+	fill(t, store, vectors)
+	_ = store.Add("0", vectors[99], vectorindex.Metadata{"replaced": "yes"})
+	if ok, err := store.Delete("1"); !ok || err != nil {
+		t.Fatalf("unexpected delete: %v %v", ok, err)
+	}
+	if ok, _ := store.Delete("missing"); ok {
+		t.Error("missing id is deleted")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openStore(t, dir)
+	defer store.Close()
+	if store.Len() != 99 {
+		t.Fatalf("unexpected length after reopen: %d", store.Len())
+	}
+	if _, _, ok := store.Index().Get("1"); ok {
+		t.Error("deleted vector is loaded")
+	}
+	_, metadata, ok := store.Index().Get("0")
+	if !ok || metadata["replaced"] != "yes" {
+		t.Errorf("unexpected metadata of replaced vector: %v", metadata)
+	}
+	matches, err := store.Search(vectors[50], 1, nil)
+	if err != nil || len(matches) != 1 || matches[0].Id != "50" || matches[0].Metadata["parity"] != "0" {
+		t.Errorf("unexpected matches: %+v %v", matches, err)
+	}
+	if err = store.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Store_ModelMismatch(t *testing.T) {
+	var dir = t.TempDir()
+	var store = openStore(t, dir)
+	_ = store.Add("a", []float32{1, 2, 3}, nil)
+	_ = store.Close()
+
+	var _, err = vectorindex.OpenStore(dir, "text-embedding-3-large", vectorindex.NewFlatIndex(embedding.CosineDistance, 0))
+	if !errors.Is(err, vectorindex.ErrModelMismatch) {
+		t.Errorf("different model is accepted: %v", err)
+	}
+	_, err = vectorindex.OpenStore(dir, storeModelId, vectorindex.NewFlatIndex(embedding.EuclideanDistance, 0))
+	if !errors.Is(err, vectorindex.ErrModelMismatch) {
+		t.Errorf("different distance type is accepted: %v", err)
+	}
+	_, err = vectorindex.OpenStore(dir, storeModelId, vectorindex.NewFlatIndex(embedding.CosineDistance, 4))
+	if !errors.Is(err, vectorindex.ErrModelMismatch) {
+		t.Errorf("different dimension is accepted: %v", err)
+	}
+
+	store = openStore(t, dir)
+	defer store.Close()
+	if err = store.Add("b", []float32{1, 2}, nil); err == nil {
+		t.Error("different dimension is written")
+	}
+}
+
+func Test_Store_Corruption(t *testing.T) {
+	var dir = t.TempDir()
+	var store = openStore(t, dir)
+	fill(t, store, randomVectors(2, 10, 4))
+	_ = store.Close()
+
+	var path = segmentFiles(t, dir)[0]
+	var data, _ = os.ReadFile(path)
+
+	// An incomplete record at the end is dropped
+	_ = os.WriteFile(path, append(data, 40, 0, 0, 0, 1, 2), 0o644)
+	store = openStore(t, dir)
+	if store.Len() != 10 {
+		t.Errorf("unexpected length: %d", store.Len())
+	}
+	_ = store.Add("10", []float32{1, 1, 1, 1}, nil)
+	_ = store.Close()
+	store = openStore(t, dir)
+	if store.Len() != 11 {
+		t.Errorf("unexpected length after recovery: %d", store.Len())
+	}
+	_ = store.Close()
+
+	// A flipped byte fails the checksum
+	data, _ = os.ReadFile(path)
+	data[len(data)/2] ^= 0xff
+	_ = os.WriteFile(path, data, 0o644)
+	var _, err = vectorindex.OpenStore(dir, storeModelId, vectorindex.NewFlatIndex(embedding.CosineDistance, 0))
+	if !errors.Is(err, vectorindex.ErrCorrupted) {
+		t.Errorf("corruption is not detected: %v", err)
+	}
+}
+
+func Test_Store_Compact(t *testing.T) {
+	var dir = t.TempDir()
+	var vectors = randomVectors(3, 200, 16)
+	var store = openStore(t, dir, vectorindex.WithSegmentSize(4096))
+
+	// Every vector is written 3 times, then half of them are deleted
+	for round := 0; round < 3; round++ {
+		fill(t, store, vectors)
+	}
+	for i := 0; i < len(vectors); i += 2 {
+		_, _ = store.Delete(strconv.Itoa(i))
+	}
+	var before = store.Stats()
+	if before.Segments < 10 || before.Records != 700 || before.Vectors != 100 {
+		t.Fatalf("unexpected stats before compaction: %+v", before)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	var after = store.Stats()
+	if after.Records != 100 || after.Vectors != 100 || after.Bytes*5 > before.Bytes {
+		t.Fatalf("unexpected stats after compaction: %+v", after)
+	}
+	if len(segmentFiles(t, dir)) != after.Segments {
+		t.Errorf("old segments are not removed: %d files, %d segments", len(segmentFiles(t, dir)), after.Segments)
+	}
+
+	// Compacted stores are still appendable and loadable
+	_ = store.Add("0", vectors[0], nil)
+	_ = store.Close()
+	store = openStore(t, dir)
+	defer store.Close()
+	if store.Len() != 101 {
+		t.Errorf("unexpected length after compaction: %d", store.Len())
+	}
+	matches, _ := store.Search(vectors[7], 1, nil)
+	if len(matches) != 1 || matches[0].Id != "7" {
+		t.Errorf("unexpected matches: %+v", matches)
+	}
+}
+
+func Test_Store_Snapshot(t *testing.T) {
+	var dir, snapshot, restored = t.TempDir(), t.TempDir(), t.TempDir()
+	var vectors = randomVectors(4, 50, 8)
+	var store = openStore(t, dir, vectorindex.WithSegmentSize(1024))
+	fill(t, store, vectors[:40])
+
+	if err := store.Snapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Snapshot(snapshot); err == nil {
+		t.Error("snapshot overwrites a store")
+	}
+
+	// Later writes are not in the snapshot
+	fill(t, store, vectors)
+	_ = store.Compact()
+	_ = store.Close()
+
+	if err := vectorindex.RestoreSnapshot(snapshot, restored); err != nil {
+		t.Fatal(err)
+	}
+	var store2 = openStore(t, restored)
+	defer store2.Close()
+	if store2.Len() != 40 {
+		t.Errorf("unexpected length of restored store: %d", store2.Len())
+	}
+	_ = store2.Add("extra", vectors[49], nil)
+
+	// Appending to the restored store does not change the snapshot
+	var store3 = openStore(t, snapshot)
+	defer store3.Close()
+	if store3.Len() != 40 {
+		t.Errorf("snapshot is changed: %d", store3.Len())
+	}
+}
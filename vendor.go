@@ -18,6 +18,9 @@ var Vendors = struct {
 	HuggingFace VendorId
 	Ollama      VendorId
 	PoohMucho   VendorId
+	Cohere      VendorId
+	Jina        VendorId
+	Voyage      VendorId
 }{
 	OpenAI:      "OpenAI",
 	Anthropic:   "Anthropic",
@@ -34,4 +37,26 @@ var Vendors = struct {
 	HuggingFace: "HuggingFace",
 	Ollama:      "Ollama",
 	PoohMucho:   "PoohMucho",
+	Cohere:      "Cohere",
+	Jina:        "Jina",
+	Voyage:      "Voyage",
+}
+
+// Default API endpoints of the hosted vendors shared by several packages,
+// without the paths of the APIs
+var vendorEndpoints = map[VendorId]string{
+	Vendors.Cohere: "https://api.cohere.com",
+	Vendors.Jina:   "https://api.jina.ai",
+	Vendors.Voyage: "https://api.voyageai.com",
+}
+
+// VendorEndpoint returns the default url of an API of a hosted vendor, e.g.
+// VendorEndpoint(Vendors.Jina, "/v1/rerank"). Empty if the vendor has no
+// default endpoint.
+func VendorEndpoint(vendor VendorId, path string) string {
+	var endpoint, ok = vendorEndpoints[vendor]
+	if !ok {
+		return ""
+	}
+	return endpoint + path
 }
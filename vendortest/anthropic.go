@@ -0,0 +1,250 @@
+package vendortest
+
+// See: https://docs.anthropic.com/en/api/messages
+// See: https://docs.anthropic.com/en/api/messages-streaming
+
+import (
+	"fmt"
+	"net/http"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+type anthropicEncoder struct{}
+
+type anthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      *string         `json:"text,omitempty"`
+	Thinking  *string         `json:"thinking,omitempty"`
+	Signature *string         `json:"signature,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	Id        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     *map[string]any `json:"input,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+type anthropicResponse struct {
+	Id           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []anthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        anthropicUsage          `json:"usage"`
+}
+
+func (e anthropicEncoder) path() string {
+	return "/v1/messages"
+}
+
+func (e anthropicEncoder) accepts(path string) bool {
+	return path == e.path()
+}
+
+func (e anthropicEncoder) authorized(request *http.Request, apiKey string) bool {
+	var key = request.Header.Get("x-api-key")
+	if apiKey == "" {
+		return key != ""
+	}
+	return key == apiKey
+}
+
+func (e anthropicEncoder) validate(request *http.Request) (int, string) {
+	if request.Header.Get("anthropic-version") == "" {
+		return http.StatusBadRequest, "anthropic-version: header is required"
+	}
+	return 0, ""
+}
+
+func (e anthropicEncoder) stream(body map[string]any) bool {
+	var stream, _ = body["stream"].(bool)
+	return stream
+}
+
+func (e anthropicEncoder) stopReason(c *replyContext) string {
+	switch c.finish {
+	case finishLength:
+		return "max_tokens"
+	case finishToolCalls:
+		return "tool_use"
+	default:
+		return "end_turn"
+	}
+}
+
+// reasoning returns a thinking or redacted_thinking block.
+func (e anthropicEncoder) reasoning(block chat.ContentBlock) anthropicContentBlock {
+	if block.RedactedReasoning != "" {
+		return anthropicContentBlock{Type: "redacted_thinking", Data: block.RedactedReasoning}
+	}
+	var thinking, signature = block.Text, block.Signature
+	return anthropicContentBlock{Type: "thinking", Thinking: &thinking, Signature: &signature}
+}
+
+func (e anthropicEncoder) content(c *replyContext) []anthropicContentBlock {
+	var blocks = []anthropicContentBlock{}
+	for _, block := range c.reasoning {
+		blocks = append(blocks, e.reasoning(block))
+	}
+	if c.text != "" {
+		var text = c.text
+		blocks = append(blocks, anthropicContentBlock{Type: "text", Text: &text})
+	}
+	for _, call := range c.calls {
+		var input = call.Arguments
+		blocks = append(blocks, anthropicContentBlock{Type: "tool_use", Id: call.Id, Name: call.Name, Input: &input})
+	}
+	return blocks
+}
+
+func (e anthropicEncoder) message(c *replyContext) anthropicResponse {
+	return anthropicResponse{
+		Id:    fmt.Sprintf("msg_vendortest%06d", c.sequence),
+		Type:  "message",
+		Role:  "assistant",
+		Model: c.model,
+		Usage: anthropicUsage{
+			InputTokens:              c.usage.InputTokens - c.usage.CachedInputTokens - c.usage.CacheCreationInputTokens,
+			OutputTokens:             c.usage.OutputTokens,
+			CacheCreationInputTokens: c.usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     c.usage.CachedInputTokens,
+		},
+	}
+}
+
+func (e anthropicEncoder) writeResponse(w http.ResponseWriter, c *replyContext) {
+	var message = e.message(c)
+	var stopReason = e.stopReason(c)
+	message.Content = e.content(c)
+	message.StopReason = &stopReason
+	writeJson(w, http.StatusOK, message)
+}
+
+func (e anthropicEncoder) writeStream(w http.ResponseWriter, c *replyContext) {
+	var events = newEventWriter(w, c, "text/event-stream")
+
+	var start = e.message(c)
+	start.Content = []anthropicContentBlock{}
+	start.Usage.OutputTokens = 1
+	events.event("message_start", map[string]any{"type": "message_start", "message": start})
+
+	var index = 0
+	for _, block := range c.reasoning {
+		var z = e.reasoning(block)
+		if z.Type == "thinking" {
+			var empty = ""
+			events.event("content_block_start", map[string]any{
+				"type":          "content_block_start",
+				"index":         index,
+				"content_block": anthropicContentBlock{Type: "thinking", Thinking: &empty, Signature: &empty},
+			})
+			events.event("content_block_delta", map[string]any{
+				"type":  "content_block_delta",
+				"index": index,
+				"delta": map[string]any{"type": "thinking_delta", "thinking": block.Text},
+			})
+			events.event("content_block_delta", map[string]any{
+				"type":  "content_block_delta",
+				"index": index,
+				"delta": map[string]any{"type": "signature_delta", "signature": block.Signature},
+			})
+		} else {
+			events.event("content_block_start", map[string]any{
+				"type":          "content_block_start",
+				"index":         index,
+				"content_block": z,
+			})
+		}
+		events.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": index})
+		events.pause()
+		index++
+	}
+
+	if len(c.chunks) > 0 {
+		var empty = ""
+		events.event("content_block_start", map[string]any{
+			"type":          "content_block_start",
+			"index":         index,
+			"content_block": anthropicContentBlock{Type: "text", Text: &empty},
+		})
+		for _, text := range c.chunks {
+			events.pause()
+			events.event("content_block_delta", map[string]any{
+				"type":  "content_block_delta",
+				"index": index,
+				"delta": map[string]any{"type": "text_delta", "text": text},
+			})
+		}
+		events.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": index})
+		index++
+	}
+
+	for _, call := range c.calls {
+		var input = map[string]any{}
+		events.pause()
+		events.event("content_block_start", map[string]any{
+			"type":          "content_block_start",
+			"index":         index,
+			"content_block": anthropicContentBlock{Type: "tool_use", Id: call.Id, Name: call.Name, Input: &input},
+		})
+		events.event("content_block_delta", map[string]any{
+			"type":  "content_block_delta",
+			"index": index,
+			"delta": map[string]any{"type": "input_json_delta", "partial_json": argumentsJson(call.Arguments)},
+		})
+		events.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": index})
+		index++
+	}
+
+	events.event("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": e.stopReason(c), "stop_sequence": nil},
+		"usage": map[string]any{"output_tokens": c.usage.OutputTokens},
+	})
+	events.event("message_stop", map[string]any{"type": "message_stop"})
+}
+
+// writeContentFilter writes the error returned when the output is blocked.
+func (e anthropicEncoder) writeContentFilter(w http.ResponseWriter, c *replyContext) {
+	e.writeError(w, http.StatusBadRequest, "Output blocked by content filtering policy")
+}
+
+func (e anthropicEncoder) writeError(w http.ResponseWriter, statusCode int, message string) {
+	var errorType string
+	switch statusCode {
+	case http.StatusBadRequest:
+		errorType = "invalid_request_error"
+	case http.StatusUnauthorized:
+		errorType = "authentication_error"
+	case http.StatusForbidden:
+		errorType = "permission_error"
+	case http.StatusNotFound:
+		errorType = "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		errorType = "request_too_large"
+	case http.StatusTooManyRequests:
+		errorType = "rate_limit_error"
+	case 529:
+		errorType = "overloaded_error"
+	default:
+		errorType = "api_error"
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	writeJson(w, statusCode, map[string]any{
+		"type":  "error",
+		"error": map[string]any{"type": errorType, "message": message},
+	})
+}
@@ -0,0 +1,117 @@
+package vendortest
+
+// See: https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_InvokeModel.html
+
+import (
+	"net/http"
+	"strings"
+)
+
+// bedrockEncoder replies the InvokeModel API, in the Anthropic messages format
+// for Claude models and in the generation format for Llama models. Bedrock
+// models do not support endpoint override, use Server.Transport.
+type bedrockEncoder struct{}
+
+type bedrockLlamaResponse struct {
+	Generation           string `json:"generation"`
+	PromptTokenCount     int    `json:"prompt_token_count"`
+	GenerationTokenCount int    `json:"generation_token_count"`
+	StopReason           string `json:"stop_reason"`
+}
+
+func (e bedrockEncoder) path() string {
+	return ""
+}
+
+// accepts paths of InvokeModel, e.g.
+// "/model/anthropic.claude-3-5-sonnet-20240620-v1:0/invoke"
+func (e bedrockEncoder) accepts(path string) bool {
+	return strings.HasPrefix(path, "/model/") && strings.HasSuffix(path, "/invoke")
+}
+
+// authorized accepts any signature of Signature Version 4, the api key is not
+// used.
+func (e bedrockEncoder) authorized(request *http.Request, apiKey string) bool {
+	return strings.HasPrefix(request.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+}
+
+func (e bedrockEncoder) validate(request *http.Request) (int, string) {
+	return 0, ""
+}
+
+// stream is always false, InvokeModelWithResponseStream is not supported.
+func (e bedrockEncoder) stream(body map[string]any) bool {
+	return false
+}
+
+func (e bedrockEncoder) modelId(c *replyContext) string {
+	return strings.TrimSuffix(strings.TrimPrefix(c.request.Path, "/model/"), "/invoke")
+}
+
+// generation returns the text of a Llama model, tool calls are json objects
+// separated by semicolons.
+func (e bedrockEncoder) generation(c *replyContext) string {
+	if len(c.calls) == 0 {
+		return c.text
+	}
+	var calls = make([]string, len(c.calls))
+	for i, call := range c.calls {
+		calls[i] = `{"name": "` + call.Name + `", "parameters": ` + argumentsJson(call.Arguments) + `}`
+	}
+	return strings.Join(calls, "; ")
+}
+
+func (e bedrockEncoder) writeResponse(w http.ResponseWriter, c *replyContext) {
+	var modelId = e.modelId(c)
+	if strings.Contains(modelId, "anthropic.") {
+		c.model = modelId
+		anthropicEncoder{}.writeResponse(w, c)
+		return
+	}
+
+	var response = bedrockLlamaResponse{
+		Generation:           e.generation(c),
+		PromptTokenCount:     c.usage.InputTokens,
+		GenerationTokenCount: c.usage.OutputTokens,
+		StopReason:           "stop",
+	}
+	if c.finish == finishLength {
+		response.StopReason = "length"
+	}
+	writeJson(w, http.StatusOK, response)
+}
+
+func (e bedrockEncoder) writeStream(w http.ResponseWriter, c *replyContext) {
+	e.writeError(w, http.StatusBadRequest, "streaming is not supported")
+}
+
+// writeContentFilter writes the error returned when a guardrail blocks the
+// output.
+func (e bedrockEncoder) writeContentFilter(w http.ResponseWriter, c *replyContext) {
+	e.writeError(w, http.StatusBadRequest, "Output blocked by content filtering policy")
+}
+
+// writeError writes the error type in the X-Amzn-ErrorType header, which is
+// read by the AWS SDK.
+func (e bedrockEncoder) writeError(w http.ResponseWriter, statusCode int, message string) {
+	var errorType string
+	switch statusCode {
+	case http.StatusBadRequest:
+		errorType = "ValidationException"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		errorType = "AccessDeniedException"
+	case http.StatusNotFound:
+		errorType = "ResourceNotFoundException"
+	case http.StatusTooManyRequests:
+		errorType = "ThrottlingException"
+	case http.StatusServiceUnavailable:
+		errorType = "ServiceUnavailableException"
+	default:
+		errorType = "InternalServerException"
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	w.Header().Set("X-Amzn-ErrorType", errorType)
+	writeJson(w, statusCode, map[string]any{"message": message})
+}
@@ -0,0 +1,146 @@
+package vendortest
+
+// See: https://github.com/ollama/ollama/blob/main/docs/api.md
+
+import (
+	"net/http"
+	"time"
+)
+
+type ollamaEncoder struct{}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model              string        `json:"model"`
+	CreatedAt          string        `json:"created_at"`
+	Message            ollamaMessage `json:"message"`
+	DoneReason         string        `json:"done_reason,omitempty"`
+	Done               bool          `json:"done"`
+	TotalDuration      int64         `json:"total_duration,omitempty"`
+	LoadDuration       int64         `json:"load_duration,omitempty"`
+	PromptEvalCount    int           `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64         `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int           `json:"eval_count,omitempty"`
+	EvalDuration       int64         `json:"eval_duration,omitempty"`
+}
+
+func (e ollamaEncoder) path() string {
+	return "/api/chat"
+}
+
+func (e ollamaEncoder) accepts(path string) bool {
+	return path == e.path()
+}
+
+// authorized accepts requests without api key, Ollama has no authentication
+// unless it is behind a proxy.
+func (e ollamaEncoder) authorized(request *http.Request, apiKey string) bool {
+	return apiKey == "" || bearerToken(request) == apiKey
+}
+
+func (e ollamaEncoder) validate(request *http.Request) (int, string) {
+	return 0, ""
+}
+
+// stream is true by default for Ollama.
+func (e ollamaEncoder) stream(body map[string]any) bool {
+	var stream, ok = body["stream"].(bool)
+	return !ok || stream
+}
+
+func (e ollamaEncoder) doneReason(c *replyContext) string {
+	if c.finish == finishLength {
+		return "length"
+	}
+	return "stop"
+}
+
+func (e ollamaEncoder) toolCalls(c *replyContext) []ollamaToolCall {
+	var calls []ollamaToolCall
+	for _, call := range c.calls {
+		var z ollamaToolCall
+		z.Function.Name = call.Name
+		z.Function.Arguments = call.Arguments
+		calls = append(calls, z)
+	}
+	return calls
+}
+
+func (e ollamaEncoder) final(c *replyContext) ollamaResponse {
+	var elapsed = time.Since(c.created).Nanoseconds()
+	return ollamaResponse{
+		Model:              c.model,
+		CreatedAt:          time.Now().UTC().Format(time.RFC3339Nano),
+		Message:            ollamaMessage{Role: "assistant"},
+		DoneReason:         e.doneReason(c),
+		Done:               true,
+		TotalDuration:      elapsed + 1,
+		PromptEvalCount:    c.usage.InputTokens,
+		PromptEvalDuration: elapsed/2 + 1,
+		EvalCount:          c.usage.OutputTokens,
+		EvalDuration:       elapsed/2 + 1,
+	}
+}
+
+func (e ollamaEncoder) writeResponse(w http.ResponseWriter, c *replyContext) {
+	var response = e.final(c)
+	response.Message.Content = c.text
+	response.Message.Thinking = c.reasoningText()
+	response.Message.ToolCalls = e.toolCalls(c)
+	writeJson(w, http.StatusOK, response)
+}
+
+// writeStream writes newline delimited json, Ollama sends tool calls in a
+// single chunk.
+func (e ollamaEncoder) writeStream(w http.ResponseWriter, c *replyContext) {
+	var events = newEventWriter(w, c, "application/x-ndjson")
+	var chunk = func(message ollamaMessage) ollamaResponse {
+		return ollamaResponse{
+			Model:     c.model,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+			Message:   message,
+		}
+	}
+
+	if reasoning := c.reasoningText(); reasoning != "" {
+		events.line(chunk(ollamaMessage{Role: "assistant", Thinking: reasoning}))
+		events.pause()
+	}
+	for i, text := range c.chunks {
+		if i > 0 {
+			events.pause()
+		}
+		events.line(chunk(ollamaMessage{Role: "assistant", Content: text}))
+	}
+	if len(c.calls) > 0 {
+		events.pause()
+		events.line(chunk(ollamaMessage{Role: "assistant", ToolCalls: e.toolCalls(c)}))
+	}
+	events.line(e.final(c))
+}
+
+// writeContentFilter writes an empty completion, Ollama has no content
+// filter.
+func (e ollamaEncoder) writeContentFilter(w http.ResponseWriter, c *replyContext) {
+	writeJson(w, http.StatusOK, e.final(c))
+}
+
+func (e ollamaEncoder) writeError(w http.ResponseWriter, statusCode int, message string) {
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	writeJson(w, statusCode, map[string]any{"error": message})
+}
@@ -0,0 +1,302 @@
+package vendortest
+
+// See: https://platform.openai.com/docs/api-reference/chat
+// See: https://help.aliyun.com/zh/model-studio/developer-reference/compatibility-of-openai-with-dashscope
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// openaiEncoder encodes OpenAI chat completions, also DashScope OpenAI
+// compatible mode.
+type openaiEncoder struct {
+	dashScope bool
+}
+
+type openaiToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	Id       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openaiAudio struct {
+	Id         string `json:"id"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Data       string `json:"data"`
+	Transcript string `json:"transcript"`
+}
+
+type openaiMessage struct {
+	Role             string           `json:"role,omitempty"`
+	Content          *string          `json:"content"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"`
+	ToolCalls        []openaiToolCall `json:"tool_calls,omitempty"`
+	Audio            *openaiAudio     `json:"audio,omitempty"`
+}
+
+type openaiDelta struct {
+	Role             string           `json:"role,omitempty"`
+	Content          *string          `json:"content,omitempty"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"`
+	ToolCalls        []openaiToolCall `json:"tool_calls,omitempty"`
+}
+
+type openaiFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"`
+}
+
+type openaiChoice struct {
+	Index                int                           `json:"index"`
+	Message              *openaiMessage                `json:"message,omitempty"`
+	Delta                *openaiDelta                  `json:"delta,omitempty"`
+	FinishReason         *string                       `json:"finish_reason"`
+	ContentFilterResults map[string]openaiFilterResult `json:"content_filter_results,omitempty"`
+}
+
+type openaiUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+type openaiResponse struct {
+	Id      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openaiChoice `json:"choices"`
+	Usage   *openaiUsage   `json:"usage,omitempty"`
+}
+
+func (e openaiEncoder) path() string {
+	if e.dashScope {
+		return "/compatible-mode/v1/chat/completions"
+	}
+	return "/v1/chat/completions"
+}
+
+// accepts also Azure OpenAI paths, e.g.
+// "/openai/deployments/gpt-4o/chat/completions"
+func (e openaiEncoder) accepts(path string) bool {
+	return path == e.path() || (!e.dashScope && strings.HasSuffix(path, "/chat/completions"))
+}
+
+func (e openaiEncoder) authorized(request *http.Request, apiKey string) bool {
+	var token = bearerToken(request)
+	if token == "" {
+		// Azure OpenAI
+		token = request.Header.Get("api-key")
+	}
+	if apiKey == "" {
+		return token != ""
+	}
+	return token == apiKey
+}
+
+func (e openaiEncoder) validate(request *http.Request) (int, string) {
+	return 0, ""
+}
+
+func (e openaiEncoder) stream(body map[string]any) bool {
+	var stream, _ = body["stream"].(bool)
+	return stream
+}
+
+func (e openaiEncoder) finishReason(c *replyContext) string {
+	switch c.finish {
+	case finishLength:
+		return "length"
+	case finishToolCalls:
+		return "tool_calls"
+	case finishContentFilter:
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+func (e openaiEncoder) usage(c *replyContext) *openaiUsage {
+	var u = &openaiUsage{
+		PromptTokens:     c.usage.InputTokens,
+		CompletionTokens: c.usage.OutputTokens,
+		TotalTokens:      c.usage.InputTokens + c.usage.OutputTokens,
+	}
+	u.PromptTokensDetails.CachedTokens = c.usage.CachedInputTokens
+	u.CompletionTokensDetails.ReasoningTokens = c.usage.ReasoningTokens
+	return u
+}
+
+func (e openaiEncoder) toolCalls(c *replyContext, indexed bool) []openaiToolCall {
+	var calls []openaiToolCall
+	for i, call := range c.calls {
+		var z = openaiToolCall{Id: call.Id, Type: "function"}
+		if indexed {
+			var index = i
+			z.Index = &index
+		}
+		z.Function.Name = call.Name
+		z.Function.Arguments = argumentsJson(call.Arguments)
+		calls = append(calls, z)
+	}
+	return calls
+}
+
+func (e openaiEncoder) id(c *replyContext) string {
+	return fmt.Sprintf("chatcmpl-vendortest%06d", c.sequence)
+}
+
+func (e openaiEncoder) writeResponse(w http.ResponseWriter, c *replyContext) {
+	var message = &openaiMessage{
+		Role:             "assistant",
+		ReasoningContent: c.reasoningText(),
+		ToolCalls:        e.toolCalls(c, false),
+	}
+	if c.text != "" || (len(c.calls) == 0 && c.audio == nil) {
+		var text = c.text
+		message.Content = &text
+	}
+	if c.audio != nil {
+		message.Audio = &openaiAudio{
+			Id:         c.audio.AudioId,
+			ExpiresAt:  c.created.Add(time.Hour).Unix(),
+			Data:       base64.StdEncoding.EncodeToString(c.audio.Data),
+			Transcript: c.audio.Transcript,
+		}
+	}
+	var finishReason = e.finishReason(c)
+
+	writeJson(w, http.StatusOK, openaiResponse{
+		Id:      e.id(c),
+		Object:  "chat.completion",
+		Created: c.created.Unix(),
+		Model:   c.model,
+		Choices: []openaiChoice{{Message: message, FinishReason: &finishReason}},
+		Usage:   e.usage(c),
+	})
+}
+
+func (e openaiEncoder) writeStream(w http.ResponseWriter, c *replyContext) {
+	var events = newEventWriter(w, c, "text/event-stream")
+	var chunk = func(delta *openaiDelta, finishReason *string) openaiResponse {
+		return openaiResponse{
+			Id:      e.id(c),
+			Object:  "chat.completion.chunk",
+			Created: c.created.Unix(),
+			Model:   c.model,
+			Choices: []openaiChoice{{Delta: delta, FinishReason: finishReason}},
+		}
+	}
+
+	var empty = ""
+	events.event("", chunk(&openaiDelta{Role: "assistant", Content: &empty}, nil))
+
+	if reasoning := c.reasoningText(); reasoning != "" {
+		events.pause()
+		events.event("", chunk(&openaiDelta{ReasoningContent: reasoning}, nil))
+	}
+
+	for _, text := range c.chunks {
+		events.pause()
+		var content = text
+		events.event("", chunk(&openaiDelta{Content: &content}, nil))
+	}
+
+	// Each tool call in a chunk, arguments are not split
+	for _, call := range e.toolCalls(c, true) {
+		events.pause()
+		events.event("", chunk(&openaiDelta{ToolCalls: []openaiToolCall{call}}, nil))
+	}
+
+	var finishReason = e.finishReason(c)
+	events.event("", chunk(&openaiDelta{}, &finishReason))
+
+	if options, ok := c.request.Json["stream_options"].(map[string]any); ok && options["include_usage"] == true {
+		var usage = chunk(nil, nil)
+		usage.Choices = []openaiChoice{}
+		usage.Usage = e.usage(c)
+		events.event("", usage)
+	}
+
+	events.event("", "[DONE]")
+}
+
+func (e openaiEncoder) writeContentFilter(w http.ResponseWriter, c *replyContext) {
+	if e.dashScope {
+		writeJson(w, http.StatusBadRequest, map[string]any{
+			"error": map[string]any{
+				"code":    "data_inspection_failed",
+				"param":   nil,
+				"message": "Output data may contain inappropriate content.",
+				"type":    "data_inspection_failed",
+			},
+		})
+		return
+	}
+
+	// Azure OpenAI content filter, the completion is omitted
+	var finishReason = "content_filter"
+	var filtered = openaiFilterResult{Filtered: false, Severity: "safe"}
+	writeJson(w, http.StatusOK, openaiResponse{
+		Id:      e.id(c),
+		Object:  "chat.completion",
+		Created: c.created.Unix(),
+		Model:   c.model,
+		Choices: []openaiChoice{{
+			Message:      &openaiMessage{Role: "assistant"},
+			FinishReason: &finishReason,
+			ContentFilterResults: map[string]openaiFilterResult{
+				"hate":      filtered,
+				"self_harm": filtered,
+				"sexual":    filtered,
+				"violence":  {Filtered: true, Severity: "high"},
+			},
+		}},
+		Usage: e.usage(c),
+	})
+}
+
+func (e openaiEncoder) writeError(w http.ResponseWriter, statusCode int, message string) {
+	var errorType, code string
+	switch statusCode {
+	case http.StatusBadRequest:
+		errorType, code = "invalid_request_error", "invalid_request"
+	case http.StatusUnauthorized:
+		errorType, code = "invalid_request_error", "invalid_api_key"
+	case http.StatusNotFound:
+		errorType, code = "invalid_request_error", "not_found"
+	case http.StatusTooManyRequests:
+		errorType, code = "requests", "rate_limit_exceeded"
+	default:
+		errorType, code = "server_error", "server_error"
+	}
+	if e.dashScope && statusCode == http.StatusTooManyRequests {
+		errorType, code = "limit_requests", "limit_requests"
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	writeJson(w, statusCode, map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    errorType,
+			"param":   nil,
+			"code":    code,
+		},
+	})
+}
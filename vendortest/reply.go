@@ -0,0 +1,174 @@
+package vendortest
+
+import (
+	"net/http"
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+// Reply is a scripted reply of the server. The Response is translated to the
+// wire format of the server protocol, or an error in the vendor format is
+// returned if StatusCode is not 200.
+type Reply struct {
+	// Zero means 200
+	StatusCode int
+	// Error message if StatusCode is not 200
+	ErrorMessage string
+	// Extra response headers, e.g. Retry-After
+	Headers http.Header
+
+	Response *chat.ModelResponse
+	// Reply as if the vendor's content filter blocked the completion:
+	//   OpenAI:    finish_reason "content_filter" with Azure filter results
+	//   DashScope: 400 "data_inspection_failed"
+	//   Anthropic: 400 "Output blocked by content filtering policy"
+	//   Ollama:    no content filter, an empty completion
+	ContentFilter bool
+
+	// Wait before sending the response headers, the wait is interrupted if the
+	// client cancels the request.
+	Delay time.Duration
+	// The text chunks of a streaming response, default is the text split by
+	// words. Only used if the client requests streaming.
+	Chunks []string
+	// Wait between streaming chunks
+	ChunkDelay time.Duration
+}
+
+// ToolCall is a tool call of a scripted reply. Id is generated if empty.
+type ToolCall struct {
+	Id        string
+	Name      string
+	Arguments map[string]any
+}
+
+func (r Reply) statusCode() int {
+	if r.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return r.StatusCode
+}
+
+// TextReply returns a reply of an assistant text message.
+func TextReply(text string) Reply {
+	return Reply{Response: &chat.ModelResponse{
+		Messages: []chat.Message{{
+			Role:     chat.RoleAssistant,
+			Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: text}},
+		}},
+		FinishReason: "stop",
+	}}
+}
+
+// ToolCallReply returns a reply of an assistant message calls tools.
+func ToolCallReply(calls ...ToolCall) Reply {
+	var message = chat.Message{Role: chat.RoleAssistant}
+	for _, call := range calls {
+		message.Contents = append(message.Contents, chat.ContentBlock{
+			Type:       chat.ContentTypeToolCall,
+			ToolCallId: call.Id,
+			ToolName:   call.Name,
+			Arguments:  call.Arguments,
+		})
+	}
+	return Reply{Response: &chat.ModelResponse{
+		Messages:     []chat.Message{message},
+		FinishReason: "tool_calls",
+	}}
+}
+
+// LengthReply returns a reply of a text truncated by max tokens.
+func LengthReply(text string) Reply {
+	var reply = TextReply(text)
+	reply.Response.FinishReason = "length"
+	return reply
+}
+
+// ErrorReply returns a reply of a vendor error.
+func ErrorReply(statusCode int, message string) Reply {
+	return Reply{StatusCode: statusCode, ErrorMessage: message}
+}
+
+// RateLimitReply returns a 429 reply with Retry-After header.
+func RateLimitReply(retryAfter time.Duration) Reply {
+	var reply = ErrorReply(http.StatusTooManyRequests, "Rate limit reached, please try again later")
+	reply.Headers = http.Header{}
+	reply.Headers.Set("Retry-After", formatSeconds(retryAfter))
+	return reply
+}
+
+// ServerErrorReply returns a 500 reply.
+func ServerErrorReply() Reply {
+	return ErrorReply(http.StatusInternalServerError, "The server had an error while processing your request")
+}
+
+// ContentFilterReply returns a reply blocked by the vendor's content filter,
+// see Reply.ContentFilter.
+func ContentFilterReply() Reply {
+	return Reply{ContentFilter: true}
+}
+
+// WithUsage sets the token usage of the reply.
+func (r Reply) WithUsage(inputTokens int, outputTokens int) Reply {
+	if r.Response != nil {
+		var response = *r.Response
+		response.Usage.InputTokens = inputTokens
+		response.Usage.OutputTokens = outputTokens
+		r.Response = &response
+	}
+	return r
+}
+
+// WithReasoning prepends a reasoning block to the reply. It is replied as
+// reasoning_content by OpenAI and DashScope (DeepSeek and QwQ), a thinking
+// block by Anthropic, or a redacted_thinking block if redacted is not empty,
+// and the thinking field by Ollama.
+func (r Reply) WithReasoning(text string, signature string, redacted string) Reply {
+	if r.Response != nil && len(r.Response.Messages) > 0 {
+		var response = *r.Response
+		var message = response.Messages[0].Copy()
+		message.Contents = append([]chat.ContentBlock{{
+			Type:              chat.ContentTypeReasoning,
+			Text:              text,
+			Signature:         signature,
+			RedactedReasoning: redacted,
+		}}, message.Contents...)
+		response.Messages = append([]chat.Message{message}, response.Messages[1:]...)
+		r.Response = &response
+	}
+	return r
+}
+
+// WithAudio appends an assistant audio block to the reply, it is replied as
+// the audio of the message by OpenAI. Audio is not streamed.
+func (r Reply) WithAudio(audioId string, data []byte, transcript string) Reply {
+	if r.Response != nil && len(r.Response.Messages) > 0 {
+		var response = *r.Response
+		var message = response.Messages[0].Copy()
+		message.Contents = append(message.Contents, chat.ContentBlock{
+			Type:       chat.ContentTypeAudio,
+			AudioId:    audioId,
+			Data:       data,
+			Transcript: transcript,
+		})
+		response.Messages = append([]chat.Message{message}, response.Messages[1:]...)
+		r.Response = &response
+	}
+	return r
+}
+
+// WithDelay sets the delay before the reply is sent.
+func (r Reply) WithDelay(delay time.Duration) Reply {
+	r.Delay = delay
+	return r
+}
+
+// WithChunks sets the text chunks of a streaming reply.
+func (r Reply) WithChunks(chunkDelay time.Duration, chunks ...string) Reply {
+	r.ChunkDelay = chunkDelay
+	r.Chunks = chunks
+	return r
+}
@@ -0,0 +1,521 @@
+// Package vendortest provides in-process fake servers speak the wire protocols
+// of the vendors, for testing code built on go-aigc without network and
+// credentials.
+//
+// A Server is scripted with replies: texts, tool calls, errors (429, 500,
+// content filter), streaming chunks and delays. It records the received
+// requests for assertions. Point a model to the server by
+// aigc.WithEndpoint(server.Endpoint()), the real translation code of the
+// model is exercised.
+package vendortest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/Pooh-Mucho/go-aigc/chat"
+)
+
+type Protocol int
+
+const (
+	// OpenAI chat completions API, also Azure OpenAI
+	ProtocolOpenAI Protocol = iota
+	// Anthropic messages API
+	ProtocolAnthropic
+	// Ollama chat API
+	ProtocolOllama
+	// Aliyun DashScope OpenAI compatible API
+	ProtocolDashScope
+	// Amazon Bedrock InvokeModel API of Claude and Llama models
+	ProtocolBedrock
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolOpenAI:
+		return "OpenAI"
+	case ProtocolAnthropic:
+		return "Anthropic"
+	case ProtocolOllama:
+		return "Ollama"
+	case ProtocolDashScope:
+		return "DashScope"
+	case ProtocolBedrock:
+		return "Bedrock"
+	}
+	return "Protocol(" + strconv.Itoa(int(p)) + ")"
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	// The json body
+	Json map[string]any
+}
+
+// Decode decodes the json body into v.
+func (r Request) Decode(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Server is a fake vendor server. Replies are sent in the order they are
+// enqueued, one per request.
+type Server struct {
+	Protocol Protocol
+	// The base url of the server, e.g. "http://127.0.0.1:52341"
+	URL string
+	// If set, requests without the api key are rejected with 401
+	ApiKey string
+	// Sent when no reply is enqueued, default is a 500 error
+	Fallback *Reply
+
+	lock     sync.Mutex
+	replies  []Reply
+	requests []Request
+	sequence int
+	server   *httptest.Server
+	encoder  protocolEncoder
+}
+
+// protocolEncoder translates replies to the wire format of a protocol.
+type protocolEncoder interface {
+	path() string
+	accepts(path string) bool
+	authorized(request *http.Request, apiKey string) bool
+	validate(request *http.Request) (int, string)
+	stream(body map[string]any) bool
+	writeResponse(w http.ResponseWriter, c *replyContext)
+	writeStream(w http.ResponseWriter, c *replyContext)
+	writeError(w http.ResponseWriter, statusCode int, message string)
+	writeContentFilter(w http.ResponseWriter, c *replyContext)
+}
+
+// NewServer starts a fake server of the protocol, Close it when done.
+func NewServer(protocol Protocol) *Server {
+	var s = &Server{Protocol: protocol}
+
+	switch protocol {
+	case ProtocolOpenAI:
+		s.encoder = openaiEncoder{}
+	case ProtocolDashScope:
+		s.encoder = openaiEncoder{dashScope: true}
+	case ProtocolAnthropic:
+		s.encoder = anthropicEncoder{}
+	case ProtocolOllama:
+		s.encoder = ollamaEncoder{}
+	case ProtocolBedrock:
+		s.encoder = bedrockEncoder{}
+	default:
+		panic(fmt.Sprintf("vendortest: unknown protocol %d", protocol))
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Endpoint returns the endpoint for aigc.WithEndpoint.
+func (s *Server) Endpoint() string {
+	if s.Protocol == ProtocolOllama || s.Protocol == ProtocolBedrock {
+		return s.URL
+	}
+	return s.URL + s.encoder.path()
+}
+
+// Transport returns an http transport sends requests of any host to the
+// server, for aigc.WithHttpTransport. It is used for models do not support
+// endpoint override.
+func (s *Server) Transport() http.RoundTripper {
+	var target, _ = url.Parse(s.URL)
+	return &redirectTransport{target: target, transport: s.server.Client().Transport}
+}
+
+// Enqueue appends scripted replies.
+func (s *Server) Enqueue(replies ...Reply) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Pending returns the number of enqueued replies not sent.
+func (s *Server) Pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.replies)
+}
+
+// Requests returns the received requests.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var z = make([]Request, len(s.requests))
+	copy(z, s.requests)
+	return z
+}
+
+// LastRequest returns the last received request, ok is false if there is no
+// request.
+func (s *Server) LastRequest() (request Request, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.requests) == 0 {
+		return Request{}, false
+	}
+	return s.requests[len(s.requests)-1], true
+}
+
+// Reset clears enqueued replies and received requests.
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.replies = nil
+	s.requests = nil
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) next(request Request) (Reply, int, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.requests = append(s.requests, request)
+	s.sequence++
+	if len(s.replies) > 0 {
+		var reply = s.replies[0]
+		s.replies = s.replies[1:]
+		return reply, s.sequence, true
+	}
+	if s.Fallback != nil {
+		return *s.Fallback, s.sequence, true
+	}
+	return Reply{}, s.sequence, false
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var request = Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone()}
+
+	request.Body, err = io.ReadAll(r.Body)
+	if err != nil {
+		s.encoder.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(request.Body) > 0 {
+		err = json.Unmarshal(request.Body, &request.Json)
+		if err != nil {
+			s.encoder.writeError(w, http.StatusBadRequest, "invalid json body: "+err.Error())
+			return
+		}
+	}
+
+	if r.Method != http.MethodPost || !s.encoder.accepts(r.URL.Path) {
+		s.encoder.writeError(w, http.StatusNotFound, fmt.Sprintf("%s %s not found", r.Method, r.URL.Path))
+		return
+	}
+	if !s.encoder.authorized(r, s.ApiKey) {
+		s.encoder.writeError(w, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	if statusCode, message := s.encoder.validate(r); statusCode != 0 {
+		s.encoder.writeError(w, statusCode, message)
+		return
+	}
+
+	var reply, sequence, ok = s.next(request)
+	if !ok {
+		s.encoder.writeError(w, http.StatusInternalServerError, "vendortest: no reply enqueued")
+		return
+	}
+
+	if reply.Delay > 0 && !sleep(r, reply.Delay) {
+		return
+	}
+
+	for name, values := range reply.Headers {
+		w.Header()[name] = values
+	}
+
+	if reply.statusCode() != http.StatusOK {
+		s.encoder.writeError(w, reply.statusCode(), reply.ErrorMessage)
+		return
+	}
+
+	var c = newReplyContext(s.Protocol, reply, request, sequence)
+	switch {
+	case reply.ContentFilter:
+		s.encoder.writeContentFilter(w, c)
+	case s.encoder.stream(request.Json):
+		s.encoder.writeStream(w, c)
+	default:
+		s.encoder.writeResponse(w, c)
+	}
+}
+
+// sleep waits for the duration, returns false if the request is cancelled.
+func sleep(r *http.Request, duration time.Duration) bool {
+	var timer = time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+type redirectTransport struct {
+	target    *url.URL
+	transport http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var z = request.Clone(request.Context())
+	z.URL.Scheme = t.target.Scheme
+	z.URL.Host = t.target.Host
+	z.Host = t.target.Host
+	return t.transport.RoundTrip(z)
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, value any) {
+	var buffer bytes.Buffer
+	var encoder = json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buffer.Len()))
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(buffer.Bytes())
+}
+
+// eventWriter writes server-sent events or newline delimited json.
+type eventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	delay   time.Duration
+}
+
+func newEventWriter(w http.ResponseWriter, c *replyContext, contentType string) *eventWriter {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	var flusher, _ = w.(http.Flusher)
+	return &eventWriter{w: w, flusher: flusher, delay: c.reply.ChunkDelay}
+}
+
+// event writes a server-sent event, event name is omitted if empty. data is
+// encoded as json unless it is a string.
+func (e *eventWriter) event(name string, data any) {
+	var buffer bytes.Buffer
+	if name != "" {
+		buffer.WriteString("event: ")
+		buffer.WriteString(name)
+		buffer.WriteByte('\n')
+	}
+	buffer.WriteString("data: ")
+	if s, ok := data.(string); ok {
+		buffer.WriteString(s)
+	} else {
+		_ = json.NewEncoder(&buffer).Encode(data)
+		buffer.Truncate(buffer.Len() - 1)
+	}
+	buffer.WriteString("\n\n")
+	e.write(buffer.Bytes())
+}
+
+// line writes a line of newline delimited json.
+func (e *eventWriter) line(data any) {
+	var buffer bytes.Buffer
+	_ = json.NewEncoder(&buffer).Encode(data)
+	e.write(buffer.Bytes())
+}
+
+func (e *eventWriter) write(data []byte) {
+	_, _ = e.w.Write(data)
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
+
+// pause waits between chunks.
+func (e *eventWriter) pause() {
+	if e.delay > 0 {
+		time.Sleep(e.delay)
+	}
+}
+
+func formatSeconds(d time.Duration) string {
+	var seconds = int((d + time.Second - 1) / time.Second)
+	return strconv.Itoa(seconds)
+}
+
+// replyContext is a reply resolved for a request: tool call ids, finish
+// reason and usage are filled.
+type replyContext struct {
+	reply   Reply
+	request Request
+	model   string
+	id      string
+	created time.Time
+	text    string
+	calls   []ToolCall
+	// Reasoning blocks, before the text
+	reasoning []chat.ContentBlock
+	// Assistant audio, only replied by OpenAI
+	audio    *chat.ContentBlock
+	finish   finishReason
+	usage    usage
+	chunks   []string
+	sequence int
+}
+
+type finishReason int
+
+const (
+	finishStop finishReason = iota
+	finishLength
+	finishToolCalls
+	finishContentFilter
+)
+
+type usage struct {
+	InputTokens              int
+	OutputTokens             int
+	CachedInputTokens        int
+	CacheCreationInputTokens int
+	ReasoningTokens          int
+}
+
+func newReplyContext(protocol Protocol, reply Reply, request Request, sequence int) *replyContext {
+	var c = &replyContext{reply: reply, request: request, created: time.Now(), sequence: sequence}
+
+	c.model, _ = request.Json["model"].(string)
+
+	if reply.Response != nil {
+		for _, message := range reply.Response.Messages {
+			for _, content := range message.Contents {
+				switch content.Type {
+				case chat.ContentTypeText:
+					c.text += content.Text
+				case chat.ContentTypeReasoning:
+					c.reasoning = append(c.reasoning, content)
+				case chat.ContentTypeAudio:
+					var audio = content
+					c.audio = &audio
+				case chat.ContentTypeToolCall:
+					var call = ToolCall{Id: content.ToolCallId, Name: content.ToolName, Arguments: content.Arguments}
+					if call.Id == "" {
+						call.Id = toolCallId(protocol, sequence, len(c.calls))
+					}
+					if call.Arguments == nil {
+						call.Arguments = map[string]any{}
+					}
+					c.calls = append(c.calls, call)
+				}
+			}
+		}
+		c.usage = usage{
+			InputTokens:              reply.Response.Usage.InputTokens,
+			OutputTokens:             reply.Response.Usage.OutputTokens,
+			CachedInputTokens:        reply.Response.Usage.CachedInputTokens,
+			CacheCreationInputTokens: reply.Response.Usage.CacheCreationInputTokens,
+			ReasoningTokens:          reply.Response.Usage.ReasoningTokens,
+		}
+		switch reply.Response.FinishReason.Type() {
+		case chat.FinishReasonLength:
+			c.finish = finishLength
+		case chat.FinishReasonToolCalls:
+			c.finish = finishToolCalls
+		case chat.FinishReasonContentFilter:
+			c.finish = finishContentFilter
+		default:
+			if len(c.calls) > 0 {
+				c.finish = finishToolCalls
+			}
+		}
+	}
+	if reply.ContentFilter {
+		c.finish = finishContentFilter
+	}
+
+	// Rough estimation if the usage is not scripted, 4 bytes per token
+	if c.usage.InputTokens == 0 {
+		c.usage.InputTokens = len(request.Body)/4 + 1
+	}
+	if c.usage.OutputTokens == 0 {
+		c.usage.OutputTokens = len(strings.Fields(c.text)) + 20*len(c.calls) + 1
+	}
+
+	c.chunks = reply.Chunks
+	if len(c.chunks) == 0 && c.text != "" {
+		c.chunks = splitWords(c.text)
+	}
+	return c
+}
+
+// reasoningText returns the text of the reasoning blocks.
+func (c *replyContext) reasoningText() string {
+	var text string
+	for _, block := range c.reasoning {
+		text += block.Text
+	}
+	return text
+}
+
+func toolCallId(protocol Protocol, sequence int, index int) string {
+	switch protocol {
+	case ProtocolAnthropic, ProtocolBedrock:
+		return fmt.Sprintf("toolu_%04d%02d", sequence, index)
+	case ProtocolOllama:
+		return ""
+	default:
+		return fmt.Sprintf("call_%04d%02d", sequence, index)
+	}
+}
+
+// splitWords splits text to chunks, each chunk is a word with its leading
+// spaces.
+func splitWords(text string) []string {
+	var chunks []string
+	var start = 0
+	for i := 1; i < len(text); i++ {
+		if text[i] == ' ' && text[i-1] != ' ' {
+			chunks = append(chunks, text[start:i])
+			start = i
+		}
+	}
+	return append(chunks, text[start:])
+}
+
+func argumentsJson(arguments map[string]any) string {
+	var buffer bytes.Buffer
+	var encoder = json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(arguments)
+	return strings.TrimRight(buffer.String(), "\n")
+}
+
+func bearerToken(request *http.Request) string {
+	var auth = request.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return auth[7:]
+	}
+	return ""
+}
@@ -0,0 +1,398 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/Pooh-Mucho/go-aigc"
+	"github.com/Pooh-Mucho/go-aigc/chat"
+	"github.com/Pooh-Mucho/go-aigc/vendortest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type protocolCase struct {
+	protocol vendortest.Protocol
+	modelId  aigc.ModelId
+	options  func(server *vendortest.Server) []aigc.ModelOptionFunc
+}
+
+var protocolCases = []protocolCase{
+	{
+		protocol: vendortest.ProtocolOpenAI,
+		modelId:  chat.Models.OpenAIGpt4oMini,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.OpenAI),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+	{
+		protocol: vendortest.ProtocolOpenAI,
+		modelId:  chat.Models.OpenAIGpt4o,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Microsoft),
+				aigc.WithEndpoint(server.URL),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+	{
+		protocol: vendortest.ProtocolAnthropic,
+		modelId:  chat.Models.AnthropicClaude3Haiku,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Anthropic),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+	{
+		protocol: vendortest.ProtocolOllama,
+		modelId:  "llama3.1:8b",
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Ollama),
+				aigc.WithEndpoint(server.Endpoint()),
+			}
+		},
+	},
+	{
+		protocol: vendortest.ProtocolDashScope,
+		modelId:  chat.Models.QwenPlus,
+		options: func(server *vendortest.Server) []aigc.ModelOptionFunc {
+			return []aigc.ModelOptionFunc{
+				aigc.WithVendor(aigc.Vendors.Alibaba),
+				aigc.WithEndpoint(server.Endpoint()),
+				aigc.WithApiKey("test-key"),
+			}
+		},
+	},
+}
+
+func newModel(t *testing.T, c protocolCase) (*vendortest.Server, chat.Model) {
+	var server = vendortest.NewServer(c.protocol)
+	t.Cleanup(server.Close)
+
+	var model, err = chat.NewModel(c.modelId, c.options(server)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, model
+}
+
+func forEachProtocol(t *testing.T, fn func(t *testing.T, c protocolCase)) {
+	for _, c := range protocolCases {
+		t.Run(c.protocol.String()+"_"+string(c.modelId), func(t *testing.T) {
+			fn(t, c)
+		})
+	}
+}
+
+func newUserRequest(text string) *chat.ModelRequest {
+	return &chat.ModelRequest{Messages: []chat.Message{{
+		Role:     chat.RoleUser,
+		Contents: []chat.ContentBlock{{Type: chat.ContentTypeText, Text: text}},
+	}}}
+}
+
+var toolAdd = chat.Tool{
+	Name:        "add",
+	Description: "Add two numbers",
+	Parameters: chat.ToolParameters{
+		Properties: []aigc.JsonSchemaProperty{
+			{Name: "x", Type: aigc.JsonSchema{Type: aigc.JsonNumber}},
+			{Name: "y", Type: aigc.JsonSchema{Type: aigc.JsonNumber}},
+		},
+		Required: []string{"x", "y"},
+	},
+	Function: func(parameters map[string]any) (any, error) {
+		return parameters["x"].(float64) + parameters["y"].(float64), nil
+	},
+}
+
+func Test_VendorTest_Text(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, c protocolCase) {
+		var server, model = newModel(t, c)
+		server.Enqueue(vendortest.TextReply("Hello! How can I help you?").WithUsage(12, 8))
+
+		var response, err = model.Complete(context.Background(), newUserRequest("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.FinishReason.Type() != chat.FinishReasonStop {
+			t.Errorf("finish reason: %s", response.FinishReason)
+		}
+		if response.Messages[0].Contents[0].Text != "Hello! How can I help you?" {
+			t.Errorf("unexpected response: %v", response)
+		}
+		if response.Usage.InputTokens != 12 || response.Usage.OutputTokens != 8 {
+			t.Errorf("unexpected usage: %+v", response.Usage)
+		}
+
+		var request, ok = server.LastRequest()
+		if !ok || !strings.Contains(string(request.Body), "hello") {
+			t.Errorf("unexpected request: %s", request.Body)
+		}
+	})
+}
+
+func Test_VendorTest_Tool_Calls(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, c protocolCase) {
+		var server, model = newModel(t, c)
+		server.Enqueue(
+			vendortest.ToolCallReply(
+				vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 1, "y": 2}},
+				vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 3, "y": 4}},
+			),
+			vendortest.TextReply("1 + 2 = 3, 3 + 4 = 7"),
+		)
+
+		var request = newUserRequest("calculate 1 + 2 and 3 + 4")
+		request.Tools = []chat.Tool{toolAdd}
+		var executor = chat.ToolExecutor{Model: model, InitialRequest: request}
+		for {
+			var ok, err = executor.Execute(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				break
+			}
+		}
+
+		if executor.Roundtrips() != 2 || server.Pending() != 0 {
+			t.Fatalf("roundtrips: %d, pending: %d", executor.Roundtrips(), server.Pending())
+		}
+		var first = executor.GetRoundtrip(0).Response
+		if first.FinishReason.Type() != chat.FinishReasonToolCalls || len(first.Messages[0].Contents) != 2 {
+			t.Fatalf("unexpected tool call response: %v", first)
+		}
+
+		// The tool results are sent back
+		var requests = server.Requests()
+		if len(requests) != 2 || !strings.Contains(string(requests[1].Body), "7") {
+			t.Fatalf("unexpected second request: %s", requests[1].Body)
+		}
+	})
+}
+
+func Test_VendorTest_Errors(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, c protocolCase) {
+		var server, model = newModel(t, c)
+		server.Enqueue(vendortest.RateLimitReply(time.Second), vendortest.ServerErrorReply())
+
+		var _, err = model.Complete(context.Background(), newUserRequest("hello"))
+		if err == nil || !strings.Contains(err.Error(), "429") {
+			t.Errorf("expected 429, got %v", err)
+		}
+		_, err = model.Complete(context.Background(), newUserRequest("hello"))
+		if err == nil || !strings.Contains(err.Error(), "500") {
+			t.Errorf("expected 500, got %v", err)
+		}
+		// Nothing enqueued
+		_, err = model.Complete(context.Background(), newUserRequest("hello"))
+		if err == nil {
+			t.Error("expected error without reply")
+		}
+	})
+}
+
+func Test_VendorTest_Content_Filter(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, c protocolCase) {
+		var server, model = newModel(t, c)
+		server.Enqueue(vendortest.ContentFilterReply())
+
+		var response, err = model.Complete(context.Background(), newUserRequest("hello"))
+		switch c.protocol {
+		case vendortest.ProtocolOpenAI:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.FinishReason.Type() != chat.FinishReasonContentFilter || response.ContentFilterResult == "" {
+				t.Errorf("unexpected response: %+v", response)
+			}
+			if len(response.ContentFilters) != 1 || !response.ContentFilters[0].Filtered ||
+				response.ContentFilters[0].Source != chat.ContentFilterCompletion {
+				t.Errorf("unexpected content filters: %+v", response.ContentFilters)
+			}
+		case vendortest.ProtocolAnthropic, vendortest.ProtocolDashScope:
+			if err == nil || !strings.Contains(err.Error(), "400") {
+				t.Errorf("expected 400, got %v", err)
+			}
+		case vendortest.ProtocolOllama:
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func Test_VendorTest_Delay(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, c protocolCase) {
+		var server, model = newModel(t, c)
+		server.Enqueue(vendortest.TextReply("slow").WithDelay(time.Second))
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		var _, err = model.Complete(ctx, newUserRequest("hello"))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+}
+
+func Test_VendorTest_Api_Key(t *testing.T) {
+	var server = vendortest.NewServer(vendortest.ProtocolOpenAI)
+	defer server.Close()
+	server.ApiKey = "right-key"
+	server.Enqueue(vendortest.TextReply("hello"))
+
+	var model, _ = chat.NewModel(chat.Models.OpenAIGpt4oMini,
+		aigc.WithEndpoint(server.Endpoint()), aigc.WithApiKey("wrong-key"))
+	var _, err = model.Complete(context.Background(), newUserRequest("hello"))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401, got %v", err)
+	}
+}
+
+// The models do not stream yet, streaming is tested by raw http requests.
+func Test_VendorTest_Streaming(t *testing.T) {
+	var cases = []struct {
+		protocol vendortest.Protocol
+		path     string
+		body     string
+		header   http.Header
+		expected []string
+	}{
+		{
+			protocol: vendortest.ProtocolOpenAI,
+			path:     "/v1/chat/completions",
+			body:     `{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true}}`,
+			header:   http.Header{"Authorization": {"Bearer key"}},
+			expected: []string{`"content":"Hello"`, `"content":" streaming"`, `"content":" world"`, `"finish_reason":"stop"`, `"usage":`, "data: [DONE]"},
+		},
+		{
+			protocol: vendortest.ProtocolDashScope,
+			path:     "/compatible-mode/v1/chat/completions",
+			body:     `{"model":"qwen-plus","stream":true}`,
+			header:   http.Header{"Authorization": {"Bearer key"}},
+			expected: []string{`"content":"Hello"`, `"content":" world"`, "data: [DONE]"},
+		},
+		{
+			protocol: vendortest.ProtocolAnthropic,
+			path:     "/v1/messages",
+			body:     `{"model":"claude-3-haiku-20240307","stream":true}`,
+			header:   http.Header{"X-Api-Key": {"key"}, "Anthropic-Version": {"2023-06-01"}},
+			expected: []string{"event: message_start", `"text":"Hello"`, `"text":" world"`, `"stop_reason":"end_turn"`, "event: message_stop"},
+		},
+		{
+			protocol: vendortest.ProtocolOllama,
+			path:     "/api/chat",
+			body:     `{"model":"llama3.1"}`,
+			expected: []string{`"content":"Hello"`, `"content":" world"`, `"done":true`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.protocol.String(), func(t *testing.T) {
+			var server = vendortest.NewServer(c.protocol)
+			defer server.Close()
+			server.Enqueue(vendortest.TextReply("Hello streaming world").WithChunks(time.Millisecond, "Hello", " streaming", " world"))
+
+			var request, _ = http.NewRequest(http.MethodPost, server.URL+c.path, strings.NewReader(c.body))
+			for name, values := range c.header {
+				request.Header[name] = values
+			}
+			var response, err = http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			var lines []string
+			var scanner = bufio.NewScanner(response.Body)
+			for scanner.Scan() {
+				if scanner.Text() != "" {
+					lines = append(lines, scanner.Text())
+				}
+			}
+			var text = strings.Join(lines, "\n")
+
+			var position = 0
+			for _, expected := range c.expected {
+				var index = strings.Index(text[position:], expected)
+				if index < 0 {
+					t.Fatalf("%s not found in order:\n%s", expected, text)
+				}
+				position += index
+			}
+		})
+	}
+}
+
+// Bedrock models do not support endpoint override, the requests are sent to
+// the server by its transport.
+func Test_VendorTest_Bedrock(t *testing.T) {
+	var modelIds = []aigc.ModelId{chat.Models.AnthropicClaude35Sonnet_20240620, chat.Models.BedrockLlama31_70B}
+	for _, modelId := range modelIds {
+		t.Run(string(modelId), func(t *testing.T) {
+			var server = vendortest.NewServer(vendortest.ProtocolBedrock)
+			t.Cleanup(server.Close)
+
+			var model, err = chat.NewModel(modelId,
+				aigc.WithVendor(aigc.Vendors.Amazon),
+				aigc.WithRegion("us-west-2"),
+				aigc.WithAccessKeySecretKey("test-access-key", "test-secret-key"),
+				aigc.WithHttpTransport(server.Transport()),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.Enqueue(
+				vendortest.ToolCallReply(
+					vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 1, "y": 2}},
+					vendortest.ToolCall{Name: "add", Arguments: map[string]any{"x": 3, "y": 4}},
+				),
+				vendortest.TextReply("1 + 2 = 3, 3 + 4 = 7").WithUsage(12, 8),
+			)
+
+			var request = newUserRequest("calculate 1 + 2 and 3 + 4")
+			request.Tools = []chat.Tool{toolAdd}
+			var executor = chat.ToolExecutor{Model: model, InitialRequest: request}
+			for {
+				var ok, err = executor.Execute(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok {
+					break
+				}
+			}
+
+			var first = executor.GetRoundtrip(0).Response
+			if first.FinishReason.Type() != chat.FinishReasonToolCalls || len(first.Messages[0].Contents) != 2 {
+				t.Fatalf("unexpected tool call response: %v", first)
+			}
+			var last = executor.LastRoundtrip().Response
+			if last.Messages[0].Contents[0].Text != "1 + 2 = 3, 3 + 4 = 7" {
+				t.Errorf("unexpected response: %v", last)
+			}
+			if last.Usage.InputTokens != 12 || last.Usage.OutputTokens != 8 {
+				t.Errorf("unexpected usage: %+v", last.Usage)
+			}
+
+			var requests = server.Requests()
+			if len(requests) != 2 || !strings.HasPrefix(requests[0].Path, "/model/") ||
+				!strings.Contains(string(requests[1].Body), "7") {
+				t.Fatalf("unexpected requests: %+v", requests)
+			}
+		})
+	}
+}